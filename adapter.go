@@ -0,0 +1,50 @@
+package gitrows
+
+import (
+	"context"
+	"os"
+)
+
+// ReaderFuncs is the Reader calling the functions, i.e: to stub the reads in the unit test of the component
+// which depends on Reader, without the repository. The nil GetFunc returns os.ErrNotExist for every key,
+// and the nil ListFunc returns no entry.
+type ReaderFuncs struct {
+	GetFunc  func(ctx context.Context, key string, opts ...GetOpt) (data []byte, err error)
+	ListFunc func(ctx context.Context, opts ...ListOpt) (entries Entries, err error)
+}
+
+var _ Reader = ReaderFuncs{}
+
+func (r ReaderFuncs) Get(ctx context.Context, key string, opts ...GetOpt) (data []byte, err error) {
+	if r.GetFunc == nil {
+		return nil, wrapError(OpGet, key, os.ErrNotExist)
+	}
+
+	return r.GetFunc(ctx, key, opts...)
+}
+
+func (r ReaderFuncs) List(ctx context.Context, opts ...ListOpt) (entries Entries, err error) {
+	if r.ListFunc == nil {
+		return &entriesImpl{kvs: make([]KV, 0)}, nil
+	}
+
+	return r.ListFunc(ctx, opts...)
+}
+
+// NopWriter is the Writer which discards every write, i.e: for the test or the dry run of the component
+// which depends on Writer. Every write succeeds with empty commit hash, and Upsert reports nothing is changed.
+type NopWriter struct{}
+
+var _ Writer = NopWriter{}
+
+func (NopWriter) Create(ctx context.Context, key string, data []byte, opts ...CreateOpt) (commitHashString string, err error) {
+	return "", nil
+}
+
+func (NopWriter) Upsert(ctx context.Context, key string, data []byte, opts ...UpsertOpt) (commitHashString string, changed bool, err error) {
+	return "", false, nil
+}
+
+func (NopWriter) Delete(ctx context.Context, key string, opts ...DeleteOpt) (commitHashString string, err error) {
+	return "", nil
+}
@@ -0,0 +1,64 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestReaderFuncs(t *testing.T) {
+	ctx := context.TODO()
+
+	var reader gitrows.Reader = gitrows.ReaderFuncs{}
+	_, err := reader.Get(ctx, "a.txt")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+	entries, err := reader.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries.KVs())
+	assert.False(t, entries.Truncated())
+
+	reader = gitrows.ReaderFuncs{
+		GetFunc: func(ctx context.Context, key string, opts ...gitrows.GetOpt) ([]byte, error) {
+			return []byte("value of " + key), nil
+		},
+	}
+
+	data, err := reader.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "value of a.txt", string(data))
+}
+
+func TestNopWriter(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	var writer gitrows.Writer = gitrows.NopWriter{}
+	commitHash, err := writer.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+	assert.Empty(t, commitHash)
+
+	_, changed, err := writer.Upsert(ctx, "a.txt", []byte("b"))
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	_, err = writer.Delete(ctx, "a.txt")
+	require.NoError(t, err)
+
+	// the DB is both sides
+	db := newTestDB(t, remote)
+	writer = db
+
+	_, err = writer.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	var reader gitrows.Reader = db
+	data, err := reader.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
@@ -0,0 +1,55 @@
+package gitrows
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrInvalidBranch returned when the branch name is not a valid git ref name.
+var ErrInvalidBranch = errors.New("invalid branch name")
+
+// validateBranch normalizes the branch name into the short name (without refs/heads/ prefix),
+// and rejects name which is not allowed by git check-ref-format, so it fails early instead of deep inside go-git.
+func validateBranch(branch string) (string, error) {
+	name := strings.TrimPrefix(branch, plumbing.NewBranchReferenceName("").String())
+
+	switch {
+	case name == "":
+		return "", fmt.Errorf("%w: branch name must not be empty", ErrInvalidBranch)
+
+	case name == "@":
+		return "", fmt.Errorf("%w: branch name must not be '@'", ErrInvalidBranch)
+
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/"):
+		return "", fmt.Errorf("%w: branch '%s' must not start or end with slash", ErrInvalidBranch, branch)
+
+	case strings.HasPrefix(name, "-"):
+		return "", fmt.Errorf("%w: branch '%s' must not start with dash", ErrInvalidBranch, branch)
+
+	case strings.HasSuffix(name, "."):
+		return "", fmt.Errorf("%w: branch '%s' must not end with dot", ErrInvalidBranch, branch)
+
+	case strings.Contains(name, ".."), strings.Contains(name, "//"), strings.Contains(name, "@{"):
+		return "", fmt.Errorf("%w: branch '%s' must not contain '..', '//' or '@{'", ErrInvalidBranch, branch)
+	}
+
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune("~^:?*[\\", r) {
+			return "", fmt.Errorf("%w: branch '%s' must not contain %q", ErrInvalidBranch, branch, r)
+		}
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return "", fmt.Errorf(
+				"%w: branch '%s' must not have component starting with dot or ending with .lock", ErrInvalidBranch, branch,
+			)
+		}
+	}
+
+	return name, nil
+}
@@ -0,0 +1,467 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// ErrBranchNotFound returned when the configured branch doesn't exist in the remote repository
+// and WithRequireExistingBranch is enabled, or the local branch is missing during the checkout after the fetch.
+var ErrBranchNotFound = errors.New("branch not found")
+
+// ErrCommitNotFound returned when the commit (or its parent) is not in the local repository,
+// i.e: it is on another branch, or beyond the depth fetched by the shallow clone.
+var ErrCommitNotFound = errors.New("commit not found")
+
+// ErrDetachedHead returned when HEAD of the local repository is detached (the commit hash instead of the branch),
+// and the checkout still fails after HEAD is re-pointed at the configured branch.
+var ErrDetachedHead = errors.New("detached HEAD")
+
+// ErrWorktreeDirty returned when the worktree of the local repository cannot be reset into the branch,
+// i.e: a directory which is not empty is in the way of the committed file.
+var ErrWorktreeDirty = errors.New("worktree dirty")
+
+// ErrRemoteUnavailable returned when the remote repository cannot be reached during sync,
+// i.e: network error or the git host is down.
+var ErrRemoteUnavailable = errors.New("remote repository unavailable")
+
+// ErrRepositoryNotFound returned when the remote repository doesn't exist on the host (or the credential can't see it),
+// unless WithAllowMissingRemote is enabled. The error is *RepositoryNotFoundError, which is also ErrRemoteUnavailable.
+var ErrRepositoryNotFound = errors.New("remote repository not found")
+
+// ErrReadOnly returned by write commands when the DB is created using WithReadOnly.
+var ErrReadOnly = errors.New("read-only database")
+
+// ErrOutsideSparsePrefix returned when the key is outside the prefix set by WithSparsePrefix.
+var ErrOutsideSparsePrefix = errors.New("key outside sparse prefix")
+
+// ErrKeyIsDirectory returned by Get when the key is the directory of other keys rather than a key,
+// unless GetDirAsList is used.
+var ErrKeyIsDirectory = errors.New("key is a directory")
+
+// ErrCaseCollision returned by write commands when WithCaseCollisionProtection is enabled
+// and the key only differs by case with an existing key.
+var ErrCaseCollision = errors.New("key collides case-insensitively with existing key")
+
+// ErrUnverifiedCommit returned by read commands when WithRejectUnsignedCommits is enabled
+// and the last commit of the key is not signed by the keyring of WithVerifyCommits.
+var ErrUnverifiedCommit = errors.New("unverified commit")
+
+// ErrValueTooLarge returned when the value is larger than the limit set by GetLimit.
+var ErrValueTooLarge = errors.New("value too large")
+
+// ErrPreconditionFailed returned by CreateIf when the predicate returns false,
+// or by Delete with DeleteIfMatch when the key is not at the expected version.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrStaleHead returned by write commands with CreateExpectedHead (or CreateIfAbsentAtCommit), UpsertExpectedHead
+// or DeleteExpectedHead when the branch tip is not the expected commit.
+var ErrStaleHead = errors.New("stale head")
+
+// ErrPushTooLarge returned by write commands with WithMaxPushBytes when the commit is larger than the limit.
+var ErrPushTooLarge = errors.New("push too large")
+
+// ErrPushRejected returned by write commands when WithLinearHistory is enabled and the local commit
+// cannot be replayed on top of the remote branch, i.e: the same key is changed by another writer.
+var ErrPushRejected = errors.New("push rejected")
+
+// ErrPushRejectedByServer returned by write commands when the push is declined by the remote repository itself,
+// i.e: by the pre-receive hook or the protected branch rule. The message of the server is in ServerRejectionError.
+var ErrPushRejectedByServer = errors.New("push rejected by server")
+
+// ErrBudgetExceeded returned by Entries.ToMap when the total size of the values is larger than the budget.
+var ErrBudgetExceeded = errors.New("memory budget exceeded")
+
+// Op is the name of the command which returns the error.
+type Op string
+
+const (
+	OpGet                 Op = "get"
+	OpGetReader           Op = "get reader"
+	OpPutReader           Op = "put reader"
+	OpGetMany             Op = "get many"
+	OpCreate              Op = "create"
+	OpCreateIf            Op = "create if"
+	OpUpsert              Op = "upsert"
+	OpDelete              Op = "delete"
+	OpList                Op = "list"
+	OpKeys                Op = "keys"
+	OpDuplicates          Op = "duplicates"
+	OpPutContentAddressed Op = "put content addressed"
+	OpListModifiedBetween Op = "list modified between"
+	OpListTree            Op = "list tree"
+	OpGetWithCommit       Op = "get with commit"
+	OpWriteToBranches     Op = "write to branches"
+	OpSchemaVersion       Op = "schema version"
+	OpSetSchemaVersion    Op = "set schema version"
+	OpIsUpToDate          Op = "is up to date"
+	OpToMap               Op = "to map"
+	OpMigrate             Op = "migrate"
+	OpMigrateDryRun       Op = "migrate dry run"
+	OpChangelog           Op = "changelog"
+	OpLastCommits         Op = "last commits"
+	OpVerify              Op = "verify"
+	OpPruneLocal          Op = "prune local"
+	OpDeleteRemoteBranch  Op = "delete remote branch"
+	OpBucketGet           Op = "bucket get"
+	OpBucketList          Op = "bucket list"
+	OpBucketPut           Op = "bucket put"
+	OpBucketDelete        Op = "bucket delete"
+	OpDeepenSince         Op = "deepen since"
+	OpDeepenForPath       Op = "deepen for path"
+	OpCompact             Op = "compact"
+	OpFlush               Op = "flush"
+	OpCopyFrom            Op = "copy from"
+	OpKeysInCommit        Op = "keys in commit"
+	OpStatus              Op = "status"
+	OpGetConcat           Op = "get concat"
+	OpListCursor          Op = "list cursor"
+	OpMkdirAll            Op = "mkdir all"
+	OpSnapshotID          Op = "snapshot id"
+	OpListByAuthor        Op = "list by author"
+	OpListWithValues      Op = "list with values"
+	OpResync              Op = "resync"
+	OpBranches            Op = "branches"
+	OpExportSubtree       Op = "export subtree"
+	OpCommitNotes         Op = "commit notes"
+)
+
+// Code classifies the error, so it can be handled programmatically without matching the error message.
+//
+// The underlying errors are mapped into Code as follows:
+//   - CodeNotFound: os.ErrNotExist (key doesn't exist), object.ErrFileNotFound, ErrBranchNotFound, ErrCommitNotFound.
+//   - CodeAlreadyExists: os.ErrExist (Create on existing key).
+//   - CodeConflict: git.ErrNonFastForwardUpdate (remote is not descendant of the local branch), ErrPushRejected,
+//     ErrCaseCollision.
+//   - CodeAuthFailed: transport.ErrAuthenticationRequired, transport.ErrAuthorizationFailed,
+//     transport.ErrInvalidAuthMethod and SSH handshake which unable to authenticate.
+//   - CodeRemoteUnavailable: ErrRemoteUnavailable, ErrRepositoryNotFound, transport.ErrRepositoryNotFound
+//     and network errors (net.Error).
+//   - CodeInvalidKey: ErrInvalidKey, ErrOutsideSparsePrefix, ErrKeyIsDirectory.
+//   - CodeReadOnly: ErrReadOnly.
+//   - CodeValueTooLarge: ErrValueTooLarge, ErrBudgetExceeded, ErrPushTooLarge.
+//   - CodeUnverifiedCommit: ErrUnverifiedCommit.
+//   - CodePreconditionFailed: ErrPreconditionFailed, ErrStaleHead.
+//   - CodeCanceled: context.Canceled and context.DeadlineExceeded.
+//   - CodeLocalState: ErrDetachedHead, ErrWorktreeDirty, which re-cloning (see VerifyRepair) recovers from.
+//   - CodeRejectedByServer: ErrPushRejectedByServer, which retrying the same write doesn't change.
+//   - CodeUnknown: everything else.
+type Code string
+
+const (
+	CodeUnknown            Code = "unknown"
+	CodeNotFound           Code = "not_found"
+	CodeAlreadyExists      Code = "already_exists"
+	CodeConflict           Code = "conflict"
+	CodeAuthFailed         Code = "auth_failed"
+	CodeRemoteUnavailable  Code = "remote_unavailable"
+	CodeInvalidKey         Code = "invalid_key"
+	CodeReadOnly           Code = "read_only"
+	CodeValueTooLarge      Code = "value_too_large"
+	CodeUnverifiedCommit   Code = "unverified_commit"
+	CodePreconditionFailed Code = "precondition_failed"
+	CodeCanceled           Code = "canceled"
+	CodeLocalState         Code = "local_state"
+	CodeRejectedByServer   Code = "rejected_by_server"
+)
+
+// Error is the error returned by all public methods of DBImpl.
+// The message is the same as the wrapped error, and the wrapped error is still accessible using errors.Is or errors.As,
+// i.e: errors.Is(err, os.ErrExist) is still true when Create existing key.
+//
+// To check the Code, use ErrorCode(err) or errors.Is(err, &Error{Code: CodeNotFound}).
+// Op and Key are only compared by errors.Is when they are not empty in the target.
+type Error struct {
+	Code Code
+	Op   Op
+	Key  string
+	Err  error
+}
+
+var _ error = (*Error)(nil)
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s command: %s", e.Op, e.Code)
+	}
+
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return (t.Code == "" || t.Code == e.Code) &&
+		(t.Op == "" || t.Op == e.Op) &&
+		(t.Key == "" || t.Key == e.Key)
+}
+
+// ErrorCode returns the Code of the *Error inside err chain, or CodeUnknown if there is none.
+func ErrorCode(err error) Code {
+	var gitRowsErr *Error
+	if errors.As(err, &gitRowsErr) {
+		return gitRowsErr.Code
+	}
+
+	return CodeUnknown
+}
+
+// wrapError wraps err into *Error with the Code classified from err.
+func wrapError(op Op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var gitRowsErr *Error
+	if errors.As(err, &gitRowsErr) {
+		return err
+	}
+
+	return &Error{
+		Code: codeOf(err),
+		Op:   op,
+		Key:  key,
+		Err:  err,
+	}
+}
+
+// codeOf classifies err into Code, see the documentation of Code for the mapping.
+func codeOf(err error) Code {
+	var multiErr *MultiError
+	if errors.As(err, &multiErr) {
+		return multiErr.code()
+	}
+
+	switch {
+	case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrOutsideSparsePrefix), errors.Is(err, ErrKeyIsDirectory):
+		return CodeInvalidKey
+
+	case errors.Is(err, ErrReadOnly):
+		return CodeReadOnly
+
+	case errors.Is(err, ErrValueTooLarge), errors.Is(err, ErrBudgetExceeded), errors.Is(err, ErrPushTooLarge):
+		return CodeValueTooLarge
+
+	case errors.Is(err, ErrUnverifiedCommit):
+		return CodeUnverifiedCommit
+
+	case errors.Is(err, ErrPreconditionFailed), errors.Is(err, ErrStaleHead):
+		return CodePreconditionFailed
+
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return CodeCanceled
+
+	case errors.Is(err, ErrDetachedHead), errors.Is(err, ErrWorktreeDirty):
+		return CodeLocalState
+
+	case errors.Is(err, ErrPushRejectedByServer):
+		return CodeRejectedByServer
+
+	case errors.Is(err, os.ErrNotExist), errors.Is(err, object.ErrFileNotFound), errors.Is(err, ErrBranchNotFound),
+		errors.Is(err, ErrCommitNotFound):
+		return CodeNotFound
+
+	case errors.Is(err, os.ErrExist):
+		return CodeAlreadyExists
+
+	case errors.Is(err, git.ErrNonFastForwardUpdate), errors.Is(err, ErrPushRejected), errors.Is(err, ErrCaseCollision):
+		return CodeConflict
+
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrInvalidAuthMethod),
+		strings.Contains(err.Error(), "unable to authenticate"):
+		return CodeAuthFailed
+
+	case errors.Is(err, ErrRemoteUnavailable), errors.Is(err, transport.ErrRepositoryNotFound):
+		return CodeRemoteUnavailable
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return CodeRemoteUnavailable
+	}
+
+	return CodeUnknown
+}
+
+// remoteUnavailableError marks the transport error, so errors.Is(err, ErrRemoteUnavailable) is true
+// while the original error is still accessible.
+type remoteUnavailableError struct {
+	err error
+}
+
+func (e *remoteUnavailableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *remoteUnavailableError) Unwrap() error {
+	return e.err
+}
+
+func (e *remoteUnavailableError) Is(target error) bool {
+	return target == ErrRemoteUnavailable
+}
+
+// RepositoryNotFoundError is the ErrRepositoryNotFound of the remote repository at URL.
+type RepositoryNotFoundError struct {
+	URL string
+	err error
+}
+
+var _ error = (*RepositoryNotFoundError)(nil)
+
+func (e *RepositoryNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRepositoryNotFound, e.URL)
+}
+
+func (e *RepositoryNotFoundError) Unwrap() error {
+	return e.err
+}
+
+// Is returns true for ErrRepositoryNotFound, and ErrRemoteUnavailable which it was reported as before.
+func (e *RepositoryNotFoundError) Is(target error) bool {
+	return target == ErrRepositoryNotFound || target == ErrRemoteUnavailable
+}
+
+// remoteError marks err of the remote repository at remoteURL as ErrRemoteUnavailable
+// when it is caused by unreachable remote repository, or as *RepositoryNotFoundError when it doesn't exist.
+func remoteError(remoteURL string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	if errors.Is(err, transport.ErrRepositoryNotFound) {
+		return &RepositoryNotFoundError{URL: remoteURL, err: err}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return &remoteUnavailableError{err: err}
+	}
+
+	return err
+}
+
+// CheckoutError is the error of the checkout of the local branch after the fetch, with the state of the local
+// references to triage it. Err wraps ErrBranchNotFound, ErrDetachedHead or ErrWorktreeDirty.
+type CheckoutError struct {
+	Branch string
+
+	// Head is the target of HEAD (i.e: "refs/heads/master"), or the commit hash when it is detached.
+	Head string
+
+	// BranchHash is the commit of the local branch, empty when it doesn't exist.
+	BranchHash string
+
+	Err error
+}
+
+var _ error = (*CheckoutError)(nil)
+
+func (e *CheckoutError) Error() string {
+	branchHash := e.BranchHash
+	if branchHash == "" {
+		branchHash = "missing"
+	}
+
+	return fmt.Sprintf("cannot checkout branch '%s' (HEAD: %s, local branch: %s): %s", e.Branch, e.Head, branchHash, e.Err)
+}
+
+func (e *CheckoutError) Unwrap() error {
+	return e.Err
+}
+
+// ServerRejectionError is the push declined by the remote repository, see ErrPushRejectedByServer.
+// errors.Is(err, ErrPushRejectedByServer) is true, and the original go-git error is still accessible using errors.Unwrap.
+type ServerRejectionError struct {
+	// Ref is the rejected reference, i.e: "refs/heads/master".
+	Ref string
+
+	// Status is the reason reported by git receive-pack, i.e: "pre-receive hook declined".
+	Status string
+
+	// Message is the text written by the server during the push (the hook stderr shown as "remote:" by git),
+	// one message per line, empty when the server doesn't explain it.
+	Message string
+
+	err error
+}
+
+var _ error = (*ServerRejectionError)(nil)
+
+func (e *ServerRejectionError) Error() string {
+	msg := fmt.Sprintf("%s: %s: %s", ErrPushRejectedByServer, e.Ref, e.Status)
+	if e.Message == "" {
+		return msg
+	}
+
+	return fmt.Sprintf("%s: %s", msg, strings.ReplaceAll(e.Message, "\n", "; "))
+}
+
+func (e *ServerRejectionError) Unwrap() error {
+	return e.err
+}
+
+func (e *ServerRejectionError) Is(target error) bool {
+	return target == ErrPushRejectedByServer
+}
+
+// MultiError collects errors per key from the command that operates on many keys at once,
+// so one failing key doesn't fail the whole batch.
+type MultiError struct {
+	Errors map[string]error
+}
+
+var _ error = (*MultiError)(nil)
+
+func (m *MultiError) Error() string {
+	keys := make([]string, 0, len(m.Errors))
+	for key := range m.Errors {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	msgs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		msgs = append(msgs, fmt.Sprintf("key '%s': %s", key, m.Errors[key]))
+	}
+
+	return fmt.Sprintf("%d error(s) occurred: %s", len(m.Errors), strings.Join(msgs, "; "))
+}
+
+// code returns the Code shared by all errors, or CodeUnknown when they are different.
+func (m *MultiError) code() Code {
+	code := Code("")
+	for _, err := range m.Errors {
+		c := codeOf(err)
+		if code != "" && code != c {
+			return CodeUnknown
+		}
+
+		code = c
+	}
+
+	if code == "" {
+		return CodeUnknown
+	}
+
+	return code
+}
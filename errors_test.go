@@ -0,0 +1,70 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code Code
+	}{
+		{name: "not exist", err: fmt.Errorf("cannot open file: %w", os.ErrNotExist), code: CodeNotFound},
+		{name: "branch not found", err: fmt.Errorf("%w: branch 'x'", ErrBranchNotFound), code: CodeNotFound},
+		{name: "commit not found", err: fmt.Errorf("%w: abc", ErrCommitNotFound), code: CodeNotFound},
+		{name: "exist", err: fmt.Errorf("%w: cannot create 'a'", os.ErrExist), code: CodeAlreadyExists},
+		{name: "non fast forward", err: fmt.Errorf("push: %w", git.ErrNonFastForwardUpdate), code: CodeConflict},
+		{name: "auth required", err: transport.ErrAuthenticationRequired, code: CodeAuthFailed},
+		{name: "authorization", err: transport.ErrAuthorizationFailed, code: CodeAuthFailed},
+		{name: "ssh handshake", err: errors.New("ssh: handshake failed: ssh: unable to authenticate"), code: CodeAuthFailed},
+		{name: "repository not found", err: transport.ErrRepositoryNotFound, code: CodeRemoteUnavailable},
+		{name: "typed repository not found", err: remoteError("file:///missing.git", transport.ErrRepositoryNotFound), code: CodeRemoteUnavailable},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, code: CodeRemoteUnavailable},
+		{name: "invalid key", err: fmt.Errorf("%w: empty", ErrInvalidKey), code: CodeInvalidKey},
+		{name: "read only", err: fmt.Errorf("create command: %w", ErrReadOnly), code: CodeReadOnly},
+		{name: "value too large", err: fmt.Errorf("get command: %w", ErrValueTooLarge), code: CodeValueTooLarge},
+		{name: "budget exceeded", err: fmt.Errorf("to map: %w", ErrBudgetExceeded), code: CodeValueTooLarge},
+		{name: "push too large", err: fmt.Errorf("create command: %w", ErrPushTooLarge), code: CodeValueTooLarge},
+		{name: "precondition failed", err: fmt.Errorf("create if command: %w", ErrPreconditionFailed), code: CodePreconditionFailed},
+		{name: "unverified commit", err: fmt.Errorf("list command: %w", ErrUnverifiedCommit), code: CodeUnverifiedCommit},
+		{name: "canceled", err: fmt.Errorf("clone: %w", context.Canceled), code: CodeCanceled},
+		{name: "deadline", err: context.DeadlineExceeded, code: CodeCanceled},
+		{name: "detached head", err: &CheckoutError{Branch: "master", Err: ErrDetachedHead}, code: CodeLocalState},
+		{name: "worktree dirty", err: fmt.Errorf("checkout: %w", ErrWorktreeDirty), code: CodeLocalState},
+		{name: "rejected by server", err: &ServerRejectionError{Ref: "refs/heads/master", Status: "pre-receive hook declined"}, code: CodeRejectedByServer},
+		{name: "unknown", err: errors.New("something else"), code: CodeUnknown},
+		{
+			name: "multi error same code",
+			err:  &MultiError{Errors: map[string]error{"a": os.ErrNotExist, "b": os.ErrNotExist}},
+			code: CodeNotFound,
+		},
+		{
+			name: "multi error different code",
+			err:  &MultiError{Errors: map[string]error{"a": os.ErrNotExist, "b": ErrInvalidKey}},
+			code: CodeUnknown,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := wrapError(OpGet, "key", test.err)
+			assert.Equal(t, test.code, ErrorCode(err))
+			assert.True(t, errors.Is(err, test.err))
+			assert.True(t, errors.Is(err, &Error{Code: test.code, Op: OpGet}))
+			assert.False(t, errors.Is(err, &Error{Op: OpCreate}))
+			assert.Equal(t, test.err.Error(), err.Error())
+		})
+	}
+
+	assert.Nil(t, wrapError(OpGet, "key", nil))
+}
@@ -2,32 +2,369 @@ package gitrows
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"path"
 	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
+// DB is the read and write commands, implemented by DBImpl.
+// Depend on Reader or Writer instead when the component only needs one side of it.
 type DB interface {
-	Get(ctx context.Context, key string) (data []byte, err error)
+	Reader
+	Writer
+}
+
+// Reader is the read commands of DB, i.e: for the component which only reads the configs,
+// see ReaderFuncs to stub it in the test.
+type Reader interface {
+	Get(ctx context.Context, key string, opts ...GetOpt) (data []byte, err error)
+	List(ctx context.Context, opts ...ListOpt) (entries Entries, err error)
+}
+
+// Writer is the write commands of DB, see NopWriter to stub it in the test.
+type Writer interface {
 	Create(ctx context.Context, key string, data []byte, opts ...CreateOpt) (commitHashString string, err error)
 	Upsert(ctx context.Context, key string, data []byte, opts ...UpsertOpt) (commitHashString string, changed bool, err error)
 	Delete(ctx context.Context, key string, opts ...DeleteOpt) (commitHashString string, err error)
-	List(ctx context.Context, opts ...ListOpt) (entries Entries, err error)
 }
 
 type Entries interface {
 	KVs() []KV
+
+	// Truncated returns true when List stops before all matching entries are returned, because of ListLimit.
+	Truncated() bool
+
+	// ToMap reads all values into map of key and value, i.e: to load all small configs under a prefix at startup.
+	// It stops with ErrBudgetExceeded (and the Key of *Error set to the key which crosses the budget)
+	// when the total size of the values is larger than maxTotalBytes, so one huge value cannot exhaust the memory.
+	// Zero maxTotalBytes means unlimited.
+	ToMap(ctx context.Context, maxTotalBytes int64) (map[string][]byte, error)
 }
 
 type KV interface {
 	Key() string
+
+	// Value returns the reader of the value in the commit captured by the List which returns the KV,
+	// so it is still that value after the later writes or syncs, and it can be read from another goroutine.
 	Value() (io.ReadCloser, error)
 	LastCommit() string
+
+	// LastCommitErr returns the error when the LastCommit cannot be resolved, in which case LastCommit is empty.
+	LastCommitErr() error
+
+	// LastCommitInfo returns the detail of LastCommit, including whether its signature is verified.
+	LastCommitInfo() CommitInfo
+
+	// Mode returns the permission of the committed file, which is either 0644 or 0755 (executable).
+	Mode() os.FileMode
+
+	// BlobHash returns the git blob hash of the committed value, see BlobHash.
+	BlobHash() string
+}
+
+// CommitInfo is the detail of the commit which last modified the key.
+type CommitInfo struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	When        time.Time
+	Message     string
+
+	// Verified is true when the commit signature is valid against the keyring of WithVerifyCommits.
+	Verified bool
+}
+
+// CommitMeta is the detail of the commit passed into the function of WithCommitMessageFunc.
+type CommitMeta struct {
+	// Sizes is the size in bytes of the committed value of each key, zero for the deleted key.
+	Sizes map[string]int64
+
+	// Changed is false when nothing is changed, i.e: empty commit of UpsertAllowEmptyCommit.
+	Changed bool
+
+	// Metadata is the metadata of CreateMeta or UpsertMeta, which is appended as the trailers after the message.
+	Metadata map[string]string
+}
+
+// ChangeType is the kind of change of the key in ChangeEntry.
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeModified ChangeType = "modified"
+	ChangeDeleted  ChangeType = "deleted"
+)
+
+// ChangeEntry is the change of one key in one commit returned by Changelog, KeysInCommit and ListByAuthor.
+type ChangeEntry struct {
+	Key        string
+	ChangeType ChangeType
+	Commit     CommitInfo
+}
+
+// KVWithValue is the entry returned by ListWithValues.
+type KVWithValue struct {
+	KV
+
+	// Data is the whole value when Inlined, otherwise nil and the value must be read from KV.Value.
+	Data    []byte
+	Inlined bool
+}
+
+// TreeNode is a directory or a file returned by ListTree.
+type TreeNode struct {
+	// Name is the last element of the Path, or empty for the root directory.
+	Name string
+
+	// Path is the key of the file, or the key prefix of the directory (without trailing slash).
+	Path  string
+	IsDir bool
+
+	// LastCommit, Size and Mode are only set for the file, see KV.Mode.
+	LastCommit string
+	Size       int64
+	Mode       os.FileMode
+
+	// Children is sorted by Name, and only set for the directory.
+	Children []TreeNode
+}
+
+// MigrationChange is the summary of the rewrite of one key returned by MigrateDryRun.
+type MigrationChange struct {
+	Key     string
+	OldSize int
+	NewSize int
+}
+
+// CreateResult is the detail of the write returned by CreateR.
+type CreateResult struct {
+	CommitHash string
+
+	// BlobHash and BytesWritten are the git blob hash and the size of the committed value,
+	// after WithJSONCanonicalization and WithLineEnding are applied.
+	BlobHash     string
+	BytesWritten int64
+
+	// Attempts is the number of the push, which is more than one when the push is reconciled by WithMergeResolver.
+	Attempts int
+	PushedAt time.Time
+
+	// BytesPushed is the approximated size of the push, since go-git doesn't report the size of the packfile:
+	// the uncompressed size of the commit, and the trees and blobs created by it which are not in its parent.
+	BytesPushed int64
+
+	// Pushed is false when the commit is only committed locally by WithPushEvery, in which case CommitHash is
+	// the local commit, and Attempts and PushedAt are zero.
+	Pushed bool
+}
+
+// UpsertResult is the detail of the write returned by UpsertR, see CreateResult.
+// When nothing is changed, CommitHash is the current HEAD, and Attempts and PushedAt are zero since nothing is pushed.
+// Changed is true only when the content differs from the committed file, while MetadataChanged is true when
+// only the mode of the file is changed (i.e: UpsertFileMode), which is still committed.
+type UpsertResult struct {
+	CommitHash      string
+	Changed         bool
+	MetadataChanged bool
+	BlobHash        string
+	BytesWritten    int64
+	Attempts        int
+	PushedAt        time.Time
+	BytesPushed     int64
+	Pushed          bool
+}
+
+// DeleteResult is the detail of the write returned by DeleteR, see CreateResult.
+type DeleteResult struct {
+	CommitHash  string
+	Attempts    int
+	PushedAt    time.Time
+	BytesPushed int64
+	Pushed      bool
+}
+
+// BlobHash returns the git blob hash of data, the SHA-1 of the "blob <size>\x00" header and data, like `git hash-object`.
+// It is the same as the BlobHash of KV and the write results, as long as data is not changed by the normalization
+// (WithLineEndingPolicy and WithJSONCanonicalization) when it is written.
+func BlobHash(data []byte) string {
+	return plumbing.ComputeHash(plumbing.BlobObject, data).String()
+}
+
+type GetOpt func(*GetConfig) error
+
+type GetConfig struct {
+	maxBytes  int64
+	offset    int64
+	length    int64
+	dirAsList bool
+}
+
+// GetLimit makes Get stop reading and returns ErrValueTooLarge when the value is larger than maxBytes.
+// When used with GetRange, the limit applies to the bytes in the range.
+// Zero means unlimited, which is the default.
+func GetLimit(maxBytes int64) GetOpt {
+	return func(config *GetConfig) error {
+		if maxBytes < 0 {
+			return fmt.Errorf("get limit must not be negative, got %d", maxBytes)
+		}
+
+		config.maxBytes = maxBytes
+		return nil
+	}
+}
+
+// GetRange only reads length bytes of the value starting from offset, i.e: to resume the download of big value.
+// Zero length means read until the end of the value.
+// Reading beyond the end of the value returns the remaining bytes, which may be empty.
+func GetRange(offset, length int64) GetOpt {
+	return func(config *GetConfig) error {
+		if offset < 0 || length < 0 {
+			return fmt.Errorf("get range must not be negative, got offset=%d length=%d", offset, length)
+		}
+
+		config.offset = offset
+		config.length = length
+		return nil
+	}
+}
+
+// GetDirAsList makes Get return the listing of the directory when the key is a directory, instead of ErrKeyIsDirectory.
+// The listing is the name of every entry directly inside the directory in the tree order, one per line,
+// with the trailing slash for the subdirectory, like `ls -p`. GetLimit and GetRange don't apply to the listing.
+func GetDirAsList() GetOpt {
+	return func(config *GetConfig) error {
+		config.dirAsList = true
+		return nil
+	}
+}
+
+type ListOpt func(*ListConfig) error
+
+type ListConfig struct {
+	prefix          string
+	limit           int
+	includeInternal bool
+	includeGitkeep  bool
+	exclude         []string
+}
+
+// ListPrefix only returns the key equal to the prefix or under it, including the nested directories.
+// The prefix is matched at the path component boundary, so "config" doesn't match "configs/app.yaml",
+// and the trailing slash is ignored: "configs/" is the same as "configs".
+// The prefix which is the key of the file returns only that key.
+func ListPrefix(prefix string) ListOpt {
+	return func(config *ListConfig) error {
+		config.prefix = cleanPrefix(prefix)
+		return nil
+	}
+}
+
+// ListLimit stops List after n matching entries, and mark the Entries as Truncated if there are more.
+// This bounds the memory used by List on huge repository.
+// Since the entries are not sorted, which n entries returned is depends on the git tree order.
+// Zero means unlimited, which is the default.
+func ListLimit(n int) ListOpt {
+	return func(config *ListConfig) error {
+		if n < 0 {
+			return fmt.Errorf("list limit must not be negative, got %d", n)
+		}
+
+		config.limit = n
+		return nil
+	}
+}
+
+// ListIncludeInternal includes the files inside the reserved .gitrows directory, which are hidden by default.
+// Their key is the file path as is, without KeyMapper and KeyEncoding.
+func ListIncludeInternal() ListOpt {
+	return func(config *ListConfig) error {
+		config.includeInternal = true
+		return nil
+	}
+}
+
+// ListIncludeGitkeep includes the .gitkeep placeholders of the directories created by MkdirAll,
+// which are hidden by default.
+func ListIncludeGitkeep() ListOpt {
+	return func(config *ListConfig) error {
+		config.includeGitkeep = true
+		return nil
+	}
+}
+
+// ListExclude hides the keys matching any of the patterns (see path.Match), i.e: "*.tmp" or "scratch/".
+// The pattern without slash is matched against every path element, so "*.tmp" hides "a/b.tmp",
+// and "scratch" hides everything under any "scratch" directory. Otherwise it is matched against the key
+// and the directories of the key from the root, so "logs/2023-*" hides "logs/2023-01/app.log".
+// The exclude wins over the include (i.e: ListPrefix) on conflict, and the trailing slash is ignored.
+func ListExclude(patterns ...string) ListOpt {
+	return func(config *ListConfig) error {
+		for _, pattern := range patterns {
+			pattern = strings.TrimSuffix(pattern, "/")
+			if pattern == "" {
+				return fmt.Errorf("list exclude pattern must not be empty")
+			}
+
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid list exclude pattern '%s': %w", pattern, err)
+			}
+
+			config.exclude = append(config.exclude, pattern)
+		}
+
+		return nil
+	}
+}
+
+// match returns true when the key is included in the List result, see ListPrefix and ListIncludeGitkeep.
+func (c *ListConfig) match(key string) bool {
+	if isGitkeep(key) && !c.includeGitkeep {
+		return false
+	}
+
+	return hasPathPrefix(key, c.prefix) && !c.excluded(key)
+}
+
+// excluded returns true when the key matches any pattern of ListExclude.
+func (c *ListConfig) excluded(key string) bool {
+	if len(c.exclude) == 0 {
+		return false
+	}
+
+	elems := strings.Split(key, "/")
+	for _, pattern := range c.exclude {
+		hasSlash := strings.Contains(pattern, "/")
+		for i, elem := range elems {
+			subject := elem
+			if hasSlash {
+				subject = strings.Join(elems[:i+1], "/")
+			}
+
+			// the pattern is validated by ListExclude
+			if matched, _ := path.Match(pattern, subject); matched {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 type CreateOpt func(*CreateConfig) error
 
 type CreateConfig struct {
-	commitMsg string
+	commitMsg     string
+	fileMode      os.FileMode
+	allowInternal bool
+	canonicalJSON bool
+	expectedHead  string
+	skipSync      bool
+	meta          map[string]string
 }
 
 func CreateCommitMsg(msg string) CreateOpt {
@@ -42,11 +379,101 @@ func CreateCommitMsg(msg string) CreateOpt {
 	}
 }
 
+// CreateFileMode set the permission of the created file, i.e: 0755 to commit shell script as executable.
+// Git only records whether the file is executable, so the tree entry is 100755 when any executable bit is set,
+// or 100644 otherwise. The default is 0644.
+func CreateFileMode(mode os.FileMode) CreateOpt {
+	return func(config *CreateConfig) error {
+		err := validateFileMode(mode)
+		if err != nil {
+			return err
+		}
+
+		config.fileMode = mode
+		return nil
+	}
+}
+
+// CreateAllowInternalPaths allows the key inside the .gitrows directory, which is reserved for the files
+// managed by gitrows itself (i.e: schema version). Without this option, such key is rejected with ErrInvalidKey.
+// The key is used as the file path as is, without KeyMapper and KeyEncoding.
+func CreateAllowInternalPaths() CreateOpt {
+	return func(config *CreateConfig) error {
+		config.allowInternal = true
+		return nil
+	}
+}
+
+// CreateCanonicalJSON writes the value in canonical JSON form, the same as WithJSONCanonicalization,
+// but the value must be valid JSON (including scalar), otherwise Create fails.
+func CreateCanonicalJSON() CreateOpt {
+	return func(config *CreateConfig) error {
+		config.canonicalJSON = true
+		return nil
+	}
+}
+
+// CreateExpectedHead only creates the key when the branch tip after the pull is the commit, otherwise ErrStaleHead,
+// i.e: to write back the state read at the commit only when nothing is changed in the whole branch since then.
+// The commit must be the full hash, and the zero hash expects the branch without any commit.
+func CreateExpectedHead(commit string) CreateOpt {
+	return func(config *CreateConfig) error {
+		err := validateExpectedHead(commit)
+		if err != nil {
+			return err
+		}
+
+		config.expectedHead = commit
+		return nil
+	}
+}
+
+// CreateSkipSync writes on the local branch as it is, without pulling the remote branch first, i.e: for the bulk
+// backfill through the sequential writes which nothing else writes to, so N writes cost N pushes instead of
+// N pulls and pushes. Read or write once without it to sync the local branch before the loop, since only
+// the first write of the DB, which has no local branch yet, still pulls.
+//
+// The local branch is as old as the last sync, so with the default force push, the remote changes pushed after it
+// are overwritten. Use it with WithForcePush(false) to fail with CodeConflict instead,
+// or also WithMergeResolver or WithLinearHistory to pull and retry only when the remote branch has been moved.
+// The CreateExpectedHead is checked against the local branch too.
+func CreateSkipSync() CreateOpt {
+	return func(config *CreateConfig) error {
+		config.skipSync = true
+		return nil
+	}
+}
+
+// CreateIfAbsentAtCommit is CreateExpectedHead for the key decided to be absent by reading the branch at the commit,
+// i.e: when the creation also depends on the other keys read at that snapshot. The Create fails with ErrStaleHead
+// when the branch has advanced past the commit, even when the key itself still doesn't exist.
+func CreateIfAbsentAtCommit(headHash string) CreateOpt {
+	return CreateExpectedHead(headHash)
+}
+
+// CreateMeta attaches the metadata to the commit as the trailers of its message, i.e: the request ID or the ticket
+// of the write for the audit, see CommitNotes. The name must only contain letters, digits and '-' like the trailer
+// of git, and the value can be any string. Calling it more than once merges the metadata.
+func CreateMeta(meta map[string]string) CreateOpt {
+	return func(config *CreateConfig) (err error) {
+		config.meta, err = mergeCommitMeta(config.meta, meta)
+		return
+	}
+}
+
 type UpsertOpt func(*UpsertConfig) error
 
 type UpsertConfig struct {
 	commitMsg        string
 	allowEmptyCommit bool
+	fileMode         os.FileMode
+	allowInternal    bool
+	canonicalJSON    bool
+	amend            bool
+	expectedHead     string
+	ifDifferentFrom  string
+	skipSync         bool
+	meta             map[string]string
 }
 
 func UpsertCommitMsg(msg string) UpsertOpt {
@@ -61,6 +488,83 @@ func UpsertCommitMsg(msg string) UpsertOpt {
 	}
 }
 
+// UpsertFileMode is like CreateFileMode, but when the key already exists it also changes the mode of the file.
+// Without this option, the mode of the existing file is kept as is.
+func UpsertFileMode(mode os.FileMode) UpsertOpt {
+	return func(config *UpsertConfig) error {
+		err := validateFileMode(mode)
+		if err != nil {
+			return err
+		}
+
+		config.fileMode = mode
+		return nil
+	}
+}
+
+// UpsertAllowInternalPaths is like CreateAllowInternalPaths, but for Upsert (and PutReader).
+func UpsertAllowInternalPaths() UpsertOpt {
+	return func(config *UpsertConfig) error {
+		config.allowInternal = true
+		return nil
+	}
+}
+
+// UpsertCanonicalJSON is like CreateCanonicalJSON, but for Upsert. It has no effect on PutReader.
+func UpsertCanonicalJSON() UpsertOpt {
+	return func(config *UpsertConfig) error {
+		config.canonicalJSON = true
+		return nil
+	}
+}
+
+// UpsertExpectedHead is like CreateExpectedHead, but for Upsert. The Upsert is never buffered by WithWriteCoalescing.
+func UpsertExpectedHead(commit string) UpsertOpt {
+	return func(config *UpsertConfig) error {
+		err := validateExpectedHead(commit)
+		if err != nil {
+			return err
+		}
+
+		config.expectedHead = commit
+		return nil
+	}
+}
+
+// UpsertSkipSync is like CreateSkipSync, but for Upsert.
+func UpsertSkipSync() UpsertOpt {
+	return func(config *UpsertConfig) error {
+		config.skipSync = true
+		return nil
+	}
+}
+
+// UpsertIfDifferentFrom skips the Upsert when the committed value of the key has the blob hash,
+// comparing only the tree entry without reading nor writing the value, i.e: to skip the upload of the content
+// which the external system already knows is the same (see BlobHash). The result is the same as the unchanged Upsert.
+// PutReader doesn't read r at all in that case, and the Upsert is never buffered by WithWriteCoalescing.
+func UpsertIfDifferentFrom(blobHash string) UpsertOpt {
+	return func(config *UpsertConfig) error {
+		if !plumbing.IsHash(blobHash) {
+			return fmt.Errorf("blob hash must be the full hash, got '%s'", blobHash)
+		}
+
+		config.ifDifferentFrom = blobHash
+		return nil
+	}
+}
+
+// UpsertAmend amends the commit at the tip of the branch instead of creating the new one on top of it,
+// when that commit is the last Upsert of the same key by this DB, i.e: to save-as-you-type without hundreds of commits.
+// The amended commit is force-pushed, and rejected with CodeConflict when the remote branch is moved meanwhile.
+// Otherwise, the new commit is created the same as without this option.
+func UpsertAmend() UpsertOpt {
+	return func(config *UpsertConfig) error {
+		config.amend = true
+		return nil
+	}
+}
+
 // UpsertAllowEmptyCommit enable empty commits to be created. An empty commit
 // is when no changes to the tree were made, but a new commit message is
 // provided. The default behavior is false, which results in ErrEmptyCommit.
@@ -71,10 +575,23 @@ func UpsertAllowEmptyCommit(b bool) UpsertOpt {
 	}
 }
 
+// UpsertMeta is like CreateMeta. The Upsert with the metadata is never buffered by WithWriteCoalescing,
+// since the batch commit cannot carry the metadata of each write.
+func UpsertMeta(meta map[string]string) UpsertOpt {
+	return func(config *UpsertConfig) (err error) {
+		config.meta, err = mergeCommitMeta(config.meta, meta)
+		return
+	}
+}
+
 type DeleteOpt func(*DeleteConfig) error
 
 type DeleteConfig struct {
-	commitMsg string
+	commitMsg     string
+	allowInternal bool
+	expectedHead  string
+	ifMatch       string
+	skipSync      bool
 }
 
 func DeleteCommitMsg(msg string) DeleteOpt {
@@ -89,16 +606,212 @@ func DeleteCommitMsg(msg string) DeleteOpt {
 	}
 }
 
-type ListOpt func(*ListConfig) error
+// DeleteAllowInternalPaths is like CreateAllowInternalPaths, but for Delete.
+func DeleteAllowInternalPaths() DeleteOpt {
+	return func(config *DeleteConfig) error {
+		config.allowInternal = true
+		return nil
+	}
+}
 
-type ListConfig struct {
-	prefix string
+// DeleteExpectedHead is like CreateExpectedHead, but for Delete. The Delete is never buffered by WithWriteCoalescing.
+func DeleteExpectedHead(commit string) DeleteOpt {
+	return func(config *DeleteConfig) error {
+		err := validateExpectedHead(commit)
+		if err != nil {
+			return err
+		}
+
+		config.expectedHead = commit
+		return nil
+	}
 }
 
-func ListPrefix(prefix string) ListOpt {
-	return func(config *ListConfig) error {
-		prefix = strings.TrimSpace(prefix)
-		config.prefix = prefix
+// DeleteSkipSync is like CreateSkipSync, but for Delete.
+func DeleteSkipSync() DeleteOpt {
+	return func(config *DeleteConfig) error {
+		config.skipSync = true
+		return nil
+	}
+}
+
+// DeleteIfMatch only deletes the key when its committed value after the pull has the blob hash (see BlobHash),
+// or when the last commit changing the key has the commit hash, otherwise ErrPreconditionFailed,
+// i.e: to delete only the version which the client has seen, the same as the If-Match header of HTTP.
+// The hash must be the full hash, and the Delete is never buffered by WithWriteCoalescing.
+func DeleteIfMatch(blobHashOrCommit string) DeleteOpt {
+	return func(config *DeleteConfig) error {
+		if !plumbing.IsHash(blobHashOrCommit) {
+			return fmt.Errorf("if match must be the full blob or commit hash, got '%s'", blobHashOrCommit)
+		}
+
+		config.ifMatch = blobHashOrCommit
+		return nil
+	}
+}
+
+type VerifyOpt func(*VerifyConfig) error
+
+type VerifyConfig struct {
+	repair bool
+}
+
+// VerifyRepair makes Verify re-clone the local repository when any problem is found,
+// the same as starting from the empty local git volume.
+func VerifyRepair(b bool) VerifyOpt {
+	return func(config *VerifyConfig) error {
+		config.repair = b
+		return nil
+	}
+}
+
+// VerifyReport is the result of each check done by Verify. The error of each check is nil when it passes.
+type VerifyReport struct {
+	// LocalHead and RemoteHead are empty when the branch doesn't exist, see IsUpToDate.
+	LocalHead  string
+	RemoteHead string
+
+	// BranchErr is set when the local branch reference cannot be resolved into a commit.
+	BranchErr error
+
+	// TreeErr is set when any object of the tree of the local branch cannot be read.
+	TreeErr error
+
+	// WorktreeErr is set when the worktree doesn't match the local branch, DirtyPaths contains the different files.
+	WorktreeErr error
+	DirtyPaths  []string
+
+	// RemoteErr is set when the remote branch cannot be listed, or it is neither the same nor the descendant
+	// of the local branch (i.e: force-pushed remote or unpushed local commit).
+	RemoteErr error
+
+	// Repaired is true when the local repository is re-cloned by VerifyRepair.
+	Repaired bool
+}
+
+// OK returns true when all checks pass.
+func (r VerifyReport) OK() bool {
+	return r.BranchErr == nil && r.TreeErr == nil && r.WorktreeErr == nil && r.RemoteErr == nil
+}
+
+type StatusOpt func(*StatusConfig) error
+
+type StatusConfig struct {
+	checkRemote bool
+}
+
+// StatusCheckRemote makes Status list the remote branch (`git ls-remote`), to report whether it is moved
+// after the last sync. Nothing is fetched.
+func StatusCheckRemote(b bool) StatusOpt {
+	return func(config *StatusConfig) error {
+		config.checkRemote = b
+		return nil
+	}
+}
+
+// SyncStatus is the state of the local repository of the DB returned by Status.
+type SyncStatus struct {
+	// LocalHead is the commit of the local branch, empty when it doesn't exist (i.e: never synced).
+	LocalHead string
+
+	// LastSyncAt is the start time of the last successful sync by this DB, zero when it never succeeded.
+	// LastSyncErr is the error of the last sync, nil when it succeeded, see LastSyncError.
+	LastSyncAt  time.Time
+	LastSyncErr error
+
+	// Clean is true when the worktree matches the local branch, otherwise DirtyPaths contains the different files.
+	// The worktree is not compared when the branch is only fetched (see Keys and WithTreeWrites),
+	// which is reported by CheckoutPending instead.
+	Clean           bool
+	DirtyPaths      []string
+	CheckoutPending bool
+
+	// Lagging is true when the last fetch returned the older commit than the last push by this DB, i.e: the mirrored
+	// or replicated backend still serving the tip before the push, so the local branch is kept at LocalHead.
+	// LaggingHead is the older commit served by that fetch.
+	Lagging     bool
+	LaggingHead string
+
+	// RemoteHead and RemoteChanged are only set with StatusCheckRemote. RemoteHead is empty when the branch
+	// doesn't exist in the remote repository, and RemoteChanged is true when it differs from LocalHead.
+	RemoteChecked bool
+	RemoteHead    string
+	RemoteChanged bool
+}
+
+type CopyOpt func(*CopyConfig) error
+
+type CopyConfig struct {
+	prefix     string
+	glob       string
+	renameFrom string
+	renameTo   string
+	batchSize  int
+	dryRun     bool
+	commitMsg  string
+}
+
+// CopyPrefix only copies the keys under the prefix, matched the same as ListPrefix.
+func CopyPrefix(prefix string) CopyOpt {
+	return func(config *CopyConfig) error {
+		config.prefix = cleanPrefix(prefix)
+		return nil
+	}
+}
+
+// CopyGlob only copies the keys matching the pattern of path.Match, i.e: "users/*/profile.json".
+func CopyGlob(pattern string) CopyOpt {
+	return func(config *CopyConfig) error {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid copy glob '%s': %w", pattern, err)
+		}
+
+		config.glob = pattern
+		return nil
+	}
+}
+
+// CopyRenamePrefix writes the source key under the prefix from into the prefix to instead, i.e: "tenant-a/"
+// into "" for splitting the tenant into its own repository. The other keys are written as is.
+// The prefix filter of CopyPrefix and CopyGlob applies to the source key.
+func CopyRenamePrefix(from, to string) CopyOpt {
+	return func(config *CopyConfig) error {
+		config.renameFrom = cleanPrefix(from)
+		config.renameTo = cleanPrefix(to)
+		return nil
+	}
+}
+
+// CopyBatchSize commits every n keys in its own commit, the default is defaultCopyBatchSize.
+func CopyBatchSize(n int) CopyOpt {
+	return func(config *CopyConfig) error {
+		if n <= 0 {
+			return fmt.Errorf("copy batch size must be positive, got %d", n)
+		}
+
+		config.batchSize = n
+		return nil
+	}
+}
+
+// CopyDryRun only counts the keys which would be copied, without writing anything.
+func CopyDryRun() CopyOpt {
+	return func(config *CopyConfig) error {
+		config.dryRun = true
+		return nil
+	}
+}
+
+// CopyCommitMsg replaces the default commit message "gitrows: COPY <n> keys" of each batch.
+func CopyCommitMsg(msg string) CopyOpt {
+	return func(config *CopyConfig) error {
+		msg = strings.TrimSpace(msg)
+		if msg == "" {
+			return nil
+		}
+
+		config.commitMsg = msg
 		return nil
 	}
 }
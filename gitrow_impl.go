@@ -5,18 +5,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/yusufsyaifudin/gitrows/pkg/giturl"
 	"io"
+	"math"
 	"net/url"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"time"
 )
 
 const gitRemoteName = "origin"
@@ -52,27 +58,216 @@ func WithLocalGitVolume(dir string) Opt {
 	}
 }
 
+// WithReadStaleness allows read commands (Get, GetMany, List) to be served from the local repository
+// without `git pull` when the last successful sync happened within the duration d.
+// Use NotifyRemoteChanged to force the next read to pull, even inside this staleness window.
+// Zero or negative duration means always pull before read, which is the default.
+func WithReadStaleness(d time.Duration) Opt {
+	return func(db *DBImpl) error {
+		db.readStaleness = d
+		return nil
+	}
+}
+
+// WithInitCommitMessage set the commit message used when gitrows creates the first commit of a new branch,
+// i.e: "gitrows: initialize branch". This helps to audit when the gitrows-managed branch is bootstrapped.
+// By default, the first commit uses the message of the command that creates it.
+func WithInitCommitMessage(msg string) Opt {
+	return func(db *DBImpl) error {
+		db.initCommitMsg = strings.TrimSpace(msg)
+		return nil
+	}
+}
+
+// WithStaleReadsOnRemoteError allows read commands (Get, GetMany, List) to be served from the local branch
+// when the remote repository is unavailable (ErrRemoteUnavailable), instead of returning the error.
+// The sync error is still available through LastSyncError. Write commands still fail in this case.
+func WithStaleReadsOnRemoteError() Opt {
+	return func(db *DBImpl) error {
+		db.staleReadsOnRemoteErr = true
+		return nil
+	}
+}
+
+// WithReadOnly makes all write commands (i.e: Create, Upsert, Delete, PutReader) return ErrReadOnly
+// immediately without touching the local or remote repository.
+// Use this for read replica, to document the intent and prevent accidental writes.
+func WithReadOnly() Opt {
+	return func(db *DBImpl) error {
+		db.readOnly = true
+		return nil
+	}
+}
+
+// WithRequireExistingBranch makes every command fail with ErrBranchNotFound when the branch
+// doesn't exist in the remote repository, instead of silently creating an orphan branch locally.
+// This helps to catch typos in the branch name early rather than serving empty data.
+func WithRequireExistingBranch(b bool) Opt {
+	return func(db *DBImpl) error {
+		db.requireExistingBranch = b
+		return nil
+	}
+}
+
+// WithCreateBranchIfMissing controls whether the first write into the branch which doesn't exist in the remote
+// repository creates it (the orphan branch), which is the default. When it is false, the write commands fail
+// with ErrBranchNotFound instead, i.e: so the typo in the branch name doesn't push the new branch,
+// while the reads still return no key. Use Branches to list the existing branches first.
+// Unlike WithRequireExistingBranch, only the write commands fail.
+func WithCreateBranchIfMissing(b bool) Opt {
+	return func(db *DBImpl) error {
+		db.createBranch = b
+		return nil
+	}
+}
+
+// WithAllowMissingRemote treats the remote repository which doesn't exist on the host yet the same as the empty one:
+// the branch is initialized locally, the reads return no key, and the first write pushes the content, i.e: for the host
+// which creates the repository on the first push. Without this option, every command fails with ErrRepositoryNotFound.
+func WithAllowMissingRemote() Opt {
+	return func(db *DBImpl) error {
+		db.allowMissingRemote = true
+		return nil
+	}
+}
+
+// WithUpdateRemoteURL set whether the URL of the remote in the existing local repository is updated
+// when it differs from WithGitSshUrl (or WithReadURL), default is true.
+// The local repository only depends on the host and path of the URL, so it is reused when only the scheme or the user
+// is changed (i.e: from HTTPS into SSH), which otherwise keeps fetching from the old URL.
+func WithUpdateRemoteURL(b bool) Opt {
+	return func(db *DBImpl) error {
+		db.updateRemoteURL = b
+		return nil
+	}
+}
+
 type DBImpl struct {
 	gitSshUser   string
 	gitSshUrl    string
 	gitURLParsed *url.URL
 	gitBranch    string
 	gitVolume    string
+	volumeRoot   string // the volume of WithLocalGitVolume, where the sync marker is written
+
+	requireExistingBranch  bool
+	createBranch           bool // WithCreateBranchIfMissing
+	allowMissingRemote     bool
+	updateRemoteURL        bool
+	readStaleness          time.Duration
+	initCommitMsg          string
+	staleReadsOnRemoteErr  bool
+	readOnly               bool
+	sparsePrefix           string
+	preservePaths          []string
+	treeWrites             bool
+	readStrategy           ReadStrategy
+	keyMapper              KeyMapper
+	keyEncoding            KeyEncoding
+	lineEnding             LineEndingPolicy
+	jsonCanonical          bool
+	verifyKeyring          openpgp.KeyRing
+	rejectUnverified       bool
+	caseCollisionProtect   bool
+	objectCacheSize        int
+	atomicPush             bool
+	atomicPushSet          bool // WithAtomicPush is used, so the push is not retried without atomic
+	forcePush              bool
+	fetchPrune             bool
+	mergeResolver          func(key string, local, remote []byte) ([]byte, error)
+	linearHistory          bool
+	coalesceWindow         time.Duration
+	coalesceMatch          func(key string) bool
+	coalesceReadYourWrites bool
+	operationTimeout       time.Duration
+	maxPushBytes           int64
+	pushEvery              int
+	useTempDir             bool
+	tempRoot               string // the temporary git volume of WithTempDir, removed on Close
 
 	privateKey    []byte
 	privateKeyPwd string
-	auth          *ssh.PublicKeys
+	auth          transport.AuthMethod
+
+	readURL           string // WithReadURL, empty means the read commands fetch from gitSshUrl
+	readPrivateKey    []byte
+	readPrivateKeyPwd string
+	readAuth          transport.AuthMethod
+
+	progress      io.Writer
+	onProgress    func(p SyncProgress)
+	onSyncStart   func()
+	onSyncEnd     func(elapsed time.Duration, err error)
+	onPush        func(stats PushStats)
+	onSkippedPath func(filePath string, err error)
+	onSyncMarker  func(filePath string, err error)
+	commitMsgFunc func(op Op, keys []string, meta CommitMeta) string
+	commitFooter  bool // WithStructuredCommitFooter
+
+	commitAllChanges bool // WithCommitAllWorktreeChanges
+
+	commitEncoding string
+
+	journalSize int        // WithOpJournal
+	journal     *opJournal // nil when it is disabled
+	journalDump io.Writer  // WithOpJournalDump
+
+	gitRepo    *git.Repository
+	worktreeFS func(dir string) billy.Filesystem // nil means the OS filesystem
+
+	baseCtx     context.Context    // WithBaseContext
+	lifetime    context.Context    // derived from baseCtx in New, cancelled by Close, nil for the sibling without it
+	endLifetime context.CancelFunc // cancels lifetime
+
+	// branchDBsMu protects branchDBs, the DBImpl of other branches used by WriteToBranches.
+	branchDBsMu sync.Mutex
+	branchDBs   map[string]*DBImpl
 
-	gitRepo *git.Repository
+	// syncMu protects the sync state below, which is accessed by NotifyRemoteChanged from another goroutine.
+	syncMu       sync.Mutex
+	lastSyncAt   time.Time // start time of the last successful forcePull
+	lastSyncErr  error     // error of the last forcePull
+	lastSyncHead string    // local branch head after the last successful forcePull, see LastSyncInfo
+	notifiedAt   time.Time // time of the last NotifyRemoteChanged
+	expectedHead string    // commit hash sent by the last NotifyRemoteChanged, may be empty
+
+	abandoned chan struct{} // closed when the remote call abandoned by remoteCall finishes, nil if there is none
+
+	checkoutPending bool // the branch is fetched, but the worktree is not checked out yet
+
+	lastUpsertKey  string        // key of the last Upsert by this DB, see UpsertAmend
+	lastUpsertHash plumbing.Hash // pushed commit of the last Upsert by this DB
+
+	lastPushed  plumbing.Hash // the last commit pushed by this DB, never rewound by the fetch, see keepPushed
+	laggingHead plumbing.Hash // the older commit served by the last fetch, zero when it is not lagging
+
+	coalescer *coalescer // buffers the writes of WithWriteCoalescing, nil without it
+
+	phaseHook func(phase string) // called at the beginning of each step of the command, only set by tests
 }
 
-var _ DB = (*DBImpl)(nil)
+var (
+	_ DB     = (*DBImpl)(nil)
+	_ Reader = (*DBImpl)(nil)
+	_ Writer = (*DBImpl)(nil)
+)
 
 func New(opts ...Opt) (*DBImpl, error) {
 	db := &DBImpl{
 		gitSshUser: "git",
 		gitBranch:  "master",
 		gitVolume:  "gitrows-data",
+		progress:   os.Stdout,
+		atomicPush: true,
+		forcePush:  true,
+
+		createBranch: true,
+
+		baseCtx: context.Background(),
+
+		journalSize: defaultOpJournalSize,
+
+		updateRemoteURL: true,
 	}
 
 	for _, opt := range opts {
@@ -82,9 +277,14 @@ func New(opts ...Opt) (*DBImpl, error) {
 		}
 	}
 
-	authSSH, err := ssh.NewPublicKeys(db.gitSshUser, db.privateKey, db.privateKeyPwd)
+	// the linear history never overwrites the remote branch, regardless of the order of WithForcePush
+	if db.linearHistory {
+		db.forcePush = false
+	}
+
+	var err error
+	db.gitBranch, err = validateBranch(db.gitBranch)
 	if err != nil {
-		err = fmt.Errorf("error ssh private key load: %w", err)
 		return nil, err
 	}
 
@@ -100,52 +300,75 @@ func New(opts ...Opt) (*DBImpl, error) {
 		return nil, err
 	}
 
+	// local repository (file://) doesn't need any authentication.
+	if db.gitURLParsed.Scheme != "file" {
+		var authSSH *ssh.PublicKeys
+		authSSH, err = ssh.NewPublicKeys(db.gitSshUser, db.privateKey, db.privateKeyPwd)
+		if err != nil {
+			err = fmt.Errorf("error ssh private key load: %w", err)
+			return nil, err
+		}
+
+		db.auth = authSSH
+	}
+
+	err = db.initReadURL()
+	if err != nil {
+		return nil, err
+	}
+
+	if db.useTempDir {
+		db.tempRoot, err = os.MkdirTemp("", "gitrows-")
+		if err != nil {
+			err = fmt.Errorf("cannot create temporary git volume: %w", err)
+			return nil, err
+		}
+
+		db.gitVolume = db.tempRoot
+	}
+
+	db.volumeRoot = db.gitVolume
+
 	// git volume should reside in different path of each git repo.
 	// i.e: github.com/yusufsyaifudin/common-dev-config
 	//      -> should create tree directory ${db.gitVolume}/github.com/yusufsyaifudin/common-dev-config
 	db.gitVolume = fmt.Sprintf("%s/%s/%s", db.gitVolume, db.gitURLParsed.Host, db.gitURLParsed.Path)
 
-	db.auth = authSSH
+	// the siblings of the coalescer and WriteToBranches share the lifetime and the journal
+	db.lifetime, db.endLifetime = context.WithCancel(db.baseCtx)
+	if db.journalSize > 0 {
+		db.journal = &opJournal{size: db.journalSize}
+	}
+
+	if db.coalesceWindow > 0 {
+		db.coalescer = newCoalescer(db)
+	}
 
 	return db, nil
 }
 
-// gitClone is like `git reset --hard HEAD && git pull` command,
-// where remove all uncommitted changes and then pull.
-// This to make sure that every command in current local git is up-to-date
-// with the origin (remote) git repository.
+// gitClone is like `git clone --no-checkout` command, but it only does `git init` of the local repository,
+// and the next fetch then receives the branch the same as `git clone --depth 1 --single-branch`.
+// Unlike the monolithic clone, the interrupted first sync (i.e: by the deadline of ctx) leaves the valid empty
+// repository, so the retry is the usual fetch instead of failing on the half-cloned directory.
+// The local repository which cannot be opened (i.e: the process died during `git init`) is removed first.
 func (db *DBImpl) gitClone(ctx context.Context) (err error) {
-	cloneOpt := &git.CloneOptions{
-		URL:        db.gitSshUrl,
-		Auth:       db.auth,
-		RemoteName: gitRemoteName,
-		// Only fetch specific branch because we don't want all git histories eats up our RAM
-		ReferenceName: plumbing.NewBranchReferenceName(db.gitBranch),
-		SingleBranch:  true, // Fetch only ReferenceName if true.
-		NoCheckout:    true,
-		Depth:         1,         // fetch only depth 1
-		Progress:      os.Stdout, // TODO: use another logging options
+	// only once per DB, since the fetch of the other command may be writing its temporary packfile now
+	if db.gitRepo == nil {
+		err = removeIncompleteClone(db.gitVolume)
+		if err != nil {
+			return
+		}
 	}
 
-	// git clone <url> --depth 1 --branch <branch> --single-branch
-	// eg: git clone git@github.com:go-git/go-git.git --depth 1 --branch master --single-branch
-	db.gitRepo, err = git.PlainCloneContext(ctx, db.gitVolume, false, cloneOpt)
+	// git init <dir>
+	_, err = git.PlainInit(db.gitVolume, false)
 	if errors.Is(err, git.ErrRepositoryAlreadyExists) {
 		err = nil // discard error caused by ErrRepositoryAlreadyExists
 	}
 
-	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
-		// discard error and create new repo here
-		err = nil
-		db.gitRepo, err = git.PlainInit(db.gitVolume, false)
-		if err != nil {
-			err = fmt.Errorf("cannot `git init` new repository: %w", err)
-			return
-		}
-	}
-
 	if err != nil {
-		err = fmt.Errorf("clone repository %s error: %w", db.gitSshUrl, err)
+		err = fmt.Errorf("cannot `git init` new repository: %w", err)
 		return
 	}
 
@@ -159,43 +382,45 @@ func (db *DBImpl) gitClone(ctx context.Context) (err error) {
 		return
 	}
 
+	// the object cache and the worktree filesystem can be replaced (i.e: in test to simulate case-insensitive filesystem)
+	db.gitRepo, err = db.reopenRepo(db.gitRepo)
+	if err != nil {
+		err = fmt.Errorf("open local repository %s with custom storage error: %w", db.gitSshUrl, err)
+		return
+	}
+
+	// like `git clone --branch <branch>`, HEAD always points to the branch, even before it is fetched
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(db.gitBranch))
+	current, err := db.gitRepo.Storer.Reference(plumbing.HEAD)
+	if err != nil || current.Type() != head.Type() || current.Target() != head.Target() {
+		err = db.gitRepo.Storer.SetReference(head)
+		if err != nil {
+			err = fmt.Errorf("cannot set reference %s: %w", head, err)
+			return
+		}
+	}
+
 	return
 }
 
 func (db *DBImpl) gitFetch(ctx context.Context) (err error) {
-	// check remote existence
-	// ex: git remote get-url origin
-	var remote *git.Remote
-	remote, err = db.gitRepo.Remote(gitRemoteName)
-	if errors.Is(err, git.ErrRemoteNotFound) {
-		// discard if remote not found, we will try to create if remote is still nil
-		err = nil
-	}
+	return db.fetchDepth(ctx, 1)
+}
 
-	if err != nil {
-		err = fmt.Errorf("cannot get remote '%s': %w", gitRemoteName, err)
+// fetchDepth is like `git fetch --depth <depth>`, the commits already fetched are kept even beyond the depth.
+func (db *DBImpl) fetchDepth(ctx context.Context, depth int) (err error) {
+	unpushed, err := db.hasUnpushed()
+	if err != nil || unpushed {
 		return
 	}
 
-	// if still nil, then try to add
-	// ex: git remote add origin <git-url>
-	if remote == nil {
-		remote, err = db.gitRepo.CreateRemote(&config.RemoteConfig{
-			Name: gitRemoteName,
-			URLs: []string{
-				db.gitSshUrl,
-			},
-		})
-
-		if err != nil {
-			err = fmt.Errorf("cannot `git remote add %s %s`: %w", gitRemoteName, db.gitSshUrl, err)
-			return
-		}
+	local, err := db.headCommit()
+	if err != nil {
+		return
 	}
 
-	// if still nil, then return error
-	if remote == nil {
-		err = fmt.Errorf("nil remote after `git remote add %s %s`", gitRemoteName, db.gitSshUrl)
+	remote, err := db.ensureRemote(gitRemoteName, db.gitSshUrl)
+	if err != nil {
 		return
 	}
 
@@ -203,22 +428,34 @@ func (db *DBImpl) gitFetch(ctx context.Context) (err error) {
 	// ex: git fetch origin master:master --depth 1
 	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
 	refSpec := fmt.Sprintf("%s:%s", branchName, branchName)
-	err = remote.FetchContext(ctx, &git.FetchOptions{
+	fetchOpt := &git.FetchOptions{
 		RemoteName: gitRemoteName,
 		RefSpecs: []config.RefSpec{
 			config.RefSpec(refSpec),
 		},
-		Depth:    1,
+		Depth:    depth,
 		Auth:     db.auth,
-		Progress: os.Stdout,
+		Progress: db.progressWriter(),
 		Force:    true,
+	}
+
+	err = db.remoteCall(ctx, func(ctx context.Context) error {
+		return remote.FetchContext(ctx, fetchOpt)
 	})
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		err = db.fetchWithoutHaves(ctx, remote, fetchOpt)
+	}
 
 	if errors.Is(err, git.NoErrAlreadyUpToDate) {
 		err = nil // discard error when contain "already up-to-date" warning
 	}
 
-	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+	if db.isEmptyRemote(err) && db.requireExistingBranch {
+		err = fmt.Errorf("%w: branch '%s' doesn't exist in %s", ErrBranchNotFound, db.gitBranch, db.gitSshUrl)
+		return
+	}
+
+	if db.isEmptyRemote(err) {
 		// create local branch on if remote repository doesn't have this branch
 		// similar like: git checkout --orphan <branch-name>
 		// https://github.com/go-git/go-git/pull/439#discussion_r908421596
@@ -233,159 +470,967 @@ func (db *DBImpl) gitFetch(ctx context.Context) (err error) {
 	}
 
 	if err != nil {
-		err = fmt.Errorf("cannot `git fetch %s %s --depth 1`: %w", gitRemoteName, refSpec, err)
+		err = fmt.Errorf("cannot `git fetch %s %s --depth %d`: %w", gitRemoteName, refSpec, depth, remoteError(db.gitSshUrl, err))
 		return
 	}
 
-	return
-}
+	err = db.keepPushed(local)
+	if err != nil {
+		return
+	}
 
-func (db *DBImpl) gitCheckout(ctx context.Context) (err error) {
-	var worktree *git.Worktree
-	worktree, err = db.gitRepo.Worktree()
+	err = db.trackRemote()
 	if err != nil {
-		err = fmt.Errorf("cannot get worktree of local git repo: %w", err)
 		return
 	}
 
-	// always do `git reset --hard` to ensure that we don't create any changes on read only repository
-	canCheckout := true
-	err = worktree.Reset(&git.ResetOptions{
-		Mode: git.HardReset,
-	})
-	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+	if db.fetchPrune {
+		err = db.pruneLocal(ctx)
+		if err != nil {
+			err = fmt.Errorf("cannot prune after `git fetch %s %s`: %w", gitRemoteName, refSpec, err)
+			return
+		}
+	}
+
+	return
+}
+
+// isEmptyRemote returns true when the fetch err means the branch doesn't exist in the remote repository yet,
+// including the missing remote repository with WithAllowMissingRemote.
+func (db *DBImpl) isEmptyRemote(err error) bool {
+	return errors.Is(err, transport.ErrEmptyRemoteRepository) || errors.Is(err, git.NoMatchingRefSpecError{}) ||
+		(db.allowMissingRemote && errors.Is(err, transport.ErrRepositoryNotFound))
+}
+
+// ensureRemote returns the remote, and adds it when it doesn't exist yet.
+func (db *DBImpl) ensureRemote(name, remoteURL string) (remote *git.Remote, err error) {
+	// check remote existence
+	// ex: git remote get-url origin
+	remote, err = db.gitRepo.Remote(name)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		// discard if remote not found, we will try to create if remote is still nil
 		err = nil
-		canCheckout = false
 	}
 
 	if err != nil {
-		err = fmt.Errorf("cannot hard reset on local git repo: %w", err)
+		err = fmt.Errorf("cannot get remote '%s': %w", name, err)
 		return
 	}
 
-	// Git branch will not be exists until it committed.
-	// In case we Clone empty git repository, mean that it doesn't have any branches.
-	// So, we only be able to check out when the branch exists.
-	// https://stackoverflow.com/a/21769914/5489910
-	// git checkout <local branch>
-	// ex: git checkout master
-	if canCheckout {
-		checkoutBranch := plumbing.NewBranchReferenceName(db.gitBranch)
-		err = worktree.Checkout(&git.CheckoutOptions{
-			Branch: checkoutBranch,
-			Force:  true,
+	// ex: git remote set-url origin <git-url>
+	if remote != nil && db.updateRemoteURL && !hasRemoteURL(remote, remoteURL) {
+		remote, err = db.setRemoteURL(name, remoteURL)
+		if err != nil {
+			return
+		}
+	}
+
+	// if still nil, then try to add
+	// ex: git remote add origin <git-url>
+	if remote == nil {
+		remote, err = db.gitRepo.CreateRemote(&config.RemoteConfig{
+			Name: name,
+			URLs: []string{
+				remoteURL,
+			},
 		})
+
 		if err != nil {
-			err = fmt.Errorf("cannot checkout on local git: `git checkout -b %s` : %w", checkoutBranch, err)
+			err = fmt.Errorf("cannot `git remote add %s %s`: %w", name, remoteURL, err)
 			return
 		}
+	}
 
+	// if still nil, then return error
+	if remote == nil {
+		err = fmt.Errorf("nil remote after `git remote add %s %s`", name, remoteURL)
 		return
 	}
 
-	// When the git checkout cannot be done because of `canCheckout` false.
-	// We can be rest assured that the current reference is in the branch name specified in the config.
-	// Because in the process gitFetch we already try to SetReference(ref) to the HEAD.
 	return
 }
 
-func (db *DBImpl) forcePull(ctx context.Context) (err error) {
-	err = db.gitClone(ctx)
-	if err != nil {
-		err = fmt.Errorf("git clone error: %w", err)
-		return
-	}
+// hasRemoteURL returns true when remoteURL is the (first) URL of the remote, which is used to fetch and push.
+func hasRemoteURL(remote *git.Remote, remoteURL string) bool {
+	urls := remote.Config().URLs
+	return len(urls) > 0 && urls[0] == remoteURL
+}
 
-	err = db.gitFetch(ctx)
+// setRemoteURL replaces the URLs of the existing remote with remoteURL, and reports it into the progress writer.
+func (db *DBImpl) setRemoteURL(name, remoteURL string) (remote *git.Remote, err error) {
+	cfg, err := db.gitRepo.Config()
 	if err != nil {
-		err = fmt.Errorf("git fetch error: %w", err)
+		err = fmt.Errorf("cannot read the config of the local repository: %w", err)
 		return
 	}
 
-	err = db.gitCheckout(ctx)
-	if err != nil {
-		err = fmt.Errorf("git checkout error: %w", err)
+	remoteCfg, ok := cfg.Remotes[name]
+	if !ok {
+		err = fmt.Errorf("cannot get remote '%s': %w", name, git.ErrRemoteNotFound)
 		return
 	}
 
-	return
-}
-
-func (db *DBImpl) Get(ctx context.Context, key string) (data []byte, err error) {
-	key = path.Clean(key)
+	oldURLs := remoteCfg.URLs
+	remoteCfg.URLs = []string{remoteURL}
 
-	err = db.forcePull(ctx)
+	err = db.gitRepo.SetConfig(cfg)
 	if err != nil {
-		err = fmt.Errorf("get command: %w", err)
+		err = fmt.Errorf("cannot `git remote set-url %s %s`: %w", name, remoteURL, err)
 		return
 	}
 
-	worktree, err := db.gitRepo.Worktree()
+	if db.progress != nil {
+		_, _ = fmt.Fprintf(db.progress, "gitrows: remote '%s' URL changed from %s to %s\n", name, strings.Join(oldURLs, ", "), remoteURL)
+	}
+
+	remote, err = db.gitRepo.Remote(name)
 	if err != nil {
-		err = fmt.Errorf("get command: cannot get worktree: %w", err)
+		err = fmt.Errorf("cannot get remote '%s': %w", name, err)
 		return
 	}
 
-	fs := worktree.Filesystem
+	return
+}
 
-	matchFile, err := fs.OpenFile(key, os.O_RDONLY, os.ModePerm)
-	defer func() {
-		if matchFile == nil {
+// fetchWithoutHaves retries the fetch without the local references of the branch.
+// go-git walks the history of the local references to negotiate the objects we already have,
+// which fails with plumbing.ErrObjectNotFound on the shallow clone once the remote branch has moved,
+// because the parent of the shallow commit is never fetched. Without the references, the fetch sends
+// no haves and receives the whole snapshot of the branch, the same as the clone.
+// The references are restored when the retry fails, and the commit objects are kept in the storage either way.
+func (db *DBImpl) fetchWithoutHaves(ctx context.Context, remote *git.Remote, fetchOpt *git.FetchOptions) (err error) {
+	refNames := []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(db.gitBranch),
+		plumbing.NewRemoteReferenceName(gitRemoteName, db.gitBranch),
+		plumbing.NewRemoteReferenceName(gitMirrorRemoteName, db.gitBranch),
+	}
+
+	saved := make([]*plumbing.Reference, 0, len(refNames))
+	for _, refName := range refNames {
+		ref, refErr := db.gitRepo.Storer.Reference(refName)
+		if errors.Is(refErr, plumbing.ErrReferenceNotFound) {
+			continue
+		}
+
+		if refErr != nil {
+			err = fmt.Errorf("cannot get reference %s: %w", refName, refErr)
 			return
 		}
 
-		if _err := matchFile.Close(); _err != nil {
-			err = fmt.Errorf("get command: failed to close file: %w", _err)
+		err = db.gitRepo.Storer.RemoveReference(refName)
+		if err != nil {
+			err = fmt.Errorf("cannot remove reference %s: %w", refName, err)
 			return
 		}
-	}()
 
-	if err != nil {
-		err = fmt.Errorf("get command: cannot open file: %w", err)
-		return
+		saved = append(saved, ref)
 	}
 
-	// protects file for access from another process
-	err = matchFile.Lock()
-	if err != nil {
-		err = fmt.Errorf(
-			"get command: cannot acquire file lock on key '%s' to protects against access from other processes: %w",
-			key, err,
-		)
+	err = db.remoteCall(ctx, func(ctx context.Context) error {
+		return remote.FetchContext(ctx, fetchOpt)
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		for _, ref := range saved {
+			if restoreErr := db.gitRepo.Storer.SetReference(ref); restoreErr != nil {
+				err = fmt.Errorf("%s (cannot restore reference %s: %v)", err, ref.Name(), restoreErr)
+			}
+		}
+
 		return
 	}
 
-	defer func() {
-		if matchFile == nil {
-			return
+	// the reference which is not in the refspec is left removed: restore the branch (fetched from the mirror
+	// into its remote-tracking reference, see mirrorFetch), and point the remote-tracking reference to the branch,
+	// otherwise its stale commit fails the next fetch in the same way.
+	for _, ref := range saved {
+		_, refErr := db.gitRepo.Storer.Reference(ref.Name())
+		if refErr == nil {
+			continue // updated by the fetch
 		}
 
-		if _err := matchFile.Unlock(); _err != nil {
-			err = fmt.Errorf("get command: failed to unlock file '%s': %w", key, _err)
+		if ref.Name().IsBranch() {
+			err = db.gitRepo.Storer.SetReference(ref)
+			if err != nil {
+				err = fmt.Errorf("cannot restore reference %s: %w", ref.Name(), err)
+				return
+			}
+
+			continue
+		}
+
+		var branchRef *plumbing.Reference
+		branchRef, err = db.gitRepo.Storer.Reference(refNames[0])
+		if err != nil {
+			err = fmt.Errorf("cannot get reference %s: %w", refNames[0], err)
 			return
 		}
-	}()
 
-	buf := &bytes.Buffer{}
-	_, err = buf.ReadFrom(matchFile)
-	if err != nil {
-		err = fmt.Errorf("get command: cannot read file buffer: %w", err)
-		return
+		err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(ref.Name(), branchRef.Hash()))
+		if err != nil {
+			err = fmt.Errorf("cannot set reference %s: %w", ref.Name(), err)
+			return
+		}
 	}
 
-	data = buf.Bytes()
-	buf.Reset()
 	return
 }
 
-// writeFile Will open the file (and create if not exist), then write data into it.
-// It will lock the file when opened, to ensure that no other process will read-write the same file.
-// After writing file, this function also do `git add` command.
-// Then it will return worktree, so later we can commit it (at once) and then pushed.
-func (db *DBImpl) writeFile(ctx context.Context, key string, data []byte, mode string) (worktree *git.Worktree, err error) {
-	key = path.Clean(key)
-
-	worktree, err = db.gitRepo.Worktree()
+func (db *DBImpl) gitCheckout(ctx context.Context) (err error) {
+	var worktree *git.Worktree
+	worktree, err = db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("cannot get worktree of local git repo: %w", err)
+		return
+	}
+
+	if len(db.preservePaths) > 0 {
+		var restore func() error
+		restore, err = db.stashPreserved(worktree)
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			if restoreErr := restore(); restoreErr != nil && err == nil {
+				err = restoreErr
+			}
+		}()
+	}
+
+	if db.sparsePrefix != "" {
+		return db.gitCheckoutSparse(worktree)
+	}
+
+	err = db.resetAndCheckout(worktree)
+
+	// HEAD may be left detached by other git client in the local repository, re-point it at the branch and retry once
+	var detachedAt plumbing.Hash
+	if err != nil {
+		detachedAt = db.detachedHead()
+	}
+
+	if !detachedAt.IsZero() {
+		err = db.attachHead()
+		if err == nil {
+			err = db.resetAndCheckout(worktree)
+		}
+	}
+
+	if err != nil {
+		err = db.checkoutError(err, detachedAt)
+		return
+	}
+
+	return
+}
+
+// resetAndCheckout is like `git reset --hard && git checkout <branch>`.
+// The branch which doesn't exist locally (i.e: empty remote repository) is not checked out, see gitFetch.
+func (db *DBImpl) resetAndCheckout(worktree *git.Worktree) (err error) {
+	// always do `git reset --hard` to ensure that we don't create any changes on read only repository
+	canCheckout := true
+	err = worktree.Reset(&git.ResetOptions{
+		Mode: git.HardReset,
+	})
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		err = nil
+		canCheckout = false
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot hard reset on local git repo: %w", err)
+		return
+	}
+
+	// Git branch will not be exists until it committed.
+	// In case we Clone empty git repository, mean that it doesn't have any branches.
+	// So, we only be able to check out when the branch exists.
+	// https://stackoverflow.com/a/21769914/5489910
+	// git checkout <local branch>
+	// ex: git checkout master
+	if canCheckout {
+		checkoutBranch := plumbing.NewBranchReferenceName(db.gitBranch)
+		err = worktree.Checkout(&git.CheckoutOptions{
+			Branch: checkoutBranch,
+			Force:  true,
+		})
+		if err != nil {
+			err = fmt.Errorf("cannot checkout on local git: `git checkout -b %s` : %w", checkoutBranch, err)
+			return
+		}
+
+		return
+	}
+
+	// When the git checkout cannot be done because of `canCheckout` false.
+	// We can be rest assured that the current reference is in the branch name specified in the config.
+	// Because in the process gitFetch we already try to SetReference(ref) to the HEAD.
+	return
+}
+
+func (db *DBImpl) forcePull(ctx context.Context) (err error) {
+	return db.pull(ctx, db.gitFetch, true)
+}
+
+// pull clones, fetches with the fetch function and checks out the branch, see forcePull.
+// Without checkout, the worktree is left behind the branch until the next pull with checkout.
+func (db *DBImpl) pull(ctx context.Context, fetch func(ctx context.Context) error, checkout bool) (err error) {
+	syncStartedAt := time.Now()
+	if db.onSyncStart != nil {
+		db.onSyncStart()
+	}
+
+	defer func() {
+		if db.onSyncEnd != nil {
+			db.onSyncEnd(time.Since(syncStartedAt), err)
+		}
+	}()
+
+	defer func() {
+		if err == nil {
+			db.writeSyncMarker(syncStartedAt)
+		}
+	}()
+
+	defer func() {
+		db.syncMu.Lock()
+		defer db.syncMu.Unlock()
+
+		db.lastSyncErr = err
+		if err == nil {
+			db.lastSyncAt = syncStartedAt
+			db.lastSyncHead, _ = db.localHead()
+			db.checkoutPending = !checkout
+		}
+	}()
+
+	err = db.phase(ctx, phaseClone)
+	if err != nil {
+		return
+	}
+
+	// the fetch abandoned by WithOperationTimeout may still write into the local repository
+	err = db.waitAbandoned(ctx)
+	if err != nil {
+		return
+	}
+
+	err = db.gitClone(ctx)
+	if err != nil {
+		err = fmt.Errorf("git clone error: %w", err)
+		return
+	}
+
+	err = db.phase(ctx, phaseFetch)
+	if err != nil {
+		return
+	}
+
+	err = fetch(ctx)
+	if err != nil {
+		err = fmt.Errorf("git fetch error: %w", err)
+		return
+	}
+
+	if !checkout {
+		return
+	}
+
+	err = db.phase(ctx, phaseCheckout)
+	if err != nil {
+		return
+	}
+
+	err = db.gitCheckout(ctx)
+	if err != nil {
+		err = fmt.Errorf("git checkout error: %w", err)
+		return
+	}
+
+	return
+}
+
+// syncForRead is like forcePull, but skip the pull when the local repository is still considered fresh,
+// that is when the last sync is within WithReadStaleness duration and no NotifyRemoteChanged after it.
+//
+// When WithStaleReadsOnRemoteError is enabled and the remote repository is unavailable,
+// the read is served from the local branch as long as it exists. The sync error is reported via LastSyncError.
+func (db *DBImpl) syncForRead(ctx context.Context) (err error) {
+	return db.syncRead(ctx, true)
+}
+
+// syncRead is syncForRead, but only checks out the worktree when checkout is true,
+// for the read which only needs the commit objects of the branch.
+func (db *DBImpl) syncRead(ctx context.Context, checkout bool) (err error) {
+	if db.isFresh(checkout) {
+		return
+	}
+
+	// the read is fetched from WithReadURL when it is set
+	err = db.pull(ctx, db.readFetch, checkout)
+	if err == nil || !db.staleReadsOnRemoteErr || !errors.Is(err, ErrRemoteUnavailable) || db.gitRepo == nil {
+		return
+	}
+
+	head, headErr := db.headCommit()
+	if headErr != nil || head == nil {
+		return
+	}
+
+	if !checkout {
+		return nil
+	}
+
+	// discard the changes in worktree, so we read the same content as the local branch
+	if checkoutErr := db.gitCheckout(ctx); checkoutErr != nil {
+		return
+	}
+
+	return nil
+}
+
+// LastSyncError returns the error of the last sync with remote repository, or nil if it was succeeded.
+// This is useful to report the sync failure when WithStaleReadsOnRemoteError is enabled,
+// since the read commands don't return the error in that case.
+func (db *DBImpl) LastSyncError() error {
+	db.syncMu.Lock()
+	defer db.syncMu.Unlock()
+
+	return db.lastSyncErr
+}
+
+// isFresh returns true when the local branch doesn't need to be synced yet, see syncForRead.
+// With worktree true, the worktree must be checked out too.
+func (db *DBImpl) isFresh(worktree bool) bool {
+	if db.readStaleness <= 0 || db.gitRepo == nil {
+		return false
+	}
+
+	db.syncMu.Lock()
+	lastSyncAt, notifiedAt, expectedHead := db.lastSyncAt, db.notifiedAt, db.expectedHead
+	checkoutPending := db.checkoutPending
+	db.syncMu.Unlock()
+
+	// the branch is fetched without checkout (see Keys), so the worktree is older than the branch
+	if worktree && checkoutPending {
+		return false
+	}
+
+	if lastSyncAt.IsZero() || time.Since(lastSyncAt) >= db.readStaleness {
+		return false
+	}
+
+	if !notifiedAt.After(lastSyncAt) {
+		return true
+	}
+
+	// notified after the last sync, but we may already have the expected commit (i.e: we pushed it ourselves).
+	if expectedHead == "" {
+		return false
+	}
+
+	commit, err := db.headCommit()
+	return err == nil && commit != nil && commit.Hash.String() == expectedHead
+}
+
+// NotifyRemoteChanged marks the local repository as stale, so the next read command always pull
+// from the remote repository even inside the WithReadStaleness window.
+// This is intended to be called from the push webhook handler (i.e: GitHub push event),
+// commitHash is the new HEAD sent by the webhook, and can be empty if it is unknown.
+// When the local branch already points to commitHash, the next read doesn't need to pull.
+func (db *DBImpl) NotifyRemoteChanged(commitHash string) {
+	db.syncMu.Lock()
+	defer db.syncMu.Unlock()
+
+	db.notifiedAt = time.Now()
+	db.expectedHead = commitHash
+}
+
+// gitPush is like `git push -f origin <branch>:<branch>` command,
+// it publishes local branch commits into remote repository.
+// gitPush pushes the local branch, and returns the pushed commit.
+// When the push is rejected as non-fast-forward and WithMergeResolver is set, the local commit is reconciled
+// on top of the remote branch and pushed again, so the pushed commit differs from the local commit before push.
+//
+// When the push fails for any reason, including the cancelled ctx, the local branch is rolled back
+// to the parent of the local commit, which is the commit pulled before the write. So the failed write
+// never stays in the local repository, where it would be served by the read or published by the next push.
+// The stats.Attempts is the number of the push, which is more than one only when the push is reconciled,
+// and the stats is also reported to WithOnPush as the push of op.
+func (db *DBImpl) gitPush(ctx context.Context, op Op) (pushed plumbing.Hash, stats PushStats, err error) {
+	startedAt := time.Now()
+	defer func() {
+		db.reportPush(op, pushed, stats, startedAt, err)
+	}()
+
+	local, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	var base plumbing.Hash
+	if local != nil && local.NumParents() > 0 {
+		base = local.ParentHashes[0]
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if rollbackErr := db.rollback(base); rollbackErr != nil {
+			err = fmt.Errorf("%w (cannot roll back the local branch: %v)", err, rollbackErr)
+		}
+	}()
+
+	err = db.phase(ctx, phasePush)
+	if err != nil {
+		return
+	}
+
+	// the push carries every commit since the remote-tracking reference (i.e: the batch of WithPushEvery),
+	// and the reconciled commit has about the same objects, so it is only measured once
+	tracked, err := db.trackedCommit()
+	if err != nil {
+		return
+	}
+
+	stats.Bytes, err = db.checkPushSize(local, tracked)
+	if err != nil {
+		return
+	}
+
+	for stats.Attempts = 1; ; stats.Attempts++ {
+		err = db.pushBranch(ctx)
+		if err == nil || !db.canReconcile(err) || stats.Attempts >= maxReconcileAttempts {
+			break
+		}
+
+		var upToDate bool
+		upToDate, err = db.reconcile(ctx)
+		if err != nil {
+			err = fmt.Errorf("cannot reconcile with the remote branch: %w", err)
+			return
+		}
+
+		if upToDate {
+			break
+		}
+	}
+
+	if err != nil && db.linearHistory && errors.Is(err, git.ErrNonFastForwardUpdate) {
+		err = fmt.Errorf("%w after %d attempts: %v", ErrPushRejected, stats.Attempts, err)
+	}
+
+	if err != nil {
+		return
+	}
+
+	head, err := db.gitRepo.Head()
+	if err != nil {
+		err = fmt.Errorf("cannot get HEAD reference after push: %w", err)
+		return
+	}
+
+	pushed = head.Hash()
+	db.markPushed(pushed)
+	err = db.trackRemote()
+	return
+}
+
+// pushBranch is like `git push <remote> <branch>:<branch>`.
+func (db *DBImpl) pushBranch(ctx context.Context) (err error) {
+	refSpec := fmt.Sprintf("%s:%s", plumbing.NewBranchReferenceName(db.gitBranch), plumbing.NewBranchReferenceName(db.gitBranch))
+	pushOpt := &git.PushOptions{
+		RemoteName: gitRemoteName,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(refSpec),
+		},
+		Auth:     db.auth,
+		Progress: db.progressWriter(),
+		Force:    db.forcePush,
+		Atomic:   db.atomicPush,
+	}
+
+	err = db.push(ctx, db.gitRepo, pushOpt)
+
+	// retry once for the remote which rejects atomic push, unless it is explicitly requested
+	if pushOpt.Atomic && !db.atomicPushSet && isAtomicPushUnsupported(err) {
+		pushOpt.Atomic = false
+		err = db.push(ctx, db.gitRepo, pushOpt)
+	}
+
+	// go-git checks the fast-forward by walking the local history until the remote commit,
+	// which ends at the missing parent of the shallow commit when the remote commit is not fetched yet
+	if !pushOpt.Force && errors.Is(err, plumbing.ErrObjectNotFound) {
+		err = fmt.Errorf("%w: the remote commit is not in the local history", git.ErrNonFastForwardUpdate)
+	}
+
+	if err != nil {
+		flag := ""
+		if pushOpt.Force {
+			flag = "-f "
+		}
+
+		err = fmt.Errorf("cannot `git push %s%s`: %w", flag, refSpec, nonFastForwardError(remoteError(db.gitSshUrl, err)))
+		return
+	}
+
+	return
+}
+
+// checkWritable returns ErrReadOnly when the DB is created using WithReadOnly.
+func (db *DBImpl) checkWritable() error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+
+	return nil
+}
+
+// gitCommit is like `git commit -m <msg>` command.
+// When the branch doesn't have any commit yet, the message from WithInitCommitMessage is used if any,
+// so the root commit of gitrows-managed branch can be distinguished.
+func (db *DBImpl) gitCommit(ctx context.Context, worktree *git.Worktree, commitMsg string, allowEmptyCommit bool) (commitHash plumbing.Hash, err error) {
+	err = db.phase(ctx, phaseCommit)
+	if err != nil {
+		if discardErr := db.discardChanges(worktree); discardErr != nil {
+			err = fmt.Errorf("%w (cannot discard the uncommitted changes: %v)", err, discardErr)
+		}
+
+		return
+	}
+
+	if db.initCommitMsg != "" || !db.createBranch {
+		var head *object.Commit
+		head, err = db.headCommit()
+		if err != nil {
+			return
+		}
+
+		if head == nil && !db.createBranch {
+			err = fmt.Errorf("%w: branch '%s' doesn't exist in %s, and WithCreateBranchIfMissing is false", ErrBranchNotFound, db.gitBranch, db.gitSshUrl)
+			if discardErr := db.discardChanges(worktree); discardErr != nil {
+				err = fmt.Errorf("%w (cannot discard the uncommitted changes: %v)", err, discardErr)
+			}
+
+			return
+		}
+
+		if head == nil && db.initCommitMsg != "" {
+			commitMsg = db.initCommitMessage(commitMsg)
+		}
+	}
+
+	// only commit what is explicitly staged by the command, so the stray changes in the worktree are not committed,
+	// unless WithCommitAllWorktreeChanges. With sparse prefix, files outside the prefix are not in the worktree
+	// and must not be committed as deleted either way.
+	commitHash, err = worktree.Commit(commitMsg, &git.CommitOptions{
+		All:               db.commitAllChanges && db.sparsePrefix == "",
+		AllowEmptyCommits: allowEmptyCommit,
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot `git commit -m %q`: %w", commitMsg, err)
+		return
+	}
+
+	if db.commitEncoding != "" {
+		commitHash, err = db.setCommitEncoding(commitHash)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// headCommit returns the last commit of local branch.
+// When the branch doesn't have any commit yet (i.e: new orphan branch), it returns nil commit without error.
+func (db *DBImpl) headCommit() (commit *object.Commit, err error) {
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	ref, err := db.gitRepo.Reference(branchName, false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("retrieving ref for branch %s error: %w", branchName, err)
+		return
+	}
+
+	commit, err = db.gitRepo.CommitObject(ref.Hash())
+	if err != nil {
+		err = fmt.Errorf("retrieving the commit object of branch %s error: %w", branchName, err)
+		return
+	}
+
+	return
+}
+
+// headFile returns the file of key in the last commit of local branch.
+// It returns nil file without error when the key doesn't exist.
+func (db *DBImpl) headFile(key string) (file *object.File, err error) {
+	commit, err := db.headCommit()
+	if err != nil || commit == nil {
+		return
+	}
+
+	file, err = commit.File(key)
+	if errors.Is(err, object.ErrFileNotFound) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot get file '%s' from commit %s: %w", key, commit.Hash, err)
+		return
+	}
+
+	return
+}
+
+func (db *DBImpl) Get(ctx context.Context, key string, opts ...GetOpt) (data []byte, err error) {
+	defer func() {
+		err = wrapError(OpGet, key, err)
+	}()
+
+	cfg := &GetConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("get command: %w", err)
+			return
+		}
+	}
+
+	key, err = validateKey(key)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	// the value buffered by WithCoalesceReadYourWrites doesn't need the sync
+	var buffered bool
+	data, buffered, err = db.readBuffered(key, cfg)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	if buffered {
+		return
+	}
+
+	// WithTreeWrites reads from the committed tree too, since the worktree is not checked out by the writes
+	readsObjects := db.treeWrites || db.readsObjects()
+	err = db.syncRead(ctx, !readsObjects)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	dir, err := db.headDir(filePath)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	if dir != nil {
+		data, err = dirListing(key, dir, cfg)
+		if err != nil {
+			err = fmt.Errorf("get command: %w", err)
+			return
+		}
+
+		return
+	}
+
+	err = db.checkCommittedPath(filePath)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	if readsObjects {
+		data, err = db.readTreeFile(filePath, cfg)
+		if err != nil {
+			err = fmt.Errorf("get command: %w", err)
+			return
+		}
+
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("get command: cannot get worktree: %w", err)
+		return
+	}
+
+	data, err = readFile(worktree.Filesystem, filePath, cfg)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	return
+}
+
+// readFile Will open the file in read-only mode, then read all its content within the range and limit of cfg.
+// It will lock the file when opened, to ensure that no other process will write the same file.
+func readFile(fs billy.Filesystem, key string, cfg *GetConfig) (data []byte, err error) {
+	reader, err := openFile(fs, key, cfg)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(reader)
+	if err != nil {
+		err = fmt.Errorf("cannot read file buffer: %w", err)
+		return
+	}
+
+	data = buf.Bytes()
+	return
+}
+
+// openFile Will open the file in read-only mode and lock it, to ensure that no other process will write the same file.
+// The returned reader only reads within the range and limit of cfg, and it must be closed to release the lock.
+func openFile(fs billy.Filesystem, key string, cfg *GetConfig) (reader io.ReadCloser, err error) {
+	matchFile, err := fs.OpenFile(key, os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		err = fmt.Errorf("cannot open file: %w", err)
+		return
+	}
+
+	// protects file for access from another process
+	err = matchFile.Lock()
+	if err != nil {
+		_ = matchFile.Close()
+		err = fmt.Errorf(
+			"cannot acquire file lock on key '%s' to protects against access from other processes: %w",
+			key, err,
+		)
+		return
+	}
+
+	var content io.Reader = matchFile
+	if cfg.offset > 0 || cfg.length > 0 {
+		length := cfg.length
+		if length == 0 {
+			length = math.MaxInt64 - cfg.offset
+		}
+
+		content = io.NewSectionReader(matchFile, cfg.offset, length)
+	}
+
+	if cfg.maxBytes > 0 {
+		content = &limitReader{
+			r:         content,
+			key:       key,
+			maxBytes:  cfg.maxBytes,
+			remaining: cfg.maxBytes,
+		}
+	}
+
+	reader = &fileReader{
+		Reader: content,
+		file:   matchFile,
+		key:    key,
+	}
+	return
+}
+
+// fileReader reads the locked file, and unlock it on Close.
+type fileReader struct {
+	io.Reader
+	file billy.File
+	key  string
+}
+
+func (f *fileReader) Close() error {
+	unlockErr := f.file.Unlock()
+	closeErr := f.file.Close()
+
+	if unlockErr != nil {
+		return fmt.Errorf("failed to unlock file '%s': %w", f.key, unlockErr)
+	}
+
+	if closeErr != nil {
+		return fmt.Errorf("failed to close file: %w", closeErr)
+	}
+
+	return nil
+}
+
+// limitReader is like io.LimitReader, but returns ErrValueTooLarge instead of io.EOF
+// when there is still data after maxBytes.
+type limitReader struct {
+	r         io.Reader
+	key       string
+	maxBytes  int64
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (n int, err error) {
+	if l.remaining <= 0 {
+		// probe one more byte to know whether the value is exactly maxBytes or larger
+		var probe [1]byte
+		n, err = l.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: value of key '%s' is larger than %d bytes", ErrValueTooLarge, l.key, l.maxBytes)
+		}
+
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+
+	n, err = l.r.Read(p)
+	l.remaining -= int64(n)
+	return
+}
+
+// writeFile Will open the file (and create if not exist), then write data into it.
+// It will lock the file when opened, to ensure that no other process will read-write the same file.
+// After writing file, this function also do `git add` command.
+// Then it will return worktree, so later we can commit it (at once) and then pushed.
+func (db *DBImpl) writeFile(ctx context.Context, key string, data []byte, mode string, fileMode os.FileMode) (worktree *git.Worktree, err error) {
+	key = path.Clean(key)
+
+	err = db.phase(ctx, phaseWrite)
+	if err != nil {
+		return
+	}
+
+	err = db.checkCaseCollision(key)
+	if err != nil {
+		return
+	}
+
+	err = db.checkNotPreserved(key)
+	if err != nil {
+		return
+	}
+
+	worktree, err = db.gitRepo.Worktree()
 	if err != nil {
 		err = fmt.Errorf("cannot get worktree: %w", err)
 		return
@@ -431,288 +1476,608 @@ func (db *DBImpl) writeFile(ctx context.Context, key string, data []byte, mode s
 		return
 	}
 
+	// the permission of OpenFile only applies for new file, so recreate the file to change the permission
+	perm := filePerm(fileInfo, fileMode)
+	if fileInfo != nil && fileInfo.Mode().Perm() != perm {
+		err = fs.Remove(key)
+		if err != nil {
+			err = fmt.Errorf("cannot change mode of '%s' to %s: %w", key, perm, err)
+			return
+		}
+	}
+
 	// Open file with mode Create if not exist, and Append if exist.
 	var matchFile billy.File
-	matchFile, err = fs.OpenFile(key, os.O_APPEND|os.O_CREATE|os.O_RDWR, os.ModePerm)
+	matchFile, err = fs.OpenFile(key, os.O_APPEND|os.O_CREATE|os.O_RDWR, perm)
+	defer func() {
+		if matchFile == nil {
+			return
+		}
+
+		if _err := matchFile.Close(); _err != nil {
+			err = fmt.Errorf("failed to close file: %w", err)
+			return
+		}
+	}()
+
+	if err != nil {
+		err = fmt.Errorf("cannot open file: %w", err)
+		return
+	}
+
+	// protects file for access from another process
+	err = matchFile.Lock()
+	if err != nil {
+		err = fmt.Errorf(
+			"cannot acquire file lock on key '%s' to protects against access from other processes: %w",
+			key, err,
+		)
+		return
+	}
+
 	defer func() {
 		if matchFile == nil {
 			return
 		}
 
-		if _err := matchFile.Close(); _err != nil {
-			err = fmt.Errorf("failed to close file: %w", err)
+		if _err := matchFile.Unlock(); _err != nil {
+			err = fmt.Errorf("failed to unlock file '%s': %w", key, _err)
+			return
+		}
+	}()
+
+	// always truncate, even on the mode CREATE, because in mode create everything has to be starts from empty file.
+	err = matchFile.Truncate(0)
+	if err != nil {
+		err = fmt.Errorf("failed to shrink the file: %w", err)
+		return
+	}
+
+	_, err = matchFile.Write(data)
+	if err != nil {
+		err = fmt.Errorf("cannot write file buffer: %w", err)
+		return
+	}
+
+	err = gitAdd(worktree, key)
+	if err != nil {
+		err = fmt.Errorf("cannot `git add %s`: %w", key, err)
+		return
+	}
+
+	return
+}
+
+// gitAdd is `git add -- <filePath>`. The file path is staged literally even when it contains the pattern characters
+// (i.e: "rules[prod].json"), so it never stages the other files matching it like worktree.AddGlob does.
+func gitAdd(worktree *git.Worktree, filePath string) error {
+	return worktree.AddWithOptions(&git.AddOptions{Path: filePath})
+}
+
+// gitRm is `git rm -- <filePath>`, the file path is removed literally like gitAdd, unlike worktree.RemoveGlob.
+func gitRm(worktree *git.Worktree, filePath string) error {
+	_, err := worktree.Remove(filePath)
+	return err
+}
+
+func (db *DBImpl) Create(ctx context.Context, key string, data []byte, opts ...CreateOpt) (commitHashString string, err error) {
+	result, err := db.CreateR(ctx, key, data, opts...)
+	return result.CommitHash, err
+}
+
+// CreateR is like Create, but returns the detail of the write in CreateResult.
+func (db *DBImpl) CreateR(ctx context.Context, key string, data []byte, opts ...CreateOpt) (result CreateResult, err error) {
+	defer func() {
+		err = wrapError(OpCreate, key, err)
+		db.recordOp(OpCreate, key, result.CommitHash, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	cfg := &CreateConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("create command: %w", err)
+			return
+		}
+	}
+
+	key, err = validateInternalKey(key, cfg.allowInternal)
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	err = db.flushBuffered(ctx, key)
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	err = db.syncForWriteUnless(ctx, cfg.skipSync)
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	err = db.checkExpectedHead(cfg.expectedHead)
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	data, err = db.canonicalizeJSON(data, cfg.canonicalJSON)
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	data = db.normalizeLineEnding(data)
+
+	var worktree *git.Worktree
+	var treeHash plumbing.Hash
+	if db.treeWrites {
+		treeHash, err = db.writeTree(ctx, filePath, data, "CREATE", cfg.fileMode)
+	} else {
+		worktree, err = db.writeFile(ctx, filePath, data, "CREATE", cfg.fileMode)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	commitMsg := db.commitMessage(OpCreate, cfg.commitMsg, "gitrows: CREATE", CommitMeta{
+		Sizes:    map[string]int64{key: int64(len(data))},
+		Changed:  true,
+		Metadata: cfg.meta,
+	})
+
+	result.BlobHash = plumbing.ComputeHash(plumbing.BlobObject, data).String()
+	result.BytesWritten = int64(len(data))
+
+	var commitHash plumbing.Hash
+	commitHash, err = db.commitChange(ctx, worktree, treeHash, commitMsg, false)
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	result.CommitHash = commitHash.String()
+
+	var stats PushStats
+	commitHash, stats, result.Pushed, err = db.pushBatched(ctx, OpCreate)
+	result.Attempts, result.BytesPushed = stats.Attempts, stats.Bytes
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	result.CommitHash = commitHash.String()
+	if result.Pushed {
+		result.PushedAt = time.Now()
+	}
+
+	return
+}
+
+func (db *DBImpl) Upsert(ctx context.Context, key string, data []byte, opts ...UpsertOpt) (commitHashString string, changed bool, err error) {
+	result, err := db.UpsertR(ctx, key, data, opts...)
+	return result.CommitHash, result.Changed, err
+}
+
+// UpsertR is like Upsert, but returns the detail of the write in UpsertResult.
+func (db *DBImpl) UpsertR(ctx context.Context, key string, data []byte, opts ...UpsertOpt) (result UpsertResult, err error) {
+	defer func() {
+		err = wrapError(OpUpsert, key, err)
+		db.recordOp(OpUpsert, key, result.CommitHash, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	cfg := &UpsertConfig{
+		allowEmptyCommit: false,
+	}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("upsert command: %w", err)
+			return
+		}
+	}
+
+	key, err = validateInternalKey(key, cfg.allowInternal)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	data, err = db.canonicalizeJSON(data, cfg.canonicalJSON)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	data = db.normalizeLineEnding(data)
+	if db.coalesces(key) && cfg.expectedHead == "" && cfg.ifDifferentFrom == "" && len(cfg.meta) == 0 {
+		result = db.coalesceUpsert(key, filePath, data, cfg)
+		return
+	}
+
+	err = db.flushBuffered(ctx, key)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	err = db.syncForWriteUnless(ctx, cfg.skipSync)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	err = db.checkExpectedHead(cfg.expectedHead)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	sameFile, err := db.committedBlob(filePath, cfg.ifDifferentFrom)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	if sameFile != nil {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("upsert command: cannot get HEAD reference: %w", err)
+			return
+		}
+
+		result.CommitHash = head.Hash().String()
+		result.BlobHash = sameFile.Hash.String()
+		result.BytesWritten = sameFile.Size
+		return
+	}
+
+	blobHash := plumbing.ComputeHash(plumbing.BlobObject, data)
+	result.BlobHash = blobHash.String()
+	result.BytesWritten = int64(len(data))
+
+	// compare with the committed content, since the rewritten file may be reported as modified by its mode only
+	oldFile, err := db.headFile(filePath)
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	result.Changed = oldFile == nil || oldFile.Hash != blobHash
+
+	var worktree *git.Worktree
+	var treeHash plumbing.Hash
+	if db.treeWrites {
+		treeHash, err = db.writeTree(ctx, filePath, data, "UPSERT", cfg.fileMode)
+		if err != nil {
+			err = fmt.Errorf("upsert command: %w", err)
+			return
+		}
+
+		var treeChanged bool
+		treeChanged, err = db.isTreeChanged(treeHash)
+		if err != nil {
+			err = fmt.Errorf("upsert command: %w", err)
+			return
+		}
+
+		result.MetadataChanged = !result.Changed && treeChanged
+	} else {
+		worktree, err = db.writeFile(ctx, filePath, data, "UPSERT", cfg.fileMode)
+		if err != nil {
+			err = fmt.Errorf("upsert command: %w", err)
+			return
+		}
+
+		var worktreeStatus git.Status
+		worktreeStatus, err = worktree.Status()
+		if err != nil {
+			err = fmt.Errorf("upsert command: cannot `git status`: %w", err)
 			return
 		}
-	}()
 
-	if err != nil {
-		err = fmt.Errorf("cannot open file: %w", err)
-		return
+		// only check the key, since with WithSparsePrefix the files outside the prefix are reported as deleted
+		fileStatus, exist := worktreeStatus[filePath]
+		result.MetadataChanged = !result.Changed && exist && fileStatus.Staging != git.Unmodified
 	}
 
-	// protects file for access from another process
-	err = matchFile.Lock()
-	if err != nil {
-		err = fmt.Errorf(
-			"cannot acquire file lock on key '%s' to protects against access from other processes: %w",
-			key, err,
-		)
+	// if allow empty commit false, and no file is changed in worktree, then skip it
+	if !cfg.allowEmptyCommit && !result.Changed && !result.MetadataChanged {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("upsert command: cannot get HEAD reference when --allow-empty and git status both false: %w", err)
+			return
+		}
+
+		// TODO: must return last commit Hash when this file is changed
+		result.CommitHash = head.Hash().String()
 		return
 	}
 
-	defer func() {
-		if matchFile == nil {
-			return
-		}
+	commitMsg := db.commitMessage(OpUpsert, cfg.commitMsg, "gitrows: UPSERT", CommitMeta{
+		Sizes:    map[string]int64{key: int64(len(data))},
+		Changed:  result.Changed,
+		Metadata: cfg.meta,
+	})
 
-		if _err := matchFile.Unlock(); _err != nil {
-			err = fmt.Errorf("failed to unlock file '%s': %w", key, _err)
+	var amended plumbing.Hash
+	if cfg.amend {
+		amended, err = db.resetForAmend(key)
+		if err != nil {
+			err = fmt.Errorf("upsert command: %w", err)
 			return
 		}
-	}()
+	}
 
-	// always truncate, even on the mode CREATE, because in mode create everything has to be starts from empty file.
-	err = matchFile.Truncate(0)
+	// the amended tree may be the same as its parent, i.e: the key is changed back
+	var commitHash plumbing.Hash
+	commitHash, err = db.commitChange(ctx, worktree, treeHash, commitMsg, cfg.allowEmptyCommit || !amended.IsZero())
 	if err != nil {
-		err = fmt.Errorf("failed to shrink the file: %w", err)
+		err = fmt.Errorf("upsert command: %w", err)
 		return
 	}
 
-	_, err = matchFile.Write(data)
-	if err != nil {
-		err = fmt.Errorf("cannot write file buffer: %w", err)
-		return
+	// using current commit as return
+	result.CommitHash = commitHash.String()
+
+	var stats PushStats
+	if amended.IsZero() {
+		commitHash, stats, result.Pushed, err = db.pushBatched(ctx, OpUpsert)
+	} else {
+		commitHash, stats, err = db.pushAmend(ctx, OpUpsert, amended)
+		result.Pushed = true
 	}
 
-	_, err = worktree.Add(key)
+	result.Attempts, result.BytesPushed = stats.Attempts, stats.Bytes
+
 	if err != nil {
-		err = fmt.Errorf("cannot `git add %s`: %w", key, err)
+		err = fmt.Errorf("upsert command: %w", err)
 		return
 	}
 
+	db.syncMu.Lock()
+	db.lastUpsertKey, db.lastUpsertHash = key, commitHash
+	db.syncMu.Unlock()
+
+	result.CommitHash = commitHash.String()
+	if result.Pushed {
+		result.PushedAt = time.Now()
+	}
+
 	return
 }
 
-func (db *DBImpl) Create(ctx context.Context, key string, data []byte, opts ...CreateOpt) (commitHashString string, err error) {
-	cfg := &CreateConfig{
-		commitMsg: "gitrows: CREATE",
+func (db *DBImpl) Delete(ctx context.Context, key string, opts ...DeleteOpt) (commitHashString string, err error) {
+	result, err := db.DeleteR(ctx, key, opts...)
+	return result.CommitHash, err
+}
+
+// DeleteR is like Delete, but returns the detail of the write in DeleteResult.
+func (db *DBImpl) DeleteR(ctx context.Context, key string, opts ...DeleteOpt) (result DeleteResult, err error) {
+	defer func() {
+		err = wrapError(OpDelete, key, err)
+		db.recordOp(OpDelete, key, result.CommitHash, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("delete command: %w", err)
+		return
 	}
 
+	cfg := &DeleteConfig{}
+
 	for _, opt := range opts {
 		err = opt(cfg)
 		if err != nil {
-			err = fmt.Errorf("create command: %w", err)
+			err = fmt.Errorf("delete command: %w", err)
 			return
 		}
 	}
 
-	key = path.Clean(key)
-	err = db.forcePull(ctx)
+	key, err = validateInternalKey(key, cfg.allowInternal)
 	if err != nil {
-		err = fmt.Errorf("create command: %w", err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	worktree, err := db.writeFile(ctx, key, data, "CREATE")
+	filePath, err := db.keyToPath(key)
 	if err != nil {
-		err = fmt.Errorf("create command: %w", err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	var commitHash plumbing.Hash
-	commitMsg := cfg.commitMsg
-	commitHash, err = worktree.Commit(commitMsg, &git.CommitOptions{
-		All:               true,
-		AllowEmptyCommits: false,
-	})
-	if err != nil {
-		err = fmt.Errorf("create command: cannot `git commit -m %q`: %w", commitMsg, err)
+	if db.coalesces(key) && cfg.expectedHead == "" && cfg.ifMatch == "" {
+		db.coalesceDelete(key, filePath)
 		return
 	}
 
-	commitHashString = commitHash.String()
-
-	refSpec := fmt.Sprintf("%s:%s", plumbing.NewBranchReferenceName(db.gitBranch), plumbing.NewBranchReferenceName(db.gitBranch))
-	err = db.gitRepo.PushContext(ctx, &git.PushOptions{
-		RemoteName: gitRemoteName,
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(refSpec),
-		},
-		Auth:     db.auth,
-		Progress: os.Stdout,
-		Force:    true,
-		Atomic:   true,
-	})
-
+	err = db.flushBuffered(ctx, key)
 	if err != nil {
-		err = fmt.Errorf("create command: cannot `git push -f %s`: %w", refSpec, err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	return
-}
-
-func (db *DBImpl) Upsert(ctx context.Context, key string, data []byte, opts ...UpsertOpt) (commitHashString string, changed bool, err error) {
-	cfg := &UpsertConfig{
-		commitMsg:        "gitrows: UPSERT",
-		allowEmptyCommit: false,
-	}
-
-	for _, opt := range opts {
-		err = opt(cfg)
-		if err != nil {
-			err = fmt.Errorf("upsert command: %w", err)
-			return
-		}
+	err = db.syncForWriteUnless(ctx, cfg.skipSync)
+	if err != nil {
+		err = fmt.Errorf("delete command: %w", err)
+		return
 	}
 
-	key = path.Clean(key)
-	err = db.forcePull(ctx)
+	err = db.checkExpectedHead(cfg.expectedHead)
 	if err != nil {
-		err = fmt.Errorf("upsert command: %w", err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	worktree, err := db.writeFile(ctx, key, data, "UPSERT")
+	err = db.checkIfMatch(key, filePath, cfg.ifMatch)
 	if err != nil {
-		err = fmt.Errorf("upsert command: %w", err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	worktreeStatus, err := worktree.Status()
+	err = db.checkCommittedPath(filePath)
 	if err != nil {
-		err = fmt.Errorf("upsert command: cannot `git status`: %w", err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	changed = !worktreeStatus.IsClean()
-
-	// if allow empty commit false, and no file is changed in worktree, then skip it
-	if !cfg.allowEmptyCommit && !changed {
-		var head *plumbing.Reference
-		head, err = db.gitRepo.Head()
-		if err != nil {
-			err = fmt.Errorf("upsert command: cannot get HEAD reference when --allow-empty and git status both false: %w", err)
-			return
-		}
+	var worktree *git.Worktree
+	var treeHash plumbing.Hash
+	if db.treeWrites {
+		treeHash, err = db.writeTree(ctx, filePath, nil, "DELETE", 0)
+	} else {
+		worktree, err = db.removeFile(ctx, filePath)
+	}
 
-		// TODO: must return last commit Hash when this file is changed
-		commitHashString = head.Hash().String()
+	if err != nil {
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	var commitHash plumbing.Hash
-	commitMsg := cfg.commitMsg
-	commitHash, err = worktree.Commit(commitMsg, &git.CommitOptions{
-		All:               true,
-		AllowEmptyCommits: cfg.allowEmptyCommit,
+	commitMsg := db.commitMessage(OpDelete, cfg.commitMsg, "gitrows: DELETE", CommitMeta{
+		Sizes:   map[string]int64{key: 0},
+		Changed: true,
 	})
+
+	var commitHash plumbing.Hash
+	commitHash, err = db.commitChange(ctx, worktree, treeHash, commitMsg, false)
 	if err != nil {
-		err = fmt.Errorf("upsert command: cannot `git commit -m %q`: %w", commitMsg, err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
-	// using current commit as return
-	commitHashString = commitHash.String()
-
-	refSpec := fmt.Sprintf("%s:%s", plumbing.NewBranchReferenceName(db.gitBranch), plumbing.NewBranchReferenceName(db.gitBranch))
-	err = db.gitRepo.PushContext(ctx, &git.PushOptions{
-		RemoteName: gitRemoteName,
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(refSpec),
-		},
-		Auth:     db.auth,
-		Progress: os.Stdout,
-		Force:    true,
-		Atomic:   true,
-	})
+	result.CommitHash = commitHash.String()
 
+	var stats PushStats
+	commitHash, stats, result.Pushed, err = db.pushBatched(ctx, OpDelete)
+	result.Attempts, result.BytesPushed = stats.Attempts, stats.Bytes
 	if err != nil {
-		err = fmt.Errorf("upsert command: cannot `git push -f %s`: %w", refSpec, err)
+		err = fmt.Errorf("delete command: %w", err)
 		return
 	}
 
+	result.CommitHash = commitHash.String()
+	if result.Pushed {
+		result.PushedAt = time.Now()
+	}
+
 	return
 }
 
-func (db *DBImpl) Delete(ctx context.Context, key string, opts ...DeleteOpt) (commitHashString string, err error) {
-	cfg := &DeleteConfig{
-		commitMsg: "gitrows: DELETE",
+// removeFile is like `git rm <key>`, and returns the worktree to commit.
+func (db *DBImpl) removeFile(ctx context.Context, key string) (worktree *git.Worktree, err error) {
+	worktree, err = db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("cannot get worktree: %w", err)
+		return
 	}
 
-	for _, opt := range opts {
-		err = opt(cfg)
-		if err != nil {
-			err = fmt.Errorf("delete command: %w", err)
-			return
-		}
-	}
+	fs := worktree.Filesystem
 
-	key = path.Clean(key)
-	err = db.forcePull(ctx)
+	// if file not exist then error
+	fileInfo, err := fs.Lstat(key)
 	if err != nil {
-		err = fmt.Errorf("delete command: %w", err)
+		err = fmt.Errorf("cannot delete '%s': %w", key, err)
 		return
 	}
 
-	worktree, err := db.gitRepo.Worktree()
-	if err != nil {
-		err = fmt.Errorf("delete command: cannot get worktree: %w", err)
+	// worktree.Remove on directory removes all files inside, which is not what Delete of one key means
+	if fileInfo.IsDir() {
+		err = fmt.Errorf("cannot delete '%s' because it is a directory", key)
 		return
 	}
 
-	fs := worktree.Filesystem
-
-	// if file not exist then error
-	err = fs.Remove(key)
+	err = db.phase(ctx, phaseWrite)
 	if err != nil {
-		err = fmt.Errorf("delete command: cannot delete '%s': %w", key, err)
 		return
 	}
 
-	_, err = worktree.Add(key)
+	err = gitRm(worktree, key)
 	if err != nil {
-		err = fmt.Errorf("delete command: cannot `git add %s`: %w", key, err)
+		err = fmt.Errorf("cannot `git rm %s`: %w", key, err)
 		return
 	}
 
-	var commitHash plumbing.Hash
-	commitMsg := cfg.commitMsg
-	commitHash, err = worktree.Commit(commitMsg, &git.CommitOptions{
-		All:               true,
-		AllowEmptyCommits: false,
-	})
+	err = removeEmptyDirs(fs, path.Dir(key))
 	if err != nil {
-		err = fmt.Errorf("delete command: cannot `git commit -m %q`: %w", commitMsg, err)
 		return
 	}
 
-	commitHashString = commitHash.String()
+	return
+}
 
-	refSpec := fmt.Sprintf("%s:%s", plumbing.NewBranchReferenceName(db.gitBranch), plumbing.NewBranchReferenceName(db.gitBranch))
-	err = db.gitRepo.PushContext(ctx, &git.PushOptions{
-		RemoteName: gitRemoteName,
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(refSpec),
-		},
-		Auth:     db.auth,
-		Progress: os.Stdout,
-		Force:    true,
-		Atomic:   true,
-	})
+// removeEmptyDirs removes dir and its parents as long as they are empty, like `git rm` does in the worktree.
+func removeEmptyDirs(fs billy.Filesystem, dir string) error {
+	for dir != "." && dir != "/" && dir != "" {
+		infos, err := fs.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("cannot read directory '%s': %w", dir, err)
+		}
 
-	if err != nil {
-		err = fmt.Errorf("delete command: cannot `git push -f %s`: %w", refSpec, err)
-		return
+		if len(infos) > 0 {
+			return nil
+		}
+
+		err = fs.Remove(dir)
+		if err != nil {
+			return fmt.Errorf("cannot remove empty directory '%s': %w", dir, err)
+		}
+
+		dir = path.Dir(dir)
 	}
 
-	return
+	return nil
 }
 
 type kvIter struct {
-	k          string
-	v          func() (io.ReadCloser, error)
-	lastCommit *object.Commit
+	k    string
+	path string // file path of k in the repository, see KeyMapper
+	v    func() (io.ReadCloser, error)
+	size int64
+	mode os.FileMode
+	hash plumbing.Hash
+
+	// the last commit is resolved on demand by the resolver, see lastCommitResolver
+	resolver      *lastCommitResolver
+	resolved      bool
+	lastCommit    *object.Commit
+	lastCommitErr error
+	verified      bool
 }
 
 func (k *kvIter) Key() string {
@@ -724,16 +2089,58 @@ func (k *kvIter) Value() (io.ReadCloser, error) {
 }
 
 func (k *kvIter) LastCommit() string {
-	if k.lastCommit == nil {
+	commit, _, _ := k.resolveLastCommit()
+	if commit == nil {
 		return ""
 	}
-	return k.lastCommit.Hash.String()
+	return commit.Hash.String()
+}
+
+func (k *kvIter) LastCommitErr() error {
+	_, _, err := k.resolveLastCommit()
+	return err
+}
+
+func (k *kvIter) LastCommitInfo() CommitInfo {
+	commit, verified, _ := k.resolveLastCommit()
+	return newCommitInfo(commit, verified)
+}
+
+func (k *kvIter) Mode() os.FileMode {
+	return k.mode
+}
+
+func (k *kvIter) BlobHash() string {
+	return k.hash.String()
+}
+
+func (k *kvIter) resolveLastCommit() (commit *object.Commit, verified bool, err error) {
+	if k.resolver == nil {
+		return k.lastCommit, k.verified, k.lastCommitErr
+	}
+
+	return k.resolver.lastCommitOf(k)
 }
 
 var _ KV = (*kvIter)(nil)
 
+// blobValue returns the Value of kvIter, which resolves the blob by its hash from the object store of repo on read,
+// instead of holding the object decoded by the List, so the value doesn't depend on the state of the later pulls.
+// repo is captured at List time, since db.gitRepo is replaced by the next sync.
+func blobValue(repo *git.Repository, hash plumbing.Hash) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		blob, err := repo.BlobObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read blob %s: %w", hash, err)
+		}
+
+		return blob.Reader()
+	}
+}
+
 type entriesImpl struct {
-	kvs []KV
+	kvs       []KV
+	truncated bool
 }
 
 var _ Entries = (*entriesImpl)(nil)
@@ -742,6 +2149,10 @@ func (e *entriesImpl) KVs() []KV {
 	return e.kvs
 }
 
+func (e *entriesImpl) Truncated() bool {
+	return e.truncated
+}
+
 // List fetch all files in current git repository.
 // This is similar like command: git ls-tree <branch-name> --name-only
 //
@@ -752,7 +2163,14 @@ func (e *entriesImpl) KVs() []KV {
 // This because, when you have 1000 commits, but 1 file is never changed after first commit,
 // then in order to track the "first commit" we need all parent commit history
 // which only be available when we `git fetch` all history.
+//
+// Since walking the history is slow, the LastCommit of each KV is only resolved on the first call and then memoized,
+// unless WithVerifyCommits or WithRejectUnsignedCommits is used which needs it up front.
 func (db *DBImpl) List(ctx context.Context, opts ...ListOpt) (entries Entries, err error) {
+	defer func() {
+		err = wrapError(OpList, "", err)
+	}()
+
 	cfg := &ListConfig{}
 
 	for _, opt := range opts {
@@ -763,94 +2181,134 @@ func (db *DBImpl) List(ctx context.Context, opts ...ListOpt) (entries Entries, e
 		}
 	}
 
-	err = db.forcePull(ctx)
+	err = db.syncRead(ctx, !db.readsObjects())
 	if err != nil {
 		err = fmt.Errorf("list command: %w", err)
 		return
 	}
 
-	// get reference of branch
-	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
-	ref, err := db.gitRepo.Reference(branchName, false)
+	kvIters, truncated, err := db.list(cfg)
 	if err != nil {
-		err = fmt.Errorf("list command: retrieving ref for branch %s error: %w", branchName, err)
+		err = fmt.Errorf("list command: %w", err)
 		return
 	}
 
+	// the verification result must be known before returning, so unverified commit can be rejected
+	if db.verifyKeyring != nil || db.rejectUnverified {
+		err = db.resolveLastCommits(kvIters)
+		if err != nil {
+			err = fmt.Errorf("list command: %w", err)
+			return
+		}
+	}
+
+	// build output using interface implementation
+	entryRow := make([]KV, 0, len(kvIters))
+	for _, kv := range kvIters {
+		entryRow = append(entryRow, kv)
+	}
+
+	entries = &entriesImpl{
+		kvs:       entryRow,
+		truncated: truncated,
+	}
+	return
+}
+
+// list returns all files in the local branch which match the cfg. Their last commit is resolved on demand,
+// call resolveLastCommits to resolve all of them at once.
+// truncated is true when there are more matching files than the cfg limit.
+// It doesn't sync with the remote repository, so caller must do it first.
+func (db *DBImpl) list(cfg *ListConfig) (kvIters []*kvIter, truncated bool, err error) {
+	kvIters = make([]*kvIter, 0)
+
 	// get all files of current branch, this similar like git ls-tree -r <branch-name>
-	commit, err := db.gitRepo.CommitObject(ref.Hash())
+	commit, err := db.headCommit()
 	if err != nil {
-		err = fmt.Errorf("list command: retrieving the commit object of branch %s error: %w", branchName, err)
 		return
 	}
 
+	// branch without any commit yet (i.e: newly created empty repository) is an empty database
+	if commit == nil {
+		return
+	}
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
 	tree, err := commit.Tree()
 	if err != nil {
-		err = fmt.Errorf("list command: retrieve the tree from the commit %s (%s) error: %w", commit.ID(), branchName, err)
+		err = fmt.Errorf("retrieve the tree from the commit %s (%s) error: %w", commit.ID(), branchName, err)
 		return
 	}
 
-	paths := make([]string, 0)
-	kvIters := make([]*kvIter, 0)
+	repo := db.gitRepo
 	err = tree.Files().ForEach(func(file *object.File) error {
+		// metadata managed by gitrows is hidden unless ListIncludeInternal is set,
+		// and other files are skipped when they are not managed by the KeyMapper
+		key, ok := file.Name, cfg.includeInternal
+		if !isMetadataPath(file.Name) {
+			key, ok = db.pathToKey(file.Name)
+		}
+
+		if !ok {
+			return nil
+		}
+
 		// when filter applied
-		if path.Clean(cfg.prefix) != "" && path.Dir(file.Name) == cfg.prefix {
-			paths = append(paths, file.Name)
-			kvIters = append(kvIters, &kvIter{
-				k: file.Name,
-				v: file.Reader,
-			})
+		if !cfg.match(key) {
 			return nil
 		}
 
-		paths = append(paths, file.Name)
+		if cfg.limit > 0 && len(kvIters) >= cfg.limit {
+			truncated = true
+			return storer.ErrStop
+		}
+
 		kvIters = append(kvIters, &kvIter{
-			k: file.Name,
-			v: file.Reader,
+			k:    key,
+			path: file.Name,
+			v:    blobValue(repo, file.Hash),
+			size: file.Size,
+			mode: fileMode(file),
+			hash: file.Hash,
 		})
 		return nil
 	})
 	if err != nil {
-		err = fmt.Errorf("list command: cannot iterate tree: %w", err)
+		err = fmt.Errorf("cannot iterate tree: %w", err)
 		return
 	}
 
+	resolver := newLastCommitResolver(db, commit)
+	for _, kv := range kvIters {
+		kv.resolver = resolver
+	}
+
+	return
+}
+
+// lastCommits returns the last commit which modifies each path, walking the history from the commit.
+// Path which is not modified within the local history is not returned.
+func (db *DBImpl) lastCommits(commit *object.Commit, paths []string) (revs map[string]*object.Commit, err error) {
 	worktree, err := db.gitRepo.Worktree()
 	if err != nil {
-		err = fmt.Errorf("list command: cannot get worktree: %w", err)
+		err = fmt.Errorf("cannot get worktree: %w", err)
 		return
 	}
 
 	fs := worktree.Filesystem
 
 	commitNodeIndex := getCommitNodeIndex(db.gitRepo, fs)
-	commitNode, err := commitNodeIndex.Get(ref.Hash())
+	commitNode, err := commitNodeIndex.Get(commit.Hash)
 	if err != nil {
-		err = fmt.Errorf("list command: cannot get commit node index: %w", err)
+		err = fmt.Errorf("cannot get commit node index: %w", err)
 		return
 	}
 
-	revs, err := getLastCommitForPaths(commitNode, paths)
+	revs, err = getLastCommitForPaths(commitNode, paths)
 	if err != nil {
-		err = fmt.Errorf("list command: cannot get last commit for paths: %w", err)
+		err = fmt.Errorf("cannot get last commit for paths: %w", err)
 		return
 	}
 
-	// build output using interface implementation
-	entryRow := make([]KV, 0)
-	for _, kv := range kvIters {
-		kv.lastCommit = commit // use current commit as default
-
-		lastCommit, exist := revs[kv.Key()]
-		if exist && lastCommit != nil {
-			kv.lastCommit = lastCommit
-		}
-
-		entryRow = append(entryRow, kv)
-	}
-
-	entries = &entriesImpl{
-		kvs: entryRow,
-	}
 	return
 }
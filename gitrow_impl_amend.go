@@ -0,0 +1,131 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// resetForAmend moves the local branch to the parent of its tip, like `git reset --soft HEAD~1`,
+// when the tip is the last Upsert of the key by this DB. The written file is kept in the index,
+// so the next commit is the amended tip. It returns the amended commit, or zero hash when the tip can't be amended.
+func (db *DBImpl) resetForAmend(key string) (amended plumbing.Hash, err error) {
+	db.syncMu.Lock()
+	lastKey, lastHash := db.lastUpsertKey, db.lastUpsertHash
+	db.syncMu.Unlock()
+
+	if lastKey != key || lastHash.IsZero() {
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil || head == nil || head.Hash != lastHash {
+		return
+	}
+
+	// the commit is never amended twice from the different tip, even when the same hash is pushed by other DB later
+	db.syncMu.Lock()
+	db.lastUpsertKey, db.lastUpsertHash = "", plumbing.ZeroHash
+	db.syncMu.Unlock()
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	if head.NumParents() == 0 {
+		err = db.gitRepo.Storer.RemoveReference(branchName)
+	} else {
+		err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(branchName, head.ParentHashes[0]))
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot reset local branch %s to amend %s: %w", branchName, head.Hash, err)
+		return
+	}
+
+	return head.Hash, nil
+}
+
+// pushAmend is like gitPush, but force-pushes the amended commit with the lease on the commit it replaces,
+// so the push is rejected when other writer has pushed on top of it. The local branch is rolled back to the
+// replaced commit on failure, which is the remote branch as far as we know.
+func (db *DBImpl) pushAmend(ctx context.Context, op Op, replaced plumbing.Hash) (pushed plumbing.Hash, stats PushStats, err error) {
+	startedAt := time.Now()
+	defer func() {
+		db.reportPush(op, pushed, stats, startedAt, err)
+	}()
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if rollbackErr := db.rollback(replaced); rollbackErr != nil {
+			err = fmt.Errorf("%w (cannot roll back the local branch: %v)", err, rollbackErr)
+		}
+	}()
+
+	err = db.phase(ctx, phasePush)
+	if err != nil {
+		return
+	}
+
+	// the remote branch is at the replaced commit, not the parent of the amended commit
+	local, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	replacedCommit, err := db.gitRepo.CommitObject(replaced)
+	if err != nil {
+		err = fmt.Errorf("cannot get commit %s: %w", replaced, err)
+		return
+	}
+
+	stats.Bytes, err = db.checkPushSize(local, replacedCommit)
+	if err != nil {
+		return
+	}
+
+	stats.Attempts = 1
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+
+	// go-git resolves the remote-tracking reference for the lease even when its hash is given,
+	// which doesn't exist when the repository is initialized locally (the remote was empty)
+	trackingName := plumbing.NewRemoteReferenceName(gitRemoteName, db.gitBranch)
+	err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(trackingName, replaced))
+	if err != nil {
+		err = fmt.Errorf("cannot set reference %s: %w", trackingName, err)
+		return
+	}
+
+	refSpec := fmt.Sprintf("+%s:%s", branchName, branchName)
+	err = db.push(ctx, db.gitRepo, &git.PushOptions{
+		RemoteName: gitRemoteName,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(refSpec),
+		},
+		Auth:     db.auth,
+		Progress: db.progressWriter(),
+		ForceWithLease: &git.ForceWithLease{
+			RefName: branchName,
+			Hash:    replaced,
+		},
+	})
+
+	if err != nil {
+		err = fmt.Errorf("cannot `git push --force-with-lease %s`: %w", refSpec, nonFastForwardError(remoteError(db.gitSshUrl, err)))
+		return
+	}
+
+	head, err := db.gitRepo.Head()
+	if err != nil {
+		err = fmt.Errorf("cannot get HEAD reference after push: %w", err)
+		return
+	}
+
+	pushed = head.Hash()
+	db.markPushed(pushed)
+	return
+}
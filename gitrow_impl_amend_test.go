@@ -0,0 +1,76 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestUpsertAmend(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	base, err := db.Create(ctx, "base.txt", []byte("base"))
+	require.NoError(t, err)
+
+	parentOf := func(hash string) string {
+		t.Helper()
+
+		commit := remoteCommit(t, remote, hash)
+		require.Len(t, commit.ParentHashes, 1)
+		return commit.ParentHashes[0].String()
+	}
+
+	// the tip is not created by Upsert of this DB
+	first, _, err := db.Upsert(ctx, "draft.txt", []byte("1"), gitrows.UpsertAmend())
+	require.NoError(t, err)
+	assert.Equal(t, base, parentOf(first))
+
+	for _, data := range []string{"12", "123"} {
+		var commitHash string
+		var changed bool
+		commitHash, changed, err = db.Upsert(ctx, "draft.txt", []byte(data), gitrows.UpsertAmend())
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, remoteHead(t, remote), commitHash)
+		assert.Equal(t, base, parentOf(commitHash))
+	}
+
+	data, err := newTestDB(t, remote).Get(ctx, "draft.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "123", string(data))
+
+	// the same content is not committed
+	tip := remoteHead(t, remote)
+	commitHash, changed, err := db.Upsert(ctx, "draft.txt", []byte("123"), gitrows.UpsertAmend())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, tip, commitHash)
+
+	// other key
+	commitHash, _, err = db.Upsert(ctx, "other.txt", []byte("o"), gitrows.UpsertAmend())
+	require.NoError(t, err)
+	assert.Equal(t, tip, parentOf(commitHash))
+
+	// the tip is the commit of other DB
+	_, _, err = db.Upsert(ctx, "draft.txt", []byte("1234"))
+	require.NoError(t, err)
+
+	other, _, err := newTestDB(t, remote).Upsert(ctx, "draft.txt", []byte("other"))
+	require.NoError(t, err)
+
+	commitHash, _, err = db.Upsert(ctx, "draft.txt", []byte("12345"), gitrows.UpsertAmend())
+	require.NoError(t, err)
+	assert.Equal(t, other, parentOf(commitHash))
+
+	// amending back to the content of the parent keeps the commit
+	commitHash, changed, err = db.Upsert(ctx, "draft.txt", []byte("other"), gitrows.UpsertAmend())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, other, parentOf(commitHash))
+	assert.Equal(t, remoteCommit(t, remote, other).TreeHash, remoteCommit(t, remote, commitHash).TreeHash)
+}
@@ -0,0 +1,44 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithBaseContext ties the lifetime of the DB to ctx, i.e: the root context of the application.
+// The DB derives its own context from ctx in New, which is cancelled by Close or when ctx is cancelled.
+// The background works (the flush of WithWriteCoalescing) run with it, and every call to the remote repository
+// (fetch, push and `git ls-remote`) is cancelled with it, on top of the ctx given to the command.
+// The default is context.Background(), so only Close ends the lifetime.
+//
+// Once ctx is cancelled, the buffered writes cannot be flushed anymore, so call Close (or Flush)
+// before cancelling it on the shutdown.
+func WithBaseContext(ctx context.Context) Opt {
+	return func(db *DBImpl) error {
+		if ctx == nil {
+			return fmt.Errorf("base context must not be nil")
+		}
+
+		db.baseCtx = ctx
+		return nil
+	}
+}
+
+// withLifetime returns ctx which is also cancelled when the lifetime of the DB ends, see WithBaseContext.
+// The cancel must be called to release the goroutine watching the lifetime.
+func (db *DBImpl) withLifetime(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.lifetime == nil || db.lifetime.Done() == nil {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-db.lifetime.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
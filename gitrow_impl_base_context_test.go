@@ -0,0 +1,64 @@
+package gitrows_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithBaseContext(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	t.Run("base context cancelled", func(t *testing.T) {
+		baseCtx, cancel := context.WithCancel(ctx)
+		db := newTestDB(t, remote, gitrows.WithBaseContext(baseCtx))
+
+		data, err := db.Get(ctx, "a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+
+		cancel()
+
+		_, err = db.Get(ctx, "a.txt")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		db := newTestDB(t, remote)
+		require.NoError(t, db.Close())
+
+		_, err := db.Get(ctx, "a.txt")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("closed with failing flush", func(t *testing.T) {
+		unavailable := "file://" + filepath.Join(t.TempDir(), "missing.git")
+		db := newTestDB(t, unavailable, gitrows.WithWriteCoalescing(10*time.Millisecond))
+		_, _, err := db.Upsert(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+
+		require.Error(t, db.Close())
+
+		_, err = db.Get(ctx, "a.txt")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// the background flush is stopped rather than retried every window
+		db.FlushErrors()
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, db.FlushErrors())
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		_, err := gitrows.New(gitrows.WithGitSshUrl(remote), gitrows.WithBaseContext(nil))
+		assert.Error(t, err)
+	})
+}
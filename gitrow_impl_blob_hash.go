@@ -0,0 +1,20 @@
+package gitrows
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// committedBlob returns the committed file of the file path when its blob hash is blobHash, see UpsertIfDifferentFrom.
+// It returns nil file when blobHash is empty, the file path doesn't exist or the hash is different.
+func (db *DBImpl) committedBlob(filePath, blobHash string) (file *object.File, err error) {
+	if blobHash == "" {
+		return
+	}
+
+	file, err = db.headFile(filePath)
+	if err != nil || file == nil || file.Hash.String() == blobHash {
+		return
+	}
+
+	return nil, nil
+}
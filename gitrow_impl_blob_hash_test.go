@@ -0,0 +1,107 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestBlobHash(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	// the same as `printf hello | git hash-object --stdin`
+	helloHash := gitrows.BlobHash([]byte("hello"))
+	assert.Equal(t, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", helloHash)
+
+	created, err := db.CreateR(ctx, "a.txt", []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, helloHash, created.BlobHash)
+
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries.KVs(), 1)
+	assert.Equal(t, helloHash, entries.KVs()[0].BlobHash())
+
+	// skipped by comparing the tree entry, even when the given value is different
+	upserted, err := db.UpsertR(ctx, "a.txt", []byte("world"), gitrows.UpsertIfDifferentFrom(helloHash))
+	require.NoError(t, err)
+	assert.False(t, upserted.Changed)
+	assert.Equal(t, created.CommitHash, upserted.CommitHash)
+	assert.Equal(t, helloHash, upserted.BlobHash)
+
+	commitHash, err := db.PutReader(ctx, "a.txt", iotest.ErrReader(errors.New("must not be read")), gitrows.UpsertIfDifferentFrom(helloHash))
+	require.NoError(t, err)
+	assert.Equal(t, created.CommitHash, commitHash)
+
+	data, err := db.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	upserted, err = db.UpsertR(ctx, "a.txt", []byte("world"), gitrows.UpsertIfDifferentFrom(gitrows.BlobHash([]byte("world"))))
+	require.NoError(t, err)
+	assert.True(t, upserted.Changed)
+	assert.Equal(t, gitrows.BlobHash([]byte("world")), upserted.BlobHash)
+	assert.Equal(t, remoteHead(t, remote), upserted.CommitHash)
+
+	_, err = db.UpsertR(ctx, "a.txt", []byte("world"), gitrows.UpsertIfDifferentFrom("hello"))
+	assert.Error(t, err)
+}
+
+// remoteBlobHash returns the blob hash of the file path in the tree of the remote master branch.
+func remoteBlobHash(t *testing.T, remoteURL, filePath string) string {
+	t.Helper()
+
+	repo, err := git.PlainOpen(strings.TrimPrefix(remoteURL, "file://"))
+	require.NoError(t, err)
+
+	commit, err := repo.CommitObject(plumbing.NewHash(remoteHead(t, remoteURL)))
+	require.NoError(t, err)
+
+	file, err := commit.File(filePath)
+	require.NoError(t, err)
+
+	return file.Hash.String()
+}
+
+func TestBlobHash_writeResults(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithJSONCanonicalization())
+
+	// the hash of the canonicalized value, which is the one committed
+	created, err := db.CreateR(ctx, "a.json", []byte(`{"b": 2, "a": 1}`))
+	require.NoError(t, err)
+	assert.Equal(t, remoteBlobHash(t, remote, "a.json"), created.BlobHash)
+	assert.Equal(t, gitrows.BlobHash([]byte("{\n  \"a\": 1,\n  \"b\": 2\n}\n")), created.BlobHash)
+
+	upserted, err := db.UpsertR(ctx, "a.json", []byte(`{"a": 2}`))
+	require.NoError(t, err)
+	assert.True(t, upserted.Changed)
+	assert.Equal(t, remoteBlobHash(t, remote, "a.json"), upserted.BlobHash)
+
+	// nothing is committed, but the hash is still the one of the committed file
+	unchanged, err := db.UpsertR(ctx, "a.json", []byte(`{ "a" : 2 }`))
+	require.NoError(t, err)
+	assert.False(t, unchanged.Changed)
+	assert.Equal(t, upserted.CommitHash, remoteHead(t, remote))
+	assert.Equal(t, remoteBlobHash(t, remote, "a.json"), unchanged.BlobHash)
+
+	// the buffered Upsert returns the hash before it is pushed
+	coalesced := newTestDB(t, remote, gitrows.WithJSONCanonicalization(), gitrows.WithWriteCoalescing(time.Hour))
+	buffered, err := coalesced.UpsertR(ctx, "a.json", []byte(`{"c": 3, "a": 3}`))
+	require.NoError(t, err)
+	require.NoError(t, coalesced.Flush(ctx))
+	assert.Equal(t, remoteBlobHash(t, remote, "a.json"), buffered.BlobHash)
+	require.NoError(t, coalesced.Close())
+}
@@ -0,0 +1,239 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// WriteToBranches upserts the same key and data into every branch, i.e: to replicate the config
+// into several environment branches. The commit hash of each succeeded branch is returned in commitHashes.
+// When some branches fail, the rest of branches are still written and the error is *MultiError
+// containing the error of each failing branch, keyed by the branch name.
+//
+// Please note, the write is NOT atomic across branches: each branch is committed and pushed one by one,
+// so a failure in one branch doesn't revert the others. Retry only the failing branches.
+//
+// Each branch other than the one in WithBranch uses its own local repository next to the local git volume,
+// which is reused for the following calls.
+func (db *DBImpl) WriteToBranches(ctx context.Context, branches []string, key string, data []byte, opts ...UpsertOpt) (commitHashes map[string]string, err error) {
+	defer func() {
+		err = wrapError(OpWriteToBranches, key, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("write to branches command: %w", err)
+		return
+	}
+
+	commitHashes = make(map[string]string, len(branches))
+	branchErrs := make(map[string]error)
+
+	seen := make(map[string]struct{}, len(branches))
+	for _, branch := range branches {
+		branch = strings.TrimSpace(branch)
+
+		name, validateErr := validateBranch(branch)
+		if validateErr != nil {
+			branchErrs[branch] = fmt.Errorf("write to branches command: %w", validateErr)
+			continue
+		}
+
+		branch = name
+		if _, exist := seen[branch]; exist {
+			continue
+		}
+
+		seen[branch] = struct{}{}
+
+		var commitHash string
+		commitHash, _, err = db.branchDB(branch).Upsert(ctx, key, data, opts...)
+		if err != nil {
+			branchErrs[branch] = err
+			continue
+		}
+
+		commitHashes[branch] = commitHash
+	}
+
+	err = nil
+	if len(branchErrs) > 0 {
+		err = &MultiError{Errors: branchErrs}
+		return
+	}
+
+	return
+}
+
+// DeleteRemoteBranch deletes the branch in the remote repository, like `git push origin :refs/heads/<name>`,
+// i.e: to clean up the stale branches created by WriteToBranches.
+// The branch of WithBranch cannot be deleted, and ErrBranchNotFound is returned when the branch doesn't exist.
+func (db *DBImpl) DeleteRemoteBranch(ctx context.Context, name string) (err error) {
+	defer func() {
+		err = wrapError(OpDeleteRemoteBranch, "", err)
+		db.recordOp(OpDeleteRemoteBranch, name, "", err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("delete remote branch command: %w", err)
+		return
+	}
+
+	branch, err := validateBranch(strings.TrimSpace(name))
+	if err != nil {
+		err = fmt.Errorf("delete remote branch command: %w", err)
+		return
+	}
+
+	if branch == db.gitBranch {
+		err = fmt.Errorf("delete remote branch command: %w: cannot delete the configured branch '%s'", ErrInvalidBranch, branch)
+		return
+	}
+
+	if db.gitRepo == nil {
+		err = db.gitClone(ctx)
+		if err != nil {
+			err = fmt.Errorf("delete remote branch command: %w", err)
+			return
+		}
+	}
+
+	refSpec := fmt.Sprintf(":%s", plumbing.NewBranchReferenceName(branch))
+	err = db.push(ctx, db.gitRepo, &git.PushOptions{
+		RemoteName: gitRemoteName,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(refSpec),
+		},
+		Auth:     db.auth,
+		Progress: db.progressWriter(),
+	})
+
+	// go-git sends nothing when the branch doesn't exist in the remote
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = fmt.Errorf("delete remote branch command: %w: branch '%s' doesn't exist in %s", ErrBranchNotFound, branch, db.gitSshUrl)
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("delete remote branch command: cannot `git push %s %s`: %w", gitRemoteName, refSpec, remoteError(db.gitSshUrl, err))
+		return
+	}
+
+	return
+}
+
+// Branches returns the name of the branches (sorted) in the remote repository, like `git ls-remote --heads <url>`,
+// without fetching any object, i.e: to check the branch exists before writing with WithCreateBranchIfMissing(false).
+// The empty remote repository has no branch.
+func (db *DBImpl) Branches(ctx context.Context) (branches []string, err error) {
+	defer func() {
+		err = wrapError(OpBranches, "", err)
+	}()
+
+	refs, err := db.remoteRefs(ctx)
+	if err != nil {
+		err = fmt.Errorf("branches command: %w", err)
+		return
+	}
+
+	branches = make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Name().IsBranch() {
+			branches = append(branches, ref.Name().Short())
+		}
+	}
+
+	sort.Strings(branches)
+	return
+}
+
+// branchDB returns the DBImpl with the same configuration but for another branch.
+// It is created only once for each branch, using its own local repository.
+func (db *DBImpl) branchDB(branch string) *DBImpl {
+	if branch == db.gitBranch {
+		return db
+	}
+
+	db.branchDBsMu.Lock()
+	defer db.branchDBsMu.Unlock()
+
+	if branchDB, exist := db.branchDBs[branch]; exist {
+		return branchDB
+	}
+
+	branchDB := db.sibling(branch, fmt.Sprintf("%s@%s", db.gitVolume, branch))
+	if db.branchDBs == nil {
+		db.branchDBs = make(map[string]*DBImpl)
+	}
+
+	db.branchDBs[branch] = branchDB
+	return branchDB
+}
+
+// sibling returns new DBImpl with the same options, but for the branch and cloned into the volume.
+// The sync state is not copied.
+func (db *DBImpl) sibling(branch, volume string) *DBImpl {
+	return &DBImpl{
+		gitSshUser:            db.gitSshUser,
+		gitSshUrl:             db.gitSshUrl,
+		gitURLParsed:          db.gitURLParsed,
+		gitBranch:             branch,
+		gitVolume:             volume,
+		requireExistingBranch: db.requireExistingBranch,
+		createBranch:          db.createBranch,
+		allowMissingRemote:    db.allowMissingRemote,
+		readStaleness:         db.readStaleness,
+		initCommitMsg:         db.initCommitMsg,
+		staleReadsOnRemoteErr: db.staleReadsOnRemoteErr,
+		readOnly:              db.readOnly,
+		sparsePrefix:          db.sparsePrefix,
+		preservePaths:         db.preservePaths,
+		treeWrites:            db.treeWrites,
+		readStrategy:          db.readStrategy,
+		updateRemoteURL:       db.updateRemoteURL,
+		keyMapper:             db.keyMapper,
+		keyEncoding:           db.keyEncoding,
+		lineEnding:            db.lineEnding,
+		jsonCanonical:         db.jsonCanonical,
+		verifyKeyring:         db.verifyKeyring,
+		rejectUnverified:      db.rejectUnverified,
+		caseCollisionProtect:  db.caseCollisionProtect,
+		objectCacheSize:       db.objectCacheSize,
+		atomicPush:            db.atomicPush,
+		atomicPushSet:         db.atomicPushSet,
+		forcePush:             db.forcePush,
+		fetchPrune:            db.fetchPrune,
+		mergeResolver:         db.mergeResolver,
+		linearHistory:         db.linearHistory,
+		operationTimeout:      db.operationTimeout,
+		maxPushBytes:          db.maxPushBytes,
+		privateKey:            db.privateKey,
+		privateKeyPwd:         db.privateKeyPwd,
+		auth:                  db.auth,
+		readURL:               db.readURL,
+		readPrivateKey:        db.readPrivateKey,
+		readPrivateKeyPwd:     db.readPrivateKeyPwd,
+		readAuth:              db.readAuth,
+		progress:              db.progress,
+		onProgress:            db.onProgress,
+		onSyncStart:           db.onSyncStart,
+		onSyncEnd:             db.onSyncEnd,
+		onPush:                db.onPush,
+		onSkippedPath:         db.onSkippedPath,
+		commitMsgFunc:         db.commitMsgFunc,
+		commitFooter:          db.commitFooter,
+		commitAllChanges:      db.commitAllChanges,
+		commitEncoding:        db.commitEncoding,
+		worktreeFS:            db.worktreeFS,
+		lifetime:              db.lifetime,
+		journal:               db.journal,
+	}
+}
@@ -0,0 +1,141 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_WriteToBranches(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	branches := []string{"master", "staging", "production", "staging"}
+	commits, err := db.WriteToBranches(ctx, branches, "config.yaml", []byte("replicas: 3"))
+	require.NoError(t, err)
+	require.Len(t, commits, 3)
+
+	for _, branch := range []string{"master", "staging", "production"} {
+		reader := newTestDB(t, remote, gitrows.WithBranch(branch))
+		data, err := reader.Get(ctx, "config.yaml")
+		require.NoError(t, err, branch)
+		assert.Equal(t, []byte("replicas: 3"), data)
+		assert.NotEmpty(t, commits[branch])
+	}
+
+	// the local repository of each branch is reused
+	commits, err = db.WriteToBranches(ctx, branches, "config.yaml", []byte("replicas: 5"))
+	require.NoError(t, err)
+	require.Len(t, commits, 3)
+
+	data, err := newTestDB(t, remote, gitrows.WithBranch("production")).Get(ctx, "config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("replicas: 5"), data)
+}
+
+func TestDBImpl_WriteToBranches_partialFailure(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "config.yaml", []byte("replicas: 1"))
+	require.NoError(t, err)
+
+	db := newTestDB(t, remote, gitrows.WithRequireExistingBranch(true))
+	commits, err := db.WriteToBranches(ctx, []string{"master", "missing"}, "config.yaml", []byte("replicas: 3"))
+	assert.Contains(t, commits, "master")
+	assert.NotContains(t, commits, "missing")
+
+	var multiErr *gitrows.MultiError
+	require.True(t, errors.As(err, &multiErr))
+	require.Len(t, multiErr.Errors, 1)
+	assert.True(t, errors.Is(multiErr.Errors["missing"], gitrows.ErrBranchNotFound))
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+	_, err = newTestDB(t, remote, gitrows.WithReadOnly()).WriteToBranches(ctx, []string{"master"}, "config.yaml", nil)
+	assert.True(t, errors.Is(err, gitrows.ErrReadOnly))
+}
+
+func TestDBImpl_DeleteRemoteBranch(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.WriteToBranches(ctx, []string{"master", "pr-1"}, "config.yaml", []byte("replicas: 3"))
+	require.NoError(t, err)
+
+	err = db.DeleteRemoteBranch(ctx, "refs/heads/pr-1")
+	require.NoError(t, err)
+
+	_, err = newTestDB(t, remote, gitrows.WithBranch("pr-1"), gitrows.WithRequireExistingBranch(true)).Get(ctx, "config.yaml")
+	assert.True(t, errors.Is(err, gitrows.ErrBranchNotFound))
+
+	// the deleted branch is not found anymore
+	err = db.DeleteRemoteBranch(ctx, "pr-1")
+	assert.True(t, errors.Is(err, gitrows.ErrBranchNotFound))
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+	// the configured branch is protected
+	err = db.DeleteRemoteBranch(ctx, "master")
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidBranch))
+
+	data, err := newTestDB(t, remote).Get(ctx, "config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("replicas: 3"), data)
+
+	err = newTestDB(t, remote, gitrows.WithReadOnly()).DeleteRemoteBranch(ctx, "pr-1")
+	assert.True(t, errors.Is(err, gitrows.ErrReadOnly))
+}
+
+func TestDBImpl_Branches(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	branches, err := db.Branches(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, branches)
+
+	_, err = db.WriteToBranches(ctx, []string{"staging", "master", "feature/a"}, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	branches, err = db.Branches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature/a", "master", "staging"}, branches)
+}
+
+func TestWithCreateBranchIfMissing(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	for _, opts := range [][]gitrows.Opt{nil, {gitrows.WithTreeWrites()}} {
+		opts = append(opts, gitrows.WithBranch("stagign"), gitrows.WithCreateBranchIfMissing(false))
+		db := newTestDB(t, remote, opts...)
+
+		_, err = db.Create(ctx, "b.txt", []byte("b"))
+		assert.ErrorIs(t, err, gitrows.ErrBranchNotFound)
+
+		_, _, err = db.Upsert(ctx, "b.txt", []byte("b"))
+		assert.ErrorIs(t, err, gitrows.ErrBranchNotFound)
+
+		// the reads still see the empty branch
+		entries, err := db.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, entries.KVs())
+	}
+
+	branches, err := newTestDB(t, remote).Branches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"master"}, branches)
+
+	// the existing branch is written as usual
+	_, err = newTestDB(t, remote, gitrows.WithCreateBranchIfMissing(false)).Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,314 @@
+package gitrows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Bucket packs many keys into the JSON object of one file, see DBImpl.Bucket.
+// The Key of *Error is the key inside the bucket, except for List.
+type Bucket struct {
+	db      *DBImpl
+	fileKey string
+}
+
+// Bucket returns the Bucket which keys are the members of the JSON object stored at fileKey,
+// i.e: to keep millions of tiny values in a few files instead of millions of files in the tree.
+//
+// Every Put and Delete rewrites and commits the whole file in canonical JSON form (see WithJSONCanonicalization),
+// so the history of each key is the history of the file. The file is created by the first Put.
+// The value must be valid JSON, and Get returns it in compact form.
+func (db *DBImpl) Bucket(fileKey string) *Bucket {
+	return &Bucket{
+		db:      db,
+		fileKey: fileKey,
+	}
+}
+
+// Get returns the value of key in the bucket, or os.ErrNotExist when either the key or the file doesn't exist.
+func (b *Bucket) Get(ctx context.Context, key string, opts ...GetOpt) (data []byte, err error) {
+	defer func() {
+		err = wrapError(OpBucketGet, key, err)
+	}()
+
+	entries, err := b.entries(ctx, opts...)
+	if err != nil {
+		err = fmt.Errorf("bucket get command: %w", err)
+		return
+	}
+
+	value, exist := entries[key]
+	if !exist {
+		err = fmt.Errorf("bucket get command: %w: key '%s' doesn't exist in bucket '%s'", os.ErrNotExist, key, b.fileKey)
+		return
+	}
+
+	return compactJSON(value)
+}
+
+// List returns the value of every key in the bucket, which is empty when the file doesn't exist.
+func (b *Bucket) List(ctx context.Context, opts ...GetOpt) (values map[string][]byte, err error) {
+	defer func() {
+		err = wrapError(OpBucketList, b.fileKey, err)
+	}()
+
+	entries, err := b.entries(ctx, opts...)
+	if err != nil {
+		err = fmt.Errorf("bucket list command: %w", err)
+		return
+	}
+
+	values = make(map[string][]byte, len(entries))
+	for key, value := range entries {
+		values[key], err = compactJSON(value)
+		if err != nil {
+			err = fmt.Errorf("bucket list command: key '%s': %w", key, err)
+			return
+		}
+	}
+
+	return
+}
+
+// Put sets the value of key in the bucket. Only UpsertCommitMsg and UpsertAllowInternalPaths (for the file) apply.
+// When the value is the same, nothing is committed and changed is false, the same as Upsert.
+func (b *Bucket) Put(ctx context.Context, key string, data []byte, opts ...UpsertOpt) (commitHashString string, changed bool, err error) {
+	defer func() {
+		err = wrapError(OpBucketPut, key, err)
+		b.db.recordOp(OpBucketPut, key, commitHashString, err)
+	}()
+
+	cfg := &UpsertConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("bucket put command: %w", err)
+			return
+		}
+	}
+
+	if strings.TrimSpace(key) == "" {
+		err = fmt.Errorf("bucket put command: %w: key must not be empty", ErrInvalidKey)
+		return
+	}
+
+	if !json.Valid(data) {
+		err = fmt.Errorf("bucket put command: value of key '%s' must be valid JSON", key)
+		return
+	}
+
+	commitMsg := b.db.commitMessage(OpBucketPut, cfg.commitMsg, "gitrows: BUCKET PUT", CommitMeta{
+		Sizes:   map[string]int64{key: int64(len(data))},
+		Changed: true,
+	})
+
+	commitHashString, changed, err = b.mutate(ctx, OpBucketPut, cfg.allowInternal, commitMsg, func(entries map[string]json.RawMessage) error {
+		entries[key] = data
+		return nil
+	})
+
+	if err != nil {
+		err = fmt.Errorf("bucket put command: %w", err)
+		return
+	}
+
+	return
+}
+
+// Delete removes key from the bucket, or returns os.ErrNotExist when it doesn't exist.
+// The file is kept as the empty object after the last key is deleted.
+func (b *Bucket) Delete(ctx context.Context, key string, opts ...DeleteOpt) (commitHashString string, err error) {
+	defer func() {
+		err = wrapError(OpBucketDelete, key, err)
+		b.db.recordOp(OpBucketDelete, key, commitHashString, err)
+	}()
+
+	cfg := &DeleteConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("bucket delete command: %w", err)
+			return
+		}
+	}
+
+	commitMsg := b.db.commitMessage(OpBucketDelete, cfg.commitMsg, "gitrows: BUCKET DELETE", CommitMeta{
+		Sizes:   map[string]int64{key: 0},
+		Changed: true,
+	})
+
+	commitHashString, _, err = b.mutate(ctx, OpBucketDelete, cfg.allowInternal, commitMsg, func(entries map[string]json.RawMessage) error {
+		if _, exist := entries[key]; !exist {
+			return fmt.Errorf("%w: key '%s' doesn't exist in bucket '%s'", os.ErrNotExist, key, b.fileKey)
+		}
+
+		delete(entries, key)
+		return nil
+	})
+
+	if err != nil {
+		err = fmt.Errorf("bucket delete command: %w", err)
+		return
+	}
+
+	return
+}
+
+// entries reads the JSON object of the file, which is empty when the file doesn't exist.
+func (b *Bucket) entries(ctx context.Context, opts ...GetOpt) (entries map[string]json.RawMessage, err error) {
+	data, err := b.db.Get(ctx, b.fileKey, opts...)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]json.RawMessage{}, nil
+	}
+
+	if err != nil {
+		return
+	}
+
+	return decodeBucket(b.fileKey, data)
+}
+
+// mutate applies fn into the JSON object of the file from the fresh pull, then commits and pushes the whole file.
+// When fn doesn't change the file, nothing is committed and the current HEAD is returned.
+// The op is the command reported to WithOnPush.
+func (b *Bucket) mutate(ctx context.Context, op Op, allowInternal bool, commitMsg string,
+	fn func(entries map[string]json.RawMessage) error) (commitHashString string, changed bool, err error) {
+	db := b.db
+
+	err = db.checkWritable()
+	if err != nil {
+		return
+	}
+
+	fileKey, err := validateInternalKey(b.fileKey, allowInternal)
+	if err != nil {
+		return
+	}
+
+	filePath, err := db.keyToPath(fileKey)
+	if err != nil {
+		return
+	}
+
+	err = db.forcePull(ctx)
+	if err != nil {
+		return
+	}
+
+	var oldData []byte
+	entries := map[string]json.RawMessage{}
+
+	file, err := db.headFile(filePath)
+	if err != nil {
+		return
+	}
+
+	if file != nil {
+		oldData, err = db.readBlob(file.Hash)
+		if err != nil {
+			err = fmt.Errorf("cannot read bucket '%s': %w", fileKey, err)
+			return
+		}
+
+		entries, err = decodeBucket(fileKey, oldData)
+		if err != nil {
+			return
+		}
+	}
+
+	err = fn(entries)
+	if err != nil {
+		return
+	}
+
+	data, err := encodeBucket(entries)
+	if err != nil {
+		err = fmt.Errorf("cannot encode bucket '%s': %w", fileKey, err)
+		return
+	}
+
+	data = db.normalizeLineEnding(data)
+	if file != nil && bytes.Equal(data, oldData) {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("cannot get HEAD reference: %w", err)
+			return
+		}
+
+		commitHashString = head.Hash().String()
+		return
+	}
+
+	worktree, err := db.writeFile(ctx, filePath, data, "UPSERT", 0)
+	if err != nil {
+		return
+	}
+
+	var commitHash plumbing.Hash
+	commitHash, err = db.gitCommit(ctx, worktree, commitMsg, false)
+	if err != nil {
+		return
+	}
+
+	changed = true
+	commitHashString = commitHash.String()
+
+	commitHash, _, err = db.gitPush(ctx, op)
+	if err != nil {
+		return
+	}
+
+	commitHashString = commitHash.String()
+	return
+}
+
+// decodeBucket parses the file of the bucket, which must be a JSON object.
+func decodeBucket(fileKey string, data []byte) (entries map[string]json.RawMessage, err error) {
+	entries = map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return
+	}
+
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		err = fmt.Errorf("bucket '%s' is not a JSON object: %w", fileKey, err)
+		return
+	}
+
+	if entries == nil {
+		entries = map[string]json.RawMessage{}
+	}
+
+	return
+}
+
+// encodeBucket writes the entries in canonical JSON form, so the same entries are always the same bytes.
+func encodeBucket(entries map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return canonicalJSON(data)
+}
+
+// compactJSON returns the JSON value without insignificant whitespace.
+func compactJSON(value json.RawMessage) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := json.Compact(buf, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,80 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestBucket(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+	bucket := db.Bucket("flags.json")
+
+	// the missing file is the empty bucket
+	values, err := bucket.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	_, err = bucket.Get(ctx, "dark-mode")
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+
+	commitHash, changed, err := bucket.Put(ctx, "dark-mode", []byte(`{"enabled": true}`))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, remoteHead(t, remote), commitHash)
+
+	_, _, err = newTestDB(t, remote).Bucket("flags.json").Put(ctx, "beta", []byte(`"on"`))
+	require.NoError(t, err)
+
+	// the same value is not committed
+	head := remoteHead(t, remote)
+	commitHash, changed, err = bucket.Put(ctx, "dark-mode", []byte(`{ "enabled" : true }`))
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, head, commitHash)
+
+	data, err := bucket.Get(ctx, "dark-mode")
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"enabled":true}`), data)
+
+	values, err = bucket.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"dark-mode": []byte(`{"enabled":true}`), "beta": []byte(`"on"`)}, values)
+
+	// the whole bucket is one canonical JSON file
+	data, err = db.Get(ctx, "flags.json")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"beta\": \"on\",\n  \"dark-mode\": {\n    \"enabled\": true\n  }\n}\n", string(data))
+
+	_, _, err = bucket.Put(ctx, "broken", []byte("not json"))
+	assert.Error(t, err)
+
+	_, err = bucket.Delete(ctx, "beta")
+	require.NoError(t, err)
+
+	_, err = bucket.Delete(ctx, "beta")
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+	values, err = newTestDB(t, remote).Bucket("flags.json").List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"dark-mode": []byte(`{"enabled":true}`)}, values)
+
+	// the file which is not a JSON object cannot be used as bucket
+	_, err = db.Create(ctx, "plain.txt", []byte("hello"))
+	require.NoError(t, err)
+
+	_, _, err = db.Bucket("plain.txt").Put(ctx, "key", []byte("1"))
+	assert.Error(t, err)
+
+	data, err = db.Get(ctx, "plain.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
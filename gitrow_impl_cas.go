@@ -0,0 +1,107 @@
+package gitrows
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// contentAddressedDir is the root directory of values written by PutContentAddressed.
+const contentAddressedDir = "cas"
+
+// PutContentAddressed stores data under the key derived from the SHA-256 hash of the data,
+// sharded using the first two characters of the hash, i.e: cas/ab/cdef0123...
+// The computed key is returned, so it can be used later in Get.
+//
+// When the key already exists with identical content, nothing is committed,
+// and the commit hash of current HEAD is returned. This makes duplicate puts a no-op.
+func (db *DBImpl) PutContentAddressed(ctx context.Context, data []byte) (key string, commitHashString string, err error) {
+	defer func() {
+		err = wrapError(OpPutContentAddressed, key, err)
+		db.recordOp(OpPutContentAddressed, key, commitHashString, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	// the key must be the hash of the stored content
+	data, err = db.canonicalizeJSON(data, false)
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	data = db.normalizeLineEnding(data)
+
+	sum := sha256.Sum256(data)
+	hexSum := hex.EncodeToString(sum[:])
+	key = path.Join(contentAddressedDir, hexSum[:2], hexSum[2:])
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	err = db.forcePull(ctx)
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	// fast path: compare the blob hash in the tree, so we don't need to read the existing content.
+	file, err := db.headFile(filePath)
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	if file != nil && file.Hash == plumbing.ComputeHash(plumbing.BlobObject, data) {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("put content addressed command: cannot get HEAD reference: %w", err)
+			return
+		}
+
+		commitHashString = head.Hash().String()
+		return
+	}
+
+	worktree, err := db.writeFile(ctx, filePath, data, "UPSERT", 0)
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	var commitHash plumbing.Hash
+	commitMsg := db.commitMessage(OpPutContentAddressed, "", "gitrows: PUT CONTENT ADDRESSED", CommitMeta{
+		Sizes:   map[string]int64{key: int64(len(data))},
+		Changed: true,
+	})
+
+	commitHash, err = db.gitCommit(ctx, worktree, commitMsg, false)
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	commitHash, _, err = db.gitPush(ctx, OpPutContentAddressed)
+	if err != nil {
+		err = fmt.Errorf("put content addressed command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	return
+}
@@ -0,0 +1,34 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_PutContentAddressed(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	key, commit, err := db.PutContentAddressed(ctx, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "cas/2c/f24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", key)
+	assert.NotEmpty(t, commit)
+
+	// duplicate put must not create new commit
+	dupKey, dupCommit, err := db.PutContentAddressed(ctx, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, key, dupKey)
+	assert.Equal(t, commit, dupCommit)
+
+	otherKey, otherCommit, err := db.PutContentAddressed(ctx, []byte("world"))
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+	assert.NotEqual(t, commit, otherCommit)
+
+	data, err := db.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
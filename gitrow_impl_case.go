@@ -0,0 +1,109 @@
+package gitrows
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// WithCaseCollisionProtection rejects writes with ErrCaseCollision when the key only differs by case
+// with an existing key (or directory) in the committed tree, i.e: writing "config.yaml" when "Config.yaml" exists.
+//
+// On case-insensitive filesystem (default on macOS and Windows) both keys are the same file in the worktree,
+// but they are committed as two entries, which breaks the checkout for other users.
+func WithCaseCollisionProtection() Opt {
+	return func(db *DBImpl) error {
+		db.caseCollisionProtect = true
+		return nil
+	}
+}
+
+// checkCommittedPath returns os.ErrNotExist when the file path doesn't exist in the committed tree
+// with the exact same case. Reading the worktree alone is not enough, because on case-insensitive filesystem
+// "config.yaml" opens the file committed as "Config.yaml".
+func (db *DBImpl) checkCommittedPath(filePath string) error {
+	file, err := db.headFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if file == nil {
+		return fmt.Errorf("%w: '%s' doesn't exist in branch %s", os.ErrNotExist, filePath, db.gitBranch)
+	}
+
+	return nil
+}
+
+// checkCaseCollision returns ErrCaseCollision when WithCaseCollisionProtection is enabled
+// and the file path collides case-insensitively with different-cased path in the committed tree.
+func (db *DBImpl) checkCaseCollision(filePath string) error {
+	if !db.caseCollisionProtect {
+		return nil
+	}
+
+	commit, err := db.headCommit()
+	if err != nil || commit == nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.ID(), err)
+	}
+
+	existing, err := caseCollision(tree, filePath)
+	if err != nil {
+		return err
+	}
+
+	if existing != "" {
+		return fmt.Errorf("%w: '%s' collides with existing '%s'", ErrCaseCollision, filePath, existing)
+	}
+
+	return nil
+}
+
+// caseCollision walks the tree along the file path, and returns the existing path which only differs by case
+// with the file path (or its parent directory), or empty string if there is none.
+func caseCollision(tree *object.Tree, filePath string) (existing string, err error) {
+	parts := strings.Split(filePath, "/")
+	for i, part := range parts {
+		var exact, folded *object.TreeEntry
+		for j := range tree.Entries {
+			entry := &tree.Entries[j]
+			if entry.Name == part {
+				exact = entry
+				break
+			}
+
+			if folded == nil && strings.EqualFold(entry.Name, part) {
+				folded = entry
+			}
+		}
+
+		if exact == nil {
+			if folded != nil {
+				existing = path.Join(path.Join(parts[:i]...), folded.Name)
+			}
+
+			return
+		}
+
+		// the rest of path is new, or the exact entry is a file
+		if i == len(parts)-1 || exact.Mode != filemode.Dir {
+			return
+		}
+
+		tree, err = tree.Tree(exact.Name)
+		if err != nil {
+			err = fmt.Errorf("cannot get tree '%s': %w", path.Join(parts[:i+1]...), err)
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,212 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// caseInsensitiveFS simulates case-insensitive but case-preserving filesystem (i.e: macOS APFS),
+// by resolving every path into the existing different-cased path if any.
+type caseInsensitiveFS struct {
+	billy.Filesystem
+}
+
+func (fs *caseInsensitiveFS) resolve(name string) string {
+	parts := strings.Split(strings.Trim(path.Clean(filepath.ToSlash(name)), "/"), "/")
+
+	resolved := ""
+	for i, part := range parts {
+		infos, err := fs.Filesystem.ReadDir(resolved)
+		if err != nil {
+			return path.Join(append([]string{resolved}, parts[i:]...)...)
+		}
+
+		for _, info := range infos {
+			if strings.EqualFold(info.Name(), part) {
+				part = info.Name()
+				break
+			}
+		}
+
+		resolved = path.Join(resolved, part)
+	}
+
+	return resolved
+}
+
+func (fs *caseInsensitiveFS) Create(filename string) (billy.File, error) {
+	return fs.Filesystem.Create(fs.resolve(filename))
+}
+
+func (fs *caseInsensitiveFS) Open(filename string) (billy.File, error) {
+	return fs.Filesystem.Open(fs.resolve(filename))
+}
+
+func (fs *caseInsensitiveFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	return fs.Filesystem.OpenFile(fs.resolve(filename), flag, perm)
+}
+
+func (fs *caseInsensitiveFS) Stat(filename string) (os.FileInfo, error) {
+	return fs.Filesystem.Stat(fs.resolve(filename))
+}
+
+func (fs *caseInsensitiveFS) Lstat(filename string) (os.FileInfo, error) {
+	return fs.Filesystem.Lstat(fs.resolve(filename))
+}
+
+func (fs *caseInsensitiveFS) Rename(oldpath, newpath string) error {
+	return fs.Filesystem.Rename(fs.resolve(oldpath), fs.resolve(newpath))
+}
+
+func (fs *caseInsensitiveFS) Remove(filename string) error {
+	return fs.Filesystem.Remove(fs.resolve(filename))
+}
+
+func (fs *caseInsensitiveFS) ReadDir(dir string) ([]os.FileInfo, error) {
+	return fs.Filesystem.ReadDir(fs.resolve(dir))
+}
+
+func (fs *caseInsensitiveFS) MkdirAll(filename string, perm os.FileMode) error {
+	return fs.Filesystem.MkdirAll(fs.resolve(filename), perm)
+}
+
+// newCaseInsensitiveTestDB creates DB which worktree is on caseInsensitiveFS.
+func newCaseInsensitiveTestDB(t *testing.T, remoteURL string, opts ...Opt) *DBImpl {
+	t.Helper()
+
+	opts = append([]Opt{
+		WithGitSshUrl(remoteURL),
+		WithLocalGitVolume(t.TempDir()),
+	}, opts...)
+
+	db, err := New(opts...)
+	require.NoError(t, err)
+
+	db.worktreeFS = func(dir string) billy.Filesystem {
+		return &caseInsensitiveFS{Filesystem: osfs.New(dir)}
+	}
+
+	return db
+}
+
+func TestCaseInsensitiveFS(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	remote := "file://" + remoteDir
+
+	writer, err := New(WithGitSshUrl(remote), WithLocalGitVolume(t.TempDir()))
+	require.NoError(t, err)
+
+	_, err = writer.Create(ctx, "Config.yaml", []byte("original"))
+	require.NoError(t, err)
+
+	_, err = writer.Create(ctx, "Data/a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	db := newCaseInsensitiveTestDB(t, remote)
+
+	// read and delete are case-exact against the committed tree
+	data, err := db.Get(ctx, "Config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("original"), data)
+
+	_, err = db.Get(ctx, "config.yaml")
+	assert.Equal(t, CodeNotFound, ErrorCode(err))
+
+	_, err = db.GetReader(ctx, "config.yaml")
+	assert.Equal(t, CodeNotFound, ErrorCode(err))
+
+	values, err := db.GetMany(ctx, []string{"Config.yaml", "config.yaml"})
+	assert.Equal(t, map[string][]byte{"Config.yaml": []byte("original")}, values)
+	assert.Equal(t, CodeNotFound, ErrorCode(err))
+
+	_, err = db.Delete(ctx, "config.yaml")
+	assert.Equal(t, CodeNotFound, ErrorCode(err))
+
+	data, err = writer.Get(ctx, "Config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("original"), data)
+
+	// writes colliding case-insensitively are rejected
+	protected := newCaseInsensitiveTestDB(t, remote, WithCaseCollisionProtection())
+
+	_, _, err = protected.Upsert(ctx, "config.yaml", []byte("collision"))
+	assert.True(t, errors.Is(err, ErrCaseCollision))
+	assert.Equal(t, CodeConflict, ErrorCode(err))
+
+	_, err = protected.Create(ctx, "data/b.txt", []byte("b"))
+	assert.True(t, errors.Is(err, ErrCaseCollision))
+
+	_, changed, err := protected.Upsert(ctx, "Config.yaml", []byte("updated"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	_, err = protected.Create(ctx, "Data/b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	entries, err := writer.List(ctx)
+	require.NoError(t, err)
+
+	keys := make([]string, 0)
+	for _, kv := range entries.KVs() {
+		keys = append(keys, kv.Key())
+	}
+
+	assert.ElementsMatch(t, []string{"Config.yaml", "Data/a.txt", "Data/b.txt"}, keys)
+}
+
+func TestCaseCollision(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "Dir/Sub/File.txt", []byte("file"))
+	require.NoError(t, err)
+
+	commit, err := db.headCommit()
+	require.NoError(t, err)
+
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+
+	tests := []struct {
+		filePath string
+		existing string
+	}{
+		{filePath: "Dir/Sub/File.txt", existing: ""},
+		{filePath: "Dir/Sub/Other.txt", existing: ""},
+		{filePath: "New/File.txt", existing: ""},
+		{filePath: "Dir/Sub/file.txt", existing: "Dir/Sub/File.txt"},
+		{filePath: "dir/Sub/File.txt", existing: "Dir"},
+		{filePath: "Dir/sub/new.txt", existing: "Dir/Sub"},
+		{filePath: "Dir/Sub/File.txt/child", existing: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filePath, func(t *testing.T) {
+			existing, err := caseCollision(tree, test.filePath)
+			assert.NoError(t, err)
+			assert.Equal(t, test.existing, existing)
+		})
+	}
+}
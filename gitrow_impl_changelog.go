@@ -0,0 +1,305 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// errShallowHistory returned when the commit is beyond the history of the (shallow) local repository.
+var errShallowHistory = errors.New("commit not found in the local history")
+
+// Changelog returns the change of every key made by each commit after fromCommit up to toCommit (inclusive),
+// following the first parent like `git log --first-parent fromCommit..toCommit`, in chronological order.
+// The key changed in several commits is returned once per commit, sorted by the key within the same commit.
+// Empty fromCommit means from the first commit, and empty toCommit means the head of the branch.
+//
+// Since we only `git fetch` with depth 1, when the range is beyond the local history,
+// the full history of the branch is cloned into memory for this call only, so it doesn't fit for very large repository.
+func (db *DBImpl) Changelog(ctx context.Context, fromCommit, toCommit string) (entries []ChangeEntry, err error) {
+	defer func() {
+		err = wrapError(OpChangelog, "", err)
+	}()
+
+	for _, hash := range []string{fromCommit, toCommit} {
+		if hash != "" && !plumbing.IsHash(hash) {
+			err = fmt.Errorf("changelog command: '%s' is not a full commit hash", hash)
+			return
+		}
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("changelog command: %w", err)
+		return
+	}
+
+	entries, err = db.changelog(db.gitRepo, fromCommit, toCommit)
+	if !errors.Is(err, errShallowHistory) {
+		if err != nil {
+			err = fmt.Errorf("changelog command: %w", err)
+		}
+
+		return
+	}
+
+	repo, err := db.cloneFullHistory(ctx)
+	if err != nil {
+		err = fmt.Errorf("changelog command: %w", err)
+		return
+	}
+
+	entries, err = db.changelog(repo, fromCommit, toCommit)
+	if err != nil {
+		err = fmt.Errorf("changelog command: %w", err)
+		return
+	}
+
+	return
+}
+
+// KeysInCommit returns the keys added, modified or deleted by the commit against its first parent (against the empty tree
+// for the root commit), sorted by the key, i.e: to react on the webhook of the push.
+//
+// Unlike Changelog, the history is not cloned: both the commit and its parent must be in the local repository,
+// otherwise ErrCommitNotFound is returned. Since we only `git fetch` with depth 1, the parent of the head commit
+// is only available after DeepenSince or in the repository which made the commit itself.
+func (db *DBImpl) KeysInCommit(ctx context.Context, commit string) (entries []ChangeEntry, err error) {
+	defer func() {
+		err = wrapError(OpKeysInCommit, "", err)
+	}()
+
+	if !plumbing.IsHash(commit) {
+		err = fmt.Errorf("keys in commit command: '%s' is not a full commit hash", commit)
+		return
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("keys in commit command: %w", err)
+		return
+	}
+
+	target, err := db.gitRepo.CommitObject(plumbing.NewHash(commit))
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		err = fmt.Errorf("keys in commit command: %w: %s is not in the local repository", ErrCommitNotFound, commit)
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("keys in commit command: cannot get commit %s: %w", commit, err)
+		return
+	}
+
+	// the parent of the shallow commit is never fetched
+	if target.NumParents() > 0 {
+		parent := target.ParentHashes[0]
+		_, err = db.gitRepo.CommitObject(parent)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			err = fmt.Errorf("keys in commit command: %w: the parent %s of commit %s is beyond the local history", ErrCommitNotFound, parent, commit)
+			return
+		}
+
+		if err != nil {
+			err = fmt.Errorf("keys in commit command: cannot get commit %s: %w", parent, err)
+			return
+		}
+	}
+
+	entries, err = db.commitChanges(target)
+	if err != nil {
+		err = fmt.Errorf("keys in commit command: %w", err)
+		return
+	}
+
+	return
+}
+
+// changelog computes Changelog from the repo, or returns errShallowHistory when the repo doesn't have enough history.
+func (db *DBImpl) changelog(repo *git.Repository, fromCommit, toCommit string) (entries []ChangeEntry, err error) {
+	if toCommit == "" {
+		var head *plumbing.Reference
+		head, err = repo.Reference(plumbing.NewBranchReferenceName(db.gitBranch), true)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			// empty repository
+			return make([]ChangeEntry, 0), nil
+		}
+
+		if err != nil {
+			err = fmt.Errorf("cannot get the head of branch '%s': %w", db.gitBranch, err)
+			return
+		}
+
+		toCommit = head.Hash().String()
+	}
+
+	shallows, err := repo.Storer.Shallow()
+	if err != nil {
+		err = fmt.Errorf("cannot read the shallow commits: %w", err)
+		return
+	}
+
+	isShallow := make(map[plumbing.Hash]struct{}, len(shallows))
+	for _, hash := range shallows {
+		isShallow[hash] = struct{}{}
+	}
+
+	// walk the first parents backward from toCommit until fromCommit
+	commits := make([]*object.Commit, 0)
+	for hash := plumbing.NewHash(toCommit); hash.String() != fromCommit; {
+		var commit *object.Commit
+		commit, err = repo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			err = fmt.Errorf("%w: %s", errShallowHistory, hash)
+			return
+		}
+
+		if err != nil {
+			err = fmt.Errorf("cannot get commit %s: %w", hash, err)
+			return
+		}
+
+		commits = append(commits, commit)
+
+		if commit.NumParents() == 0 {
+			if fromCommit != "" {
+				err = fmt.Errorf("commit %s is not the first-parent ancestor of %s", fromCommit, toCommit)
+				return
+			}
+
+			break
+		}
+
+		// the parent of shallow commit is never fetched
+		if _, shallow := isShallow[hash]; shallow {
+			err = fmt.Errorf("%w: %s", errShallowHistory, commit.ParentHashes[0])
+			return
+		}
+
+		hash = commit.ParentHashes[0]
+	}
+
+	entries = make([]ChangeEntry, 0)
+	for i := len(commits) - 1; i >= 0; i-- {
+		var changes []ChangeEntry
+		changes, err = db.commitChanges(commits[i])
+		if err != nil {
+			return
+		}
+
+		entries = append(entries, changes...)
+	}
+
+	return
+}
+
+// commitChanges returns the changes of keys made by the commit against its first parent.
+func (db *DBImpl) commitChanges(commit *object.Commit) (entries []ChangeEntry, err error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		err = fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.Hash, err)
+		return
+	}
+
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		var parent *object.Commit
+		parent, err = commit.Parent(0)
+		if err != nil {
+			err = fmt.Errorf("cannot get parent of commit %s: %w", commit.Hash, err)
+			return
+		}
+
+		parentTree, err = parent.Tree()
+		if err != nil {
+			err = fmt.Errorf("retrieve the tree from the commit %s error: %w", parent.Hash, err)
+			return
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		err = fmt.Errorf("cannot diff commit %s: %w", commit.Hash, err)
+		return
+	}
+
+	info := newCommitInfo(commit, db.verifyCommit(commit))
+
+	entries = make([]ChangeEntry, 0, len(changes))
+	for _, change := range changes {
+		var action merkletrie.Action
+		action, err = change.Action()
+		if err != nil {
+			err = fmt.Errorf("cannot get the change action in commit %s: %w", commit.Hash, err)
+			return
+		}
+
+		entry := ChangeEntry{Commit: info}
+
+		name := change.To.Name
+		switch action {
+		case merkletrie.Insert:
+			entry.ChangeType = ChangeAdded
+		case merkletrie.Modify:
+			entry.ChangeType = ChangeModified
+		case merkletrie.Delete:
+			entry.ChangeType = ChangeDeleted
+			name = change.From.Name
+		}
+
+		// the internal files of gitrows are not keys
+		if isMetadataPath(name) {
+			continue
+		}
+
+		var ok bool
+		entry.Key, ok = db.pathToKey(name)
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return
+}
+
+// cloneFullHistory clones all commits of the branch into memory, without the worktree.
+func (db *DBImpl) cloneFullHistory(ctx context.Context) (repo *git.Repository, err error) {
+	// git clone <url> --bare --branch <branch> --single-branch
+	cloneOpt := &git.CloneOptions{
+		URL:           db.gitSshUrl,
+		Auth:          db.auth,
+		RemoteName:    gitRemoteName,
+		ReferenceName: plumbing.NewBranchReferenceName(db.gitBranch),
+		SingleBranch:  true,
+		NoCheckout:    true,
+		Tags:          git.NoTags,
+		Progress:      db.progressWriter(),
+	}
+
+	// the result is only read when the clone finishes in time, see remoteCall
+	var cloned *git.Repository
+	err = db.remoteCall(ctx, func(ctx context.Context) (err error) {
+		cloned, err = git.CloneContext(ctx, memory.NewStorage(), nil, cloneOpt)
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot clone the full history of %s: %w", db.gitSshUrl, remoteError(db.gitSshUrl, err))
+		return
+	}
+
+	repo = cloned
+	return
+}
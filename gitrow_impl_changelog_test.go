@@ -0,0 +1,132 @@
+package gitrows_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_Changelog(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	entries, err := db.Changelog(ctx, "", "")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	first, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	second, err := db.Create(ctx, "configs/b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	third, _, err := db.Upsert(ctx, "a.txt", []byte("a2"), gitrows.UpsertCommitMsg("update a"))
+	require.NoError(t, err)
+
+	fourth, err := db.Delete(ctx, "configs/b.txt")
+	require.NoError(t, err)
+
+	type change struct {
+		key        string
+		changeType gitrows.ChangeType
+		commit     string
+	}
+
+	summary := func(entries []gitrows.ChangeEntry) []change {
+		changes := make([]change, 0, len(entries))
+		for _, entry := range entries {
+			changes = append(changes, change{key: entry.Key, changeType: entry.ChangeType, commit: entry.Commit.Hash})
+		}
+
+		return changes
+	}
+
+	all := []change{
+		{key: "a.txt", changeType: gitrows.ChangeAdded, commit: first},
+		{key: "configs/b.txt", changeType: gitrows.ChangeAdded, commit: second},
+		{key: "a.txt", changeType: gitrows.ChangeModified, commit: third},
+		{key: "configs/b.txt", changeType: gitrows.ChangeDeleted, commit: fourth},
+	}
+
+	// the writer has all commits in the local repository
+	entries, err = db.Changelog(ctx, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, all, summary(entries))
+
+	assert.Equal(t, "update a", entries[2].Commit.Message)
+	assert.Equal(t, "gitrows", entries[2].Commit.AuthorName)
+	assert.False(t, entries[2].Commit.When.IsZero())
+
+	// fresh clone only has the head commit, so the history is deepened
+	entries, err = newTestDB(t, remote).Changelog(ctx, first, third)
+	require.NoError(t, err)
+	assert.Equal(t, all[1:3], summary(entries))
+
+	entries, err = newTestDB(t, remote).Changelog(ctx, third, "")
+	require.NoError(t, err)
+	assert.Equal(t, all[3:], summary(entries))
+
+	entries, err = newTestDB(t, remote).Changelog(ctx, fourth, fourth)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// toCommit is before fromCommit
+	_, err = newTestDB(t, remote).Changelog(ctx, third, first)
+	assert.Error(t, err)
+
+	_, err = db.Changelog(ctx, "HEAD~1", "")
+	assert.Error(t, err)
+
+	_, err = db.Changelog(ctx, strings.Repeat("0", 40), "")
+	assert.Error(t, err)
+}
+
+func TestDBImpl_KeysInCommit(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	first, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "configs/b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	third, err := db.Delete(ctx, "a.txt")
+	require.NoError(t, err)
+
+	keys := func(entries []gitrows.ChangeEntry) map[string]gitrows.ChangeType {
+		changes := make(map[string]gitrows.ChangeType, len(entries))
+		for _, entry := range entries {
+			changes[entry.Key] = entry.ChangeType
+		}
+
+		return changes
+	}
+
+	// the root commit is compared against the empty tree
+	entries, err := db.KeysInCommit(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]gitrows.ChangeType{"a.txt": gitrows.ChangeAdded}, keys(entries))
+	assert.Equal(t, first, entries[0].Commit.Hash)
+
+	entries, err = db.KeysInCommit(ctx, third)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]gitrows.ChangeType{"a.txt": gitrows.ChangeDeleted}, keys(entries))
+
+	// fresh clone doesn't have the parent of the head commit
+	_, err = newTestDB(t, remote).KeysInCommit(ctx, third)
+	assert.ErrorIs(t, err, gitrows.ErrCommitNotFound)
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+	_, err = db.KeysInCommit(ctx, strings.Repeat("0", 40))
+	assert.ErrorIs(t, err, gitrows.ErrCommitNotFound)
+
+	_, err = db.KeysInCommit(ctx, "HEAD")
+	assert.Error(t, err)
+}
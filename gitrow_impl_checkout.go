@@ -0,0 +1,66 @@
+package gitrows
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// detachedHead returns the commit of HEAD when it is detached (the commit hash instead of the branch), or zero hash.
+func (db *DBImpl) detachedHead() plumbing.Hash {
+	head, err := db.gitRepo.Storer.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.HashReference {
+		return plumbing.ZeroHash
+	}
+
+	return head.Hash()
+}
+
+// attachHead is like `git symbolic-ref HEAD refs/heads/<branch>`.
+func (db *DBImpl) attachHead() error {
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	err := db.gitRepo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchName))
+	if err != nil {
+		return fmt.Errorf("cannot set HEAD to %s: %w", branchName, err)
+	}
+
+	return nil
+}
+
+// checkoutError classifies the error of resetAndCheckout into *CheckoutError with the state of the references.
+// The detachedAt is the commit of the detached HEAD before it is re-pointed at the branch, zero when it is not detached.
+func (db *DBImpl) checkoutError(err error, detachedAt plumbing.Hash) error {
+	checkoutErr := &CheckoutError{
+		Branch: db.gitBranch,
+		Head:   detachedAt.String(),
+	}
+
+	if detachedAt.IsZero() {
+		head, headErr := db.gitRepo.Storer.Reference(plumbing.HEAD)
+		switch {
+		case headErr != nil:
+			checkoutErr.Head = fmt.Sprintf("unknown (%v)", headErr)
+		case head.Type() == plumbing.HashReference:
+			checkoutErr.Head = head.Hash().String()
+		default:
+			checkoutErr.Head = head.Target().String()
+		}
+	}
+
+	branch, branchErr := db.gitRepo.Storer.Reference(plumbing.NewBranchReferenceName(db.gitBranch))
+	if branchErr == nil {
+		checkoutErr.BranchHash = branch.Hash().String()
+	}
+
+	switch {
+	case !detachedAt.IsZero():
+		checkoutErr.Err = fmt.Errorf("%w: %v", ErrDetachedHead, err)
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		checkoutErr.Err = fmt.Errorf("%w: %v", ErrBranchNotFound, err)
+	default:
+		checkoutErr.Err = fmt.Errorf("%w: %v", ErrWorktreeDirty, err)
+	}
+
+	return checkoutErr
+}
@@ -0,0 +1,122 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitCheckout_localState(t *testing.T) {
+	ctx := context.TODO()
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func() *DBImpl {
+		db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+		require.NoError(t, err)
+		return db
+	}
+
+	_, err = newDB().Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	missingCommit := plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")
+	master := plumbing.NewBranchReferenceName("master")
+
+	// synced returns the DB with the local repository checked out
+	synced := func(t *testing.T) *DBImpl {
+		t.Helper()
+
+		db := newDB()
+		_, err := db.Get(ctx, "a.txt")
+		require.NoError(t, err)
+		return db
+	}
+
+	// blockFile replaces the committed file with the directory which is not empty
+	blockFile := func(t *testing.T, db *DBImpl) {
+		t.Helper()
+
+		filePath := filepath.Join(db.gitVolume, "a.txt")
+		require.NoError(t, os.Remove(filePath))
+		require.NoError(t, os.MkdirAll(filepath.Join(filePath, "nested"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(filePath, "nested", "b.txt"), []byte("b"), 0644))
+	}
+
+	checkoutError := func(t *testing.T, err error) *CheckoutError {
+		t.Helper()
+
+		var checkoutErr *CheckoutError
+		require.True(t, errors.As(err, &checkoutErr), "%v", err)
+		assert.Equal(t, "master", checkoutErr.Branch)
+		return checkoutErr
+	}
+
+	t.Run("detached HEAD is re-attached", func(t *testing.T) {
+		db := synced(t)
+		require.NoError(t, db.gitRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, missingCommit)))
+
+		require.NoError(t, db.gitCheckout(ctx))
+
+		head, err := db.gitRepo.Storer.Reference(plumbing.HEAD)
+		require.NoError(t, err)
+		assert.Equal(t, plumbing.SymbolicReference, head.Type())
+		assert.Equal(t, master, head.Target())
+
+		data, err := os.ReadFile(filepath.Join(db.gitVolume, "a.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+	})
+
+	t.Run("detached HEAD cannot be recovered", func(t *testing.T) {
+		db := synced(t)
+		blockFile(t, db)
+		require.NoError(t, db.gitRepo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, missingCommit)))
+
+		err := db.gitCheckout(ctx)
+		assert.ErrorIs(t, err, ErrDetachedHead)
+		assert.Equal(t, CodeLocalState, codeOf(err))
+		assert.Equal(t, missingCommit.String(), checkoutError(t, err).Head)
+	})
+
+	t.Run("worktree dirty", func(t *testing.T) {
+		db := synced(t)
+		blockFile(t, db)
+
+		err := db.gitCheckout(ctx)
+		assert.ErrorIs(t, err, ErrWorktreeDirty)
+		assert.Equal(t, CodeLocalState, codeOf(err))
+
+		checkoutErr := checkoutError(t, err)
+		assert.Equal(t, master.String(), checkoutErr.Head)
+		assert.NotEmpty(t, checkoutErr.BranchHash)
+	})
+
+	t.Run("local branch missing", func(t *testing.T) {
+		db := synced(t)
+		branch, err := db.gitRepo.Storer.Reference(master)
+		require.NoError(t, err)
+
+		other := plumbing.NewBranchReferenceName("other")
+		require.NoError(t, db.gitRepo.Storer.SetReference(plumbing.NewHashReference(other, branch.Hash())))
+		require.NoError(t, db.gitRepo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, other)))
+		require.NoError(t, db.gitRepo.Storer.RemoveReference(master))
+
+		err = db.gitCheckout(ctx)
+		assert.ErrorIs(t, err, ErrBranchNotFound)
+		assert.Equal(t, CodeNotFound, codeOf(err))
+
+		checkoutErr := checkoutError(t, err)
+		assert.Equal(t, other.String(), checkoutErr.Head)
+		assert.Empty(t, checkoutErr.BranchHash)
+		assert.Contains(t, err.Error(), "local branch: missing")
+	})
+}
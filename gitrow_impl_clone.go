@@ -0,0 +1,54 @@
+package gitrows
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// removeIncompleteClone removes the local repository in dir which has the .git directory, but cannot be opened,
+// i.e: it has no HEAD because the first sync is interrupted during `git init`. The temporary packfiles left
+// by the interrupted fetch are removed too, since go-git never resumes nor cleans them.
+// The directory without .git is left as is.
+func removeIncompleteClone(dir string) (err error) {
+	gitDir := filepath.Join(dir, git.GitDirName)
+	_, err = os.Stat(gitDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot stat '%s': %w", gitDir, err)
+	}
+
+	_, err = git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		err = os.RemoveAll(gitDir)
+		if err != nil {
+			return fmt.Errorf("cannot remove incomplete local repository '%s': %w", gitDir, err)
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("open local repository '%s' error: %w", dir, err)
+	}
+
+	tmpPacks, err := filepath.Glob(filepath.Join(gitDir, "objects", "pack", "tmp_*"))
+	if err != nil {
+		return fmt.Errorf("cannot find temporary packfiles: %w", err)
+	}
+
+	for _, tmpPack := range tmpPacks {
+		err = os.Remove(tmpPack)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot remove temporary packfile '%s': %w", tmpPack, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestGitClone_resume(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	t.Run("interrupted first sync", func(t *testing.T) {
+		volume := t.TempDir()
+		repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+
+		// the deadline is hit while receiving the branch
+		interruptCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithCloneProgress(func(p gitrows.SyncProgress) {
+			cancel()
+		}))
+
+		_, err := db.Get(interruptCtx, "a.txt")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = os.Stat(filepath.Join(repoDir, ".git", "HEAD"))
+		require.NoError(t, err)
+
+		data, err := db.Get(ctx, "a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+
+		// the other process starts from the same local repository
+		data, err = newTestDB(t, remote, gitrows.WithLocalGitVolume(volume)).Get(ctx, "a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+	})
+
+	t.Run("broken local repository", func(t *testing.T) {
+		volume := t.TempDir()
+		repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+
+		// no HEAD, with the leftover of the interrupted fetch
+		packDir := filepath.Join(repoDir, ".git", "objects", "pack")
+		require.NoError(t, os.MkdirAll(packDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(packDir, "tmp_pack_123"), []byte("partial"), 0644))
+
+		data, err := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume)).Get(ctx, "a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+
+		_, err = os.Stat(filepath.Join(packDir, "tmp_pack_123"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("leftover temporary packfile", func(t *testing.T) {
+		volume := t.TempDir()
+		repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+
+		_, err := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume)).Get(ctx, "a.txt")
+		require.NoError(t, err)
+
+		tmpPack := filepath.Join(repoDir, ".git", "objects", "pack", "tmp_pack_456")
+		require.NoError(t, os.WriteFile(tmpPack, []byte("partial"), 0644))
+
+		_, err = newTestDB(t, remote, gitrows.WithLocalGitVolume(volume)).Get(ctx, "a.txt")
+		require.NoError(t, err)
+
+		_, err = os.Stat(tmpPack)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,540 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// maxFlushErrors is the number of background flush errors kept until FlushErrors, the older are dropped.
+const maxFlushErrors = 64
+
+// WithWriteCoalescing buffers Upsert and Delete in memory and returns the provisional result immediately,
+// then commits and pushes everything buffered as one commit after the window since the first buffered write,
+// or on Flush and Close. The writes of the same key within the window collapse into the latest one,
+// i.e: for the metrics-like workload which writes the same keys every few seconds.
+//
+// The buffered write is not visible to the read until it is flushed (except Get with WithCoalesceReadYourWrites),
+// and it is lost when the process exits
+// without Close. Only UpsertFileMode, UpsertAllowInternalPaths and UpsertCanonicalJSON apply to the buffered Upsert,
+// and the buffered Delete of the key which doesn't exist does nothing. The flush uses its own local repository
+// (the volume suffixed by "@coalesce"), so it doesn't race with the other commands of the DB.
+// The command which writes the key directly (Create, CreateIf, PutReader, and Upsert or Delete with the options
+// which are not buffered, i.e: UpsertExpectedHead or DeleteIfMatch) flushes the buffered write of the key first,
+// and fails when it cannot be flushed.
+// The error of the background flush is kept for FlushErrors, and the failed writes are retried in the next window.
+// When the batch fails because of its writes rather than the remote repository, the keys are written one commit each,
+// so the other keys are still pushed: the write which can never succeed (CodeInvalidKey, CodeValueTooLarge
+// or CodeRejectedByServer) is dropped, and the error is *MultiError with the failing keys.
+// The background flush doesn't push the commits left unpushed by WithPushEvery, only Flush and Close do.
+func WithWriteCoalescing(window time.Duration) Opt {
+	return func(db *DBImpl) error {
+		if window <= 0 {
+			return fmt.Errorf("write coalescing window must be positive, got %s", window)
+		}
+
+		db.coalesceWindow = window
+		return nil
+	}
+}
+
+// WithCoalesce is like WithWriteCoalescing, but only buffers the writes of the key which match returns true for,
+// i.e: for the hot keys like counters, while the other keys are written immediately as usual.
+// The nil match buffers every key, the same as WithWriteCoalescing.
+//
+// The read within the window still returns the last committed value rather than the buffered one,
+// unless WithCoalesceReadYourWrites is used.
+func WithCoalesce(match func(key string) bool, window time.Duration) Opt {
+	return func(db *DBImpl) error {
+		err := WithWriteCoalescing(window)(db)
+		if err != nil {
+			return err
+		}
+
+		db.coalesceMatch = match
+		return nil
+	}
+}
+
+// WithCoalesceReadYourWrites makes Get of this DB return the value buffered by WithWriteCoalescing (or WithCoalesce)
+// until it is flushed, and os.ErrNotExist for the buffered Delete, instead of the last committed value.
+// The other reads (i.e: GetReader and List) and the other DBs still see the last committed value.
+func WithCoalesceReadYourWrites() Opt {
+	return func(db *DBImpl) error {
+		db.coalesceReadYourWrites = true
+		return nil
+	}
+}
+
+// coalesces returns true when the write of key is buffered, see WithCoalesce.
+func (db *DBImpl) coalesces(key string) bool {
+	return db.coalescer != nil && (db.coalesceMatch == nil || db.coalesceMatch(key))
+}
+
+// Flush commits and pushes the writes buffered by WithWriteCoalescing, then pushes the commits left unpushed
+// by WithPushEvery, and returns the error of this flush.
+// It does nothing without both options or when nothing is buffered nor unpushed.
+func (db *DBImpl) Flush(ctx context.Context) (err error) {
+	defer func() {
+		err = wrapError(OpFlush, "", err)
+		db.recordOp(OpFlush, "", "", err)
+	}()
+
+	if db.coalescer != nil {
+		err = db.coalescer.flush(ctx)
+		if err != nil {
+			err = fmt.Errorf("flush command: %w", err)
+			return
+		}
+	}
+
+	err = db.pushPending(ctx)
+	if err != nil {
+		err = fmt.Errorf("flush command: %w", err)
+		return
+	}
+
+	return
+}
+
+// FlushErrors returns and clears the errors of the background flush of WithWriteCoalescing, oldest first.
+func (db *DBImpl) FlushErrors() []error {
+	if db.coalescer == nil {
+		return nil
+	}
+
+	c := db.coalescer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := c.errs
+	c.errs = nil
+	return errs
+}
+
+// coalescer buffers the writes of WithWriteCoalescing.
+type coalescer struct {
+	db     *DBImpl // writes the buffered changes, see DBImpl.sibling
+	parent *DBImpl // notified after each flush, so its next read pulls the flushed commit
+	window time.Duration
+
+	// mu protects the buffer and errs, flushMu makes only one flush runs at a time.
+	mu       sync.Mutex
+	pending  map[string]pendingWrite
+	flushing map[string]pendingWrite // the batch being flushed, still readable by WithCoalesceReadYourWrites
+	timer    *time.Timer
+	errs     []error
+	flushMu  sync.Mutex
+}
+
+// pendingWrite is the latest buffered write of the key.
+type pendingWrite struct {
+	filePath string
+	data     []byte
+	fileMode os.FileMode
+	deleted  bool
+}
+
+func newCoalescer(db *DBImpl) *coalescer {
+	return &coalescer{
+		db:      db.sibling(db.gitBranch, fmt.Sprintf("%s@coalesce", db.gitVolume)),
+		parent:  db,
+		window:  db.coalesceWindow,
+		pending: make(map[string]pendingWrite),
+	}
+}
+
+// add buffers the write, replacing the previous one of the same key, and schedules the flush.
+func (c *coalescer) add(key string, write pendingWrite) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[key] = write
+	c.schedule()
+}
+
+// schedule starts the window when it is not started yet. The caller must hold mu.
+func (c *coalescer) schedule() {
+	if c.timer != nil || len(c.pending) == 0 {
+		return
+	}
+
+	// only the buffered writes, the local repository of the parent must not be used concurrently
+	// with its foreground commands
+	c.timer = time.AfterFunc(c.window, func() {
+		err := c.flush(c.parent.lifetime)
+		if err != nil {
+			err = wrapError(OpFlush, "", fmt.Errorf("flush command: %w", err))
+		}
+
+		c.parent.recordOp(OpFlush, "", "", err)
+		if err == nil {
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.errs = append(c.errs, err)
+		if len(c.errs) > maxFlushErrors {
+			c.errs = c.errs[len(c.errs)-maxFlushErrors:]
+		}
+	})
+}
+
+// stop cancels the scheduled flush, i.e: on Close.
+func (c *coalescer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+// flush takes every buffered write, and commits them as one commit, see writeFlushing.
+func (c *coalescer) flush(ctx context.Context) (err error) {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	batch := c.pending
+	c.pending = make(map[string]pendingWrite)
+	c.flushing = batch
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+
+	return c.writeFlushing(ctx, batch)
+}
+
+// flushKey flushes the buffered write of key alone, before the key is written directly by the command which is not
+// buffered (i.e: Upsert with UpsertExpectedHead, or Create), so the older buffered write is not flushed after it.
+// Holding flushMu, the batch being flushed is pushed (or buffered again) before the write of key is taken.
+func (c *coalescer) flushKey(ctx context.Context, key string) (err error) {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	batch := make(map[string]pendingWrite)
+	if write, ok := c.pending[key]; ok {
+		batch[key] = write
+		delete(c.pending, key)
+	}
+
+	c.flushing = batch
+	c.mu.Unlock()
+
+	return c.writeFlushing(ctx, batch)
+}
+
+// writeFlushing commits the batch taken by flush or flushKey. The caller must hold flushMu.
+// On failure, the writes which are not replaced meanwhile are buffered again for the next window.
+func (c *coalescer) writeFlushing(ctx context.Context, batch map[string]pendingWrite) (err error) {
+	if len(batch) == 0 {
+		return
+	}
+
+	commitHash, err := c.write(ctx, batch)
+	if err != nil && !remoteWideError(err) {
+		// the write which can never succeed must not block the others, so they are written one by one to find it
+		commitHash, batch, err = c.writeEach(ctx, batch, err)
+	}
+
+	if !commitHash.IsZero() {
+		c.parent.NotifyRemoteChanged(commitHash.String())
+	}
+
+	if err != nil {
+		c.mu.Lock()
+		c.flushing = nil
+		for key, write := range batch {
+			if _, exist := c.pending[key]; !exist {
+				c.pending[key] = write
+			}
+		}
+
+		c.schedule()
+		c.mu.Unlock()
+		return
+	}
+
+	c.mu.Lock()
+	c.flushing = nil
+	c.mu.Unlock()
+	return
+}
+
+// writeEach writes every key of the failed batch in its own commit, so the other keys are still pushed.
+// The write failing with permanentWriteError is dropped, and the other failing writes are returned in retry
+// to be buffered again, including the rest of the batch once any write fails with remoteWideError.
+// The batchErr is the error of the single key batch, which is not written again.
+// The err is *MultiError of the failing keys, and commitHash is the last commit pushed.
+func (c *coalescer) writeEach(
+	ctx context.Context, batch map[string]pendingWrite, batchErr error,
+) (commitHash plumbing.Hash, retry map[string]pendingWrite, err error) {
+	keys := make([]string, 0, len(batch))
+	for key := range batch {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	retry = make(map[string]pendingWrite)
+	keyErrs := make(map[string]error)
+	stopped := false
+	for _, key := range keys {
+		if stopped {
+			retry[key] = batch[key]
+			continue
+		}
+
+		hash, writeErr := plumbing.ZeroHash, batchErr
+		if len(batch) > 1 {
+			hash, writeErr = c.write(ctx, map[string]pendingWrite{key: batch[key]})
+		}
+
+		switch {
+		case writeErr == nil:
+			if !hash.IsZero() {
+				commitHash = hash
+			}
+
+		case permanentWriteError(writeErr):
+			keyErrs[key] = fmt.Errorf("the write is dropped: %w", writeErr)
+
+		default:
+			keyErrs[key] = writeErr
+			retry[key] = batch[key]
+			stopped = remoteWideError(writeErr)
+		}
+	}
+
+	if len(keyErrs) > 0 {
+		err = &MultiError{Errors: keyErrs}
+	}
+
+	return
+}
+
+// buffered returns the latest buffered write of key, including the one being flushed.
+func (c *coalescer) buffered(key string) (write pendingWrite, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	write, ok = c.pending[key]
+	if !ok {
+		write, ok = c.flushing[key]
+	}
+
+	return
+}
+
+// permanentWriteError returns true when writing the same value again cannot succeed.
+func permanentWriteError(err error) bool {
+	switch codeOf(err) {
+	case CodeInvalidKey, CodeValueTooLarge, CodeRejectedByServer:
+		return true
+	}
+
+	return false
+}
+
+// remoteWideError returns true when the error is about the remote repository rather than the written keys,
+// i.e: it is unavailable, so writing the keys one by one fails the same.
+func remoteWideError(err error) bool {
+	switch codeOf(err) {
+	case CodeRemoteUnavailable, CodeAuthFailed, CodeCanceled, CodeConflict:
+		return true
+	}
+
+	return false
+}
+
+// write commits and pushes the batch, see writeBatch.
+func (c *coalescer) write(ctx context.Context, batch map[string]pendingWrite) (commitHash plumbing.Hash, err error) {
+	return c.db.writeBatch(ctx, OpFlush, "", fmt.Sprintf("gitrows: FLUSH %d keys", len(batch)), batch)
+}
+
+// writeBatch applies the batch of key and its write into the fresh pull, then commits and pushes it in one commit.
+// When the batch doesn't change anything, nothing is committed and the current HEAD is returned.
+func (db *DBImpl) writeBatch(
+	ctx context.Context, op Op, commitMsg, defaultCommitMsg string, batch map[string]pendingWrite,
+) (commitHash plumbing.Hash, err error) {
+	err = db.forcePull(ctx)
+	if err != nil {
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("cannot get worktree: %w", err)
+		return
+	}
+
+	keys := make([]string, 0, len(batch))
+	for key := range batch {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	sizes := make(map[string]int64, len(keys))
+	for _, key := range keys {
+		write := batch[key]
+		sizes[key] = int64(len(write.data))
+
+		if !write.deleted {
+			_, err = db.writeFile(ctx, write.filePath, write.data, "UPSERT", write.fileMode)
+			if err != nil {
+				err = fmt.Errorf("key '%s': %w", key, err)
+				return
+			}
+
+			continue
+		}
+
+		err = db.removeCommitted(worktree, write.filePath)
+		if err != nil {
+			err = fmt.Errorf("key '%s': %w", key, err)
+			return
+		}
+	}
+
+	worktreeStatus, err := worktree.Status()
+	if err != nil {
+		err = fmt.Errorf("cannot `git status`: %w", err)
+		return
+	}
+
+	// only check the keys, since with WithSparsePrefix the files outside the prefix are reported as deleted
+	changed := false
+	for _, write := range batch {
+		fileStatus, exist := worktreeStatus[write.filePath]
+		changed = changed || (exist && fileStatus.Staging != git.Unmodified)
+	}
+
+	if !changed {
+		head, headErr := db.headCommit()
+		if headErr != nil || head == nil {
+			return plumbing.ZeroHash, headErr
+		}
+
+		return head.Hash, nil
+	}
+
+	commitMsg = db.commitMessage(op, commitMsg, defaultCommitMsg, CommitMeta{
+		Sizes:   sizes,
+		Changed: true,
+	})
+
+	_, err = db.gitCommit(ctx, worktree, commitMsg, false)
+	if err != nil {
+		return
+	}
+
+	commitHash, _, err = db.gitPush(ctx, op)
+	return
+}
+
+// removeCommitted is like `git rm`, but does nothing when the file path is not committed.
+func (db *DBImpl) removeCommitted(worktree *git.Worktree, filePath string) (err error) {
+	file, err := db.headFile(filePath)
+	if err != nil || file == nil {
+		return
+	}
+
+	err = gitRm(worktree, filePath)
+	if err != nil {
+		err = fmt.Errorf("cannot `git rm %s`: %w", filePath, err)
+		return
+	}
+
+	return removeEmptyDirs(worktree.Filesystem, path.Dir(filePath))
+}
+
+// flushBuffered flushes the write of key buffered by WithWriteCoalescing, before the command writes the key directly.
+func (db *DBImpl) flushBuffered(ctx context.Context, key string) (err error) {
+	if db.coalescer == nil {
+		return
+	}
+
+	err = db.coalescer.flushKey(ctx, key)
+	if err != nil {
+		err = fmt.Errorf("cannot flush the buffered write of key '%s': %w", key, err)
+		return
+	}
+
+	return
+}
+
+// coalesceUpsert buffers the Upsert, see WithWriteCoalescing.
+func (db *DBImpl) coalesceUpsert(key, filePath string, data []byte, cfg *UpsertConfig) (result UpsertResult) {
+	// the caller may reuse data after Upsert returns
+	db.coalescer.add(key, pendingWrite{
+		filePath: filePath,
+		data:     append([]byte(nil), data...),
+		fileMode: cfg.fileMode,
+	})
+
+	result.Changed = true
+	result.BlobHash = plumbing.ComputeHash(plumbing.BlobObject, data).String()
+	result.BytesWritten = int64(len(data))
+	return
+}
+
+// coalesceDelete buffers the Delete, see WithWriteCoalescing.
+func (db *DBImpl) coalesceDelete(key, filePath string) {
+	db.coalescer.add(key, pendingWrite{
+		filePath: filePath,
+		deleted:  true,
+	})
+}
+
+// readBuffered returns the value of key buffered by WithWriteCoalescing within the range and limit of cfg,
+// when WithCoalesceReadYourWrites is enabled. The ok is false when the latest write of key is already flushed.
+func (db *DBImpl) readBuffered(key string, cfg *GetConfig) (data []byte, ok bool, err error) {
+	if db.coalescer == nil || !db.coalesceReadYourWrites {
+		return
+	}
+
+	write, ok := db.coalescer.buffered(key)
+	if !ok {
+		return
+	}
+
+	if write.deleted {
+		err = fmt.Errorf("%w: key '%s' is deleted by the buffered write", os.ErrNotExist, key)
+		return
+	}
+
+	data = write.data
+	if cfg.offset >= int64(len(data)) {
+		data = []byte{}
+	} else {
+		data = data[cfg.offset:]
+	}
+
+	if cfg.length > 0 && cfg.length < int64(len(data)) {
+		data = data[:cfg.length]
+	}
+
+	if cfg.maxBytes > 0 && int64(len(data)) > cfg.maxBytes {
+		err = fmt.Errorf("%w: value of key '%s' is larger than %d bytes", ErrValueTooLarge, key, cfg.maxBytes)
+		return
+	}
+
+	// the buffer is kept until it is flushed, so the caller must not modify it
+	data = append([]byte(nil), data...)
+	return
+}
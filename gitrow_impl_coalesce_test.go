@@ -0,0 +1,396 @@
+package gitrows_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithWriteCoalescing(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithWriteCoalescing(time.Hour))
+
+	remoteHasBranch := func() bool {
+		repo, err := git.PlainOpen(remote[len("file://"):])
+		require.NoError(t, err)
+
+		_, err = repo.Reference(plumbing.NewBranchReferenceName("master"), false)
+		return err == nil
+	}
+
+	// buffered until flushed, and the same key collapses into the latest value
+	result, err := db.UpsertR(ctx, "a.txt", []byte("1"))
+	require.NoError(t, err)
+	assert.Empty(t, result.CommitHash)
+	assert.True(t, result.Changed)
+
+	_, _, err = db.Upsert(ctx, "a.txt", []byte("2"))
+	require.NoError(t, err)
+	_, _, err = db.Upsert(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+	_, err = db.Delete(ctx, "missing.txt")
+	require.NoError(t, err)
+	assert.False(t, remoteHasBranch())
+
+	require.NoError(t, db.Flush(ctx))
+	head := remoteCommit(t, remote, remoteHead(t, remote))
+	assert.Empty(t, head.ParentHashes)
+	assert.Equal(t, "gitrows: FLUSH 3 keys", head.Message)
+
+	data, err := db.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(data))
+
+	// nothing buffered
+	require.NoError(t, db.Flush(ctx))
+	assert.Equal(t, head.Hash.String(), remoteHead(t, remote))
+
+	// Close flushes synchronously
+	_, _, err = db.Upsert(ctx, "a.txt", []byte("3"))
+	require.NoError(t, err)
+	_, err = db.Delete(ctx, "b.txt")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	reader := newTestDB(t, remote)
+	data, err = reader.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(data))
+
+	_, err = reader.Get(ctx, "b.txt")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, head.Hash, remoteCommit(t, remote, remoteHead(t, remote)).ParentHashes[0])
+
+	_, err = gitrows.New(gitrows.WithWriteCoalescing(0))
+	assert.Error(t, err)
+}
+
+func TestWithWriteCoalescing_background(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithWriteCoalescing(20*time.Millisecond))
+
+	_, _, err := db.Upsert(ctx, "a.txt", []byte("1"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		data, err := newTestDB(t, remote).Get(ctx, "a.txt")
+		return err == nil && string(data) == "1"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Empty(t, db.FlushErrors())
+
+	// the failed flush is kept for FlushErrors, and the write is retried by Close
+	unavailable := "file://" + filepath.Join(t.TempDir(), "missing.git")
+	db = newTestDB(t, unavailable, gitrows.WithWriteCoalescing(20*time.Millisecond))
+	_, _, err = db.Upsert(ctx, "a.txt", []byte("1"))
+	require.NoError(t, err)
+
+	var errs []error
+	assert.Eventually(t, func() bool {
+		errs = append(errs, db.FlushErrors()...)
+		return len(errs) > 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.ErrorIs(t, errs[0], &gitrows.Error{Op: gitrows.OpFlush})
+	assert.Error(t, db.Close())
+}
+
+func TestWithWriteCoalescing_dropsPermanentFailure(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithWriteCoalescing(time.Hour), gitrows.WithMaxPushBytes(2000))
+
+	for key, size := range map[string]int{"a.txt": 10, "big.bin": 5000, "b.txt": 10} {
+		_, _, err := db.Upsert(ctx, key, bytes.Repeat([]byte("x"), size))
+		require.NoError(t, err)
+	}
+
+	// the batch is too large, so the keys are written one by one and only the key which is too large is dropped
+	err := db.Flush(ctx)
+	assert.Equal(t, gitrows.CodeValueTooLarge, gitrows.ErrorCode(err))
+
+	var multiErr *gitrows.MultiError
+	require.ErrorAs(t, err, &multiErr)
+	require.Len(t, multiErr.Errors, 1)
+	assert.ErrorIs(t, multiErr.Errors["big.bin"], gitrows.ErrPushTooLarge)
+
+	keys, err := newTestDB(t, remote).Keys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, keys)
+
+	// nothing is left to retry
+	require.NoError(t, db.Flush(ctx))
+	require.NoError(t, db.Close())
+}
+
+// TestWithWriteCoalescing_directWrite writes the buffered key by the commands which are not buffered,
+// which must flush the older buffered write first rather than letting the later Flush replace their value.
+func TestWithWriteCoalescing_directWrite(t *testing.T) {
+	ctx := context.TODO()
+
+	tests := []struct {
+		name     string
+		buffered func(db *gitrows.DBImpl) error
+		write    func(t *testing.T, db *gitrows.DBImpl, remote string) error
+		want     string // the value of a.txt after Flush, empty when it is deleted
+	}{
+		{
+			name: "upsert expected head",
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				// the flushed write moves the head
+				head := remoteHead(t, remote)
+				_, _, err := db.Upsert(ctx, "a.txt", []byte("new"), gitrows.UpsertExpectedHead(head))
+				require.ErrorIs(t, err, gitrows.ErrStaleHead)
+
+				_, _, err = db.Upsert(ctx, "a.txt", []byte("new"), gitrows.UpsertExpectedHead(remoteHead(t, remote)))
+				return err
+			},
+			want: "new",
+		},
+		{
+			name: "upsert if different from",
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				_, _, err := db.Upsert(ctx, "a.txt", []byte("new"), gitrows.UpsertIfDifferentFrom(gitrows.BlobHash([]byte("new"))))
+				return err
+			},
+			want: "new",
+		},
+		{
+			name: "upsert meta",
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				_, _, err := db.Upsert(ctx, "a.txt", []byte("new"), gitrows.UpsertMeta(map[string]string{"Source": "test"}))
+				return err
+			},
+			want: "new",
+		},
+		{
+			name: "put reader",
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				_, err := db.PutReader(ctx, "a.txt", strings.NewReader("new"))
+				return err
+			},
+			want: "new",
+		},
+		{
+			name: "delete if match",
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				// only matches the buffered value
+				_, err := db.Delete(ctx, "a.txt", gitrows.DeleteIfMatch(gitrows.BlobHash([]byte("buffered"))))
+				return err
+			},
+		},
+		{
+			name: "delete expected head",
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				head := remoteHead(t, remote)
+				_, err := db.Delete(ctx, "a.txt", gitrows.DeleteExpectedHead(head))
+				require.ErrorIs(t, err, gitrows.ErrStaleHead)
+
+				_, err = db.Delete(ctx, "a.txt", gitrows.DeleteExpectedHead(remoteHead(t, remote)))
+				return err
+			},
+		},
+		{
+			name: "create",
+			buffered: func(db *gitrows.DBImpl) error {
+				_, err := db.Delete(ctx, "a.txt")
+				return err
+			},
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				_, err := db.Create(ctx, "a.txt", []byte("new"))
+				return err
+			},
+			want: "new",
+		},
+		{
+			name: "create if",
+			buffered: func(db *gitrows.DBImpl) error {
+				_, err := db.Delete(ctx, "a.txt")
+				return err
+			},
+			write: func(t *testing.T, db *gitrows.DBImpl, remote string) error {
+				_, err := db.CreateIf(ctx, "a.txt", []byte("new"), func(entries gitrows.Entries) bool {
+					return len(entries.KVs()) == 1
+				})
+				return err
+			},
+			want: "new",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newTestRemote(t)
+			seed := newTestDB(t, remote)
+			_, err := seed.Create(ctx, "a.txt", []byte("committed"))
+			require.NoError(t, err)
+			_, err = seed.Create(ctx, "b.txt", []byte("b"))
+			require.NoError(t, err)
+
+			db := newTestDB(t, remote, gitrows.WithWriteCoalescing(time.Hour))
+			if tt.buffered == nil {
+				tt.buffered = func(db *gitrows.DBImpl) error {
+					_, _, err := db.Upsert(ctx, "a.txt", []byte("buffered"))
+					return err
+				}
+			}
+
+			require.NoError(t, tt.buffered(db))
+			require.NoError(t, tt.write(t, db, remote))
+			require.NoError(t, db.Flush(ctx))
+			require.NoError(t, db.Close())
+
+			data, err := newTestDB(t, remote).Get(ctx, "a.txt")
+			if tt.want == "" {
+				assert.ErrorIs(t, err, os.ErrNotExist)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+// TestWithWriteCoalescing_directWriteInFlight writes the key directly while the background flush may be pushing
+// the older buffered write of it, which must land before the direct write.
+func TestWithWriteCoalescing_directWriteInFlight(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	_, err := newTestDB(t, remote).Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	db := newTestDB(t, remote, gitrows.WithWriteCoalescing(time.Millisecond))
+	for i := 0; i < 5; i++ {
+		_, _, err = db.Upsert(ctx, "a.txt", []byte(fmt.Sprint("buffered ", i)))
+		require.NoError(t, err)
+
+		time.Sleep(time.Millisecond)
+
+		_, _, err = db.Upsert(ctx, "a.txt", []byte(fmt.Sprint("direct ", i)), gitrows.UpsertMeta(map[string]string{"N": fmt.Sprint(i)}))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, db.Close())
+	assert.Empty(t, db.FlushErrors())
+
+	data, err := newTestDB(t, remote).Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "direct 4", string(data))
+}
+
+// TestWithWriteCoalescing_pushEvery runs the background flush alongside the foreground writes of WithPushEvery,
+// which must not touch the local repository of the DB from the timer, see `go test -race`.
+// The foreground commits are only pushed by Close, since the remote rejects the pushes racing each other.
+func TestWithWriteCoalescing_pushEvery(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	hot := func(key string) bool {
+		return strings.HasPrefix(key, "counters/")
+	}
+
+	db := newTestDB(t, remote, gitrows.WithCoalesce(hot, 5*time.Millisecond), gitrows.WithPushEvery(100))
+
+	keys := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("configs/%d.json", i)
+		keys = append(keys, key)
+
+		_, err := db.Create(ctx, key, []byte("{}"))
+		require.NoError(t, err)
+
+		_, _, err = db.Upsert(ctx, "counters/visits", []byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+
+		_, err = db.Get(ctx, key)
+		require.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	require.NoError(t, db.Close())
+	assert.Empty(t, db.FlushErrors())
+
+	entries, err := newTestDB(t, remote).List(ctx, gitrows.ListPrefix("configs"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, keys, listKeys(entries))
+}
+
+func TestWithCoalesce(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	hot := func(key string) bool {
+		return strings.HasPrefix(key, "counters/")
+	}
+
+	db := newTestDB(t, remote, gitrows.WithCoalesce(hot, time.Hour), gitrows.WithCoalesceReadYourWrites())
+
+	// the other keys are written immediately
+	created, err := db.Create(ctx, "config.json", []byte("{}"))
+	require.NoError(t, err)
+	_, _, err = db.Upsert(ctx, "config.json", []byte(`{"a":1}`))
+	require.NoError(t, err)
+	assert.NotEqual(t, created, remoteHead(t, remote))
+
+	tip := remoteHead(t, remote)
+	for _, value := range []string{"1", "12", "123"} {
+		_, _, err = db.Upsert(ctx, "counters/visits", []byte(value))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, tip, remoteHead(t, remote))
+
+	// read your writes, while the other DB still sees the committed state
+	data, err := db.Get(ctx, "counters/visits")
+	require.NoError(t, err)
+	assert.Equal(t, "123", string(data))
+
+	data, err = db.Get(ctx, "counters/visits", gitrows.GetRange(1, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(data))
+
+	_, err = db.Get(ctx, "counters/visits", gitrows.GetLimit(2))
+	assert.ErrorIs(t, err, gitrows.ErrValueTooLarge)
+
+	_, err = newTestDB(t, remote).Get(ctx, "counters/visits")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, db.Flush(ctx))
+	assert.Equal(t, "gitrows: FLUSH 1 keys", remoteCommit(t, remote, remoteHead(t, remote)).Message)
+
+	data, err = newTestDB(t, remote).Get(ctx, "counters/visits")
+	require.NoError(t, err)
+	assert.Equal(t, "123", string(data))
+
+	_, err = db.Delete(ctx, "counters/visits")
+	require.NoError(t, err)
+	_, err = db.Get(ctx, "counters/visits")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// without read your writes, the committed value is returned until the flush
+	plain := newTestDB(t, remote, gitrows.WithCoalesce(hot, time.Hour))
+	_, _, err = plain.Upsert(ctx, "counters/visits", []byte("4"))
+	require.NoError(t, err)
+
+	data, err = plain.Get(ctx, "counters/visits")
+	require.NoError(t, err)
+	assert.Equal(t, "123", string(data))
+	require.NoError(t, plain.Close())
+	require.NoError(t, db.Close())
+
+	_, err = gitrows.New(gitrows.WithCoalesce(hot, 0))
+	assert.Error(t, err)
+}
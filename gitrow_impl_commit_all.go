@@ -0,0 +1,13 @@
+package gitrows
+
+// WithCommitAllWorktreeChanges commits every change of the tracked files in the local repository with the write
+// commands, like `git commit --all`, rather than only the paths written or removed by the command, which is
+// the default. The stray change (i.e: the file edited in place by other code sharing the volume) is then
+// committed and pushed together with the command, so only use it when something relies on that.
+// It has no effect with WithSparsePrefix, which never commits the files outside the prefix.
+func WithCommitAllWorktreeChanges() Opt {
+	return func(db *DBImpl) error {
+		db.commitAllChanges = true
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestUpsert_strayWorktreeChanges(t *testing.T) {
+	ctx := context.TODO()
+
+	tests := []struct {
+		name          string
+		opts          []gitrows.Opt
+		wantCommitted bool
+	}{
+		{name: "default"},
+		{name: "commit all worktree changes", opts: []gitrows.Opt{gitrows.WithCommitAllWorktreeChanges()}, wantCommitted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newTestRemote(t)
+			volume := t.TempDir()
+			repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+
+			db := newTestDB(t, remote, append(tt.opts, gitrows.WithLocalGitVolume(volume))...)
+			_, err := db.Create(ctx, "configs/app.yaml", []byte("replicas: 1"))
+			require.NoError(t, err)
+
+			// other code edits the tracked file in place, and the editor leaves its backup
+			require.NoError(t, os.WriteFile(filepath.Join(repoDir, "configs", "app.yaml"), []byte("replicas: 100"), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(repoDir, "configs", ".app.yaml.swp"), []byte("swap"), 0644))
+
+			// without the sync, the checkout doesn't reset the worktree before the commit
+			commitHash, _, err := db.Upsert(ctx, "configs/db.yaml", []byte("host: db"), gitrows.UpsertSkipSync())
+			require.NoError(t, err)
+
+			tree, err := remoteCommit(t, remote, commitHash).Tree()
+			require.NoError(t, err)
+
+			_, err = tree.File("configs/.app.yaml.swp")
+			assert.Error(t, err)
+
+			file, err := tree.File("configs/app.yaml")
+			require.NoError(t, err)
+
+			content, err := file.Contents()
+			require.NoError(t, err)
+
+			if tt.wantCommitted {
+				assert.Equal(t, "replicas: 100", content)
+			} else {
+				assert.Equal(t, "replicas: 1", content)
+			}
+		})
+	}
+}
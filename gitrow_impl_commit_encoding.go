@@ -0,0 +1,100 @@
+package gitrows
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// WithCommitEncoding records enc (i.e: "UTF-8" or "EUC-JP") as the `encoding` header of every commit created by gitrows,
+// so git clients decode the non-ASCII commit message properly. Without this option, no header is written,
+// which git treats as UTF-8.
+//
+// The message is written as is, not converted, so it must be encoded in enc already.
+// Since go-git doesn't support the header, it is kept only by the commits created by gitrows, not by Compact.
+func WithCommitEncoding(enc string) Opt {
+	return func(db *DBImpl) error {
+		enc = strings.TrimSpace(enc)
+		if enc == "" || strings.IndexFunc(enc, unicode.IsSpace) >= 0 || strings.IndexFunc(enc, unicode.IsControl) >= 0 {
+			return fmt.Errorf("invalid commit encoding %q", enc)
+		}
+
+		db.commitEncoding = enc
+		return nil
+	}
+}
+
+// setCommitEncoding rewrites the commit with the `encoding` header of WithCommitEncoding,
+// and moves the local branch to the rewritten commit.
+func (db *DBImpl) setCommitEncoding(commitHash plumbing.Hash) (rewritten plumbing.Hash, err error) {
+	obj, err := db.gitRepo.Storer.EncodedObject(plumbing.CommitObject, commitHash)
+	if err != nil {
+		err = fmt.Errorf("cannot get commit %s: %w", commitHash, err)
+		return
+	}
+
+	reader, err := obj.Reader()
+	if err != nil {
+		err = fmt.Errorf("cannot read commit %s: %w", commitHash, err)
+		return
+	}
+
+	raw, err := io.ReadAll(reader)
+	if _err := reader.Close(); _err != nil && err == nil {
+		err = _err
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot read commit %s: %w", commitHash, err)
+		return
+	}
+
+	// the header goes after the committer, which is the last header written by go-git for the unsigned commit
+	end := bytes.Index(raw, []byte("\n\n"))
+	if end < 0 {
+		end = len(raw)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(raw[:end])
+	fmt.Fprintf(buf, "\nencoding %s", db.commitEncoding)
+	buf.Write(raw[end:])
+
+	newObj := db.gitRepo.Storer.NewEncodedObject()
+	newObj.SetType(plumbing.CommitObject)
+
+	writer, err := newObj.Writer()
+	if err != nil {
+		err = fmt.Errorf("cannot write commit: %w", err)
+		return
+	}
+
+	_, err = writer.Write(buf.Bytes())
+	if _err := writer.Close(); _err != nil && err == nil {
+		err = _err
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot write commit: %w", err)
+		return
+	}
+
+	rewritten, err = db.gitRepo.Storer.SetEncodedObject(newObj)
+	if err != nil {
+		err = fmt.Errorf("cannot store commit: %w", err)
+		return
+	}
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(branchName, rewritten))
+	if err != nil {
+		err = fmt.Errorf("cannot set reference %s: %w", branchName, err)
+		return
+	}
+
+	return
+}
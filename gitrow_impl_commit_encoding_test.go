@@ -0,0 +1,60 @@
+package gitrows_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithCommitEncoding(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	rawCommit := func(hash string) string {
+		repo, err := git.PlainOpen(strings.TrimPrefix(remote, "file://"))
+		require.NoError(t, err)
+
+		obj, err := repo.Storer.EncodedObject(plumbing.CommitObject, plumbing.NewHash(hash))
+		require.NoError(t, err)
+
+		reader, err := obj.Reader()
+		require.NoError(t, err)
+		defer reader.Close()
+
+		raw, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(raw)
+	}
+
+	// no header by default, which means UTF-8
+	commitHash, err := newTestDB(t, remote).Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+	assert.NotContains(t, rawCommit(commitHash), "\nencoding ")
+
+	db := newTestDB(t, remote, gitrows.WithCommitEncoding("UTF-8"))
+	commitHash, err = db.Create(ctx, "b.txt", []byte("b"), gitrows.CreateCommitMsg("設定を更新"))
+	require.NoError(t, err)
+	assert.Equal(t, remoteHead(t, remote), commitHash)
+
+	raw := rawCommit(commitHash)
+	assert.Contains(t, raw, "\nencoding UTF-8\n\n設定を更新")
+
+	commit := remoteCommit(t, remote, commitHash)
+	assert.Equal(t, "設定を更新", commit.Message)
+	assert.Len(t, commit.ParentHashes, 1)
+
+	// the rewritten commit is the local branch, so the next write is on top of it
+	commitHash, _, err = db.Upsert(ctx, "b.txt", []byte("b2"))
+	require.NoError(t, err)
+	assert.Equal(t, commit.Hash, remoteCommit(t, remote, commitHash).ParentHashes[0])
+
+	_, err = gitrows.New(gitrows.WithCommitEncoding("UTF 8"))
+	assert.Error(t, err)
+}
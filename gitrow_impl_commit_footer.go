@@ -0,0 +1,124 @@
+package gitrows
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	footerOpPrefix  = "X-Gitrows-Op: "
+	footerKeyPrefix = "X-Gitrows-Key: "
+)
+
+// WithStructuredCommitFooter appends the footer of the command and the committed keys to the message
+// of every commit created by gitrows, after the message chosen by WithCommitMessageFunc (or the per-call message,
+// or WithInitCommitMessage), i.e: for the tools reading the git log without diffing each commit.
+//
+//	gitrows: UPSERT
+//
+//	X-Gitrows-Op: upsert
+//	X-Gitrows-Key: configs/a.yaml
+//
+// The batch commits (i.e: CopyFrom and WithWriteCoalescing) have one X-Gitrows-Key line per key, and the commit
+// without keys (i.e: Compact) only has X-Gitrows-Op. Use ParseCommitFooter to read it back.
+func WithStructuredCommitFooter(enabled bool) Opt {
+	return func(db *DBImpl) error {
+		db.commitFooter = enabled
+		return nil
+	}
+}
+
+// ParseCommitFooter returns the command and the keys (sorted) of the footer of WithStructuredCommitFooter
+// in the commit message, or false when the message has no footer, i.e: the commit is created by other git client.
+func ParseCommitFooter(message string) (op Op, keys []string, ok bool) {
+	lines := strings.Split(strings.TrimRight(message, "\r\n\t "), "\n")
+
+	// the footer is the last paragraph
+	start := len(lines)
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+
+	for _, line := range lines[start:] {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, footerOpPrefix) && !ok:
+			op, ok = Op(strings.TrimPrefix(line, footerOpPrefix)), true
+
+		case strings.HasPrefix(line, footerKeyPrefix):
+			keys = append(keys, footerValue(strings.TrimPrefix(line, footerKeyPrefix)))
+		}
+	}
+
+	if !ok {
+		return "", nil, false
+	}
+
+	sort.Strings(keys)
+	return
+}
+
+// withCommitFooter appends the footer of WithStructuredCommitFooter to the message when it is enabled,
+// and the trailers of the metadata of CreateMeta or UpsertMeta, in the same last paragraph.
+func (db *DBImpl) withCommitFooter(msg string, op Op, meta CommitMeta) string {
+	if !db.commitFooter && len(meta.Metadata) == 0 {
+		return msg
+	}
+
+	lines := make([]string, 0)
+	if db.commitFooter {
+		keys := make([]string, 0, len(meta.Sizes))
+		for key := range meta.Sizes {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		lines = append(lines, footerOpPrefix+string(op))
+		for _, key := range keys {
+			lines = append(lines, footerKeyPrefix+footerKey(key))
+		}
+	}
+
+	lines = append(lines, metaTrailers(meta.Metadata)...)
+	return strings.TrimRight(msg, "\r\n\t ") + "\n\n" + strings.Join(lines, "\n")
+}
+
+// footerKey returns the key in the X-Gitrows-Key line, quoted when it cannot be written as is,
+// i.e: the key ending with space, which is trimmed with the message. It also quotes the value of the metadata.
+func footerKey(key string) string {
+	if key == "" || strings.HasPrefix(key, `"`) || strings.TrimRight(key, " \t") != key || strings.ContainsAny(key, "\r\n") {
+		return strconv.Quote(key)
+	}
+
+	return key
+}
+
+// footerValue returns the key of footerKey.
+func footerValue(value string) string {
+	if key, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+		return key
+	}
+
+	return value
+}
+
+// initCommitMessage returns the message of WithInitCommitMessage replacing the message of commitMessage,
+// keeping the footer of WithStructuredCommitFooter and the trailers of the metadata.
+func (db *DBImpl) initCommitMessage(commitMsg string) string {
+	i := -1
+	if db.commitFooter {
+		i = strings.LastIndex(commitMsg, "\n\n"+footerOpPrefix)
+	}
+
+	if i < 0 {
+		i = strings.LastIndex(commitMsg, "\n\n"+footerMetaPrefix)
+	}
+
+	if i < 0 {
+		return db.initCommitMsg
+	}
+
+	return db.initCommitMsg + commitMsg[i:]
+}
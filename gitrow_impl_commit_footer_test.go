@@ -0,0 +1,100 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithStructuredCommitFooter(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote,
+		gitrows.WithStructuredCommitFooter(true),
+		gitrows.WithInitCommitMessage("gitrows: initialize branch"),
+	)
+
+	footer := func(t *testing.T, commitHash string) (gitrows.Op, []string) {
+		t.Helper()
+
+		op, keys, ok := gitrows.ParseCommitFooter(remoteCommit(t, remote, commitHash).Message)
+		require.True(t, ok)
+		return op, keys
+	}
+
+	commitHash, err := db.Create(ctx, "configs/a.yaml", []byte("a"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "gitrows: initialize branch\n\nX-Gitrows-Op: create\nX-Gitrows-Key: configs/a.yaml",
+		remoteCommit(t, remote, commitHash).Message)
+
+	commitHash, _, err = db.Upsert(ctx, "configs/a.yaml", []byte("b"), gitrows.UpsertCommitMsg("update a\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "update a\n\nX-Gitrows-Op: upsert\nX-Gitrows-Key: configs/a.yaml", remoteCommit(t, remote, commitHash).Message)
+
+	commitHash, err = db.Create(ctx, "notes/trailing ", []byte("c"))
+	require.NoError(t, err)
+
+	op, keys := footer(t, commitHash)
+	assert.Equal(t, gitrows.OpCreate, op)
+	assert.Equal(t, []string{"notes/trailing "}, keys)
+
+	commitHash, err = db.Delete(ctx, "configs/a.yaml")
+	require.NoError(t, err)
+
+	op, keys = footer(t, commitHash)
+	assert.Equal(t, gitrows.OpDelete, op)
+	assert.Equal(t, []string{"configs/a.yaml"}, keys)
+}
+
+func TestParseCommitFooter(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		op      gitrows.Op
+		keys    []string
+		ok      bool
+	}{
+		{
+			name:    "batch",
+			message: "gitrows: COPY 2 keys\n\nX-Gitrows-Op: copy from\nX-Gitrows-Key: b.txt\nX-Gitrows-Key: a.txt\n",
+			op:      gitrows.OpCopyFrom,
+			keys:    []string{"a.txt", "b.txt"},
+			ok:      true,
+		},
+		{
+			name:    "without keys",
+			message: "gitrows: COMPACT 3 commits\n\nX-Gitrows-Op: compact",
+			op:      gitrows.OpCompact,
+			ok:      true,
+		},
+		{
+			name:    "other trailers",
+			message: "fix\r\n\r\nX-Gitrows-Op: upsert\r\nX-Gitrows-Key: a.txt\r\nSigned-off-by: someone <someone@example.com>\r\n",
+			op:      gitrows.OpUpsert,
+			keys:    []string{"a.txt"},
+			ok:      true,
+		},
+		{
+			name:    "not the last paragraph",
+			message: "fix\n\nX-Gitrows-Op: upsert\n\nmore detail",
+		},
+		{
+			name:    "other git client",
+			message: "update a.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, keys, ok := gitrows.ParseCommitFooter(tt.message)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.op, op)
+			assert.Equal(t, tt.keys, keys)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package gitrows
+
+import (
+	"sort"
+	"strings"
+)
+
+// WithCommitMessageFunc set the function which generates the message of every commit created by gitrows,
+// with the command, the committed keys (sorted) and the CommitMeta. Empty string falls back to the built-in default,
+// i.e: "gitrows: UPSERT".
+//
+// The message is chosen in this order: WithInitCommitMessage for the first commit of the branch,
+// the per-call message (i.e: UpsertCommitMsg), WithCommitMessageFunc, then the built-in default.
+func WithCommitMessageFunc(fn func(op Op, keys []string, meta CommitMeta) string) Opt {
+	return func(db *DBImpl) error {
+		db.commitMsgFunc = fn
+		return nil
+	}
+}
+
+// commitMessage returns the message of the commit created by op, see WithCommitMessageFunc for the precedence.
+// The explicit is the per-call message, and the builtin is the default message of the command.
+// WithInitCommitMessage is applied later in gitCommit, since it depends on the branch state.
+func (db *DBImpl) commitMessage(op Op, explicit, builtin string, meta CommitMeta) string {
+	return db.withCommitFooter(db.commitSubject(op, explicit, builtin, meta), op, meta)
+}
+
+// commitSubject returns the message of commitMessage, without the footer of WithStructuredCommitFooter.
+func (db *DBImpl) commitSubject(op Op, explicit, builtin string, meta CommitMeta) string {
+	if explicit != "" {
+		return explicit
+	}
+
+	if db.commitMsgFunc == nil {
+		return builtin
+	}
+
+	keys := make([]string, 0, len(meta.Sizes))
+	for key := range meta.Sizes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	msg := strings.TrimSpace(db.commitMsgFunc(op, keys, meta))
+	if msg == "" {
+		return builtin
+	}
+
+	return msg
+}
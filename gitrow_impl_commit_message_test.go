@@ -0,0 +1,89 @@
+package gitrows_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithCommitMessageFunc(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	type call struct {
+		op   gitrows.Op
+		keys []string
+		meta gitrows.CommitMeta
+	}
+
+	calls := make([]call, 0)
+	db := newTestDB(t, remote,
+		gitrows.WithInitCommitMessage("gitrows: initialize branch"),
+		gitrows.WithCommitMessageFunc(func(op gitrows.Op, keys []string, meta gitrows.CommitMeta) string {
+			calls = append(calls, call{op: op, keys: keys, meta: meta})
+			if strings.HasPrefix(keys[0], "default/") {
+				return " "
+			}
+
+			return fmt.Sprintf("%s %s (%d bytes)", op, strings.Join(keys, ","), meta.Sizes[keys[0]])
+		}),
+	)
+
+	message := func(commitHash string) string {
+		return remoteCommit(t, remote, commitHash).Message
+	}
+
+	// the first commit uses WithInitCommitMessage
+	commitHash, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "gitrows: initialize branch", message(commitHash))
+
+	commitHash, err = db.Create(ctx, "b.txt", []byte("bb"))
+	require.NoError(t, err)
+	assert.Equal(t, "create b.txt (2 bytes)", message(commitHash))
+
+	commitHash, _, err = db.Upsert(ctx, "b.txt", []byte("bbb"))
+	require.NoError(t, err)
+	assert.Equal(t, "upsert b.txt (3 bytes)", message(commitHash))
+
+	commitHash, err = db.Delete(ctx, "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "delete b.txt (0 bytes)", message(commitHash))
+
+	// empty message falls back to the built-in default
+	commitHash, _, err = db.Upsert(ctx, "default/c.txt", []byte("c"))
+	require.NoError(t, err)
+	assert.Equal(t, "gitrows: UPSERT", message(commitHash))
+
+	// the per-call message is never passed to the function
+	n := len(calls)
+	commitHash, _, err = db.Upsert(ctx, "b.txt", []byte("b"), gitrows.UpsertCommitMsg("explicit"))
+	require.NoError(t, err)
+	assert.Equal(t, "explicit", message(commitHash))
+	assert.Len(t, calls, n)
+
+	// changed is false for empty commit
+	commitHash, _, err = db.Upsert(ctx, "b.txt", []byte("b"), gitrows.UpsertAllowEmptyCommit(true))
+	require.NoError(t, err)
+	assert.Equal(t, "upsert b.txt (1 bytes)", message(commitHash))
+	assert.False(t, calls[len(calls)-1].meta.Changed)
+	assert.True(t, calls[len(calls)-2].meta.Changed)
+
+	commitHash, migrated, err := db.Migrate(ctx, "", func(key string, data []byte) ([]byte, bool, error) {
+		return append(data, '!'), false, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, migrated)
+	assert.Equal(t, "migrate a.txt,b.txt,default/c.txt (2 bytes)", message(commitHash))
+	assert.Equal(t, gitrows.OpMigrate, calls[len(calls)-1].op)
+
+	// without the function, the built-in default is used
+	commitHash, err = newTestDB(t, remote).Create(ctx, "d.txt", []byte("d"))
+	require.NoError(t, err)
+	assert.Equal(t, "gitrows: CREATE", message(commitHash))
+}
@@ -0,0 +1,117 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// footerMetaPrefix is the prefix of the trailer name of each metadata of CreateMeta and UpsertMeta,
+// i.e: "X-Gitrows-Meta-Request-Id: 42", so it is never confused with the other trailers like Signed-off-by.
+const footerMetaPrefix = "X-Gitrows-Meta-"
+
+// mergeCommitMeta returns dst with the metadata of src, or error when the name of src cannot be the trailer name.
+func mergeCommitMeta(dst, src map[string]string) (map[string]string, error) {
+	for name := range src {
+		if name == "" || strings.TrimFunc(name, isTrailerNameRune) != "" {
+			return dst, fmt.Errorf("invalid commit metadata name '%s': must only contain letters, digits and '-'", name)
+		}
+	}
+
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+
+	for name, value := range src {
+		dst[name] = value
+	}
+
+	return dst, nil
+}
+
+func isTrailerNameRune(r rune) bool {
+	return r == '-' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+// metaTrailers returns one trailer line of each metadata, sorted by the name.
+func metaTrailers(meta map[string]string) []string {
+	names := make([]string, 0, len(meta))
+	for name := range meta {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, footerMetaPrefix+name+": "+footerKey(meta[name]))
+	}
+
+	return lines
+}
+
+// parseCommitMeta returns the metadata in the last paragraph of the commit message.
+func parseCommitMeta(message string) map[string]string {
+	lines := strings.Split(strings.TrimRight(message, "\r\n\t "), "\n")
+
+	start := len(lines)
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+
+	meta := make(map[string]string)
+	for _, line := range lines[start:] {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasPrefix(line, footerMetaPrefix) {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, footerMetaPrefix), ": ")
+		if !ok {
+			continue
+		}
+
+		meta[name] = footerValue(value)
+	}
+
+	return meta
+}
+
+// CommitNotes returns the metadata attached to the commit by CreateMeta or UpsertMeta,
+// which is empty when the commit has none, i.e: the commit created by other git client.
+// The commit is looked up in the local repository after fetching the branch, so the older commit beyond
+// the local history is ErrCommitNotFound, unless the history is deepened first, see DeepenSince.
+func (db *DBImpl) CommitNotes(ctx context.Context, hash string) (meta map[string]string, err error) {
+	defer func() {
+		err = wrapError(OpCommitNotes, "", err)
+	}()
+
+	if !plumbing.IsHash(hash) {
+		err = fmt.Errorf("commit notes command: '%s' is not a full commit hash", hash)
+		return
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("commit notes command: %w", err)
+		return
+	}
+
+	commit, err := db.gitRepo.CommitObject(plumbing.NewHash(hash))
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		err = fmt.Errorf("commit notes command: %w: %s is not in the local repository", ErrCommitNotFound, hash)
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("commit notes command: cannot get commit %s: %w", hash, err)
+		return
+	}
+
+	meta = parseCommitMeta(commit.Message)
+	return
+}
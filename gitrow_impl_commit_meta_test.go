@@ -0,0 +1,79 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_CommitNotes(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithInitCommitMessage("gitrows: initialize branch"))
+
+	meta := map[string]string{"Request-Id": "req-42", "Actor": "svc: billing", "Note": "multi\nline"}
+	created, err := db.Create(ctx, "a.txt", []byte("a"), gitrows.CreateMeta(meta))
+	require.NoError(t, err)
+
+	message := remoteCommit(t, remote, created).Message
+	assert.True(t, strings.HasPrefix(message, "gitrows: initialize branch\n\n"), message)
+	assert.Contains(t, message, "\nX-Gitrows-Meta-Request-Id: req-42")
+
+	// the other DB reads it back from the remote repository
+	notes, err := newTestDB(t, remote).CommitNotes(ctx, created)
+	require.NoError(t, err)
+	assert.Equal(t, meta, notes)
+
+	upserted, _, err := db.Upsert(ctx, "a.txt", []byte("b"),
+		gitrows.UpsertCommitMsg("update a"),
+		gitrows.UpsertMeta(map[string]string{"Ticket": "OPS-1"}),
+		gitrows.UpsertMeta(map[string]string{"Empty": ""}),
+	)
+	require.NoError(t, err)
+
+	notes, err = db.CommitNotes(ctx, upserted)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Ticket": "OPS-1", "Empty": ""}, notes)
+
+	plain, err := db.Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	notes, err = db.CommitNotes(ctx, plain)
+	require.NoError(t, err)
+	assert.Empty(t, notes)
+
+	_, err = db.CommitNotes(ctx, strings.Repeat("0", 40))
+	assert.True(t, errors.Is(err, gitrows.ErrCommitNotFound), "%v", err)
+
+	_, err = db.CommitNotes(ctx, "HEAD")
+	assert.Error(t, err)
+
+	_, err = db.Create(ctx, "c.txt", []byte("c"), gitrows.CreateMeta(map[string]string{"Request Id": "1"}))
+	assert.Error(t, err)
+}
+
+func TestDBImpl_CommitNotes_withFooter(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithStructuredCommitFooter(true))
+
+	commitHash, err := db.Create(ctx, "a.txt", []byte("a"), gitrows.CreateMeta(map[string]string{"Request-Id": "req-42"}))
+	require.NoError(t, err)
+
+	message := remoteCommit(t, remote, commitHash).Message
+	assert.Equal(t, "gitrows: CREATE\n\nX-Gitrows-Op: create\nX-Gitrows-Key: a.txt\nX-Gitrows-Meta-Request-Id: req-42", message)
+
+	op, keys, ok := gitrows.ParseCommitFooter(message)
+	require.True(t, ok)
+	assert.Equal(t, gitrows.OpCreate, op)
+	assert.Equal(t, []string{"a.txt"}, keys)
+
+	notes, err := db.CommitNotes(ctx, commitHash)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Request-Id": "req-42"}, notes)
+}
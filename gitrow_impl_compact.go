@@ -0,0 +1,262 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Compact squashes the history older than olderThan, i.e: for the key which is updated every few seconds.
+// Every run of consecutive commits before the cutoff which only change the keys under the prefix (including
+// the nested directories, like Migrate) is squashed into one commit with the tree, author and committer
+// of the newest commit of the run. The other commits are kept, and empty prefix squashes the whole history
+// before the cutoff into one baseline commit. The newBase is the rewritten newest commit before the cutoff,
+// or empty when nothing is squashed.
+//
+// WARNING: this is destructive. The branch is rewritten and force-pushed, so every commit hash changes
+// and the commit signatures are dropped. The push is rejected with CodeConflict when another writer pushes
+// during the compaction. Other DB converges on its next sync, but the unpushed commit based on the old
+// history is rejected with WithForcePush(false), or overwrites the compacted history with the force push.
+func (db *DBImpl) Compact(ctx context.Context, prefix string, olderThan time.Duration) (newBase string, err error) {
+	defer func() {
+		err = wrapError(OpCompact, prefix, err)
+		db.recordOp(OpCompact, prefix, newBase, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("compact command: %w", err)
+		return
+	}
+
+	if olderThan < 0 {
+		err = fmt.Errorf("compact command: older than must not be negative, got %s", olderThan)
+		return
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	prefix = strings.Trim(path.Clean("/"+prefix), "/")
+
+	repo, err := db.cloneFullHistory(ctx)
+	if err != nil {
+		err = fmt.Errorf("compact command: %w", err)
+		return
+	}
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	ref, err := repo.Reference(branchName, false)
+	if err != nil {
+		err = fmt.Errorf("compact command: cannot get reference %s: %w", branchName, err)
+		return
+	}
+
+	// oldest first
+	chain := make([]*object.Commit, 0)
+	for hash := ref.Hash(); ; {
+		var commit *object.Commit
+		commit, err = repo.CommitObject(hash)
+		if err != nil {
+			err = fmt.Errorf("compact command: cannot get commit %s: %w", hash, err)
+			return
+		}
+
+		chain = append([]*object.Commit{commit}, chain...)
+		if commit.NumParents() == 0 {
+			break
+		}
+
+		hash = commit.ParentHashes[0]
+	}
+
+	old := 0
+	for i, commit := range chain {
+		if !commit.Committer.When.After(cutoff) {
+			old = i + 1
+		}
+	}
+
+	var parent plumbing.Hash
+	squashed := false
+	run := make([]*object.Commit, 0)
+	flush := func() error {
+		if len(run) == 0 {
+			return nil
+		}
+
+		newest := run[len(run)-1]
+		msg := newest.Message
+		if len(run) > 1 {
+			squashed = true
+			msg = db.commitMessage(OpCompact, "", fmt.Sprintf("gitrows: COMPACT %d commits", len(run)), CommitMeta{Changed: true})
+		}
+
+		hash, err := rewriteCommit(repo, newest, msg, parent)
+		parent, run = hash, run[:0]
+		return err
+	}
+
+	for _, commit := range chain[:old] {
+		var onlyPrefix bool
+		onlyPrefix, err = db.changesOnlyUnder(repo, commit, prefix)
+		if err != nil {
+			err = fmt.Errorf("compact command: %w", err)
+			return
+		}
+
+		if onlyPrefix {
+			run = append(run, commit)
+			continue
+		}
+
+		err = flush()
+		if err == nil {
+			run = append(run, commit)
+			err = flush()
+		}
+
+		if err != nil {
+			err = fmt.Errorf("compact command: %w", err)
+			return
+		}
+	}
+
+	err = flush()
+	if err != nil {
+		err = fmt.Errorf("compact command: %w", err)
+		return
+	}
+
+	if !squashed {
+		return
+	}
+
+	newBase = parent.String()
+	for _, commit := range chain[old:] {
+		parent, err = rewriteCommit(repo, commit, commit.Message, parent)
+		if err != nil {
+			err = fmt.Errorf("compact command: %w", err)
+			return
+		}
+	}
+
+	err = repo.Storer.SetReference(plumbing.NewHashReference(branchName, parent))
+	if err != nil {
+		err = fmt.Errorf("compact command: cannot set reference %s: %w", branchName, err)
+		return
+	}
+
+	err = db.phase(ctx, phasePush)
+	if err != nil {
+		err = fmt.Errorf("compact command: %w", err)
+		return
+	}
+
+	// the lease rejects the push when the remote branch is changed after the clone
+	refSpec := fmt.Sprintf("+%s:%s", branchName, branchName)
+	err = db.push(ctx, repo, &git.PushOptions{
+		RemoteName: gitRemoteName,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(refSpec),
+		},
+		Auth:     db.auth,
+		Progress: db.progressWriter(),
+		ForceWithLease: &git.ForceWithLease{
+			RefName: branchName,
+			Hash:    ref.Hash(),
+		},
+	})
+
+	if err != nil {
+		newBase = ""
+		err = fmt.Errorf("compact command: cannot `git push --force-with-lease %s`: %w", refSpec, nonFastForwardError(remoteError(db.gitSshUrl, err)))
+		return
+	}
+
+	err = db.forcePull(ctx)
+	if err != nil {
+		err = fmt.Errorf("compact command: %w", err)
+		return
+	}
+
+	return
+}
+
+// changesOnlyUnder returns true when every file path changed by the commit (compared to its first parent)
+// is the key under the prefix.
+func (db *DBImpl) changesOnlyUnder(repo *git.Repository, commit *object.Commit, prefix string) (bool, error) {
+	if prefix == "" {
+		return true, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return false, fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.Hash, err)
+	}
+
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		var parent *object.Commit
+		parent, err = repo.CommitObject(commit.ParentHashes[0])
+		if err != nil {
+			return false, fmt.Errorf("cannot get parent of commit %s: %w", commit.Hash, err)
+		}
+
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return false, fmt.Errorf("retrieve the tree from the commit %s error: %w", parent.Hash, err)
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return false, fmt.Errorf("cannot diff commit %s: %w", commit.Hash, err)
+	}
+
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+
+		key, ok := db.pathToKey(name)
+		if !ok || (key != prefix && !strings.HasPrefix(key, prefix+"/")) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// rewriteCommit stores the copy of commit with the message and the parent (none when zero), without the signature.
+func rewriteCommit(repo *git.Repository, commit *object.Commit, msg string, parent plumbing.Hash) (plumbing.Hash, error) {
+	rewritten := &object.Commit{
+		Author:    commit.Author,
+		Committer: commit.Committer,
+		Message:   msg,
+		TreeHash:  commit.TreeHash,
+	}
+
+	if !parent.IsZero() {
+		rewritten.ParentHashes = []plumbing.Hash{parent}
+	}
+
+	obj := repo.Storer.NewEncodedObject()
+	err := rewritten.Encode(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("cannot encode commit: %w", err)
+	}
+
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("cannot store commit: %w", err)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,141 @@
+package gitrows
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_Compact(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	remote, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func() *DBImpl {
+		db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+		require.NoError(t, err)
+		return db
+	}
+
+	remoteMessages := func() []string {
+		ref, err := remote.Reference(plumbing.NewBranchReferenceName("master"), false)
+		require.NoError(t, err)
+
+		iter, err := remote.Log(&git.LogOptions{From: ref.Hash()})
+		require.NoError(t, err)
+
+		msgs := make([]string, 0)
+		err = iter.ForEach(func(commit *object.Commit) error {
+			msgs = append([]string{commit.Message}, msgs...)
+			return nil
+		})
+		require.NoError(t, err)
+		return msgs
+	}
+
+	db := newDB()
+	_, err = db.Create(ctx, "config.yaml", []byte("v1"), CreateCommitMsg("config"))
+	require.NoError(t, err)
+
+	write := func(key, data string) {
+		_, _, err := db.Upsert(ctx, key, []byte(data), UpsertCommitMsg(key+"="+data))
+		require.NoError(t, err)
+	}
+
+	for _, data := range []string{"1", "2", "3"} {
+		write("hot/counter", data)
+	}
+
+	write("other.yaml", "o")
+	write("hot/nested/counter", "4")
+	write("hot/counter", "5")
+
+	// the reader has the shallow clone of the old history
+	reader := newDB()
+	_, err = reader.Get(ctx, "hot/counter")
+	require.NoError(t, err)
+
+	// nothing is older than an hour
+	newBase, err := db.Compact(ctx, "hot", time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, newBase)
+	assert.Len(t, remoteMessages(), 7)
+
+	// only the runs of commits under the prefix are squashed
+	newBase, err = db.Compact(ctx, "/hot/", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"config",
+		"gitrows: COMPACT 3 commits",
+		"other.yaml=o",
+		"gitrows: COMPACT 2 commits",
+	}, remoteMessages())
+
+	head, err := db.remoteHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, head, newBase)
+
+	// already compacted
+	newBase, err = db.Compact(ctx, "hot", 0)
+	require.NoError(t, err)
+	assert.Empty(t, newBase)
+
+	// both the writer and the reader converge into the rewritten branch
+	for _, d := range []*DBImpl{db, reader} {
+		values, err := d.GetMany(ctx, []string{"config.yaml", "hot/counter", "hot/nested/counter", "other.yaml"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{
+			"config.yaml":        []byte("v1"),
+			"hot/counter":        []byte("5"),
+			"hot/nested/counter": []byte("4"),
+			"other.yaml":         []byte("o"),
+		}, values)
+	}
+
+	_, _, err = reader.Upsert(ctx, "hot/counter", []byte("6"), UpsertCommitMsg("hot/counter=6"))
+	require.NoError(t, err)
+
+	// empty prefix squashes everything into one baseline commit
+	newBase, err = reader.Compact(ctx, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gitrows: COMPACT 5 commits"}, remoteMessages())
+
+	commit, err := remote.CommitObject(plumbing.NewHash(newBase))
+	require.NoError(t, err)
+	assert.Zero(t, commit.NumParents())
+
+	data, err := db.Get(ctx, "hot/counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("6"), data)
+
+	// the write during the compaction is not lost
+	write("hot/counter", "7")
+	write("hot/counter", "8")
+
+	var otherHead string
+	reader.phaseHook = func(phase string) {
+		if phase == phasePush {
+			otherHead, _, err = newDB().Upsert(ctx, "other.yaml", []byte("racing"))
+			require.NoError(t, err)
+		}
+	}
+
+	newBase, err = reader.Compact(ctx, "", 0)
+	assert.Empty(t, newBase)
+	assert.Equal(t, CodeConflict, ErrorCode(err), err)
+
+	head, err = db.remoteHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, otherHead, head)
+
+	reader.phaseHook = nil
+}
@@ -0,0 +1,94 @@
+package gitrows
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GetConcat returns the values of the keys under the prefix concatenated with sep between them,
+// like `cat configs/*`, i.e: to assemble the config from its fragments in one `git fetch`.
+//
+// The prefix matches the same keys as ListPrefix, including the nested directories. When the prefix contains
+// the pattern of path.Match (i.e: "configs/*.yaml"), only the keys matching it are read.
+// The values are always concatenated in the lexical (byte-wise) order of their key, regardless of the tree order
+// or KeyEncoding, and sep is not written after the last one. No matching key returns the empty value.
+//
+// The values are read from the blobs of the local branch, so the worktree is not checked out.
+func (db *DBImpl) GetConcat(ctx context.Context, prefix string, sep []byte) (data []byte, err error) {
+	var failingKey string
+	defer func() {
+		err = wrapError(OpGetConcat, failingKey, err)
+	}()
+
+	cfg := &ListConfig{}
+	glob := ""
+	if strings.ContainsAny(prefix, `*?[\`) {
+		_, err = path.Match(prefix, "")
+		if err != nil {
+			err = fmt.Errorf("get concat command: invalid pattern '%s': %w", prefix, err)
+			return
+		}
+
+		glob = prefix
+	} else {
+		err = ListPrefix(prefix)(cfg)
+		if err != nil {
+			err = fmt.Errorf("get concat command: %w", err)
+			return
+		}
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("get concat command: %w", err)
+		return
+	}
+
+	entries := make(map[string]object.TreeEntry)
+	err = db.walkKeys(cfg, func(key string, entry object.TreeEntry) {
+		if glob != "" {
+			if matched, _ := path.Match(glob, key); !matched {
+				return
+			}
+		}
+
+		entries[key] = entry
+	})
+	if err != nil {
+		err = fmt.Errorf("get concat command: %w", err)
+		return
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	for i, key := range keys {
+		if i > 0 {
+			buf.Write(sep)
+		}
+
+		var value []byte
+		value, err = db.readBlob(entries[key].Hash)
+		if err != nil {
+			failingKey = key
+			err = fmt.Errorf("get concat command: cannot read '%s': %w", key, err)
+			return
+		}
+
+		buf.Write(value)
+	}
+
+	data = buf.Bytes()
+	return
+}
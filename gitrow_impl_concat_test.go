@@ -0,0 +1,48 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_GetConcat(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	data, err := db.GetConcat(ctx, "configs", []byte("\n"))
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	for key, value := range map[string]string{
+		"configs/20-db.yaml":          "db: postgres",
+		"configs/10-app.yaml":         "app: gitrows",
+		"configs/30-notes.txt":        "notes",
+		"configs/nested/40-deep.yaml": "deep: true",
+		"other/50-other.yaml":         "other: true",
+	} {
+		_, err = db.Create(ctx, key, []byte(value))
+		require.NoError(t, err)
+	}
+
+	reader := newTestDB(t, remote)
+
+	// every key under the directory, in the lexical order of the key
+	data, err = reader.GetConcat(ctx, "configs/", []byte("\n---\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "app: gitrows\n---\ndb: postgres\n---\nnotes\n---\ndeep: true", string(data))
+
+	data, err = reader.GetConcat(ctx, "configs/*.yaml", []byte("\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "app: gitrows\ndb: postgres", string(data))
+
+	data, err = reader.GetConcat(ctx, "*/*.yaml", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "app: gitrowsdb: postgresother: true", string(data))
+
+	_, err = reader.GetConcat(ctx, "configs/[", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// defaultCopyBatchSize is the number of keys committed together by CopyFrom without CopyBatchSize.
+const defaultCopyBatchSize = 500
+
+// CopyFrom writes every key of src (other branch or repository) into this DB, i.e: for re-sharding or splitting
+// the repository. The keys of src are listed once, and written in batches of CopyBatchSize keys,
+// each one pull, commit and push, rather than one pull per key of the loop of Get and Upsert.
+// The value is written with the same mode, and the key which is already identical in this DB doesn't change anything.
+//
+// The copied is the number of keys of src written into this DB, or which would be written with CopyDryRun.
+// When the batch fails, the former batches are already pushed, and the returned *Error contains the failing key
+// when the error is about one key.
+func (db *DBImpl) CopyFrom(ctx context.Context, src DB, opts ...CopyOpt) (copied int, err error) {
+	var failingKey string
+	defer func() {
+		err = wrapError(OpCopyFrom, failingKey, err)
+		db.recordOp(OpCopyFrom, failingKey, "", err)
+	}()
+
+	cfg := &CopyConfig{
+		batchSize: defaultCopyBatchSize,
+	}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("copy from command: %w", err)
+			return
+		}
+	}
+
+	if src == nil {
+		err = fmt.Errorf("copy from command: source DB must not be nil")
+		return
+	}
+
+	if !cfg.dryRun {
+		err = db.checkWritable()
+		if err != nil {
+			err = fmt.Errorf("copy from command: %w", err)
+			return
+		}
+	}
+
+	entries, err := src.List(ctx)
+	if err != nil {
+		err = fmt.Errorf("copy from command: cannot list the source: %w", err)
+		return
+	}
+
+	kvs := make([]KV, 0)
+	for _, kv := range entries.KVs() {
+		if cfg.match(kv.Key()) {
+			kvs = append(kvs, kv)
+		}
+	}
+
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key() < kvs[j].Key()
+	})
+
+	if cfg.dryRun {
+		copied = len(kvs)
+		return
+	}
+
+	for start := 0; start < len(kvs); start += cfg.batchSize {
+		end := start + cfg.batchSize
+		if end > len(kvs) {
+			end = len(kvs)
+		}
+
+		var batch map[string]pendingWrite
+		batch, failingKey, err = db.copyBatch(kvs[start:end], cfg)
+		if err != nil {
+			err = fmt.Errorf("copy from command: %w", err)
+			return
+		}
+
+		defaultMsg := fmt.Sprintf("gitrows: COPY %d keys", len(batch))
+		_, err = db.writeBatch(ctx, OpCopyFrom, cfg.commitMsg, defaultMsg, batch)
+		if err != nil {
+			err = fmt.Errorf("copy from command: %w", err)
+			return
+		}
+
+		copied += len(batch)
+	}
+
+	return
+}
+
+// copyBatch reads the value of the source keys, and returns the write of their destination key.
+func (db *DBImpl) copyBatch(kvs []KV, cfg *CopyConfig) (batch map[string]pendingWrite, failingKey string, err error) {
+	batch = make(map[string]pendingWrite, len(kvs))
+	for _, kv := range kvs {
+		failingKey = kv.Key()
+
+		var key, filePath string
+		key, err = validateKey(cfg.rename(kv.Key()))
+		if err != nil {
+			return
+		}
+
+		filePath, err = db.keyToPath(key)
+		if err != nil {
+			return
+		}
+
+		var data []byte
+		data, err = readKV(kv)
+		if err != nil {
+			err = fmt.Errorf("cannot read '%s' from the source: %w", kv.Key(), err)
+			return
+		}
+
+		batch[key] = pendingWrite{
+			filePath: filePath,
+			data:     db.normalizeLineEnding(data),
+			fileMode: kv.Mode(),
+		}
+	}
+
+	return batch, "", nil
+}
+
+// readKV reads all value of kv.
+func readKV(kv KV) (data []byte, err error) {
+	reader, err := kv.Value()
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	return io.ReadAll(reader)
+}
+
+// match returns true when the source key is copied.
+func (c *CopyConfig) match(key string) bool {
+	if c.prefix != "" && !hasPathPrefix(key, c.prefix) {
+		return false
+	}
+
+	if c.glob != "" {
+		matched, _ := path.Match(c.glob, key)
+		return matched
+	}
+
+	return true
+}
+
+// rename returns the destination of the source key, see CopyRenamePrefix.
+func (c *CopyConfig) rename(key string) string {
+	if c.renameFrom == c.renameTo || !hasPathPrefix(key, c.renameFrom) {
+		return key
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(key, c.renameFrom), "/")
+	return path.Join(c.renameTo, rest)
+}
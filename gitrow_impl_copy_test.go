@@ -0,0 +1,87 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestCopyFrom(t *testing.T) {
+	ctx := context.TODO()
+	srcRemote := newTestRemote(t)
+	src := newTestDB(t, srcRemote)
+
+	for key, value := range map[string]string{
+		"tenant-a/users/1.json": "a1",
+		"tenant-a/users/2.json": "a2",
+		"tenant-a/readme.txt":   "readme",
+		"tenant-b/users/1.json": "b1",
+	} {
+		_, err := src.Create(ctx, key, []byte(value))
+		require.NoError(t, err)
+	}
+
+	_, _, err := src.Upsert(ctx, "tenant-a/run.sh", []byte("#!/bin/sh"), gitrows.UpsertFileMode(0755))
+	require.NoError(t, err)
+
+	dstRemote := newTestRemote(t)
+	dst := newTestDB(t, dstRemote)
+
+	count, err := dst.CopyFrom(ctx, src, gitrows.CopyPrefix("tenant-a"), gitrows.CopyDryRun())
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	count, err = dst.CopyFrom(ctx, src, gitrows.CopyPrefix("tenant-a"), gitrows.CopyGlob("*/users/*.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// moved to the root, in batches of 2 keys
+	count, err = dst.CopyFrom(ctx, src,
+		gitrows.CopyPrefix("tenant-a"),
+		gitrows.CopyRenamePrefix("tenant-a", ""),
+		gitrows.CopyBatchSize(2),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	entries, err := newTestDB(t, dstRemote).List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"tenant-a/users/1.json", "tenant-a/users/2.json",
+		"readme.txt", "run.sh", "users/1.json", "users/2.json",
+	}, listKeys(entries))
+
+	for _, kv := range entries.KVs() {
+		if kv.Key() == "run.sh" {
+			assert.Equal(t, os.FileMode(0755), kv.Mode())
+		}
+	}
+
+	data, err := dst.Get(ctx, "users/2.json")
+	require.NoError(t, err)
+	assert.Equal(t, "a2", string(data))
+
+	// one commit for the glob, and two batches for the rename
+	head := remoteCommit(t, dstRemote, remoteHead(t, dstRemote))
+	assert.Equal(t, "gitrows: COPY 2 keys", head.Message)
+	require.Len(t, head.ParentHashes, 1)
+	parent := remoteCommit(t, dstRemote, head.ParentHashes[0].String())
+	require.Len(t, parent.ParentHashes, 1)
+	assert.Empty(t, remoteCommit(t, dstRemote, parent.ParentHashes[0].String()).ParentHashes)
+
+	// copying again doesn't commit anything
+	tip := remoteHead(t, dstRemote)
+	_, err = dst.CopyFrom(ctx, src, gitrows.CopyPrefix("tenant-a"), gitrows.CopyRenamePrefix("tenant-a", ""))
+	require.NoError(t, err)
+	assert.Equal(t, tip, remoteHead(t, dstRemote))
+
+	_, err = dst.CopyFrom(ctx, src, gitrows.CopyBatchSize(0))
+	assert.Error(t, err)
+
+	_, err = newTestDB(t, dstRemote, gitrows.WithReadOnly()).CopyFrom(ctx, src)
+	assert.ErrorIs(t, err, gitrows.ErrReadOnly)
+}
@@ -0,0 +1,126 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// CreateIf is like Create, but only creates the key when predicate returns true, otherwise ErrPreconditionFailed,
+// i.e: only add the key when there are fewer than N keys.
+//
+// The predicate is evaluated against the Entries of all keys (the same as List without any ListOpt)
+// from the same pull which the write is based on, so it sees a consistent snapshot of the branch.
+// LastCommit and Value of the Entries are read from that snapshot too.
+func (db *DBImpl) CreateIf(ctx context.Context, key string, data []byte, predicate func(Entries) bool, opts ...CreateOpt) (commitHashString string, err error) {
+	defer func() {
+		err = wrapError(OpCreateIf, key, err)
+		db.recordOp(OpCreateIf, key, commitHashString, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	if predicate == nil {
+		err = fmt.Errorf("create if command: predicate must not be nil")
+		return
+	}
+
+	cfg := &CreateConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("create if command: %w", err)
+			return
+		}
+	}
+
+	key, err = validateInternalKey(key, cfg.allowInternal)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	err = db.flushBuffered(ctx, key)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	err = db.forcePull(ctx)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	err = db.checkExpectedHead(cfg.expectedHead)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	kvIters, _, err := db.list(&ListConfig{})
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	entryRow := make([]KV, 0, len(kvIters))
+	for _, kv := range kvIters {
+		entryRow = append(entryRow, kv)
+	}
+
+	if !predicate(&entriesImpl{kvs: entryRow}) {
+		err = fmt.Errorf("create if command: %w: predicate returns false for key '%s'", ErrPreconditionFailed, key)
+		return
+	}
+
+	data, err = db.canonicalizeJSON(data, cfg.canonicalJSON)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	data = db.normalizeLineEnding(data)
+	worktree, err := db.writeFile(ctx, filePath, data, "CREATE", cfg.fileMode)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	commitMsg := db.commitMessage(OpCreateIf, cfg.commitMsg, "gitrows: CREATE", CommitMeta{
+		Sizes:    map[string]int64{key: int64(len(data))},
+		Changed:  true,
+		Metadata: cfg.meta,
+	})
+
+	var commitHash plumbing.Hash
+	commitHash, err = db.gitCommit(ctx, worktree, commitMsg, false)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	commitHash, _, err = db.gitPush(ctx, OpCreateIf)
+	if err != nil {
+		err = fmt.Errorf("create if command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	return
+}
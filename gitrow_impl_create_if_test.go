@@ -0,0 +1,56 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_CreateIf(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	fewerThan := func(n int) func(gitrows.Entries) bool {
+		return func(entries gitrows.Entries) bool {
+			return len(entries.KVs()) < n
+		}
+	}
+
+	_, err := db.CreateIf(ctx, "seats/1", []byte("alice"), fewerThan(2))
+	require.NoError(t, err)
+
+	// the predicate sees the writes of other DB, since it runs after the pull
+	_, err = newTestDB(t, remote).CreateIf(ctx, "seats/2", []byte("bob"), fewerThan(2))
+	require.NoError(t, err)
+
+	head := remoteHead(t, remote)
+
+	commitHash, err := db.CreateIf(ctx, "seats/3", []byte("carol"), fewerThan(2))
+	assert.Empty(t, commitHash)
+	assert.True(t, errors.Is(err, gitrows.ErrPreconditionFailed), err)
+	assert.Equal(t, gitrows.CodePreconditionFailed, gitrows.ErrorCode(err))
+	assert.Equal(t, head, remoteHead(t, remote))
+
+	// the predicate can read the values
+	_, err = db.CreateIf(ctx, "seats/3", []byte("carol"), func(entries gitrows.Entries) bool {
+		values, err := entries.ToMap(ctx, 0)
+		return err == nil && string(values["seats/1"]) == "alice"
+	})
+	require.NoError(t, err)
+
+	entries, err := newTestDB(t, remote).List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"seats/1", "seats/2", "seats/3"}, listKeys(entries))
+
+	// the same rules as Create still apply
+	_, err = db.CreateIf(ctx, "seats/1", []byte("dave"), fewerThan(10))
+	assert.Equal(t, gitrows.CodeAlreadyExists, gitrows.ErrorCode(err))
+
+	_, err = db.CreateIf(ctx, "seats/4", []byte("dave"), nil)
+	assert.Error(t, err)
+}
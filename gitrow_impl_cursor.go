@@ -0,0 +1,139 @@
+package gitrows
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ListCursor returns at most limit entries sorted by the key, i.e: to serve the large dataset page by page.
+// The cursor is empty for the first page, then the nextCursor returned by the previous page,
+// which is empty when there are no more entries.
+//
+// The cursor only encodes the last returned key, and the next page starts from the key right after it
+// at the head of the branch when the next page is requested. So the key added or deleted between the pages
+// never shifts the other keys like the offset does: every key existing during the whole iteration is returned once.
+// It accepts the same ListOpt as List, except ListLimit which is replaced by limit.
+//
+// The keys are sorted from the tree walk without reading the value, so only the values of the page are loaded.
+func (db *DBImpl) ListCursor(ctx context.Context, cursor string, limit int, opts ...ListOpt) (entries []KV, nextCursor string, err error) {
+	defer func() {
+		err = wrapError(OpListCursor, "", err)
+	}()
+
+	cfg := &ListConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("list cursor command: %w", err)
+			return
+		}
+	}
+
+	// the limit of the walk would stop in the tree order, not the key order
+	cfg.limit = 0
+
+	if limit <= 0 {
+		err = fmt.Errorf("list cursor command: limit must be positive, got %d", limit)
+		return
+	}
+
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		err = fmt.Errorf("list cursor command: %w", err)
+		return
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("list cursor command: %w", err)
+		return
+	}
+
+	type candidate struct {
+		key   string
+		entry object.TreeEntry
+	}
+
+	candidates := make([]candidate, 0)
+	err = db.walkKeys(cfg, func(key string, entry object.TreeEntry) {
+		if cursor == "" || key > after {
+			candidates = append(candidates, candidate{key: key, entry: entry})
+		}
+	})
+	if err != nil {
+		err = fmt.Errorf("list cursor command: %w", err)
+		return
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].key < candidates[j].key
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+		nextCursor = encodeCursor(candidates[limit-1].key)
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		err = fmt.Errorf("list cursor command: %w", err)
+		return
+	}
+
+	resolver := newLastCommitResolver(db, head)
+	kvIters := make([]*kvIter, 0, len(candidates))
+	for _, c := range candidates {
+		blob, blobErr := db.gitRepo.BlobObject(c.entry.Hash)
+		if blobErr != nil {
+			err = fmt.Errorf("list cursor command: cannot read '%s': %w", c.key, blobErr)
+			return
+		}
+
+		file := object.NewFile(c.entry.Name, c.entry.Mode, blob)
+		kvIters = append(kvIters, &kvIter{
+			k:        c.key,
+			path:     file.Name,
+			v:        blobValue(db.gitRepo, file.Hash),
+			size:     file.Size,
+			mode:     fileMode(file),
+			hash:     file.Hash,
+			resolver: resolver,
+		})
+	}
+
+	// the same as List, the verification result must be known before returning
+	if db.verifyKeyring != nil || db.rejectUnverified {
+		err = db.resolveLastCommits(kvIters)
+		if err != nil {
+			err = fmt.Errorf("list cursor command: %w", err)
+			return
+		}
+	}
+
+	entries = make([]KV, 0, len(kvIters))
+	for _, kv := range kvIters {
+		entries = append(entries, kv)
+	}
+
+	return
+}
+
+// encodeCursor returns the opaque cursor of ListCursor for the last returned key.
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeCursor returns the last returned key encoded in the cursor of ListCursor.
+func decodeCursor(cursor string) (key string, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		err = fmt.Errorf("invalid cursor '%s': %w", cursor, err)
+		return
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,77 @@
+package gitrows_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_ListCursor(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	for _, key := range []string{"users/3.json", "users/1.json", "users/1/nested.json", "users/2.json", "other.txt"} {
+		_, err := db.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	keys := func(entries []gitrows.KV) []string {
+		out := make([]string, 0, len(entries))
+		for _, kv := range entries {
+			out = append(out, kv.Key())
+		}
+		return out
+	}
+
+	reader := newTestDB(t, remote)
+	entries, cursor, err := reader.ListCursor(ctx, "", 2, gitrows.ListPrefix("users"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users/1.json", "users/1/nested.json"}, keys(entries))
+	require.NotEmpty(t, cursor)
+
+	value, err := entries[0].Value()
+	require.NoError(t, err)
+	data, err := io.ReadAll(value)
+	require.NoError(t, err)
+	require.NoError(t, value.Close())
+	assert.Equal(t, "users/1.json", string(data))
+
+	// the key added before the cursor, and the key deleted after the cursor, don't shift the next page
+	_, err = db.Create(ctx, "users/0.json", []byte("0"))
+	require.NoError(t, err)
+	_, err = db.Delete(ctx, "users/2.json")
+	require.NoError(t, err)
+
+	entries, cursor, err = reader.ListCursor(ctx, cursor, 2, gitrows.ListPrefix("users"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users/3.json"}, keys(entries))
+	assert.Empty(t, cursor)
+
+	// the page exactly at the end doesn't return the cursor of the empty page
+	entries, cursor, err = reader.ListCursor(ctx, "", 5)
+	require.NoError(t, err)
+	assert.Len(t, entries, 5)
+	assert.Empty(t, cursor)
+
+	all := make([]string, 0)
+	for cursor, first := "", true; first || cursor != ""; first = false {
+		entries, cursor, err = reader.ListCursor(ctx, cursor, 1)
+		require.NoError(t, err)
+		all = append(all, keys(entries)...)
+	}
+	assert.Equal(t, []string{"other.txt", "users/0.json", "users/1.json", "users/1/nested.json", "users/3.json"}, all)
+
+	for _, limit := range []int{0, -1} {
+		_, _, err = reader.ListCursor(ctx, "", limit)
+		assert.Error(t, err, fmt.Sprint(limit))
+	}
+
+	_, _, err = reader.ListCursor(ctx, "not a cursor!", 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,243 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// DeepenSince fetches more history of the branch into the local (shallow) repository, until it has the commit
+// older than since or the whole history, so the history based commands (i.e: LastCommits, Changelog and the LastCommit
+// of List) are accurate within that period without cloning the years of unrelated commits.
+// The history is deepened by doubling the depth of `git fetch --depth`, and it is kept by the following syncs.
+//
+// It returns the number of commits newly available, which is zero when the local history is already deep enough.
+func (db *DBImpl) DeepenSince(ctx context.Context, since time.Time) (newCommits int, err error) {
+	defer func() {
+		err = wrapError(OpDeepenSince, "", err)
+	}()
+
+	newCommits, err = db.deepen(ctx, func(history localHistory) (bool, error) {
+		return !history.oldest.After(since), nil
+	})
+
+	if err != nil {
+		err = fmt.Errorf("deepen since command: %w", err)
+		return
+	}
+
+	return
+}
+
+// DeepenForPath is like DeepenSince, but deepens until the local history has maxCommits commits which change the key,
+// following the first parent like Changelog does.
+func (db *DBImpl) DeepenForPath(ctx context.Context, key string, maxCommits int) (newCommits int, err error) {
+	defer func() {
+		err = wrapError(OpDeepenForPath, key, err)
+	}()
+
+	if maxCommits <= 0 {
+		err = fmt.Errorf("deepen for path command: max commits must be positive, got %d", maxCommits)
+		return
+	}
+
+	key, err = validateKey(key)
+	if err != nil {
+		err = fmt.Errorf("deepen for path command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("deepen for path command: %w", err)
+		return
+	}
+
+	newCommits, err = db.deepen(ctx, func(history localHistory) (bool, error) {
+		changes, err := pathChanges(history.firstParents, filePath)
+		return changes >= maxCommits, err
+	})
+
+	if err != nil {
+		err = fmt.Errorf("deepen for path command: %w", err)
+		return
+	}
+
+	return
+}
+
+// localHistory is the history of the local branch, until the missing parent of the shallow commit.
+type localHistory struct {
+	commits      int
+	firstParents []*object.Commit
+	oldest       time.Time
+
+	// complete is true when the history reaches the root commit, so there is nothing to deepen.
+	complete bool
+}
+
+// deepen syncs the branch, then fetches with the doubled depth until enough returns true,
+// the history is complete or the remote doesn't send any more commit.
+func (db *DBImpl) deepen(ctx context.Context, enough func(history localHistory) (bool, error)) (newCommits int, err error) {
+	err = db.forcePull(ctx)
+	if err != nil {
+		return
+	}
+
+	history, err := db.localHistory()
+	if err != nil {
+		return
+	}
+
+	initial := history.commits
+	for !history.complete {
+		var done bool
+		done, err = enough(history)
+		if err != nil || done {
+			break
+		}
+
+		err = db.fetchDepth(ctx, 2*len(history.firstParents))
+		if err != nil {
+			return
+		}
+
+		var deeper localHistory
+		deeper, err = db.localHistory()
+		if err != nil {
+			return
+		}
+
+		if deeper.commits <= history.commits {
+			break // the remote doesn't have more history, i.e: it is shallow too
+		}
+
+		history = deeper
+	}
+
+	if err != nil {
+		return
+	}
+
+	// the branch may be moved by the fetch when the remote is changed after the sync
+	err = db.gitCheckout(ctx)
+	if err != nil {
+		return
+	}
+
+	newCommits = history.commits - initial
+	if newCommits < 0 {
+		newCommits = 0
+	}
+
+	return
+}
+
+// localHistory walks every commit reachable from the local branch.
+func (db *DBImpl) localHistory() (history localHistory, err error) {
+	head, err := db.headCommit()
+	if err != nil || head == nil {
+		history.complete = true
+		return
+	}
+
+	for commit := head; commit != nil; {
+		history.firstParents = append(history.firstParents, commit)
+		if commit.NumParents() == 0 {
+			break
+		}
+
+		commit, err = db.gitRepo.CommitObject(commit.ParentHashes[0])
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			commit, err = nil, nil
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	history.complete = true
+	seen := map[plumbing.Hash]struct{}{}
+	queue := []plumbing.Hash{head.Hash}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if _, exist := seen[hash]; exist {
+			continue
+		}
+
+		seen[hash] = struct{}{}
+
+		var commit *object.Commit
+		commit, err = db.gitRepo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			err = nil
+			history.complete = false
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+
+		history.commits++
+		if history.oldest.IsZero() || commit.Committer.When.Before(history.oldest) {
+			history.oldest = commit.Committer.When
+		}
+
+		queue = append(queue, commit.ParentHashes...)
+	}
+
+	return
+}
+
+// pathChanges counts the commits which change the file path compared to its parent.
+// The oldest commit only counts when it is the root commit, since its parent is not available.
+func pathChanges(firstParents []*object.Commit, filePath string) (changes int, err error) {
+	for i, commit := range firstParents {
+		var parent *object.Commit
+		if i+1 < len(firstParents) {
+			parent = firstParents[i+1]
+		} else if commit.NumParents() > 0 {
+			break
+		}
+
+		var hash, parentHash plumbing.Hash
+		hash, err = fileHash(commit, filePath)
+		if err != nil {
+			return
+		}
+
+		if parent != nil {
+			parentHash, err = fileHash(parent, filePath)
+			if err != nil {
+				return
+			}
+		}
+
+		if hash != parentHash {
+			changes++
+		}
+	}
+
+	return
+}
+
+// fileHash returns the blob hash of the file path in the commit, or zero hash when it doesn't exist.
+func fileHash(commit *object.Commit, filePath string) (hash plumbing.Hash, err error) {
+	file, err := commit.File(filePath)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return plumbing.ZeroHash, nil
+	}
+
+	if err != nil {
+		return
+	}
+
+	return file.Hash, nil
+}
@@ -0,0 +1,67 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_Deepen(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "a.txt", []byte("a1"))
+	require.NoError(t, err)
+
+	lastA, _, err := db.Upsert(ctx, "a.txt", []byte("a2"))
+	require.NoError(t, err)
+
+	for _, key := range []string{"b.txt", "c.txt", "d.txt", "e.txt"} {
+		_, err = db.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	reader := newTestDB(t, remote)
+
+	// the history of now is only the head, which the fresh clone already has
+	newCommits, err := reader.DeepenSince(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, newCommits)
+
+	newCommits, err = reader.DeepenForPath(ctx, "a.txt", 1)
+	require.NoError(t, err)
+	assert.Positive(t, newCommits)
+
+	commitHashes, err := reader.LastCommits(ctx, []string{"a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, lastA, commitHashes["a.txt"])
+
+	// idempotent, and the deepened history is kept by the following sync
+	_, _, err = db.Upsert(ctx, "f.txt", []byte("f"))
+	require.NoError(t, err)
+
+	newCommits, err = reader.DeepenForPath(ctx, "a.txt", 1)
+	require.NoError(t, err)
+	assert.Zero(t, newCommits)
+
+	commitHashes, err = reader.LastCommits(ctx, []string{"a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, lastA, commitHashes["a.txt"])
+
+	// deepen the fresh clone until the whole history of 7 commits
+	reader = newTestDB(t, remote)
+	newCommits, err = reader.DeepenSince(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 6, newCommits)
+
+	newCommits, err = reader.DeepenSince(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Zero(t, newCommits)
+
+	_, err = reader.DeepenForPath(ctx, "a.txt", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Duplicates returns the keys which have identical value at the head of the branch, grouped by the blob hash.
+// Key with unique value is not returned. It is computed from the tree entries like Keys,
+// so no value is read, and it accepts the same ListOpt as List.
+func (db *DBImpl) Duplicates(ctx context.Context, opts ...ListOpt) (duplicates map[string][]string, err error) {
+	defer func() {
+		err = wrapError(OpDuplicates, "", err)
+	}()
+
+	cfg := &ListConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("duplicates command: %w", err)
+			return
+		}
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("duplicates command: %w", err)
+		return
+	}
+
+	groups := make(map[string][]string)
+	err = db.walkKeys(cfg, func(key string, entry object.TreeEntry) {
+		hash := entry.Hash.String()
+		groups[hash] = append(groups[hash], key)
+	})
+	if err != nil {
+		err = fmt.Errorf("duplicates command: %w", err)
+		return
+	}
+
+	duplicates = make(map[string][]string)
+	for hash, keys := range groups {
+		if len(keys) > 1 {
+			duplicates[hash] = keys
+		}
+	}
+
+	return
+}
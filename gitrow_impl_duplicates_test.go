@@ -0,0 +1,48 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_Duplicates(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	duplicates, err := db.Duplicates(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, duplicates)
+
+	for key, value := range map[string]string{
+		"a/one.txt":   "same",
+		"a/two.txt":   "same",
+		"b/three.txt": "same",
+		"a/x.yaml":    "other",
+		"b/y.yaml":    "other",
+		"unique.txt":  "unique",
+	} {
+		_, err = db.Create(ctx, key, []byte(value))
+		require.NoError(t, err)
+	}
+
+	same := plumbing.ComputeHash(plumbing.BlobObject, []byte("same")).String()
+	other := plumbing.ComputeHash(plumbing.BlobObject, []byte("other")).String()
+
+	duplicates, err = newTestDB(t, remote).Duplicates(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		same:  {"a/one.txt", "a/two.txt", "b/three.txt"},
+		other: {"a/x.yaml", "b/y.yaml"},
+	}, duplicates)
+
+	// only keys matching the filter are grouped
+	duplicates, err = db.Duplicates(ctx, gitrows.ListPrefix("a"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{same: {"a/one.txt", "a/two.txt"}}, duplicates)
+}
@@ -0,0 +1,43 @@
+package gitrows
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// validateExpectedHead returns error when the commit is not the full commit hash.
+func validateExpectedHead(commit string) error {
+	if !plumbing.IsHash(commit) {
+		return fmt.Errorf("expected head must be the full commit hash, got '%s'", commit)
+	}
+
+	return nil
+}
+
+// checkExpectedHead returns ErrStaleHead when the local branch (right after the pull) is not the expected commit,
+// or nothing when expected is empty.
+//
+// The branch may still be moved by other writer between the pull and the push. Use WithForcePush(false)
+// so the push is rejected in that case instead of overwriting it.
+func (db *DBImpl) checkExpectedHead(expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		return err
+	}
+
+	actual := plumbing.ZeroHash
+	if head != nil {
+		actual = head.Hash
+	}
+
+	if actual != plumbing.NewHash(expected) {
+		return fmt.Errorf("%w: branch '%s' is at %s, expected %s", ErrStaleHead, db.gitBranch, actual, expected)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWriteExpectedHead(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	// the zero hash expects the empty branch
+	head, err := db.Create(ctx, "a.txt", []byte("a"), gitrows.CreateExpectedHead(plumbing.ZeroHash.String()))
+	require.NoError(t, err)
+
+	head, _, err = db.Upsert(ctx, "a.txt", []byte("a2"), gitrows.UpsertExpectedHead(head))
+	require.NoError(t, err)
+	assert.Equal(t, remoteHead(t, remote), head)
+
+	// other writer moves the branch, even for the unrelated key
+	_, err = newTestDB(t, remote).Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	tip := remoteHead(t, remote)
+	_, _, err = db.Upsert(ctx, "a.txt", []byte("a3"), gitrows.UpsertExpectedHead(head))
+	assert.ErrorIs(t, err, gitrows.ErrStaleHead)
+	assert.Equal(t, gitrows.CodePreconditionFailed, gitrows.ErrorCode(err))
+
+	_, err = db.Create(ctx, "c.txt", []byte("c"), gitrows.CreateExpectedHead(head))
+	assert.ErrorIs(t, err, gitrows.ErrStaleHead)
+
+	_, err = db.Delete(ctx, "a.txt", gitrows.DeleteExpectedHead(head))
+	assert.ErrorIs(t, err, gitrows.ErrStaleHead)
+	assert.Equal(t, tip, remoteHead(t, remote))
+
+	_, err = db.Delete(ctx, "a.txt", gitrows.DeleteExpectedHead(tip))
+	require.NoError(t, err)
+
+	_, _, err = db.Upsert(ctx, "a.txt", []byte("a"), gitrows.UpsertExpectedHead("abc"))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, gitrows.ErrStaleHead)
+}
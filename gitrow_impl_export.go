@@ -0,0 +1,138 @@
+package gitrows
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ExportSubtree writes the gzipped tar of every key under the prefix (see ListPrefix) into w,
+// i.e: to transfer the whole namespace to other service as one HTTP response.
+// The values are streamed from the git objects of the local branch after fetching it, without the worktree,
+// so the tar is the snapshot of the branch head at that time, not including the writes after it.
+//
+// The name of each file is the key relative to the prefix (the base name when the prefix is the key itself),
+// with its mode, and the modification time of every file is the time of the head commit.
+// The internal and .gitkeep files are excluded like List. When it fails, w may contain the partial tar.
+func (db *DBImpl) ExportSubtree(ctx context.Context, prefix string, w io.Writer) (err error) {
+	defer func() {
+		err = wrapError(OpExportSubtree, "", err)
+	}()
+
+	if w == nil {
+		err = fmt.Errorf("export subtree command: writer must not be nil")
+		return
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("export subtree command: %w", err)
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		err = fmt.Errorf("export subtree command: %w", err)
+		return
+	}
+
+	cfg := &ListConfig{prefix: cleanPrefix(prefix)}
+	keys := make([]string, 0)
+	entries := make([]object.TreeEntry, 0)
+	err = db.walkKeys(cfg, func(key string, entry object.TreeEntry) {
+		keys = append(keys, key)
+		entries = append(entries, entry)
+	})
+
+	if err != nil {
+		err = fmt.Errorf("export subtree command: %w", err)
+		return
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for i, key := range keys {
+		err = ctx.Err()
+		if err != nil {
+			err = fmt.Errorf("export subtree command: %w", err)
+			return
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(key, cfg.prefix), "/")
+		if name == "" {
+			name = path.Base(key)
+		}
+
+		err = db.exportFile(tw, name, entries[i], head)
+		if err != nil {
+			err = fmt.Errorf("export subtree command: cannot export '%s': %w", key, err)
+			return
+		}
+	}
+
+	err = tw.Close()
+	if err == nil {
+		err = gz.Close()
+	}
+
+	if err != nil {
+		err = fmt.Errorf("export subtree command: %w", err)
+		return
+	}
+
+	return
+}
+
+// exportFile writes the blob of the tree entry into the tar, the symlink is written as the link to its target.
+func (db *DBImpl) exportFile(tw *tar.Writer, name string, entry object.TreeEntry, head *object.Commit) (err error) {
+	blob, err := db.gitRepo.BlobObject(entry.Hash)
+	if err != nil {
+		return
+	}
+
+	hdr := &tar.Header{
+		Name:    name,
+		ModTime: head.Committer.When,
+		Mode:    int64(fileMode(object.NewFile(name, entry.Mode, blob))),
+	}
+
+	if entry.Mode == filemode.Symlink {
+		var target []byte
+		target, err = readBlob(blob)
+		if err != nil {
+			return
+		}
+
+		hdr.Typeflag = tar.TypeSymlink
+		hdr.Linkname = string(target)
+		return tw.WriteHeader(hdr)
+	}
+
+	hdr.Typeflag = tar.TypeReg
+	hdr.Size = blob.Size
+	err = tw.WriteHeader(hdr)
+	if err != nil {
+		return
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	_, err = io.Copy(tw, reader)
+	return
+}
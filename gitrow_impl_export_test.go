@@ -0,0 +1,77 @@
+package gitrows_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestExportSubtree(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "datasets/a.json", []byte(`{"a":1}`))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "datasets/nested/run.sh", []byte("#!/bin/sh"), gitrows.CreateFileMode(0755))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "datasetsx/b.json", []byte(`{"b":2}`))
+	require.NoError(t, err)
+
+	type file struct {
+		data string
+		mode os.FileMode
+	}
+
+	untar := func(t *testing.T, prefix string) map[string]file {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, db.ExportSubtree(ctx, prefix, &buf))
+
+		gz, err := gzip.NewReader(&buf)
+		require.NoError(t, err)
+
+		files := make(map[string]file)
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, byte(tar.TypeReg), hdr.Typeflag)
+
+			data, err := io.ReadAll(tr)
+			require.NoError(t, err)
+
+			files[hdr.Name] = file{data: string(data), mode: os.FileMode(hdr.Mode)}
+		}
+
+		return files
+	}
+
+	assert.Equal(t, map[string]file{
+		"a.json":        {data: `{"a":1}`, mode: 0644},
+		"nested/run.sh": {data: "#!/bin/sh", mode: 0755},
+	}, untar(t, "datasets/"))
+
+	assert.Equal(t, map[string]file{
+		"a.json": {data: `{"a":1}`, mode: 0644},
+	}, untar(t, "datasets/a.json"))
+
+	assert.Len(t, untar(t, ""), 3)
+	assert.Empty(t, untar(t, "missing"))
+}
@@ -0,0 +1,66 @@
+package gitrows
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// defaultFileMode is the permission of the file created without CreateFileMode or UpsertFileMode.
+const defaultFileMode os.FileMode = 0644
+
+// validateFileMode only accepts the permission bits, since gitrows only writes regular file.
+func validateFileMode(mode os.FileMode) error {
+	if mode == 0 || mode != mode.Perm() {
+		return fmt.Errorf("invalid file mode %s: only non-zero permission bits are allowed", mode)
+	}
+
+	return nil
+}
+
+// filePerm returns the permission to write the file with. Zero mode keeps the permission of the existing file,
+// or defaultFileMode for new file.
+func filePerm(fileInfo os.FileInfo, mode os.FileMode) os.FileMode {
+	if mode != 0 {
+		return mode
+	}
+
+	if fileInfo != nil {
+		return fileInfo.Mode().Perm()
+	}
+
+	return defaultFileMode
+}
+
+// fileMode returns the permission of the committed file.
+func fileMode(file *object.File) os.FileMode {
+	mode, err := file.Mode.ToOSFileMode()
+	if err != nil {
+		return defaultFileMode
+	}
+
+	return mode.Perm()
+}
+
+// createTempFile is like billy.TempFile, but creates the file with the permission,
+// because billy.Filesystem (i.e: osfs) doesn't support changing the permission of existing file.
+func createTempFile(fs billy.Filesystem, dir, prefix string, perm os.FileMode) (file billy.File, err error) {
+	for i := 0; i < 10000; i++ {
+		name := path.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+
+		file, err = fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+
+		return
+	}
+
+	err = fmt.Errorf("cannot find unused temporary file name with prefix '%s'", path.Join(dir, prefix))
+	return
+}
@@ -0,0 +1,105 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+// remoteFileMode returns the mode of the tree entry at the head of master branch in the remote.
+func remoteFileMode(t *testing.T, remoteURL, name string) filemode.FileMode {
+	t.Helper()
+
+	repo, err := git.PlainOpen(strings.TrimPrefix(remoteURL, "file://"))
+	require.NoError(t, err)
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+
+	file, err := commit.File(name)
+	require.NoError(t, err)
+
+	return file.Mode
+}
+
+// listModes returns the mode of every key.
+func listModes(t *testing.T, db gitrows.DB) map[string]os.FileMode {
+	t.Helper()
+
+	entries, err := db.List(context.TODO())
+	require.NoError(t, err)
+
+	modes := make(map[string]os.FileMode)
+	for _, kv := range entries.KVs() {
+		modes[kv.Key()] = kv.Mode()
+	}
+
+	return modes
+}
+
+func TestDBImpl_FileMode(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "scripts/deploy.sh", []byte("#!/bin/sh\n"), gitrows.CreateFileMode(0755))
+	require.NoError(t, err)
+	assert.Equal(t, filemode.Executable, remoteFileMode(t, remote, "scripts/deploy.sh"))
+
+	_, err = db.Create(ctx, "readme.md", []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, filemode.Regular, remoteFileMode(t, remote, "readme.md"))
+
+	_, err = db.PutReader(ctx, "scripts/build.sh", strings.NewReader("#!/bin/sh\n"), gitrows.UpsertFileMode(0755))
+	require.NoError(t, err)
+	assert.Equal(t, filemode.Executable, remoteFileMode(t, remote, "scripts/build.sh"))
+
+	expected := map[string]os.FileMode{
+		"scripts/deploy.sh": 0755,
+		"scripts/build.sh":  0755,
+		"readme.md":         0644,
+	}
+	assert.Equal(t, expected, listModes(t, newTestDB(t, remote)))
+
+	// executable bit is preserved by fresh checkout and upsert without UpsertFileMode
+	other := newTestDB(t, remote)
+	_, changed, err := other.Upsert(ctx, "scripts/deploy.sh", []byte("#!/bin/sh\necho deploy\n"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, filemode.Executable, remoteFileMode(t, remote, "scripts/deploy.sh"))
+
+	_, err = other.PutReader(ctx, "scripts/build.sh", strings.NewReader("#!/bin/sh\necho build\n"))
+	require.NoError(t, err)
+	assert.Equal(t, filemode.Executable, remoteFileMode(t, remote, "scripts/build.sh"))
+
+	// changing only the mode is committed, but reported as the metadata change
+	result, err := other.UpsertR(ctx, "scripts/deploy.sh", []byte("#!/bin/sh\necho deploy\n"), gitrows.UpsertFileMode(0644))
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.True(t, result.MetadataChanged)
+	assert.Equal(t, filemode.Regular, remoteFileMode(t, remote, "scripts/deploy.sh"))
+
+	result, err = other.UpsertR(ctx, "readme.md", []byte("hello"), gitrows.UpsertFileMode(0755))
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.True(t, result.MetadataChanged)
+	assert.Equal(t, filemode.Executable, remoteFileMode(t, remote, "readme.md"))
+
+	// invalid mode
+	_, err = db.Create(ctx, "invalid.sh", []byte("x"), gitrows.CreateFileMode(os.ModeDir|0755))
+	assert.Error(t, err)
+
+	_, _, err = db.Upsert(ctx, "invalid.sh", []byte("x"), gitrows.UpsertFileMode(0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package gitrows
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// headDir returns the tree of the file path in the local branch when it is a directory,
+// or nil tree when it doesn't exist or it is a file.
+func (db *DBImpl) headDir(filePath string) (tree *object.Tree, err error) {
+	commit, err := db.headCommit()
+	if err != nil || commit == nil {
+		return
+	}
+
+	root, err := commit.Tree()
+	if err != nil {
+		err = fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.Hash, err)
+		return
+	}
+
+	// the entry is checked first, so the value of the file is never read
+	entry, err := root.FindEntry(filePath)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot find '%s': %w", filePath, err)
+		return
+	}
+
+	if entry.Mode != filemode.Dir {
+		return
+	}
+
+	tree, err = object.GetTree(db.gitRepo.Storer, entry.Hash)
+	if err != nil {
+		err = fmt.Errorf("cannot get directory '%s': %w", filePath, err)
+		return
+	}
+
+	return
+}
+
+// dirListing returns the listing of the directory of key, see GetDirAsList, or ErrKeyIsDirectory without it.
+func dirListing(key string, tree *object.Tree, cfg *GetConfig) (data []byte, err error) {
+	if !cfg.dirAsList {
+		err = fmt.Errorf("%w: '%s' contains other keys, use GetDirAsList to list them", ErrKeyIsDirectory, key)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	for _, entry := range tree.Entries {
+		buf.WriteString(entry.Name)
+		if entry.Mode == filemode.Dir {
+			buf.WriteByte('/')
+		}
+
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
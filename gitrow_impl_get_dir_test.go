@@ -0,0 +1,48 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestGet_directory(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	for _, key := range []string{"users/1.json", "users/2.json", "users/admins/3.json"} {
+		_, err := db.Create(ctx, key, []byte("{}"))
+		require.NoError(t, err)
+	}
+
+	for _, reader := range []*gitrows.DBImpl{db, newTestDB(t, remote, gitrows.WithReadStrategy(gitrows.ReadObjectStore))} {
+		_, err := reader.Get(ctx, "users")
+		assert.ErrorIs(t, err, gitrows.ErrKeyIsDirectory)
+		assert.NotErrorIs(t, err, os.ErrNotExist)
+		assert.Equal(t, gitrows.CodeInvalidKey, gitrows.ErrorCode(err))
+
+		_, err = reader.Get(ctx, "users/")
+		assert.ErrorIs(t, err, gitrows.ErrKeyIsDirectory)
+
+		data, err := reader.Get(ctx, "users", gitrows.GetDirAsList())
+		require.NoError(t, err)
+		assert.Equal(t, "1.json\n2.json\nadmins/\n", string(data))
+
+		data, err = reader.Get(ctx, "users/admins", gitrows.GetDirAsList())
+		require.NoError(t, err)
+		assert.Equal(t, "3.json\n", string(data))
+
+		// the files and the missing keys are not affected
+		data, err = reader.Get(ctx, "users/1.json", gitrows.GetDirAsList())
+		require.NoError(t, err)
+		assert.Equal(t, "{}", string(data))
+
+		_, err = reader.Get(ctx, "groups", gitrows.GetDirAsList())
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+}
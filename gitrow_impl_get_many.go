@@ -0,0 +1,123 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// getManyConcurrency is the number of workers used by GetMany to read the files.
+const getManyConcurrency = 8
+
+// GetMany reads all keys using only one `git pull`, instead of one pull for each key as in Get.
+// Found values are returned in the map using the requested key as its key.
+// When some keys cannot be read (i.e: not exist), the rest of keys are still returned
+// and the error is *MultiError containing the error of each failing key.
+func (db *DBImpl) GetMany(ctx context.Context, keys []string) (values map[string][]byte, err error) {
+	defer func() {
+		err = wrapError(OpGetMany, "", err)
+	}()
+
+	err = db.syncForRead(ctx)
+	if err != nil {
+		err = fmt.Errorf("get many command: %w", err)
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("get many command: cannot get worktree: %w", err)
+		return
+	}
+
+	fs := worktree.Filesystem
+
+	var mu sync.Mutex
+	values = make(map[string][]byte, len(keys))
+	keyErrs := make(map[string]error)
+
+	jobs := make(chan getManyJob)
+	wg := sync.WaitGroup{}
+	for i := 0; i < getManyConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				data, readErr := readKey(ctx, fs, job.filePath)
+
+				mu.Lock()
+				if readErr != nil {
+					keyErrs[job.key] = readErr
+				} else {
+					values[job.key] = data
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, exist := seen[key]; exist {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		// resolve the path before dispatching to workers, since the git tree is not safe for concurrent use
+		filePath, pathErr := db.readPath(key)
+		if pathErr != nil {
+			mu.Lock()
+			keyErrs[key] = pathErr
+			mu.Unlock()
+			continue
+		}
+
+		jobs <- getManyJob{key: key, filePath: filePath}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if len(keyErrs) > 0 {
+		err = &MultiError{Errors: keyErrs}
+		return
+	}
+
+	return
+}
+
+// getManyJob is the key to be read by GetMany worker, along with its file path.
+type getManyJob struct {
+	key      string
+	filePath string
+}
+
+// readPath validates and maps the key into file path which exists in the committed tree.
+func (db *DBImpl) readPath(key string) (filePath string, err error) {
+	key, err = validateKey(key)
+	if err != nil {
+		return
+	}
+
+	filePath, err = db.keyToPath(key)
+	if err != nil {
+		return
+	}
+
+	err = db.checkCommittedPath(filePath)
+	return
+}
+
+// readKey reads the file of the file path, unless the ctx is already done.
+func readKey(ctx context.Context, fs billy.Filesystem, filePath string) (data []byte, err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+
+	return readFile(fs, filePath, &GetConfig{})
+}
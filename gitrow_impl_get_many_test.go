@@ -0,0 +1,37 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_GetMany(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	_, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "dir/b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	values, err := db.GetMany(ctx, []string{"a.txt", "dir/b.txt", "missing.txt", "a.txt"})
+	assert.Equal(t, map[string][]byte{
+		"a.txt":     []byte("a"),
+		"dir/b.txt": []byte("b"),
+	}, values)
+
+	var multiErr *gitrows.MultiError
+	require.True(t, errors.As(err, &multiErr), err)
+	assert.Len(t, multiErr.Errors, 1)
+	assert.Contains(t, multiErr.Errors, "missing.txt")
+
+	values, err = db.GetMany(ctx, []string{"a.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"a.txt": []byte("a")}, values)
+}
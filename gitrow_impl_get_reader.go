@@ -0,0 +1,77 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// GetReader is like Get, but returns the reader of the value instead of reading it all into memory.
+// GetLimit and GetRange are applied to the reader, so reading beyond the limit returns ErrValueTooLarge.
+//
+// The file is locked until the reader is closed (except with ReadObjectStore), so the caller MUST close it.
+// Other commands on the same DBImpl may change the worktree while the reader is still open,
+// therefore read and close it as soon as possible.
+func (db *DBImpl) GetReader(ctx context.Context, key string, opts ...GetOpt) (reader io.ReadCloser, err error) {
+	defer func() {
+		err = wrapError(OpGetReader, key, err)
+	}()
+
+	cfg := &GetConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("get reader command: %w", err)
+			return
+		}
+	}
+
+	key, err = validateKey(key)
+	if err != nil {
+		err = fmt.Errorf("get reader command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("get reader command: %w", err)
+		return
+	}
+
+	err = db.syncRead(ctx, !db.readsObjects())
+	if err != nil {
+		err = fmt.Errorf("get reader command: %w", err)
+		return
+	}
+
+	err = db.checkCommittedPath(filePath)
+	if err != nil {
+		err = fmt.Errorf("get reader command: %w", err)
+		return
+	}
+
+	if db.readsObjects() {
+		reader, err = db.openTreeFile(filePath, cfg)
+		if err != nil {
+			err = fmt.Errorf("get reader command: %w", err)
+			return
+		}
+
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("get reader command: cannot get worktree: %w", err)
+		return
+	}
+
+	reader, err = openFile(worktree.Filesystem, filePath, cfg)
+	if err != nil {
+		err = fmt.Errorf("get reader command: %w", err)
+		return
+	}
+
+	return
+}
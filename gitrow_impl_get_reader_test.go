@@ -0,0 +1,82 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_Get_limitAndRange(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	_, err := db.Create(ctx, "big.txt", []byte("0123456789"))
+	require.NoError(t, err)
+
+	data, err := db.Get(ctx, "big.txt", gitrows.GetLimit(10))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0123456789"), data)
+
+	data, err = db.Get(ctx, "big.txt", gitrows.GetLimit(9))
+	assert.True(t, errors.Is(err, gitrows.ErrValueTooLarge))
+	assert.Equal(t, gitrows.CodeValueTooLarge, gitrows.ErrorCode(err))
+	assert.Nil(t, data)
+
+	data, err = db.Get(ctx, "big.txt", gitrows.GetRange(3, 4))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("3456"), data)
+
+	data, err = db.Get(ctx, "big.txt", gitrows.GetRange(7, 0))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("789"), data)
+
+	data, err = db.Get(ctx, "big.txt", gitrows.GetRange(20, 5))
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	// the limit applies to the range
+	data, err = db.Get(ctx, "big.txt", gitrows.GetRange(2, 5), gitrows.GetLimit(5))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("23456"), data)
+
+	_, err = db.Get(ctx, "big.txt", gitrows.GetRange(2, 0), gitrows.GetLimit(5))
+	assert.True(t, errors.Is(err, gitrows.ErrValueTooLarge))
+
+	_, err = db.Get(ctx, "big.txt", gitrows.GetLimit(-1))
+	assert.Error(t, err)
+
+	_, err = db.Get(ctx, "big.txt", gitrows.GetRange(-1, 2))
+	assert.Error(t, err)
+}
+
+func TestDBImpl_GetReader(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	_, err := db.Create(ctx, "big.txt", []byte("0123456789"))
+	require.NoError(t, err)
+
+	reader, err := db.GetReader(ctx, "big.txt", gitrows.GetRange(5, 0))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("56789"), data)
+	assert.NoError(t, reader.Close())
+
+	reader, err = db.GetReader(ctx, "big.txt", gitrows.GetLimit(4))
+	require.NoError(t, err)
+
+	data, err = io.ReadAll(reader)
+	assert.True(t, errors.Is(err, gitrows.ErrValueTooLarge))
+	assert.Equal(t, []byte("0123"), data)
+	assert.NoError(t, reader.Close())
+
+	_, err = db.GetReader(ctx, "not-exist.txt")
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+}
@@ -0,0 +1,115 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// gitkeepName is the name of the empty file which keeps the directory in git, since git only tracks files.
+const gitkeepName = ".gitkeep"
+
+// isGitkeep returns true when the key is the placeholder of the directory created by MkdirAll.
+func isGitkeep(key string) bool {
+	return path.Base(key) == gitkeepName
+}
+
+// MkdirAll creates the directory of prefix (and its parents) in the repository, before any key is written under it,
+// i.e: for the external tooling which lists the directories. Since git doesn't track the empty directory,
+// it commits the empty key "<prefix>/.gitkeep", which List hides unless ListIncludeGitkeep is used.
+//
+// Like `mkdir -p`, nothing is committed when the directory already exists, and the current HEAD is returned.
+// The placeholder is an ordinary key otherwise, so Delete removes it and the directory together.
+func (db *DBImpl) MkdirAll(ctx context.Context, prefix string) (commitHashString string, err error) {
+	prefix = cleanPrefix(prefix)
+	defer func() {
+		err = wrapError(OpMkdirAll, prefix, err)
+		db.recordOp(OpMkdirAll, prefix, commitHashString, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	if prefix == "" {
+		err = fmt.Errorf("mkdir all command: %w: the root directory always exists", ErrInvalidKey)
+		return
+	}
+
+	key, err := validateKey(path.Join(prefix, gitkeepName))
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	err = db.syncForWrite(ctx)
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	// the directory of the placeholder, which is the prefix unless KeyMapper moves it elsewhere
+	dir, err := db.headDir(path.Dir(filePath))
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	if dir != nil {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("mkdir all command: cannot get HEAD reference: %w", err)
+			return
+		}
+
+		commitHashString = head.Hash().String()
+		return
+	}
+
+	var worktree *git.Worktree
+	var treeHash plumbing.Hash
+	if db.treeWrites {
+		treeHash, err = db.writeTree(ctx, filePath, []byte{}, "CREATE", 0)
+	} else {
+		worktree, err = db.writeFile(ctx, filePath, []byte{}, "CREATE", 0)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	commitMsg := db.commitMessage(OpMkdirAll, "", fmt.Sprintf("gitrows: MKDIR %s", prefix), CommitMeta{
+		Sizes:   map[string]int64{key: 0},
+		Changed: true,
+	})
+
+	commitHash, err := db.commitChange(ctx, worktree, treeHash, commitMsg, false)
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	commitHash, _, err = db.gitPush(ctx, OpMkdirAll)
+	if err != nil {
+		err = fmt.Errorf("mkdir all command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+	return
+}
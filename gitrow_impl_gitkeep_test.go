@@ -0,0 +1,57 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_MkdirAll(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	head, err := db.MkdirAll(ctx, "/reports/2023/")
+	require.NoError(t, err)
+	assert.Equal(t, remoteHead(t, remote), head)
+	assert.Equal(t, "gitrows: MKDIR reports/2023", remoteCommit(t, remote, head).Message)
+
+	// the directory exists, including its parent
+	for _, prefix := range []string{"reports/2023", "reports"} {
+		commitHash, err := db.MkdirAll(ctx, prefix)
+		require.NoError(t, err)
+		assert.Equal(t, head, commitHash)
+	}
+
+	reader := newTestDB(t, remote)
+	_, err = reader.Get(ctx, "reports/2023")
+	assert.ErrorIs(t, err, gitrows.ErrKeyIsDirectory)
+
+	entries, err := reader.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries.KVs())
+
+	entries, err = reader.List(ctx, gitrows.ListIncludeGitkeep())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reports/2023/.gitkeep"}, listKeys(entries))
+
+	_, err = db.Create(ctx, "reports/2023/q1.json", []byte("{}"))
+	require.NoError(t, err)
+
+	entries, err = reader.List(ctx, gitrows.ListPrefix("reports"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reports/2023/q1.json"}, listKeys(entries))
+
+	keys, err := reader.Keys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reports/2023/q1.json"}, keys)
+
+	_, err = db.MkdirAll(ctx, "/")
+	assert.ErrorIs(t, err, gitrows.ErrInvalidKey)
+
+	_, err = newTestDB(t, remote, gitrows.WithReadOnly()).MkdirAll(ctx, "other")
+	assert.ErrorIs(t, err, gitrows.ErrReadOnly)
+}
@@ -0,0 +1,53 @@
+package gitrows
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// checkIfMatch returns ErrPreconditionFailed when the key (right after the pull) doesn't exist,
+// or neither its blob hash nor its last commit is the expected hash, see DeleteIfMatch.
+// It returns nothing when expected is empty.
+func (db *DBImpl) checkIfMatch(key, filePath, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	file, err := db.headFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if file == nil {
+		return fmt.Errorf("%w: key '%s' doesn't exist, expected %s", ErrPreconditionFailed, key, expected)
+	}
+
+	expectedHash := plumbing.NewHash(expected)
+	if file.Hash == expectedHash {
+		return nil
+	}
+
+	// the blob hash is compared first, so the history is only walked for the commit hash
+	head, err := db.headCommit()
+	if err != nil {
+		return err
+	}
+
+	revs, err := db.lastCommits(head, []string{filePath})
+	if err != nil {
+		return err
+	}
+
+	lastCommit := head.Hash
+	if commit, exist := revs[filePath]; exist && commit != nil {
+		lastCommit = commit.Hash
+	}
+
+	if lastCommit == expectedHash {
+		return nil
+	}
+
+	return fmt.Errorf("%w: key '%s' is at blob %s of commit %s, expected %s",
+		ErrPreconditionFailed, key, file.Hash, lastCommit, expected)
+}
@@ -0,0 +1,68 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDeleteIfMatch(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	created, err := db.CreateR(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+	bCommit, err := db.Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+	_, err = db.Create(ctx, "c.txt", []byte("c"))
+	require.NoError(t, err)
+
+	// other writer changes the key after the client has seen it
+	_, _, err = newTestDB(t, remote).Upsert(ctx, "a.txt", []byte("a2"))
+	require.NoError(t, err)
+
+	tip := remoteHead(t, remote)
+	for _, stale := range []string{created.BlobHash, created.CommitHash} {
+		_, err = db.Delete(ctx, "a.txt", gitrows.DeleteIfMatch(stale))
+		assert.ErrorIs(t, err, gitrows.ErrPreconditionFailed)
+		assert.Equal(t, gitrows.CodePreconditionFailed, gitrows.ErrorCode(err))
+	}
+
+	_, err = db.Delete(ctx, "missing.txt", gitrows.DeleteIfMatch(created.BlobHash))
+	assert.ErrorIs(t, err, gitrows.ErrPreconditionFailed)
+	assert.Equal(t, tip, remoteHead(t, remote))
+
+	// the blob hash of the current value
+	_, err = db.Delete(ctx, "a.txt", gitrows.DeleteIfMatch(gitrows.BlobHash([]byte("a2"))))
+	require.NoError(t, err)
+
+	// the last commit of the key, while the branch has advanced since then
+	require.NotEqual(t, bCommit, remoteHead(t, remote))
+	_, err = db.Delete(ctx, "b.txt", gitrows.DeleteIfMatch(bCommit))
+	require.NoError(t, err)
+
+	_, err = db.Delete(ctx, "b.txt", gitrows.DeleteIfMatch("abc"))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, gitrows.ErrPreconditionFailed)
+}
+
+func TestCreateIfAbsentAtCommit(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	head, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "b.txt", []byte("b"), gitrows.CreateIfAbsentAtCommit(head))
+	require.NoError(t, err)
+
+	// b.txt was created after the snapshot, so c.txt is rejected although it doesn't exist
+	_, err = db.Create(ctx, "c.txt", []byte("c"), gitrows.CreateIfAbsentAtCommit(head))
+	assert.ErrorIs(t, err, gitrows.ErrStaleHead)
+	assert.Equal(t, gitrows.CodePreconditionFailed, gitrows.ErrorCode(err))
+}
@@ -0,0 +1,242 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Verify checks the integrity of the local repository without syncing it first, i.e: after crash recovery:
+//   - the local branch reference resolves into a commit,
+//   - every object of its tree is readable,
+//   - the worktree matches the local branch (skipped when only fetched, see Keys),
+//   - the remote branch (`git ls-remote`) is the same or the descendant of the local branch.
+//
+// Every check is reported in VerifyReport instead of failing on the first one.
+// Never-synced DB has nothing to verify. To check whether the remote branch is the descendant,
+// the full history of the branch is cloned into memory when both heads differ.
+// With VerifyRepair, the local repository is re-cloned when any check fails, and the error is the error of re-cloning.
+func (db *DBImpl) Verify(ctx context.Context, opts ...VerifyOpt) (report VerifyReport, err error) {
+	defer func() {
+		err = wrapError(OpVerify, "", err)
+	}()
+
+	cfg := &VerifyConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("verify command: %w", err)
+			return
+		}
+	}
+
+	repo := db.gitRepo
+	if repo == nil {
+		repo, err = git.PlainOpen(db.gitVolume)
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			err, repo = nil, nil
+		}
+
+		if err != nil {
+			report.BranchErr = fmt.Errorf("open local repository %s error: %w", db.gitSshUrl, err)
+			err, repo = nil, nil
+		}
+	}
+
+	var commit *object.Commit
+	if repo != nil {
+		commit, report.BranchErr = db.verifyBranch(repo)
+	}
+
+	if commit != nil {
+		report.LocalHead = commit.Hash.String()
+		report.TreeErr = verifyTree(commit)
+	}
+
+	if repo != nil && report.BranchErr == nil && !db.isCheckoutPending() {
+		report.DirtyPaths, report.WorktreeErr = db.verifyWorktree(repo)
+	}
+
+	report.RemoteHead, report.RemoteErr = db.remoteHead(ctx)
+	if report.RemoteErr == nil && report.BranchErr == nil {
+		report.RemoteErr = db.verifyRemote(ctx, report.LocalHead, report.RemoteHead)
+	}
+
+	if !cfg.repair || report.OK() {
+		return
+	}
+
+	err = db.reclone(ctx)
+	if err != nil {
+		err = fmt.Errorf("verify command: cannot repair the local repository: %w", err)
+		return
+	}
+
+	report.Repaired = true
+	return
+}
+
+// verifyBranch returns the commit of the local branch, or nil when the branch doesn't exist yet.
+func (db *DBImpl) verifyBranch(repo *git.Repository) (commit *object.Commit, err error) {
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	ref, err := repo.Reference(branchName, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("retrieving ref for branch %s error: %w", branchName, err)
+		return
+	}
+
+	commit, err = repo.CommitObject(ref.Hash())
+	if err != nil {
+		err = fmt.Errorf("retrieving commit %s of branch %s error: %w", ref.Hash(), branchName, err)
+		return
+	}
+
+	return
+}
+
+// verifyTree walks the tree of the commit, and reads the header of every blob.
+func verifyTree(commit *object.Commit) (err error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		err = fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.Hash, err)
+		return
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		var name string
+		var entry object.TreeEntry
+		name, entry, err = walker.Next()
+		if errors.Is(err, io.EOF) {
+			err = nil
+			return
+		}
+
+		if err != nil {
+			err = fmt.Errorf("cannot iterate tree of commit %s: %w", commit.Hash, err)
+			return
+		}
+
+		if entry.Mode == filemode.Dir || entry.Mode == filemode.Submodule {
+			continue
+		}
+
+		_, err = tree.File(name)
+		if err != nil {
+			err = fmt.Errorf("cannot read blob %s of '%s': %w", entry.Hash, name, err)
+			return
+		}
+	}
+}
+
+// verifyWorktree returns the files in the worktree which are different from the local branch.
+// With WithSparsePrefix, the files outside the prefix are not checked out, so they are ignored.
+func (db *DBImpl) verifyWorktree(repo *git.Repository) (dirtyPaths []string, err error) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("cannot get worktree: %w", err)
+		return
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		err = fmt.Errorf("cannot `git status`: %w", err)
+		return
+	}
+
+	for filePath, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+
+		if db.checkSparsePrefix(filePath) != nil {
+			continue
+		}
+
+		dirtyPaths = append(dirtyPaths, filePath)
+	}
+
+	if len(dirtyPaths) > 0 {
+		sort.Strings(dirtyPaths)
+		err = fmt.Errorf("worktree has %d files different from branch %s", len(dirtyPaths), db.gitBranch)
+	}
+
+	return
+}
+
+// verifyRemote returns error when the remote head is neither the same nor the descendant of the local head.
+func (db *DBImpl) verifyRemote(ctx context.Context, localHead, remoteHead string) (err error) {
+	switch {
+	case localHead == remoteHead || localHead == "":
+		return nil
+
+	case remoteHead == "":
+		return fmt.Errorf("%w: branch '%s' doesn't exist in %s, but exists locally", ErrBranchNotFound, db.gitBranch, db.gitSshUrl)
+	}
+
+	repo, err := db.cloneFullHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	remoteCommit, err := repo.CommitObject(plumbing.NewHash(remoteHead))
+	if err != nil {
+		return fmt.Errorf("cannot get remote commit %s: %w", remoteHead, err)
+	}
+
+	localCommit, err := repo.CommitObject(plumbing.NewHash(localHead))
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return fmt.Errorf("local commit %s doesn't exist in the remote branch %s", localHead, db.gitBranch)
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot get local commit %s: %w", localHead, err)
+	}
+
+	isAncestor, err := localCommit.IsAncestor(remoteCommit)
+	if err != nil {
+		return fmt.Errorf("cannot check whether %s is ancestor of %s: %w", localHead, remoteHead, err)
+	}
+
+	if !isAncestor {
+		return fmt.Errorf("remote commit %s is not descendant of local commit %s", remoteHead, localHead)
+	}
+
+	return nil
+}
+
+// isCheckoutPending returns true when the branch is fetched without checking out the worktree, see syncBranch.
+func (db *DBImpl) isCheckoutPending() bool {
+	db.syncMu.Lock()
+	defer db.syncMu.Unlock()
+
+	return db.checkoutPending
+}
+
+// reclone removes the local repository, then clones it again.
+func (db *DBImpl) reclone(ctx context.Context) (err error) {
+	db.gitRepo = nil
+
+	err = os.RemoveAll(db.gitVolume)
+	if err != nil {
+		err = fmt.Errorf("cannot remove local repository '%s': %w", db.gitVolume, err)
+		return
+	}
+
+	return db.forcePull(ctx)
+}
@@ -0,0 +1,127 @@
+package gitrows
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_Verify(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func() *DBImpl {
+		db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+		require.NoError(t, err)
+		return db
+	}
+
+	db := newDB()
+
+	// never synced
+	report, err := db.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report)
+
+	_, err = db.Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	report, err = db.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report)
+	assert.NotEmpty(t, report.LocalHead)
+	assert.Equal(t, report.LocalHead, report.RemoteHead)
+
+	// remote is ahead of local
+	_, err = newDB().Create(ctx, "other.md", []byte("other"))
+	require.NoError(t, err)
+
+	report, err = db.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report)
+	assert.NotEqual(t, report.LocalHead, report.RemoteHead)
+
+	t.Run("dirty worktree", func(t *testing.T) {
+		db := newDB()
+		_, err := db.Get(ctx, "note.md")
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filepath.Join(db.gitVolume, "note.md"), []byte("changed"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(db.gitVolume, "untracked.md"), []byte("new"), 0644))
+
+		report, err := db.Verify(ctx)
+		require.NoError(t, err)
+		assert.False(t, report.OK())
+		assert.Error(t, report.WorktreeErr)
+		assert.Equal(t, []string{"note.md", "untracked.md"}, report.DirtyPaths)
+		assert.NoError(t, report.BranchErr)
+		assert.NoError(t, report.TreeErr)
+		assert.NoError(t, report.RemoteErr)
+
+		report, err = db.Verify(ctx, VerifyRepair(true))
+		require.NoError(t, err)
+		assert.True(t, report.Repaired)
+
+		report, err = db.Verify(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.OK(), report)
+	})
+
+	t.Run("unpushed local commit", func(t *testing.T) {
+		db := newDB()
+		_, err := db.Get(ctx, "note.md")
+		require.NoError(t, err)
+
+		worktree, err := db.writeFile(ctx, "local.md", []byte("local"), "CREATE", 0)
+		require.NoError(t, err)
+
+		_, err = db.gitCommit(ctx, worktree, "local only", false)
+		require.NoError(t, err)
+
+		report, err := db.Verify(ctx)
+		require.NoError(t, err)
+		assert.False(t, report.OK())
+		assert.Error(t, report.RemoteErr)
+		assert.NoError(t, report.WorktreeErr)
+
+		report, err = db.Verify(ctx, VerifyRepair(true))
+		require.NoError(t, err)
+		assert.True(t, report.Repaired)
+
+		report, err = db.Verify(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.OK(), report)
+		assert.Equal(t, report.RemoteHead, report.LocalHead)
+	})
+
+	t.Run("missing blob", func(t *testing.T) {
+		file, err := db.headFile("note.md")
+		require.NoError(t, err)
+		require.NotNil(t, file)
+
+		// locally written objects are loose objects
+		hash := file.Hash.String()
+		require.NoError(t, os.Remove(filepath.Join(db.gitVolume, ".git", "objects", hash[:2], hash[2:])))
+
+		report, err := db.Verify(ctx)
+		require.NoError(t, err)
+		assert.False(t, report.OK())
+		assert.Error(t, report.TreeErr)
+
+		report, err = db.Verify(ctx, VerifyRepair(true))
+		require.NoError(t, err)
+		assert.True(t, report.Repaired)
+
+		data, err := db.Get(ctx, "note.md")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("hello"), data)
+	})
+}
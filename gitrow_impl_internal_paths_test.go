@@ -0,0 +1,89 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_InternalPaths(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "note.md", []byte("note"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.SetSchemaVersion(ctx, 1))
+
+	// every write command rejects the reserved directory by default
+	_, err = db.Create(ctx, ".gitrows/lock", []byte("lock"))
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey), err)
+
+	_, _, err = db.Upsert(ctx, ".gitrows/version", []byte("2\n"))
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey), err)
+
+	_, err = db.PutReader(ctx, ".gitrows/version", strings.NewReader("2\n"))
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey), err)
+
+	_, err = db.Delete(ctx, ".gitrows/version")
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey), err)
+
+	version, err := db.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	// List hides the reserved directory by default
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"note.md"}, listKeys(entries))
+
+	entries, err = db.List(ctx, gitrows.ListIncludeInternal())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"note.md", ".gitrows/version"}, listKeys(entries))
+
+	root, err := db.ListTree(ctx)
+	require.NoError(t, err)
+	require.Len(t, root.Children, 1)
+	assert.Equal(t, "note.md", root.Children[0].Name)
+
+	// explicit option allows the reserved directory
+	_, err = db.Create(ctx, ".gitrows/lock", []byte("lock"), gitrows.CreateAllowInternalPaths())
+	require.NoError(t, err)
+
+	_, changed, err := db.Upsert(ctx, ".gitrows/version", []byte("2\n"), gitrows.UpsertAllowInternalPaths())
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	version, err = db.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	_, err = db.PutReader(ctx, ".gitrows/trash/note.md", strings.NewReader("note"), gitrows.UpsertAllowInternalPaths())
+	require.NoError(t, err)
+
+	entries, err = db.List(ctx, gitrows.ListIncludeInternal(), gitrows.ListPrefix(".gitrows"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{".gitrows/lock", ".gitrows/trash/note.md", ".gitrows/version"}, listKeys(entries))
+
+	_, err = db.Delete(ctx, ".gitrows/lock", gitrows.DeleteAllowInternalPaths())
+	require.NoError(t, err)
+
+	entries, err = newTestDB(t, remote).List(ctx, gitrows.ListIncludeInternal())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"note.md", ".gitrows/version", ".gitrows/trash/note.md"}, listKeys(entries))
+
+	// internal path is not mapped by KeyMapper or KeyEncoding
+	encoded := newTestDB(t, remote, gitrows.WithKeyEncoding(gitrows.PercentEncode))
+	_, _, err = encoded.Upsert(ctx, ".gitrows/version", []byte("3\n"), gitrows.UpsertAllowInternalPaths())
+	require.NoError(t, err)
+
+	version, err = db.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+}
@@ -0,0 +1,151 @@
+package gitrows
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// defaultOpJournalSize is the number of OpRecord kept by default, see WithOpJournal.
+const defaultOpJournalSize = 128
+
+// OpRecord is one mutating command called on this DB in the current process, see RecentOps.
+type OpRecord struct {
+	When time.Time // the time the command returned
+	Op   Op
+	Key  string // the key (or the prefix, or the branch) of the command, empty when it is about many keys
+
+	// Commit is the commit hash returned by the command, and Message is the message of that commit.
+	// Both are empty when the command doesn't return the commit (i.e: CopyFrom and Flush) or fails.
+	Commit  string
+	Message string
+
+	Err error // the error returned by the command, nil on success
+}
+
+// String returns the record as one line, the same as written by Close with WithOpJournalDump.
+func (r OpRecord) String() string {
+	s := fmt.Sprintf("%s %s", r.When.UTC().Format(time.RFC3339Nano), r.Op)
+	if r.Key != "" {
+		s += fmt.Sprintf(" %q", r.Key)
+	}
+
+	if r.Commit != "" {
+		s += " " + r.Commit
+	}
+
+	if r.Message != "" {
+		subject, _, _ := strings.Cut(r.Message, "\n")
+		s += fmt.Sprintf(" %q", subject)
+	}
+
+	if r.Err != nil {
+		s += fmt.Sprintf(" error: %v", r.Err)
+	}
+
+	return s
+}
+
+// opJournal is the ring buffer of the last OpRecord, shared by the DB and its siblings.
+type opJournal struct {
+	mu      sync.Mutex
+	size    int
+	records []OpRecord
+	next    int // index of the oldest record once the buffer is full
+}
+
+// WithOpJournal sets the number of the last mutating commands kept in memory for RecentOps, default is 128.
+// Each record only holds the key, the commit and its message, so the journal is cheap enough to be always on.
+// Zero disables the journal.
+func WithOpJournal(size int) Opt {
+	return func(db *DBImpl) error {
+		if size < 0 {
+			return fmt.Errorf("op journal size must not be negative, got %d", size)
+		}
+
+		db.journalSize = size
+		return nil
+	}
+}
+
+// WithOpJournalDump writes the records of RecentOps into w on Close, one record per line from the oldest,
+// i.e: os.Stderr to find what the service pushed before it stopped.
+func WithOpJournalDump(w io.Writer) Opt {
+	return func(db *DBImpl) error {
+		db.journalDump = w
+		return nil
+	}
+}
+
+// RecentOps returns the last n mutating commands called on this DB (and its branches of WriteToBranches)
+// in the current process, from the oldest to the newest. The failing commands are included with their error.
+// Zero or negative n returns all records kept, see WithOpJournal.
+func (db *DBImpl) RecentOps(n int) []OpRecord {
+	j := db.journal
+	if j == nil {
+		return []OpRecord{}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	records := make([]OpRecord, 0, len(j.records))
+	records = append(records, j.records[j.next:]...)
+	records = append(records, j.records[:j.next]...)
+	if n > 0 && n < len(records) {
+		records = records[len(records)-n:]
+	}
+
+	return records
+}
+
+// recordOp appends the command into the journal, it is called in the deferred function of each mutating command.
+func (db *DBImpl) recordOp(op Op, key, commitHash string, err error) {
+	j := db.journal
+	if j == nil {
+		return
+	}
+
+	record := OpRecord{
+		When: time.Now(),
+		Op:   op,
+		Key:  key,
+		Err:  err,
+	}
+
+	if err == nil && commitHash != "" {
+		record.Commit = commitHash
+		if db.gitRepo != nil {
+			commit, _err := db.gitRepo.CommitObject(plumbing.NewHash(commitHash))
+			if _err == nil {
+				record.Message = commit.Message
+			}
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.records) < j.size {
+		j.records = append(j.records, record)
+		return
+	}
+
+	j.records[j.next] = record
+	j.next = (j.next + 1) % len(j.records)
+}
+
+// dumpOpJournal writes the journal into the writer of WithOpJournalDump.
+func (db *DBImpl) dumpOpJournal() {
+	if db.journalDump == nil {
+		return
+	}
+
+	for _, record := range db.RecentOps(0) {
+		_, _ = fmt.Fprintln(db.journalDump, record.String())
+	}
+}
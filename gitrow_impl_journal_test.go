@@ -0,0 +1,79 @@
+package gitrows_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_RecentOps(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	dump := &bytes.Buffer{}
+	db := newTestDB(t, remote, gitrows.WithOpJournal(3), gitrows.WithOpJournalDump(dump))
+
+	assert.Empty(t, db.RecentOps(0))
+
+	created, err := db.Create(ctx, "a.txt", []byte("a"), gitrows.CreateCommitMsg("create a\n\nmore detail"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "a.txt", []byte("a"))
+	require.ErrorIs(t, err, os.ErrExist)
+
+	records := db.RecentOps(0)
+	require.Len(t, records, 2)
+	assert.Equal(t, gitrows.OpCreate, records[0].Op)
+	assert.Equal(t, "a.txt", records[0].Key)
+	assert.Equal(t, created, records[0].Commit)
+	assert.Equal(t, "create a\n\nmore detail", records[0].Message)
+	assert.NoError(t, records[0].Err)
+	assert.False(t, records[0].When.IsZero())
+
+	assert.Empty(t, records[1].Commit)
+	assert.ErrorIs(t, records[1].Err, os.ErrExist)
+
+	_, _, err = db.Upsert(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	deleted, err := db.Delete(ctx, "a.txt")
+	require.NoError(t, err)
+
+	// the oldest is dropped
+	records = db.RecentOps(0)
+	require.Len(t, records, 3)
+	assert.Equal(t, []gitrows.Op{gitrows.OpCreate, gitrows.OpUpsert, gitrows.OpDelete},
+		[]gitrows.Op{records[0].Op, records[1].Op, records[2].Op})
+	assert.Error(t, records[0].Err)
+
+	records = db.RecentOps(1)
+	require.Len(t, records, 1)
+	assert.Equal(t, deleted, records[0].Commit)
+	assert.Equal(t, "gitrows: DELETE", records[0].Message)
+
+	require.NoError(t, db.Close())
+
+	lines := strings.Split(strings.TrimSpace(dump.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], ` create "a.txt" error: `)
+	assert.Contains(t, lines[2], ` delete "a.txt" `+deleted+` "gitrows: DELETE"`)
+}
+
+func TestWithOpJournal(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := gitrows.New(gitrows.WithOpJournal(-1))
+	assert.Error(t, err)
+
+	db := newTestDB(t, remote, gitrows.WithOpJournal(0))
+
+	_, err = db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+	assert.Empty(t, db.RecentOps(0))
+}
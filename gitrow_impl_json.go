@@ -0,0 +1,78 @@
+package gitrows
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WithJSONCanonicalization rewrites the JSON object or array value into canonical form
+// (sorted object keys, two-space indentation and trailing newline) on Create, Upsert and PutContentAddressed,
+// so the same logical JSON from different producers is committed as the same bytes,
+// and Upsert of semantically identical JSON is not a change.
+// Other value (including JSON scalar, i.e: "123") is written byte-identical.
+//
+// PutReader streams the value, so it is never canonicalized.
+func WithJSONCanonicalization() Opt {
+	return func(db *DBImpl) error {
+		db.jsonCanonical = true
+		return nil
+	}
+}
+
+// canonicalizeJSON returns data in canonical JSON form when WithJSONCanonicalization is enabled and data is
+// JSON object or array, otherwise data as is.
+// When explicit is true (i.e: UpsertCanonicalJSON), data must be valid JSON regardless of WithJSONCanonicalization.
+func (db *DBImpl) canonicalizeJSON(data []byte, explicit bool) ([]byte, error) {
+	if !explicit && (!db.jsonCanonical || !isJSONContainer(data)) {
+		return data, nil
+	}
+
+	canonical, err := canonicalJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot canonicalize JSON value: %w", err)
+	}
+
+	return canonical, nil
+}
+
+// isJSONContainer returns true when data is valid JSON object or array.
+func isJSONContainer(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+
+	return json.Valid(trimmed)
+}
+
+// canonicalJSON re-encodes the JSON data, where encoding/json sorts the object keys.
+// Numbers are kept as written, so large integer doesn't lose precision through float64.
+func canonicalJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+
+	// reject trailing data, i.e: two JSON values
+	if _, err = decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level JSON value")
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	err = encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,109 @@
+package gitrows
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	canonicalNested := "{\n  \"a\": [\n    3,\n    {\n      \"x\": null,\n      \"y\": true\n    },\n    \"<b>\"\n  ],\n  \"b\": {\n    \"c\": 12345678901234567890,\n    \"d\": 1.50\n  }\n}\n"
+
+	tests := []struct {
+		name      string
+		data      string
+		canonical string
+	}{
+		{name: "nested", data: `{"b":{"d":1.50,"c":12345678901234567890},"a":[3,{"y":true,"x":null},"<b>"]}`, canonical: canonicalNested},
+		{name: "already canonical", data: canonicalNested, canonical: canonicalNested},
+		{name: "array", data: " [ {\"b\":1,\"a\":2}, [] ]\r\n", canonical: "[\n  {\n    \"a\": 2,\n    \"b\": 1\n  },\n  []\n]\n"},
+		{name: "empty object", data: "{ }", canonical: "{}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			canonical, err := canonicalJSON([]byte(test.data))
+			require.NoError(t, err)
+			assert.Equal(t, test.canonical, string(canonical))
+		})
+	}
+
+	for _, data := range []string{`{"a":1} {"b":2}`, `{"a":1}]`, `{"a":`} {
+		_, err := canonicalJSON([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestDBImpl_JSONCanonicalization(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()), WithJSONCanonicalization())
+	require.NoError(t, err)
+
+	first, err := db.Create(ctx, "config.json", []byte(`{"name":"app","ports":[80,443],"db":{"port":5432,"host":"localhost"}}`))
+	require.NoError(t, err)
+
+	data, err := db.Get(ctx, "config.json")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"db\": {\n    \"host\": \"localhost\",\n    \"port\": 5432\n  },\n  \"name\": \"app\",\n  \"ports\": [\n    80,\n    443\n  ]\n}\n", string(data))
+
+	// semantically identical JSON is not a change
+	commitHash, changed, err := db.Upsert(ctx, "config.json", []byte("{ \"ports\": [80, 443], \"db\": {\"host\": \"localhost\", \"port\": 5432}, \"name\": \"app\" }"))
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, first, commitHash)
+
+	_, changed, err = db.Upsert(ctx, "config.json", []byte(`{"ports":[443,80],"db":{"host":"localhost","port":5432},"name":"app"}`))
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// non-JSON and JSON scalar are byte-identical
+	for key, value := range map[string]string{
+		"note.txt":    "{not json",
+		"number.json": " 123 ",
+		"text.md":     "# title\n",
+	} {
+		_, err = db.Create(ctx, key, []byte(value))
+		require.NoError(t, err)
+
+		data, err = db.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, value, string(data))
+	}
+
+	// explicit option requires valid JSON, even without WithJSONCanonicalization
+	plain, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+	require.NoError(t, err)
+
+	_, _, err = plain.Upsert(ctx, "number.json", []byte(" 123 "), UpsertCanonicalJSON())
+	require.NoError(t, err)
+
+	data, err = plain.Get(ctx, "number.json")
+	require.NoError(t, err)
+	assert.Equal(t, "123\n", string(data))
+
+	_, _, err = plain.Upsert(ctx, "note.txt", []byte("{not json"), UpsertCanonicalJSON())
+	assert.Error(t, err)
+
+	_, err = plain.Create(ctx, "new.json", []byte(`{"b":1,"a":2}`), CreateCanonicalJSON())
+	require.NoError(t, err)
+
+	data, err = plain.Get(ctx, "new.json")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": 2,\n  \"b\": 1\n}\n", string(data))
+
+	_, _, err = plain.Upsert(ctx, "raw.json", []byte(`{"b":1,"a":2}`))
+	require.NoError(t, err)
+
+	data, err = plain.Get(ctx, "raw.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":1,"a":2}`, string(data))
+}
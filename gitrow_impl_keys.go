@@ -0,0 +1,134 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Keys returns only the keys, which is the cheapest way to enumerate the keys.
+// Unlike List, it doesn't read the value nor resolve the last commit, and after the `git fetch`
+// it reads the names from the tree of the branch without checking out the worktree.
+// It accepts the same ListOpt as List, but ListLimit stops silently, use List to know whether it is truncated.
+func (db *DBImpl) Keys(ctx context.Context, opts ...ListOpt) (keys []string, err error) {
+	defer func() {
+		err = wrapError(OpKeys, "", err)
+	}()
+
+	cfg := &ListConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("keys command: %w", err)
+			return
+		}
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("keys command: %w", err)
+		return
+	}
+
+	keys = make([]string, 0)
+	err = db.walkKeys(cfg, func(key string, _ object.TreeEntry) {
+		keys = append(keys, key)
+	})
+	if err != nil {
+		err = fmt.Errorf("keys command: %w", err)
+		return
+	}
+
+	return
+}
+
+// walkKeys calls fn for every key in the tree of the local branch which matches the cfg, in the tree order.
+// The tree walker only reads the tree objects, while tree.Files used by list also reads every blob.
+// The entry.Name is the file path of the key in the repository, rather than only the base name.
+func (db *DBImpl) walkKeys(cfg *ListConfig, fn func(key string, entry object.TreeEntry)) (err error) {
+	commit, err := db.headCommit()
+	if err != nil || commit == nil {
+		return
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		err = fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.ID(), err)
+		return
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for n := 0; cfg.limit == 0 || n < cfg.limit; {
+		var name string
+		var entry object.TreeEntry
+		name, entry, err = walker.Next()
+		if errors.Is(err, io.EOF) {
+			err = nil
+			return
+		}
+
+		if err != nil {
+			err = fmt.Errorf("cannot iterate tree: %w", err)
+			return
+		}
+
+		if entry.Mode == filemode.Dir || entry.Mode == filemode.Submodule {
+			continue
+		}
+
+		// the same filter as list
+		key, ok := name, cfg.includeInternal
+		if !isMetadataPath(name) {
+			key, ok = db.pathToKey(name)
+		}
+
+		if !ok || !cfg.match(key) {
+			continue
+		}
+
+		entry.Name = name
+		fn(key, entry)
+		n++
+	}
+
+	return
+}
+
+// syncBranch is like syncForRead, but only does `git fetch` of the branch without checking out the worktree,
+// for the command which only reads the commit objects. The next syncForRead then does the checkout.
+func (db *DBImpl) syncBranch(ctx context.Context) (err error) {
+	if db.isFresh(true) {
+		return
+	}
+
+	err = db.gitClone(ctx)
+	if err == nil {
+		err = db.readFetch(ctx)
+	}
+
+	if err == nil {
+		db.syncMu.Lock()
+		db.checkoutPending = true
+		db.syncMu.Unlock()
+		return
+	}
+
+	err = fmt.Errorf("git sync error: %w", err)
+	if !db.staleReadsOnRemoteErr || !errors.Is(err, ErrRemoteUnavailable) || db.gitRepo == nil {
+		return
+	}
+
+	// serve from the local branch, the same as syncForRead
+	head, headErr := db.headCommit()
+	if headErr != nil || head == nil {
+		return
+	}
+
+	return nil
+}
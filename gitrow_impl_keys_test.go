@@ -0,0 +1,85 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_Keys(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithReadStaleness(time.Hour))
+
+	keys, err := db.Keys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, keys)
+
+	writer := newTestDB(t, remote)
+	for _, key := range []string{"configs/app.yaml", "configs/db.yaml", "readme.md"} {
+		_, err = writer.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.SetSchemaVersion(ctx, 1))
+
+	keys, err = db.Keys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configs/app.yaml", "configs/db.yaml", "readme.md"}, keys)
+
+	keys, err = db.Keys(ctx, gitrows.ListPrefix("configs"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configs/app.yaml", "configs/db.yaml"}, keys)
+
+	keys, err = db.Keys(ctx, gitrows.ListPrefix("configs/app.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configs/app.yaml"}, keys)
+
+	keys, err = db.Keys(ctx, gitrows.ListPrefix("config"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, keys)
+
+	keys, err = db.Keys(ctx, gitrows.ListLimit(1))
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	keys, err = db.Keys(ctx, gitrows.ListIncludeInternal(), gitrows.ListPrefix(".gitrows"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{".gitrows/version"}, keys)
+
+	// Keys doesn't check out the worktree, so the next read still syncs inside the staleness window.
+	// Flat keys only, since re-fetching the shallow clone with unchanged sub-tree fails in go-git.
+	t.Run("checkout pending", func(t *testing.T) {
+		remote := newTestRemote(t)
+		db := newTestDB(t, remote, gitrows.WithReadStaleness(time.Hour))
+
+		writer := newTestDB(t, remote)
+		_, err := writer.Create(ctx, "readme.md", []byte("v1"))
+		require.NoError(t, err)
+
+		data, err := db.Get(ctx, "readme.md")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1"), data)
+
+		_, _, err = writer.Upsert(ctx, "readme.md", []byte("v2"))
+		require.NoError(t, err)
+
+		_, err = writer.Create(ctx, "license.md", []byte("MIT"))
+		require.NoError(t, err)
+
+		// after Keys the local branch is the notified head, but the worktree is not
+		db.NotifyRemoteChanged(remoteHead(t, remote))
+
+		keys, err := db.Keys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"license.md", "readme.md"}, keys)
+
+		data, err = db.Get(ctx, "readme.md")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v2"), data)
+	})
+}
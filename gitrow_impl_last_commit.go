@@ -0,0 +1,103 @@
+package gitrows
+
+import (
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// lastCommitResolver resolves the last commit of the kvIters returned by list on demand,
+// because walking the commit history for every file is slow on large repository.
+// The result is memoized in the kvIter, including the error.
+type lastCommitResolver struct {
+	mu       sync.Mutex // protects the resolution, since go-git repository is not safe for concurrent use
+	db       *DBImpl
+	head     *object.Commit
+	verified map[plumbing.Hash]bool
+}
+
+func newLastCommitResolver(db *DBImpl, head *object.Commit) *lastCommitResolver {
+	return &lastCommitResolver{
+		db:       db,
+		head:     head,
+		verified: make(map[plumbing.Hash]bool),
+	}
+}
+
+// resolve sets the last commit of kvIters which are not resolved yet, using one walk of the commit history.
+func (r *lastCommitResolver) resolve(kvIters []*kvIter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := make([]*kvIter, 0, len(kvIters))
+	paths := make([]string, 0, len(kvIters))
+	for _, kv := range kvIters {
+		if kv.resolved {
+			continue
+		}
+
+		pending = append(pending, kv)
+		paths = append(paths, kv.path)
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	revs, err := r.db.lastCommits(r.head, paths)
+	for _, kv := range pending {
+		kv.resolved = true
+		if err != nil {
+			kv.lastCommitErr = err
+			continue
+		}
+
+		kv.lastCommit = r.head // use current commit as default
+		if lastCommit, exist := revs[kv.path]; exist && lastCommit != nil {
+			kv.lastCommit = lastCommit
+		}
+
+		ok, checked := r.verified[kv.lastCommit.Hash]
+		if !checked {
+			ok = r.db.verifyCommit(kv.lastCommit)
+			r.verified[kv.lastCommit.Hash] = ok
+		}
+
+		kv.verified = ok
+	}
+}
+
+// lastCommitOf returns the last commit of kv, resolving it first when it is not resolved yet.
+func (r *lastCommitResolver) lastCommitOf(kv *kvIter) (commit *object.Commit, verified bool, err error) {
+	r.resolve([]*kvIter{kv})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return kv.lastCommit, kv.verified, kv.lastCommitErr
+}
+
+// resolveLastCommits resolves the last commit of all kvIters at once, for the command which needs all of them
+// (i.e: ListModifiedBetween). It returns ErrUnverifiedCommit when WithRejectUnsignedCommits is enabled
+// and the last commit of any kvIter is not verified.
+func (db *DBImpl) resolveLastCommits(kvIters []*kvIter) error {
+	if len(kvIters) == 0 {
+		return nil
+	}
+
+	// all kvIters returned by the same list share the resolver
+	kvIters[0].resolver.resolve(kvIters)
+
+	for _, kv := range kvIters {
+		if kv.lastCommitErr != nil {
+			return kv.lastCommitErr
+		}
+
+		if !kv.verified && db.rejectUnverified {
+			return unverifiedCommitError(kv.k, kv.lastCommit)
+		}
+	}
+
+	return nil
+}
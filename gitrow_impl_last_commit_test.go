@@ -0,0 +1,78 @@
+package gitrows
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_List_lazyLastCommit(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+	require.NoError(t, err)
+
+	first, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	second, err := db.Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries.KVs(), 2)
+
+	for _, kv := range entries.KVs() {
+		assert.False(t, kv.(*kvIter).resolved, kv.Key())
+	}
+
+	// resolved on first call and memoized, concurrent calls are safe
+	lastCommits := make([]string, len(entries.KVs()))
+	wg := sync.WaitGroup{}
+	for i, kv := range entries.KVs() {
+		wg.Add(1)
+		go func(i int, kv KV) {
+			defer wg.Done()
+			lastCommits[i] = kv.LastCommit()
+		}(i, kv)
+	}
+
+	wg.Wait()
+
+	assert.ElementsMatch(t, []string{first, second}, lastCommits)
+	for _, kv := range entries.KVs() {
+		assert.True(t, kv.(*kvIter).resolved, kv.Key())
+		assert.NoError(t, kv.LastCommitErr())
+		assert.Equal(t, kv.LastCommit(), kv.LastCommitInfo().Hash)
+	}
+
+	// resolution failure is reported instead of silently empty
+	missing := &kvIter{
+		k:        "a.txt",
+		path:     "a.txt",
+		resolver: newLastCommitResolver(db, &object.Commit{Hash: plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")}),
+	}
+
+	assert.Empty(t, missing.LastCommit())
+	assert.Error(t, missing.LastCommitErr())
+	assert.Equal(t, CommitInfo{}, missing.LastCommitInfo())
+
+	// ListModifiedBetween resolves all of them up front
+	kvIters, _, err := db.list(&ListConfig{})
+	require.NoError(t, err)
+	require.NoError(t, db.resolveLastCommits(kvIters))
+	for _, kv := range kvIters {
+		assert.True(t, kv.resolved, kv.k)
+	}
+}
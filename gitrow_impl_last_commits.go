@@ -0,0 +1,83 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+)
+
+// LastCommits returns the hash of the last commit which modifies each key, walking the history only once
+// instead of once for each key as in GetWithCommit, i.e: to build the concurrency tokens of known keys.
+// The same caveat of LastCommit in List applies: key which is not modified within the local history
+// is reported as modified by the head commit.
+// When some keys cannot be resolved (i.e: not exist), the rest of keys are still returned
+// and the error is *MultiError containing the error of each failing key.
+func (db *DBImpl) LastCommits(ctx context.Context, keys []string) (commitHashes map[string]string, err error) {
+	defer func() {
+		err = wrapError(OpLastCommits, "", err)
+	}()
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("last commits command: %w", err)
+		return
+	}
+
+	commitHashes = make(map[string]string, len(keys))
+	keyErrs := make(map[string]error)
+
+	paths := make([]string, 0, len(keys))
+	pathKeys := make(map[string][]string, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, exist := seen[key]; exist {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		filePath, pathErr := db.readPath(key)
+		if pathErr != nil {
+			keyErrs[key] = pathErr
+			continue
+		}
+
+		// different keys may map into the same path, i.e: "/note.md" and "note.md"
+		if _, exist := pathKeys[filePath]; !exist {
+			paths = append(paths, filePath)
+		}
+
+		pathKeys[filePath] = append(pathKeys[filePath], key)
+	}
+
+	if len(paths) > 0 {
+		head, headErr := db.headCommit()
+		if headErr != nil {
+			err = fmt.Errorf("last commits command: %w", headErr)
+			return
+		}
+
+		revs, revErr := db.lastCommits(head, paths)
+		if revErr != nil {
+			err = fmt.Errorf("last commits command: %w", revErr)
+			return
+		}
+
+		for filePath, keys := range pathKeys {
+			lastCommit := head // use current commit as default, the same as List
+			if rev, exist := revs[filePath]; exist && rev != nil {
+				lastCommit = rev
+			}
+
+			for _, key := range keys {
+				commitHashes[key] = lastCommit.Hash.String()
+			}
+		}
+	}
+
+	if len(keyErrs) > 0 {
+		err = &MultiError{Errors: keyErrs}
+		return
+	}
+
+	return
+}
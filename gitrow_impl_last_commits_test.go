@@ -0,0 +1,47 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_LastCommits(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	first, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	second, err := db.Create(ctx, "dir/b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "c.txt", []byte("c"))
+	require.NoError(t, err)
+
+	// the writer has all commits in the local repository
+	commitHashes, err := db.LastCommits(ctx, []string{"a.txt", "/a.txt", "dir/b.txt", "missing.txt", "a.txt"})
+	assert.Equal(t, map[string]string{
+		"a.txt":     first,
+		"/a.txt":    first,
+		"dir/b.txt": second,
+	}, commitHashes)
+
+	var multiErr *gitrows.MultiError
+	require.True(t, errors.As(err, &multiErr), err)
+	assert.Len(t, multiErr.Errors, 1)
+	assert.True(t, errors.Is(multiErr.Errors["missing.txt"], os.ErrNotExist), multiErr.Errors["missing.txt"])
+
+	// fresh clone only has the head commit, see List
+	commitHashes, err = newTestDB(t, remote).LastCommits(ctx, []string{"a.txt", "dir/b.txt"})
+	require.NoError(t, err)
+
+	head := remoteHead(t, remote)
+	assert.Equal(t, map[string]string{"a.txt": head, "dir/b.txt": head}, commitHashes)
+}
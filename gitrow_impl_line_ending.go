@@ -0,0 +1,124 @@
+package gitrows
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// binarySniffLen is the number of leading bytes checked for NUL to detect binary content, the same as git does.
+const binarySniffLen = 8000
+
+// LineEndingPolicy is how the line ending of the value is treated on write, see WithLineEndingPolicy.
+type LineEndingPolicy int
+
+const (
+	// LineEndingPreserve writes the value as is, which is the default.
+	LineEndingPreserve LineEndingPolicy = iota
+
+	// LineEndingLF converts CRLF into LF before the value is written, so CRLF content over identical LF content
+	// is not changed. Binary content (containing NUL in the first 8000 bytes) is never converted.
+	LineEndingLF
+)
+
+// WithLineEndingPolicy set the LineEndingPolicy of write commands (i.e: Create, Upsert, PutReader),
+// i.e: LineEndingLF to stop editors on Windows from producing noisy diffs.
+func WithLineEndingPolicy(policy LineEndingPolicy) Opt {
+	return func(db *DBImpl) error {
+		switch policy {
+		case LineEndingPreserve, LineEndingLF:
+			db.lineEnding = policy
+			return nil
+		default:
+			return fmt.Errorf("unknown line ending policy %d", policy)
+		}
+	}
+}
+
+// normalizeLineEnding returns data converted according to the LineEndingPolicy.
+func (db *DBImpl) normalizeLineEnding(data []byte) []byte {
+	if db.lineEnding != LineEndingLF || isBinary(data) {
+		return data
+	}
+
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// normalizeLineEndingReader is like normalizeLineEnding, but for the value streamed from r.
+func (db *DBImpl) normalizeLineEndingReader(r io.Reader) io.Reader {
+	if db.lineEnding != LineEndingLF {
+		return r
+	}
+
+	buffered := bufio.NewReaderSize(r, binarySniffLen)
+	head, _ := buffered.Peek(binarySniffLen)
+	if isBinary(head) {
+		return buffered
+	}
+
+	return &lfReader{r: buffered}
+}
+
+// isBinary returns true when data contains NUL in the first binarySniffLen bytes.
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// lfReader converts CRLF into LF, while lone CR is kept as is.
+type lfReader struct {
+	r         *bufio.Reader
+	pendingCR bool
+}
+
+func (l *lfReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		var c byte
+		c, err = l.r.ReadByte()
+		if err != nil {
+			if l.pendingCR {
+				p[n] = '\r'
+				n++
+				l.pendingCR = false
+			}
+
+			// return the data first, then the error on the next Read
+			if n > 0 && err == io.EOF {
+				err = nil
+			}
+
+			return
+		}
+
+		if l.pendingCR {
+			l.pendingCR = false
+			if c != '\n' {
+				p[n] = '\r'
+				n++
+				if n == len(p) {
+					_ = l.r.UnreadByte()
+					return
+				}
+			}
+		}
+
+		if c == '\r' {
+			l.pendingCR = true
+			continue
+		}
+
+		p[n] = c
+		n++
+
+		// don't block waiting for more data when some is already read
+		if l.r.Buffered() == 0 {
+			return
+		}
+	}
+
+	return
+}
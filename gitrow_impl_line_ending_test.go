@@ -0,0 +1,74 @@
+package gitrows_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithLineEndingPolicy(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithLineEndingPolicy(gitrows.LineEndingLF))
+
+	_, err := db.Create(ctx, "config.yaml", []byte("a: 1\nb: 2\n"))
+	require.NoError(t, err)
+
+	_, changed, err := db.Upsert(ctx, "config.yaml", []byte("a: 1\r\nb: 2\r\n"))
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	_, changed, err = db.Upsert(ctx, "config.yaml", []byte("a: 1\r\nb: 3\r\n"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	data, err := db.Get(ctx, "config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a: 1\nb: 3\n"), data)
+
+	// lone CR is kept
+	_, err = db.Create(ctx, "cr.txt", []byte("a\rb\r\r\nc\r"))
+	require.NoError(t, err)
+
+	data, err = db.Get(ctx, "cr.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a\rb\r\nc\r"), data)
+
+	// binary content is never touched
+	binary := []byte("\x00\x01\r\n\x02\r\n")
+	_, err = db.Create(ctx, "image.bin", binary)
+	require.NoError(t, err)
+
+	data, err = db.Get(ctx, "image.bin")
+	require.NoError(t, err)
+	assert.Equal(t, binary, data)
+
+	// streaming write is converted too, regardless how the reader is chunked
+	text := strings.Repeat("line\r\n", 5000) + "last\r"
+	_, err = db.PutReader(ctx, "stream.txt", iotest.OneByteReader(strings.NewReader(text)))
+	require.NoError(t, err)
+
+	data, err = db.Get(ctx, "stream.txt")
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("line\n", 5000)+"last\r", string(data))
+
+	streamBinary := append(bytes.Repeat([]byte("x\r\n"), 10), 0)
+	_, err = db.PutReader(ctx, "stream.bin", bytes.NewReader(streamBinary))
+	require.NoError(t, err)
+
+	data, err = db.Get(ctx, "stream.bin")
+	require.NoError(t, err)
+	assert.Equal(t, streamBinary, data)
+
+	// preserve policy is the default
+	preserve := newTestDB(t, remote)
+	_, changed, err = preserve.Upsert(ctx, "config.yaml", []byte("a: 1\r\nb: 3\r\n"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+}
@@ -0,0 +1,98 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ListByAuthor returns the change of every key made by each commit of the author within the window,
+// i.e: for the audit of everything one user changed last month. The author is matched by the email case-insensitively,
+// and the window is since (inclusive) until until (exclusive) by the author time of the commit, see CommitInfo.When.
+// Zero until means up to the head of the branch.
+//
+// The commits are walked following the first parent like Changelog, and the entries are sorted by the commit time,
+// then by the key within the same commit. The local history is deepened like DeepenSince until it covers since,
+// and it is kept by the following syncs.
+func (db *DBImpl) ListByAuthor(ctx context.Context, authorEmail string, since, until time.Time) (entries []ChangeEntry, err error) {
+	defer func() {
+		err = wrapError(OpListByAuthor, "", err)
+	}()
+
+	if authorEmail == "" {
+		err = fmt.Errorf("list by author command: author email must not be empty")
+		return
+	}
+
+	if !until.IsZero() && until.Before(since) {
+		err = fmt.Errorf("list by author command: until %s is before since %s", until, since)
+		return
+	}
+
+	_, err = db.deepen(ctx, func(history localHistory) (bool, error) {
+		return !history.oldest.After(since), nil
+	})
+	if err != nil {
+		err = fmt.Errorf("list by author command: %w", err)
+		return
+	}
+
+	history, err := db.localHistory()
+	if err != nil {
+		err = fmt.Errorf("list by author command: %w", err)
+		return
+	}
+
+	type changeID struct {
+		commit string
+		key    string
+	}
+
+	seen := make(map[changeID]struct{})
+	entries = make([]ChangeEntry, 0)
+	// from the oldest, so the commits made within the same second keep their order
+	for i := len(history.firstParents) - 1; i >= 0; i-- {
+		commit := history.firstParents[i]
+		when := commit.Author.When
+		if when.Before(since) || (!until.IsZero() && !when.Before(until)) {
+			continue
+		}
+
+		if !strings.EqualFold(commit.Author.Email, authorEmail) {
+			continue
+		}
+
+		// the parent of the oldest commit is not fetched when the remote doesn't have more history
+		if i == len(history.firstParents)-1 && commit.NumParents() > 0 {
+			err = fmt.Errorf("list by author command: %w: the parent %s of commit %s is beyond the local history",
+				ErrCommitNotFound, commit.ParentHashes[0], commit.Hash)
+			return
+		}
+
+		var changes []ChangeEntry
+		changes, err = db.commitChanges(commit)
+		if err != nil {
+			err = fmt.Errorf("list by author command: %w", err)
+			return
+		}
+
+		for _, change := range changes {
+			id := changeID{commit: change.Commit.Hash, key: change.Key}
+			if _, exist := seen[id]; exist {
+				continue
+			}
+
+			seen[id] = struct{}{}
+			entries = append(entries, change)
+		}
+	}
+
+	// the changes of each commit are already sorted by the key
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Commit.When.Before(entries[j].Commit.When)
+	})
+
+	return
+}
@@ -0,0 +1,72 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_ListByAuthor(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	since := time.Now().Add(-time.Hour)
+
+	writer := newTestDB(t, remote)
+	_, err := writer.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	pushCommit(t, remote, nil, "b.txt", []byte("b"))
+	first := remoteHead(t, remote)
+
+	_, _, err = writer.Upsert(ctx, "a.txt", []byte("a2"))
+	require.NoError(t, err)
+
+	pushCommit(t, remote, nil, "b.txt", []byte("b2"))
+	second := remoteHead(t, remote)
+
+	_, err = writer.Create(ctx, "c.txt", []byte("c"))
+	require.NoError(t, err)
+
+	type change struct {
+		key        string
+		changeType gitrows.ChangeType
+		commit     string
+	}
+
+	summary := func(entries []gitrows.ChangeEntry) []change {
+		changes := make([]change, 0, len(entries))
+		for _, entry := range entries {
+			assert.Equal(t, "signer@example.com", entry.Commit.AuthorEmail)
+			changes = append(changes, change{key: entry.Key, changeType: entry.ChangeType, commit: entry.Commit.Hash})
+		}
+
+		return changes
+	}
+
+	// the new DB deepens its shallow clone
+	db := newTestDB(t, remote)
+	entries, err := db.ListByAuthor(ctx, "Signer@Example.com", since, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []change{
+		{key: "b.txt", changeType: gitrows.ChangeAdded, commit: first},
+		{key: "b.txt", changeType: gitrows.ChangeModified, commit: second},
+	}, summary(entries))
+
+	entries, err = db.ListByAuthor(ctx, "signer@example.com", since, since.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	entries, err = db.ListByAuthor(ctx, "nobody@example.com", since, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = db.ListByAuthor(ctx, "", since, time.Time{})
+	assert.Error(t, err)
+
+	_, err = db.ListByAuthor(ctx, "signer@example.com", since, since.Add(-time.Minute))
+	assert.Error(t, err)
+}
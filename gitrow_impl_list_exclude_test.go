@@ -0,0 +1,81 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestListExclude(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	writer := newTestDB(t, remote)
+	for _, key := range []string{
+		"a.txt",
+		"a.tmp",
+		"configs/app.yaml",
+		"configs/app.yaml.tmp",
+		"configs/scratch/note.txt",
+		"logs/2023-01/app.log",
+		"logs/2024-01/app.log",
+	} {
+		_, err := writer.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	db := newTestDB(t, remote)
+
+	tests := []struct {
+		name string
+		opts []gitrows.ListOpt
+		keys []string
+	}{
+		{
+			name: "base name glob",
+			opts: []gitrows.ListOpt{gitrows.ListExclude("*.tmp")},
+			keys: []string{"a.txt", "configs/app.yaml", "configs/scratch/note.txt", "logs/2023-01/app.log", "logs/2024-01/app.log"},
+		},
+		{
+			name: "prefix with directory name",
+			opts: []gitrows.ListOpt{gitrows.ListPrefix("configs"), gitrows.ListExclude("scratch/")},
+			keys: []string{"configs/app.yaml", "configs/app.yaml.tmp"},
+		},
+		{
+			name: "prefix with several globs",
+			opts: []gitrows.ListOpt{gitrows.ListPrefix("configs"), gitrows.ListExclude("*.tmp", "configs/scratch")},
+			keys: []string{"configs/app.yaml"},
+		},
+		{
+			name: "glob of directories from the root",
+			opts: []gitrows.ListOpt{gitrows.ListPrefix("logs"), gitrows.ListExclude("logs/2023-*")},
+			keys: []string{"logs/2024-01/app.log"},
+		},
+		{
+			name: "exclude wins over prefix",
+			opts: []gitrows.ListOpt{gitrows.ListPrefix("configs/app.yaml"), gitrows.ListExclude("app.yaml")},
+			keys: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := db.List(ctx, tt.opts...)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.keys, listKeys(entries))
+
+			keys, err := db.Keys(ctx, tt.opts...)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.keys, keys)
+		})
+	}
+
+	_, err := db.List(ctx, gitrows.ListExclude("[a-"))
+	assert.Error(t, err)
+
+	_, err = db.List(ctx, gitrows.ListExclude("/"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ListModifiedBetween returns all keys whose last-modifying commit is authored within from and to (inclusive).
+// It accepts the same ListOpt as List.
+//
+// Please note, that the last commit is computed the same way as List, which means it is only accurate
+// when the local repository has enough history. Since we only `git fetch` with depth 1, file which is not changed
+// within the fetched history is reported as modified by the oldest commit available in the local repository.
+func (db *DBImpl) ListModifiedBetween(ctx context.Context, from, to time.Time, opts ...ListOpt) (kvs []KV, err error) {
+	defer func() {
+		err = wrapError(OpListModifiedBetween, "", err)
+	}()
+
+	cfg := &ListConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("list modified between command: %w", err)
+			return
+		}
+	}
+
+	err = db.syncForRead(ctx)
+	if err != nil {
+		err = fmt.Errorf("list modified between command: %w", err)
+		return
+	}
+
+	kvIters, _, err := db.list(cfg)
+	if err != nil {
+		err = fmt.Errorf("list modified between command: %w", err)
+		return
+	}
+
+	err = db.resolveLastCommits(kvIters)
+	if err != nil {
+		err = fmt.Errorf("list modified between command: %w", err)
+		return
+	}
+
+	kvs = make([]KV, 0)
+	for _, kv := range kvIters {
+		if kv.lastCommit == nil {
+			continue
+		}
+
+		when := kv.lastCommit.Author.When
+		if when.Before(from) || when.After(to) {
+			continue
+		}
+
+		kvs = append(kvs, kv)
+	}
+
+	return
+}
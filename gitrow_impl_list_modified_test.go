@@ -0,0 +1,35 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_ListModifiedBetween(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	_, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	kvs, err := db.ListModifiedBetween(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+
+	keys := make([]string, 0)
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key())
+	}
+	assert.ElementsMatch(t, []string{"a.txt", "b.txt"}, keys)
+
+	kvs, err = db.ListModifiedBetween(ctx, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, kvs)
+}
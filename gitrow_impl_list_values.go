@@ -0,0 +1,124 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ListWithValues is like List, but the value up to maxInlineSize bytes is read into KVWithValue.Data while walking
+// the tree, i.e: to load all small feature flags in one call instead of one Get per entry.
+// The larger value is not read, and KV.Value returns its lazy reader like List does.
+// It accepts the same ListOpt as List, and the entries are in the tree order like Keys.
+//
+// Every inlined value is held in memory at once, so the memory used is up to maxInlineSize times the number of entries:
+// keep maxInlineSize small, and narrow the entries with ListPrefix on the large repository.
+func (db *DBImpl) ListWithValues(ctx context.Context, maxInlineSize int, opts ...ListOpt) (entries []KVWithValue, err error) {
+	defer func() {
+		err = wrapError(OpListWithValues, "", err)
+	}()
+
+	cfg := &ListConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("list with values command: %w", err)
+			return
+		}
+	}
+
+	if maxInlineSize < 0 {
+		err = fmt.Errorf("list with values command: max inline size must not be negative, got %d", maxInlineSize)
+		return
+	}
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("list with values command: %w", err)
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		err = fmt.Errorf("list with values command: %w", err)
+		return
+	}
+
+	resolver := newLastCommitResolver(db, head)
+	kvIters := make([]*kvIter, 0)
+	entries = make([]KVWithValue, 0)
+	var readErr error
+	err = db.walkKeys(cfg, func(key string, entry object.TreeEntry) {
+		if readErr != nil {
+			return
+		}
+
+		blob, blobErr := db.gitRepo.BlobObject(entry.Hash)
+		if blobErr != nil {
+			readErr = fmt.Errorf("cannot read '%s': %w", key, blobErr)
+			return
+		}
+
+		file := object.NewFile(entry.Name, entry.Mode, blob)
+		kv := &kvIter{
+			k:        key,
+			path:     file.Name,
+			v:        blobValue(db.gitRepo, file.Hash),
+			size:     file.Size,
+			mode:     fileMode(file),
+			hash:     file.Hash,
+			resolver: resolver,
+		}
+
+		withValue := KVWithValue{KV: kv}
+		if blob.Size <= int64(maxInlineSize) {
+			withValue.Data, readErr = readBlob(blob)
+			if readErr != nil {
+				readErr = fmt.Errorf("cannot read '%s': %w", key, readErr)
+				return
+			}
+
+			withValue.Inlined = true
+		}
+
+		kvIters = append(kvIters, kv)
+		entries = append(entries, withValue)
+	})
+	if err == nil {
+		err = readErr
+	}
+
+	if err != nil {
+		err = fmt.Errorf("list with values command: %w", err)
+		return
+	}
+
+	// the same as List, the verification result must be known before returning
+	if db.verifyKeyring != nil || db.rejectUnverified {
+		err = db.resolveLastCommits(kvIters)
+		if err != nil {
+			err = fmt.Errorf("list with values command: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// readBlob reads all content of the blob.
+func readBlob(blob *object.Blob) (data []byte, err error) {
+	reader, err := blob.Reader()
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,66 @@
+package gitrows_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_ListWithValues(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	writer := newTestDB(t, remote)
+	_, err := writer.Create(ctx, "flags/a.json", []byte("true"))
+	require.NoError(t, err)
+
+	_, err = writer.Create(ctx, "flags/big.json", []byte(strings.Repeat("x", 100)))
+	require.NoError(t, err)
+
+	_, err = writer.Create(ctx, "flags/empty.json", []byte{})
+	require.NoError(t, err)
+
+	_, err = writer.Create(ctx, "other.txt", []byte("o"))
+	require.NoError(t, err)
+
+	db := newTestDB(t, remote)
+	entries, err := db.ListWithValues(ctx, 10, gitrows.ListPrefix("flags"))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	byKey := make(map[string]gitrows.KVWithValue)
+	for _, entry := range entries {
+		byKey[entry.Key()] = entry
+	}
+
+	assert.True(t, byKey["flags/a.json"].Inlined)
+	assert.Equal(t, "true", string(byKey["flags/a.json"].Data))
+	assert.NotEmpty(t, byKey["flags/a.json"].LastCommit())
+
+	assert.True(t, byKey["flags/empty.json"].Inlined)
+	assert.Empty(t, byKey["flags/empty.json"].Data)
+
+	// the larger value is still readable lazily
+	big := byKey["flags/big.json"]
+	assert.False(t, big.Inlined)
+	assert.Nil(t, big.Data)
+
+	reader, err := big.Value()
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, strings.Repeat("x", 100), string(data))
+
+	entries, err = db.ListWithValues(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	_, err = db.ListWithValues(ctx, -1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxReconcileAttempts is the number of push attempts when the push keeps being rejected, see WithMergeResolver.
+const maxReconcileAttempts = 3
+
+// WithMergeResolver set the function which resolves the conflicting key when the push is rejected because
+// the remote branch is changed after the pull, i.e: another writer pushes in between. It only applies with
+// WithForcePush(false), since the force push simply overwrites the remote changes.
+//
+// Instead of failing with CodeConflict, the local commit is recreated on top of the remote branch:
+// keys changed only by the local commit are kept, and resolve is called for every key changed on both sides
+// with different value. The local or remote is nil when the key is deleted on that side,
+// and returning nil deletes the key. Error from resolve aborts the write.
+func WithMergeResolver(resolve func(key string, local, remote []byte) ([]byte, error)) Opt {
+	return func(db *DBImpl) error {
+		db.mergeResolver = resolve
+		return nil
+	}
+}
+
+// canReconcile returns true when the push error can be reconciled by WithMergeResolver or WithLinearHistory.
+func (db *DBImpl) canReconcile(err error) bool {
+	return (db.mergeResolver != nil || db.linearHistory) && !db.forcePush && db.pushEvery <= 1 &&
+		errors.Is(err, git.ErrNonFastForwardUpdate)
+}
+
+// reconcile fetches the remote branch, then recreates the local commit on top of it.
+// It returns upToDate true when nothing is left to push, i.e: every conflict is resolved into the remote value.
+func (db *DBImpl) reconcile(ctx context.Context) (upToDate bool, err error) {
+	local, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	if local == nil {
+		err = fmt.Errorf("no local commit to reconcile")
+		return
+	}
+
+	var baseTree *object.Tree
+	if local.NumParents() > 0 {
+		var base *object.Commit
+		base, err = local.Parent(0)
+		if err != nil {
+			err = fmt.Errorf("cannot get parent of local commit %s: %w", local.Hash, err)
+			return
+		}
+
+		baseTree, err = base.Tree()
+		if err != nil {
+			err = fmt.Errorf("retrieve the tree from the commit %s error: %w", base.Hash, err)
+			return
+		}
+	}
+
+	localChanges, err := treeChanges(baseTree, local)
+	if err != nil {
+		return
+	}
+
+	// rewind the local branch to the pulled commit, so the fetch is the same as the usual sync.
+	// The local commit object is kept, and the local branch then points to the remote branch.
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	if local.NumParents() > 0 {
+		err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(branchName, local.ParentHashes[0]))
+	} else {
+		err = db.gitRepo.Storer.RemoveReference(branchName)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot rewind local branch %s: %w", branchName, err)
+		return
+	}
+
+	err = db.gitFetch(ctx)
+	if err != nil {
+		return
+	}
+
+	remote, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	remoteChanges, err := treeChanges(baseTree, remote)
+	if err != nil {
+		return
+	}
+
+	err = db.gitCheckout(ctx)
+	if err != nil {
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("cannot get worktree: %w", err)
+		return
+	}
+
+	for filePath, localEntry := range localChanges {
+		remoteEntry, changedRemotely := remoteChanges[filePath]
+
+		mode := localEntry.Mode
+		var data []byte
+		switch {
+		case changedRemotely && remoteEntry.Hash == localEntry.Hash:
+			continue // the same change on both sides
+
+		case changedRemotely:
+			data, err = db.resolveConflict(filePath, localEntry, remoteEntry)
+			if err != nil {
+				return
+			}
+
+			if localEntry.Hash.IsZero() {
+				mode = remoteEntry.Mode
+			}
+
+		case !localEntry.Hash.IsZero():
+			data, err = db.readBlob(localEntry.Hash)
+			if err != nil {
+				err = fmt.Errorf("cannot read local '%s': %w", filePath, err)
+				return
+			}
+		}
+
+		err = db.applyChange(ctx, worktree, filePath, data, mode)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = db.gitCommit(ctx, worktree, local.Message, false)
+	if errors.Is(err, git.ErrEmptyCommit) {
+		upToDate, err = true, nil
+		return
+	}
+
+	return
+}
+
+// resolveConflict calls the WithMergeResolver function with the local and remote value of the file path.
+// Without it (i.e: only WithLinearHistory), the conflict cannot be resolved and ErrPushRejected is returned.
+func (db *DBImpl) resolveConflict(filePath string, localEntry, remoteEntry object.TreeEntry) (data []byte, err error) {
+	if db.mergeResolver == nil {
+		err = fmt.Errorf("%w: '%s' is changed in the remote branch", ErrPushRejected, filePath)
+		return
+	}
+
+	var localData, remoteData []byte
+	if !localEntry.Hash.IsZero() {
+		localData, err = db.readBlob(localEntry.Hash)
+		if err != nil {
+			err = fmt.Errorf("cannot read local '%s': %w", filePath, err)
+			return
+		}
+	}
+
+	if !remoteEntry.Hash.IsZero() {
+		remoteData, err = db.readBlob(remoteEntry.Hash)
+		if err != nil {
+			err = fmt.Errorf("cannot read remote '%s': %w", filePath, err)
+			return
+		}
+	}
+
+	key, ok := filePath, isMetadataPath(filePath)
+	if !ok {
+		key, ok = db.pathToKey(filePath)
+	}
+
+	if !ok {
+		key = filePath
+	}
+
+	data, err = db.mergeResolver(key, localData, remoteData)
+	if err != nil {
+		err = fmt.Errorf("cannot resolve conflict of key '%s': %w", key, err)
+		return
+	}
+
+	return
+}
+
+// applyChange writes the data into the file path, or deletes it when data is nil.
+func (db *DBImpl) applyChange(ctx context.Context, worktree *git.Worktree, filePath string, data []byte, mode filemode.FileMode) (err error) {
+	if data == nil {
+		_, statErr := worktree.Filesystem.Lstat(filePath)
+		if statErr != nil {
+			return nil // already deleted in the remote
+		}
+
+		err = gitRm(worktree, filePath)
+		if err != nil {
+			err = fmt.Errorf("cannot `git rm %s`: %w", filePath, err)
+			return
+		}
+
+		return removeEmptyDirs(worktree.Filesystem, path.Dir(filePath))
+	}
+
+	// zero mode keeps the mode of the existing file
+	var perm os.FileMode
+	if mode != filemode.Empty {
+		var osMode os.FileMode
+		osMode, err = mode.ToOSFileMode()
+		if err != nil {
+			err = fmt.Errorf("invalid mode of '%s': %w", filePath, err)
+			return
+		}
+
+		perm = osMode.Perm()
+	}
+
+	_, err = db.writeFile(ctx, filePath, data, "UPSERT", perm)
+	return
+}
+
+// treeChanges returns the entry of every file path changed from the base tree to the tree of the commit.
+// The deleted file has the zero hash.
+func treeChanges(base *object.Tree, commit *object.Commit) (changes map[string]object.TreeEntry, err error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		err = fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.Hash, err)
+		return
+	}
+
+	diff, err := object.DiffTree(base, tree)
+	if err != nil {
+		err = fmt.Errorf("cannot diff commit %s: %w", commit.Hash, err)
+		return
+	}
+
+	changes = make(map[string]object.TreeEntry, len(diff))
+	for _, change := range diff {
+		if change.To.Name == "" {
+			changes[change.From.Name] = object.TreeEntry{Name: change.From.Name}
+			continue
+		}
+
+		changes[change.To.Name] = change.To.TreeEntry
+	}
+
+	return
+}
@@ -0,0 +1,138 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMergeResolver(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func(opts ...Opt) *DBImpl {
+		opts = append([]Opt{WithGitSshUrl("file://" + remoteDir), WithLocalGitVolume(t.TempDir())}, opts...)
+		db, err := New(opts...)
+		require.NoError(t, err)
+		return db
+	}
+
+	writer := newDB()
+	for _, key := range []string{"a.txt", "b.txt", "c.txt"} {
+		_, err = writer.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	type conflict struct {
+		key           string
+		local, remote string
+	}
+
+	// the local commit is made after the pull, but another writer pushes before the push
+	racingWrite := func(db *DBImpl, local, remote map[string][]byte) (string, error) {
+		require.NoError(t, db.forcePull(ctx))
+
+		other := newDB()
+		for key, data := range remote {
+			var err error
+			if data == nil {
+				_, err = other.Delete(ctx, key)
+			} else {
+				_, _, err = other.Upsert(ctx, key, data)
+			}
+
+			require.NoError(t, err)
+		}
+
+		worktree, err := db.gitRepo.Worktree()
+		require.NoError(t, err)
+
+		for key, data := range local {
+			if data == nil {
+				_, err = worktree.Remove(key)
+			} else {
+				_, err = db.writeFile(ctx, key, data, "UPSERT", 0)
+			}
+
+			require.NoError(t, err)
+		}
+
+		_, err = db.gitCommit(ctx, worktree, "local write", false)
+		require.NoError(t, err)
+
+		pushed, _, err := db.gitPush(ctx, OpUpsert)
+		return pushed.String(), err
+	}
+
+	conflicts := make([]conflict, 0)
+	db := newDB(WithForcePush(false), WithMergeResolver(func(key string, local, remote []byte) ([]byte, error) {
+		conflicts = append(conflicts, conflict{key: key, local: string(local), remote: string(remote)})
+		if key == "c.txt" {
+			return nil, nil
+		}
+
+		return append(append(local, '+'), remote...), nil
+	}))
+
+	pushed, err := racingWrite(db,
+		map[string][]byte{"a.txt": []byte("local a"), "b.txt": []byte("local b"), "c.txt": nil},
+		map[string][]byte{"a.txt": []byte("remote a"), "c.txt": []byte("remote c"), "d.txt": []byte("remote d")},
+	)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []conflict{
+		{key: "a.txt", local: "local a", remote: "remote a"},
+		{key: "c.txt", local: "", remote: "remote c"},
+	}, conflicts)
+
+	// the reconciled commit is pushed on top of the remote commit
+	reader := newDB()
+	head, err := reader.remoteHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, head, pushed)
+
+	values, err := reader.GetMany(ctx, []string{"a.txt", "b.txt", "d.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"a.txt": []byte("local a+remote a"),
+		"b.txt": []byte("local b"),
+		"d.txt": []byte("remote d"),
+	}, values)
+
+	_, err = reader.Get(ctx, "c.txt")
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+
+	// the error of resolver aborts the write
+	errResolve := errors.New("cannot merge")
+	db = newDB(WithForcePush(false), WithMergeResolver(func(key string, local, remote []byte) ([]byte, error) {
+		return nil, errResolve
+	}))
+
+	_, err = racingWrite(db, map[string][]byte{"a.txt": []byte("local")}, map[string][]byte{"a.txt": []byte("remote")})
+	assert.True(t, errors.Is(err, errResolve), err)
+	assert.Contains(t, err.Error(), "a.txt")
+
+	// resolving into the remote value leaves nothing to push
+	db = newDB(WithForcePush(false), WithMergeResolver(func(key string, local, remote []byte) ([]byte, error) {
+		return remote, nil
+	}))
+
+	pushed, err = racingWrite(db, map[string][]byte{"a.txt": []byte("local")}, map[string][]byte{"a.txt": []byte("remote")})
+	require.NoError(t, err)
+
+	head, err = reader.remoteHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, head, pushed)
+
+	// without resolver, the rejected push is the conflict
+	_, err = racingWrite(newDB(WithForcePush(false)), map[string][]byte{"a.txt": []byte("x")}, map[string][]byte{"b.txt": []byte("y")})
+	assert.True(t, errors.Is(err, git.ErrNonFastForwardUpdate), err)
+}
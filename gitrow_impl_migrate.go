@@ -0,0 +1,269 @@
+package gitrows
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// migration is the key matched by the prefix of Migrate, with the value at the head of the branch.
+type migration struct {
+	key      string
+	filePath string
+	data     []byte
+	newData  []byte
+}
+
+// Migrate applies fn to the value of every key under the prefix (matched the same as ListPrefix),
+// then commits all rewritten values in a single commit and push it. Empty prefix means all keys.
+// The fn may return skip true to keep the value as is, and the key which new value is identical is not counted as migrated.
+// It accepts the same UpsertOpt as Upsert, the commit message defaults to "gitrows: MIGRATE".
+//
+// When fn returns error for any key, nothing is committed, the worktree is reset
+// and the returned *Error contains the failing key. Use MigrateDryRun to preview the changes.
+func (db *DBImpl) Migrate(
+	ctx context.Context, prefix string, fn func(key string, data []byte) (newData []byte, skip bool, err error), opts ...UpsertOpt,
+) (commitHashString string, migrated int, err error) {
+	var failingKey string
+	defer func() {
+		err = wrapError(OpMigrate, failingKey, err)
+		db.recordOp(OpMigrate, failingKey, commitHashString, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("migrate command: %w", err)
+		return
+	}
+
+	cfg := &UpsertConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("migrate command: %w", err)
+			return
+		}
+	}
+
+	err = db.forcePull(ctx)
+	if err != nil {
+		err = fmt.Errorf("migrate command: %w", err)
+		return
+	}
+
+	migrations, failingKey, err := db.migrations(prefix, fn, cfg.canonicalJSON)
+	if err != nil {
+		err = fmt.Errorf("migrate command: %w", err)
+		return
+	}
+
+	migrated = len(migrations)
+	if migrated == 0 && !cfg.allowEmptyCommit {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("migrate command: cannot get HEAD reference when nothing is migrated: %w", err)
+			return
+		}
+
+		commitHashString = head.Hash().String()
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("migrate command: cannot get worktree: %w", err)
+		return
+	}
+
+	for _, m := range migrations {
+		_, err = db.writeFile(ctx, m.filePath, m.newData, "UPSERT", cfg.fileMode)
+		if err != nil {
+			failingKey = m.key
+			break
+		}
+	}
+
+	if err != nil {
+		// don't leave the staged values of the former keys for the next command
+		if resetErr := db.gitCheckout(ctx); resetErr != nil {
+			err = fmt.Errorf("%w (and reset the worktree error: %s)", err, resetErr)
+		}
+
+		err = fmt.Errorf("migrate command: %w", err)
+		return
+	}
+
+	meta := CommitMeta{
+		Sizes:    make(map[string]int64, len(migrations)),
+		Changed:  migrated > 0,
+		Metadata: cfg.meta,
+	}
+
+	for _, m := range migrations {
+		meta.Sizes[m.key] = int64(len(m.newData))
+	}
+
+	commitMsg := db.commitMessage(OpMigrate, cfg.commitMsg, "gitrows: MIGRATE", meta)
+
+	var commitHash plumbing.Hash
+	commitHash, err = db.gitCommit(ctx, worktree, commitMsg, cfg.allowEmptyCommit)
+	if err != nil {
+		err = fmt.Errorf("migrate command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	commitHash, _, err = db.gitPush(ctx, OpMigrate)
+	if err != nil {
+		err = fmt.Errorf("migrate command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	return
+}
+
+// MigrateDryRun is like Migrate, but only returns the keys which would be rewritten, without writing anything.
+// It doesn't require the DB to be writable.
+func (db *DBImpl) MigrateDryRun(
+	ctx context.Context, prefix string, fn func(key string, data []byte) (newData []byte, skip bool, err error), opts ...UpsertOpt,
+) (changes []MigrationChange, err error) {
+	var failingKey string
+	defer func() {
+		err = wrapError(OpMigrateDryRun, failingKey, err)
+	}()
+
+	cfg := &UpsertConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("migrate dry run command: %w", err)
+			return
+		}
+	}
+
+	err = db.syncForRead(ctx)
+	if err != nil {
+		err = fmt.Errorf("migrate dry run command: %w", err)
+		return
+	}
+
+	migrations, failingKey, err := db.migrations(prefix, fn, cfg.canonicalJSON)
+	if err != nil {
+		err = fmt.Errorf("migrate dry run command: %w", err)
+		return
+	}
+
+	changes = make([]MigrationChange, 0, len(migrations))
+	for _, m := range migrations {
+		changes = append(changes, MigrationChange{
+			Key:     m.key,
+			OldSize: len(m.data),
+			NewSize: len(m.newData),
+		})
+	}
+
+	return
+}
+
+// migrations applies fn to every key under the prefix at the head of the branch,
+// and returns the keys which value is changed. The key of the error is returned as failingKey.
+func (db *DBImpl) migrations(
+	prefix string, fn func(key string, data []byte) (newData []byte, skip bool, err error), canonicalJSON bool,
+) (migrations []migration, failingKey string, err error) {
+	if fn == nil {
+		err = fmt.Errorf("migration function must not be nil")
+		return
+	}
+
+	prefix = cleanPrefix(prefix)
+
+	type entry struct {
+		key  string
+		hash plumbing.Hash
+	}
+
+	entries := make([]entry, 0)
+	err = db.walkKeys(&ListConfig{}, func(key string, treeEntry object.TreeEntry) {
+		if hasPathPrefix(key, prefix) {
+			entries = append(entries, entry{key: key, hash: treeEntry.Hash})
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	migrations = make([]migration, 0)
+	for _, e := range entries {
+		m := migration{key: e.key}
+
+		m.filePath, err = db.keyToPath(e.key)
+		if err != nil {
+			failingKey = e.key
+			return
+		}
+
+		m.data, err = db.readBlob(e.hash)
+		if err != nil {
+			err = fmt.Errorf("cannot read '%s': %w", e.key, err)
+			failingKey = e.key
+			return
+		}
+
+		var skip bool
+		m.newData, skip, err = fn(e.key, m.data)
+		if err != nil {
+			err = fmt.Errorf("cannot migrate '%s': %w", e.key, err)
+			failingKey = e.key
+			return
+		}
+
+		if skip {
+			continue
+		}
+
+		m.newData, err = db.canonicalizeJSON(m.newData, canonicalJSON)
+		if err != nil {
+			failingKey = e.key
+			return
+		}
+
+		m.newData = db.normalizeLineEnding(m.newData)
+		if bytes.Equal(m.data, m.newData) {
+			continue
+		}
+
+		migrations = append(migrations, m)
+	}
+
+	return
+}
+
+// readBlob returns the content of the blob object.
+func (db *DBImpl) readBlob(hash plumbing.Hash) (data []byte, err error) {
+	blob, err := db.gitRepo.BlobObject(hash)
+	if err != nil {
+		return
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,125 @@
+package gitrows_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_Migrate(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	for key, value := range map[string]string{
+		"configs/app.json":    `{"name":"app"}`,
+		"configs/db/db.json":  `{"name":"db"}`,
+		"configs/done.json":   `{"name":"done","version":2}`,
+		"configs/readme.md":   "readme",
+		"other/service.json":  `{"name":"service"}`,
+		"configsx/wrong.json": `{"name":"wrong"}`,
+	} {
+		_, err := db.Create(ctx, key, []byte(value))
+		require.NoError(t, err)
+	}
+
+	addVersion := func(key string, data []byte) ([]byte, bool, error) {
+		if !bytes.HasPrefix(data, []byte("{")) {
+			return nil, true, nil
+		}
+
+		if bytes.Contains(data, []byte(`"version":2`)) {
+			return data, false, nil
+		}
+
+		return append(bytes.TrimSuffix(data, []byte("}")), []byte(`,"version":2}`)...), false, nil
+	}
+
+	changes, err := db.MigrateDryRun(ctx, "configs/", addVersion)
+	require.NoError(t, err)
+	assert.Equal(t, []gitrows.MigrationChange{
+		{Key: "configs/app.json", OldSize: 14, NewSize: 26},
+		{Key: "configs/db/db.json", OldSize: 13, NewSize: 25},
+	}, changes)
+
+	before := remoteHead(t, remote)
+
+	commitHash, migrated, err := db.Migrate(ctx, "configs/", addVersion, gitrows.UpsertCommitMsg("add version 2"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+	assert.Equal(t, remoteHead(t, remote), commitHash)
+
+	// single commit on top of the previous head
+	commit := remoteCommit(t, remote, commitHash)
+	assert.Equal(t, "add version 2", commit.Message)
+	require.Equal(t, 1, commit.NumParents())
+	assert.Equal(t, before, commit.ParentHashes[0].String())
+
+	reader := newTestDB(t, remote)
+	for key, value := range map[string]string{
+		"configs/app.json":    `{"name":"app","version":2}`,
+		"configs/db/db.json":  `{"name":"db","version":2}`,
+		"configs/done.json":   `{"name":"done","version":2}`,
+		"configs/readme.md":   "readme",
+		"other/service.json":  `{"name":"service"}`,
+		"configsx/wrong.json": `{"name":"wrong"}`,
+	} {
+		data, err := reader.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, value, string(data), key)
+	}
+
+	// nothing to migrate returns the current head
+	commitHash, migrated, err = db.Migrate(ctx, "configs", addVersion)
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+	assert.Equal(t, remoteHead(t, remote), commitHash)
+}
+
+func TestDBImpl_Migrate_error(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	for _, key := range []string{"a.txt", "b.txt", "c.txt"} {
+		_, err := db.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	before := remoteHead(t, remote)
+	errBroken := errors.New("broken value")
+
+	_, migrated, err := db.Migrate(ctx, "", func(key string, data []byte) ([]byte, bool, error) {
+		if key == "b.txt" {
+			return nil, false, errBroken
+		}
+
+		return []byte("migrated"), false, nil
+	})
+	assert.True(t, errors.Is(err, errBroken), err)
+	assert.Equal(t, 0, migrated)
+	assert.Equal(t, before, remoteHead(t, remote))
+
+	var gitRowsErr *gitrows.Error
+	require.True(t, errors.As(err, &gitRowsErr))
+	assert.Equal(t, gitrows.OpMigrate, gitRowsErr.Op)
+	assert.Equal(t, "b.txt", gitRowsErr.Key)
+
+	// the next write doesn't contain any value of the aborted migration
+	_, _, err = db.Upsert(ctx, "c.txt", []byte("updated"))
+	require.NoError(t, err)
+
+	data, err := newTestDB(t, remote).Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a.txt"), data)
+
+	_, _, err = newTestDB(t, remote, gitrows.WithReadOnly()).Migrate(ctx, "", func(key string, data []byte) ([]byte, bool, error) {
+		return data, false, nil
+	})
+	assert.True(t, errors.Is(err, gitrows.ErrReadOnly), err)
+}
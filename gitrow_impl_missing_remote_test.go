@@ -0,0 +1,73 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestMissingRemote(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("absent", func(t *testing.T) {
+		remote := "file://" + filepath.Join(t.TempDir(), "missing.git")
+
+		_, err := newTestDB(t, remote).List(ctx)
+		assert.ErrorIs(t, err, gitrows.ErrRepositoryNotFound)
+		assert.ErrorIs(t, err, gitrows.ErrRemoteUnavailable)
+		assert.Equal(t, gitrows.CodeRemoteUnavailable, gitrows.ErrorCode(err))
+
+		var notFound *gitrows.RepositoryNotFoundError
+		require.True(t, errors.As(err, &notFound), err)
+		assert.Equal(t, remote, notFound.URL)
+
+		_, _, _, err = newTestDB(t, remote).IsUpToDate(ctx)
+		assert.ErrorIs(t, err, gitrows.ErrRepositoryNotFound)
+	})
+
+	t.Run("absent with WithAllowMissingRemote", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "created-later.git")
+		remote := "file://" + dir
+		db := newTestDB(t, remote, gitrows.WithAllowMissingRemote())
+
+		entries, err := db.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, entries.KVs())
+
+		_, err = db.Get(ctx, "a.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		upToDate, _, _, err := db.IsUpToDate(ctx)
+		require.NoError(t, err)
+		assert.True(t, upToDate)
+
+		// the file transport can't create the repository by the push
+		_, err = db.Create(ctx, "a.txt", []byte("a"))
+		assert.ErrorIs(t, err, gitrows.ErrRepositoryNotFound)
+
+		// the repository is created on the host, still empty
+		_, err = git.PlainInit(dir, true)
+		require.NoError(t, err)
+
+		commitHash, err := db.Create(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+		assert.Equal(t, remoteHead(t, remote), commitHash)
+	})
+
+	t.Run("present with WithAllowMissingRemote", func(t *testing.T) {
+		remote := newTestRemote(t)
+		_, err := newTestDB(t, remote).Create(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+
+		data, err := newTestDB(t, remote, gitrows.WithAllowMissingRemote()).Get(ctx, "a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+	})
+}
@@ -0,0 +1,52 @@
+package gitrows
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// WithObjectCacheSize set the maximum bytes of the in-memory LRU cache of git objects (commits, trees and blobs)
+// used by the local repository. Zero uses the go-git default (96 MiB).
+//
+// The cache is filled while walking the tree and the commit history (i.e: List and its last commit lookup),
+// so smaller cache caps the memory of memory-constrained services, but repeated reads have to decompress
+// the same objects from disk again, which is slower on large repositories.
+// The initial `git clone` still uses the go-git default cache.
+func WithObjectCacheSize(bytes int) Opt {
+	return func(db *DBImpl) error {
+		if bytes < 0 {
+			return fmt.Errorf("object cache size must not be negative, got %d", bytes)
+		}
+
+		db.objectCacheSize = bytes
+		return nil
+	}
+}
+
+// reopenRepo opens the repository again with the object cache of WithObjectCacheSize
+// and the worktree filesystem of worktreeFS, when any of them is set.
+func (db *DBImpl) reopenRepo(repo *git.Repository) (*git.Repository, error) {
+	if db.objectCacheSize == 0 && db.worktreeFS == nil {
+		return repo, nil
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get worktree: %w", err)
+	}
+
+	fs := worktree.Filesystem
+	if db.worktreeFS != nil {
+		fs = db.worktreeFS(db.gitVolume)
+	}
+
+	storer := repo.Storer
+	if s, ok := storer.(*filesystem.Storage); ok && db.objectCacheSize > 0 {
+		storer = filesystem.NewStorage(s.Filesystem(), cache.NewObjectLRU(cache.FileSize(db.objectCacheSize)))
+	}
+
+	return git.Open(storer, fs)
+}
@@ -0,0 +1,37 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithObjectCacheSize(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	// tiny cache evicts every object, but reads must still work
+	db := newTestDB(t, remote, gitrows.WithObjectCacheSize(1))
+
+	_, err := db.Create(ctx, "configs/app.yaml", []byte("app"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "configs/db.yaml", []byte("db"))
+	require.NoError(t, err)
+
+	reader := newTestDB(t, remote, gitrows.WithObjectCacheSize(1))
+
+	entries, err := reader.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configs/app.yaml", "configs/db.yaml"}, listKeys(entries))
+
+	data, err := reader.Get(ctx, "configs/db.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("db"), data)
+
+	_, err = gitrows.New(gitrows.WithGitSshUrl(remote), gitrows.WithObjectCacheSize(-1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package gitrows
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+)
+
+// preserveStashDir is where the preserved files are moved during the checkout, inside the .git directory
+// of the worktree filesystem, which is never touched by the reset.
+const preserveStashDir = git.GitDirName + "/gitrows-preserved"
+
+// WithPreservePaths keeps the untracked files in the local repository which path (relative to the repository root)
+// or one of its parent directories matches one of the patterns of path.Match, i.e: the caches, scratch files
+// or derived artifacts placed next to the clone. Like .gitignore, the pattern without slash (i.e: "*.tmp")
+// matches the name in any directory, while "build/*.tmp" only matches in the build directory.
+// Without it, the checkout of every sync removes them like `git clean`.
+//
+// The preserved paths are never committed: writing the key which file path matches the patterns fails with
+// ErrInvalidKey. When the file is committed by other writer, the committed one replaces the preserved one.
+func WithPreservePaths(patterns []string) Opt {
+	return func(db *DBImpl) error {
+		for _, pattern := range patterns {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return fmt.Errorf("invalid preserve path pattern '%s': %w", pattern, err)
+			}
+		}
+
+		db.preservePaths = append(db.preservePaths, patterns...)
+		return nil
+	}
+}
+
+// isPreserved returns true when the file path or one of its parent directories matches WithPreservePaths.
+func (db *DBImpl) isPreserved(filePath string) bool {
+	for p := path.Clean(filePath); p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		for _, pattern := range db.preservePaths {
+			name := p
+			if !strings.Contains(pattern, "/") {
+				name = path.Base(p)
+			}
+
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkNotPreserved returns ErrInvalidKey when the file path is preserved, so it is never committed.
+func (db *DBImpl) checkNotPreserved(filePath string) error {
+	if len(db.preservePaths) > 0 && db.isPreserved(filePath) {
+		return fmt.Errorf("%w: '%s' matches the preserved paths", ErrInvalidKey, filePath)
+	}
+
+	return nil
+}
+
+// stashPreserved moves every untracked preserved file into preserveStashDir, and returns the function
+// which moves them back after the checkout. The files left by the interrupted checkout are moved back first.
+func (db *DBImpl) stashPreserved(worktree *git.Worktree) (restore func() error, err error) {
+	fs := worktree.Filesystem
+	restore = func() error {
+		return restorePreserved(fs)
+	}
+
+	err = restore()
+	if err != nil {
+		return
+	}
+
+	idx, err := db.gitRepo.Storer.Index()
+	if err != nil {
+		err = fmt.Errorf("cannot read the index: %w", err)
+		return
+	}
+
+	tracked := make(map[string]struct{}, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		tracked[entry.Name] = struct{}{}
+	}
+
+	files := make([]string, 0)
+	err = walkFiles(fs, "", func(filePath string) error {
+		if _, exist := tracked[filePath]; !exist && db.isPreserved(filePath) {
+			files = append(files, filePath)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	for _, filePath := range files {
+		err = moveFile(fs, filePath, path.Join(preserveStashDir, filePath))
+		if err != nil {
+			err = fmt.Errorf("cannot preserve '%s': %w", filePath, err)
+			return
+		}
+	}
+
+	return
+}
+
+// restorePreserved moves the files in preserveStashDir back, unless the checkout has created the same file.
+func restorePreserved(fs billy.Filesystem) (err error) {
+	_, err = fs.Lstat(preserveStashDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot stat '%s': %w", preserveStashDir, err)
+	}
+
+	err = walkFiles(fs, preserveStashDir, func(stashed string) error {
+		filePath := strings.TrimPrefix(stashed, preserveStashDir+"/")
+		if _, statErr := fs.Lstat(filePath); statErr == nil {
+			return nil
+		}
+
+		moveErr := moveFile(fs, stashed, filePath)
+		if moveErr != nil {
+			return fmt.Errorf("cannot restore preserved '%s': %w", filePath, moveErr)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	return util.RemoveAll(fs, preserveStashDir)
+}
+
+// walkFiles calls fn with the path of every file (or symlink) under dir, except the .git directory of the root.
+func walkFiles(fs billy.Filesystem, dir string, fn func(filePath string) error) error {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("cannot read directory '%s': %w", dir, err)
+	}
+
+	for _, info := range infos {
+		if dir == "" && info.Name() == git.GitDirName {
+			continue
+		}
+
+		filePath := path.Join(dir, info.Name())
+		if info.IsDir() {
+			err = walkFiles(fs, filePath, fn)
+		} else {
+			err = fn(filePath)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveFile renames the file, creating the parent directories of the destination.
+func moveFile(fs billy.Filesystem, from, to string) error {
+	err := fs.MkdirAll(path.Dir(to), 0755)
+	if err != nil {
+		return err
+	}
+
+	return fs.Rename(from, to)
+}
@@ -0,0 +1,78 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithPreservePaths(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	volume := t.TempDir()
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithPreservePaths([]string{".cache", "*.tmp"}))
+
+	_, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	// the clone is in <volume>/<host>/<path>, and the host of file:// is empty
+	repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+	sidecars := map[string]string{
+		".cache/index/x": "cache",
+		"scratch.tmp":    "scratch",
+		"dir/notes.tmp":  "notes",
+		"junk.txt":       "junk",
+	}
+
+	for name, content := range sidecars {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoDir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644))
+	}
+
+	_, err = newTestDB(t, remote).Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	_, _, err = db.Upsert(ctx, "a.txt", []byte("a2"))
+	require.NoError(t, err)
+
+	data, err := db.Get(ctx, "b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+
+	for name, content := range sidecars {
+		data, err = os.ReadFile(filepath.Join(repoDir, name))
+		if name == "junk.txt" {
+			assert.ErrorIs(t, err, os.ErrNotExist)
+			continue
+		}
+
+		require.NoError(t, err, name)
+		assert.Equal(t, content, string(data))
+	}
+
+	// never committed
+	assert.Equal(t, []string{"a.txt", "b.txt"}, listKeys(mustList(t, newTestDB(t, remote))))
+
+	_, err = db.Create(ctx, "draft.tmp", []byte("x"))
+	assert.ErrorIs(t, err, gitrows.ErrInvalidKey)
+
+	_, _, err = db.Upsert(ctx, ".cache/y", []byte("x"))
+	assert.ErrorIs(t, err, gitrows.ErrInvalidKey)
+
+	_, err = gitrows.New(gitrows.WithPreservePaths([]string{"["}))
+	assert.Error(t, err)
+}
+
+func mustList(t *testing.T, db *gitrows.DBImpl) gitrows.Entries {
+	t.Helper()
+
+	entries, err := db.List(context.TODO())
+	require.NoError(t, err)
+	return entries
+}
@@ -0,0 +1,107 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// WithFetchPrune runs PruneLocal after every successful `git fetch`, like `git fetch --prune`.
+// Since go-git doesn't support pruning on fetch, this costs one more `git ls-remote` for every sync.
+func WithFetchPrune() Opt {
+	return func(db *DBImpl) error {
+		db.fetchPrune = true
+		return nil
+	}
+}
+
+// PruneLocal removes the local branches and remote-tracking references (refs/remotes/origin/*)
+// which branch no longer exists in the remote repository, i.e: left by deleted or renamed branch.
+// The branch of WithBranch is never removed, even when it doesn't exist in the remote repository yet.
+func (db *DBImpl) PruneLocal(ctx context.Context) (err error) {
+	defer func() {
+		err = wrapError(OpPruneLocal, "", err)
+	}()
+
+	if db.gitRepo == nil {
+		err = db.gitClone(ctx)
+		if err != nil {
+			err = fmt.Errorf("prune local command: %w", err)
+			return
+		}
+	}
+
+	err = db.pruneLocal(ctx)
+	if err != nil {
+		err = fmt.Errorf("prune local command: %w", err)
+		return
+	}
+
+	return
+}
+
+// pruneLocal removes the local references which branch doesn't exist in the remote repository.
+func (db *DBImpl) pruneLocal(ctx context.Context) (err error) {
+	refs, err := db.remoteRefs(ctx)
+	if err != nil {
+		return
+	}
+
+	remoteBranches := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		if ref.Name().IsBranch() {
+			remoteBranches[ref.Name().Short()] = struct{}{}
+		}
+	}
+
+	localRefs, err := db.gitRepo.References()
+	if err != nil {
+		err = fmt.Errorf("cannot list local references: %w", err)
+		return
+	}
+
+	remotePrefix := plumbing.NewRemoteReferenceName(gitRemoteName, "").String()
+	remoteHEAD := plumbing.NewRemoteHEADReferenceName(gitRemoteName)
+
+	stale := make([]plumbing.ReferenceName, 0)
+	err = localRefs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+
+		var branch string
+		switch {
+		case name.IsBranch():
+			branch = name.Short()
+		case strings.HasPrefix(name.String(), remotePrefix) && name != remoteHEAD:
+			branch = strings.TrimPrefix(name.String(), remotePrefix)
+		default:
+			// tag, HEAD, and the reference of other remotes are not managed by gitrows
+			return nil
+		}
+
+		if branch == db.gitBranch {
+			return nil
+		}
+
+		if _, exist := remoteBranches[branch]; !exist {
+			stale = append(stale, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot iterate local references: %w", err)
+		return
+	}
+
+	for _, name := range stale {
+		err = db.gitRepo.Storer.RemoveReference(name)
+		if err != nil {
+			err = fmt.Errorf("cannot remove local reference %s: %w", name, err)
+			return
+		}
+	}
+
+	return
+}
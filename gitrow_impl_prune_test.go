@@ -0,0 +1,108 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFetchPrune(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	remote, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()), WithFetchPrune())
+	require.NoError(t, err)
+
+	commitHash, err := db.Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	// the feature branch exists in the remote, and was fetched before into the local repository
+	head := plumbing.NewHash(commitHash)
+	feature := plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), head)
+	require.NoError(t, remote.Storer.SetReference(feature))
+
+	localRefs := []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName("feature"),
+		plumbing.NewRemoteReferenceName(gitRemoteName, "feature"),
+	}
+
+	for _, name := range localRefs {
+		require.NoError(t, db.gitRepo.Storer.SetReference(plumbing.NewHashReference(name, head)))
+	}
+
+	hasRef := func(name plumbing.ReferenceName) bool {
+		_, err := db.gitRepo.Reference(name, false)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return false
+		}
+
+		require.NoError(t, err)
+		return true
+	}
+
+	_, _, err = db.Upsert(ctx, "note.md", []byte("hello 2"))
+	require.NoError(t, err)
+
+	for _, name := range localRefs {
+		assert.True(t, hasRef(name), name)
+	}
+
+	// after the branch is deleted in the remote, the next sync removes the local references
+	require.NoError(t, remote.Storer.RemoveReference(feature.Name()))
+
+	_, _, err = db.Upsert(ctx, "note.md", []byte("hello 3"))
+	require.NoError(t, err)
+
+	for _, name := range localRefs {
+		assert.False(t, hasRef(name), name)
+	}
+
+	assert.True(t, hasRef(plumbing.NewBranchReferenceName("master")))
+
+	data, err := db.Get(ctx, "note.md")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello 3"), data)
+}
+
+func TestDBImpl_PruneLocal(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()), WithBranch("staging"))
+	require.NoError(t, err)
+
+	// the configured branch is never removed, even when the remote is still empty
+	require.NoError(t, db.PruneLocal(ctx))
+
+	commitHash, err := db.Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	stale := plumbing.NewRemoteReferenceName(gitRemoteName, "renamed")
+	require.NoError(t, db.gitRepo.Storer.SetReference(plumbing.NewHashReference(stale, plumbing.NewHash(commitHash))))
+
+	tag := plumbing.NewTagReferenceName("v1")
+	require.NoError(t, db.gitRepo.Storer.SetReference(plumbing.NewHashReference(tag, plumbing.NewHash(commitHash))))
+
+	require.NoError(t, db.PruneLocal(ctx))
+
+	_, err = db.gitRepo.Reference(stale, false)
+	assert.True(t, errors.Is(err, plumbing.ErrReferenceNotFound), err)
+
+	_, err = db.gitRepo.Reference(tag, false)
+	assert.NoError(t, err)
+
+	_, err = db.gitRepo.Reference(plumbing.NewBranchReferenceName("staging"), false)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,183 @@
+package gitrows
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// WithAtomicPush set whether the push requests the atomic capability, default is true.
+// When it is not set explicitly and the remote rejects the atomic push, the push is retried once without it.
+// Please note that go-git already skips the atomic capability when the remote doesn't advertise it,
+// so this is only needed for the remote which advertises but then rejects it.
+func WithAtomicPush(b bool) Opt {
+	return func(db *DBImpl) error {
+		db.atomicPush = b
+		db.atomicPushSet = true
+		return nil
+	}
+}
+
+// WithForcePush set whether the push is forced (`git push -f`), default is true.
+// Without force push, the push is rejected with CodeConflict when the remote is changed after the pull,
+// instead of overwriting the remote changes.
+func WithForcePush(b bool) Opt {
+	return func(db *DBImpl) error {
+		db.forcePush = b
+		return nil
+	}
+}
+
+// WithLinearHistory guarantees the linear history of the branch: every commit has exactly the remote tip as the parent.
+// The push is never forced (WithForcePush is ignored), and when the remote branch is moved after the pull,
+// the local commit is replayed on top of it and pushed again, like WithMergeResolver does.
+// When the same key is changed by both, it is resolved by WithMergeResolver if any, otherwise the write fails
+// with ErrPushRejected. So does the write which is still rejected after the retries.
+func WithLinearHistory() Opt {
+	return func(db *DBImpl) error {
+		db.linearHistory = true
+		return nil
+	}
+}
+
+// isAtomicPushUnsupported returns true when the push error is caused by the remote which doesn't support atomic push,
+// i.e: "the receiving end does not support --atomic push" or "server does not support atomic push".
+func isAtomicPushUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "atomic") &&
+		(strings.Contains(msg, "not support") || strings.Contains(msg, "unsupported"))
+}
+
+// nonFastForwardError wraps the rejected push into git.ErrNonFastForwardUpdate,
+// since go-git reports it as plain error without the sentinel, see codeOf.
+func nonFastForwardError(err error) error {
+	if err == nil || errors.Is(err, git.ErrNonFastForwardUpdate) || !strings.Contains(err.Error(), "non-fast-forward") {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", git.ErrNonFastForwardUpdate, strings.TrimPrefix(err.Error(), "non-fast-forward update: "))
+}
+
+// maxServerMessage is the number of bytes of the remote messages kept for ServerRejectionError.
+const maxServerMessage = 4096
+
+// pushCommandErrorRe matches the rejected ref reported by go-git, i.e: "command error on refs/heads/master: pre-receive hook declined".
+var pushCommandErrorRe = regexp.MustCompile(`command error on (\S+): (.+)$`)
+
+// push is like repo.PushContext, but keeps the messages sent by the remote during the push
+// (the pre-receive hook output), so the rejection by the server is returned as ServerRejectionError.
+func (db *DBImpl) push(ctx context.Context, repo *git.Repository, pushOpt *git.PushOptions) error {
+	// the copy is never changed after the push is abandoned, see remoteCall
+	opt := *pushOpt
+	capture := &serverMessage{}
+	if opt.Progress == nil {
+		opt.Progress = capture
+	} else {
+		opt.Progress = io.MultiWriter(opt.Progress, capture)
+	}
+
+	err := db.remoteCall(ctx, func(ctx context.Context) error {
+		return repo.PushContext(ctx, &opt)
+	})
+	return serverRejectionError(err, capture.String())
+}
+
+// serverRejectionError returns ServerRejectionError when the push is declined by the server,
+// i.e: by the pre-receive hook or the protected branch, rather than the remote branch being changed.
+func serverRejectionError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	m := pushCommandErrorRe.FindStringSubmatch(err.Error())
+	if len(m) < 3 {
+		return err
+	}
+
+	status := strings.ToLower(m[2])
+	if strings.Contains(status, "non-fast-forward") || strings.Contains(status, "fetch first") {
+		return err
+	}
+
+	for _, reason := range []string{"declined", "denied", "protected", "prohibited", "hook"} {
+		if strings.Contains(status, reason) {
+			return &ServerRejectionError{
+				Ref:     m[1],
+				Status:  m[2],
+				Message: message,
+				err:     err,
+			}
+		}
+	}
+
+	return err
+}
+
+// serverMessage collects the messages of the remote, without the progress of counting or compressing objects.
+type serverMessage struct {
+	mu    sync.Mutex // the abandoned push may still write, see remoteCall
+	buf   bytes.Buffer
+	lines []string
+	size  int
+}
+
+var _ io.Writer = (*serverMessage)(nil)
+
+func (s *serverMessage) Write(b []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err = s.buf.Write(b)
+	if err != nil {
+		return
+	}
+
+	for {
+		data := s.buf.Bytes()
+		idx := bytes.IndexAny(data, "\r\n")
+		if idx < 0 {
+			return
+		}
+
+		s.add(string(data[:idx]))
+		s.buf.Next(idx + 1)
+	}
+}
+
+func (s *serverMessage) add(line string) {
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "remote:"))
+	if line == "" || progressLineRe.MatchString(line) || strings.HasPrefix(line, "Total ") {
+		return
+	}
+
+	if s.size+len(line) > maxServerMessage {
+		return
+	}
+
+	s.size += len(line) + 1
+	s.lines = append(s.lines, line)
+}
+
+// String returns the collected messages, including the last message without trailing newline.
+func (s *serverMessage) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buf.Len() > 0 {
+		s.add(s.buf.String())
+		s.buf.Reset()
+	}
+
+	return strings.Join(s.lines, "\n")
+}
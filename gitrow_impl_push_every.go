@@ -0,0 +1,198 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// WithPushEvery makes Create, Upsert and Delete commit locally, and only push when n commits are not pushed yet
+// (including the commit of the write) or on Flush and Close, i.e: to cut the round-trips of the bursty writer
+// while still keeping one commit per write in the history. The returned CommitHash is the local commit,
+// and CreateResult.Pushed tells whether the write is pushed. The other write commands push right away,
+// including the former commits which are not pushed yet.
+//
+// The unpushed commits are counted from the local branch to the remote-tracking reference, which is kept
+// in the local repository, so they are still pushed by the next write after restart from the same volume.
+// As long as there is any unpushed commit, nothing is fetched: the read serves the local branch,
+// and the write is on top of it. So the remote changes in the meantime are overwritten by the default force push,
+// or rejected with CodeConflict by WithForcePush(false), since the batch is not reconciled by WithMergeResolver
+// nor WithLinearHistory. When the push fails, only the commit of the write which pushes is rolled back.
+// One means pushing every commit, which is the default.
+func WithPushEvery(n int) Opt {
+	return func(db *DBImpl) error {
+		if n < 1 {
+			return fmt.Errorf("push every must be positive, got %d", n)
+		}
+
+		db.pushEvery = n
+		return nil
+	}
+}
+
+// pushBatched is gitPush, but only commits locally with WithPushEvery until there are enough unpushed commits.
+// It returns the local commit with pushed false when nothing is pushed.
+func (db *DBImpl) pushBatched(ctx context.Context, op Op) (commit plumbing.Hash, stats PushStats, pushed bool, err error) {
+	if db.pushEvery > 1 {
+		var unpushed int
+		unpushed, err = db.unpushedCommits()
+		if err != nil {
+			return
+		}
+
+		if unpushed < db.pushEvery {
+			var head *object.Commit
+			head, err = db.headCommit()
+			if err != nil || head == nil {
+				return
+			}
+
+			return head.Hash, stats, false, nil
+		}
+	}
+
+	commit, stats, err = db.gitPush(ctx, op)
+	return commit, stats, err == nil, err
+}
+
+// pushPending pushes the commits left unpushed by WithPushEvery, see Flush. Unlike gitPush, the local branch
+// is never rolled back since the commits are already returned to the writes, so they are pushed again next time.
+func (db *DBImpl) pushPending(ctx context.Context) (err error) {
+	unpushed, err := db.hasUnpushed()
+	if err != nil || !unpushed {
+		return
+	}
+
+	var pushed plumbing.Hash
+	var stats PushStats
+	startedAt := time.Now()
+	defer func() {
+		db.reportPush(OpFlush, pushed, stats, startedAt, err)
+	}()
+
+	local, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	base, err := db.trackedCommit()
+	if err != nil {
+		return
+	}
+
+	stats.Bytes, err = db.checkPushSize(local, base)
+	if err != nil {
+		return
+	}
+
+	stats.Attempts = 1
+	err = db.pushBranch(ctx)
+	if err != nil {
+		return
+	}
+
+	pushed = local.Hash
+	db.markPushed(pushed)
+	return db.trackRemote()
+}
+
+// hasUnpushed returns true when WithPushEvery leaves any commit unpushed, so the local branch must not be moved
+// by the fetch.
+func (db *DBImpl) hasUnpushed() (bool, error) {
+	if db.pushEvery <= 1 {
+		return false, nil
+	}
+
+	unpushed, err := db.unpushedCommits()
+	return unpushed > 0, err
+}
+
+// unpushedCommits counts the first parents of the local branch until the commit of the remote-tracking reference,
+// up to WithPushEvery. Without the reference (i.e: the remote is empty), every commit of the local branch is unpushed.
+func (db *DBImpl) unpushedCommits() (unpushed int, err error) {
+	if db.gitRepo == nil {
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil || head == nil {
+		return
+	}
+
+	tracked, err := db.trackedCommit()
+	if err != nil {
+		return
+	}
+
+	for commit := head; commit != nil && unpushed < db.pushEvery; {
+		if tracked != nil && commit.Hash == tracked.Hash {
+			break
+		}
+
+		unpushed++
+		if commit.NumParents() == 0 {
+			break
+		}
+
+		// the parent of the shallow commit is never fetched, so it is not pushed by this DB either
+		commit, err = db.gitRepo.CommitObject(commit.ParentHashes[0])
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			commit, err = nil, nil
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// trackedCommit returns the commit of the remote-tracking reference, or nil when it doesn't exist.
+func (db *DBImpl) trackedCommit() (commit *object.Commit, err error) {
+	trackingName := plumbing.NewRemoteReferenceName(gitRemoteName, db.gitBranch)
+	ref, err := db.gitRepo.Storer.Reference(trackingName)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot get reference %s: %w", trackingName, err)
+		return
+	}
+
+	commit, err = db.gitRepo.CommitObject(ref.Hash())
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		commit, err = nil, nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot get commit %s: %w", ref.Hash(), err)
+		return
+	}
+
+	return
+}
+
+// trackRemote points the remote-tracking reference to the local branch, after the local branch is the same as
+// the remote branch (pushed or fetched), so unpushedCommits counts from it like `git status` does.
+func (db *DBImpl) trackRemote() (err error) {
+	head, err := db.headCommit()
+	if err != nil || head == nil {
+		return
+	}
+
+	trackingName := plumbing.NewRemoteReferenceName(gitRemoteName, db.gitBranch)
+	err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(trackingName, head.Hash))
+	if err != nil {
+		err = fmt.Errorf("cannot set reference %s: %w", trackingName, err)
+		return
+	}
+
+	return
+}
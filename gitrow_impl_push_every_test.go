@@ -0,0 +1,151 @@
+package gitrows_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithPushEvery(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("push every n commits", func(t *testing.T) {
+		remote := newTestRemote(t)
+		db := newTestDB(t, remote, gitrows.WithPushEvery(3))
+
+		first, err := db.CreateR(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+		assert.False(t, first.Pushed)
+		assert.True(t, first.PushedAt.IsZero())
+
+		keys, err := newTestDB(t, remote).Keys(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+
+		second, err := db.UpsertR(ctx, "b.txt", []byte("b"))
+		require.NoError(t, err)
+		assert.False(t, second.Pushed)
+
+		// the read serves the local commits
+		data, err := db.Get(ctx, "b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(data))
+
+		third, err := db.CreateR(ctx, "c.txt", []byte("c"))
+		require.NoError(t, err)
+		assert.True(t, third.Pushed)
+		assert.False(t, third.PushedAt.IsZero())
+		assert.Equal(t, remoteHead(t, remote), third.CommitHash)
+
+		// one commit per write
+		commit := remoteCommit(t, remote, third.CommitHash)
+		require.Equal(t, 1, commit.NumParents())
+		assert.Equal(t, second.CommitHash, commit.ParentHashes[0].String())
+
+		deleted, err := db.DeleteR(ctx, "a.txt")
+		require.NoError(t, err)
+		assert.False(t, deleted.Pushed)
+		assert.Equal(t, third.CommitHash, remoteHead(t, remote))
+
+		require.NoError(t, db.Flush(ctx))
+		assert.Equal(t, deleted.CommitHash, remoteHead(t, remote))
+
+		// nothing left to push
+		require.NoError(t, db.Flush(ctx))
+
+		keys, err = newTestDB(t, remote).Keys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b.txt", "c.txt"}, keys)
+	})
+
+	t.Run("fetches after the push", func(t *testing.T) {
+		remote := newTestRemote(t)
+		db := newTestDB(t, remote, gitrows.WithPushEvery(2))
+
+		_, err := db.Create(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+
+		_, err = db.Create(ctx, "b.txt", []byte("b"))
+		require.NoError(t, err)
+
+		_, err = newTestDB(t, remote).Create(ctx, "other.txt", []byte("o"))
+		require.NoError(t, err)
+
+		data, err := db.Get(ctx, "other.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "o", string(data))
+	})
+
+	t.Run("restart from the same volume", func(t *testing.T) {
+		remote := newTestRemote(t)
+		volume := t.TempDir()
+
+		_, err := newTestDB(t, remote).Create(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+
+		before := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithPushEvery(2))
+		result, err := before.UpsertR(ctx, "b.txt", []byte("b"))
+		require.NoError(t, err)
+		assert.False(t, result.Pushed)
+
+		after := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithPushEvery(2))
+		data, err := after.Get(ctx, "b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(data))
+
+		result, err = after.UpsertR(ctx, "c.txt", []byte("c"))
+		require.NoError(t, err)
+		assert.True(t, result.Pushed)
+
+		keys, err := newTestDB(t, remote).Keys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, keys)
+	})
+
+	t.Run("close pushes", func(t *testing.T) {
+		remote := newTestRemote(t)
+		db := newTestDB(t, remote, gitrows.WithPushEvery(10))
+
+		commitHash, err := db.Create(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+		assert.Equal(t, commitHash, remoteHead(t, remote))
+	})
+
+	t.Run("max push bytes of the batch", func(t *testing.T) {
+		remote := newTestRemote(t)
+		pushes := make([]gitrows.PushStats, 0)
+		db := newTestDB(t, remote, gitrows.WithPushEvery(3), gitrows.WithMaxPushBytes(1000),
+			gitrows.WithOnPush(func(stats gitrows.PushStats) {
+				pushes = append(pushes, stats)
+			}),
+		)
+
+		// each commit is under the limit, but not the three of them
+		for _, key := range []string{"a.txt", "b.txt"} {
+			_, err := db.Create(ctx, key, bytes.Repeat([]byte("x"), 300))
+			require.NoError(t, err)
+		}
+
+		_, err := db.Create(ctx, "c.txt", bytes.Repeat([]byte("x"), 300))
+		assert.ErrorIs(t, err, gitrows.ErrPushTooLarge)
+
+		require.Len(t, pushes, 1)
+		assert.Greater(t, pushes[0].Bytes, int64(900))
+
+		// only the commit of the failing write is rolled back
+		require.NoError(t, db.Flush(ctx))
+		keys, err := newTestDB(t, remote).Keys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "b.txt"}, keys)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := gitrows.New(gitrows.WithGitSshUrl(newTestRemote(t)), gitrows.WithPushEvery(0))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,183 @@
+package gitrows
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// PushStats is the push of one write, reported to the callback of WithOnPush.
+type PushStats struct {
+	Op Op
+
+	// Commit is the pushed commit, or empty when the push fails.
+	Commit string
+
+	// Bytes is the approximated size of the push, see CreateResult.BytesPushed.
+	Bytes int64
+
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+}
+
+// WithOnPush set the callback which is called after every push of the write commands, succeed or not,
+// i.e: to record the growth of the repository per command, and alert when one write is unexpectedly large.
+func WithOnPush(fn func(stats PushStats)) Opt {
+	return func(db *DBImpl) error {
+		db.onPush = fn
+		return nil
+	}
+}
+
+// WithMaxPushBytes makes the write commands fail with ErrPushTooLarge before pushing, when the size of the commit
+// (see CreateResult.BytesPushed) is larger than n bytes, i.e: to stop the write which accidentally commits
+// the whole directory of dependencies. The local commit is rolled back, so nothing is written at all.
+// Zero means no limit, which is the default.
+func WithMaxPushBytes(n int64) Opt {
+	return func(db *DBImpl) error {
+		if n < 0 {
+			return fmt.Errorf("max push bytes must not be negative, got %d", n)
+		}
+
+		db.maxPushBytes = n
+		return nil
+	}
+}
+
+// checkPushSize returns the size of the objects of commit which are not in base (i.e: the remote-tracking commit,
+// so the commits batched by WithPushEvery are measured together), or ErrPushTooLarge when it is larger than
+// WithMaxPushBytes. The base may be nil when the remote branch doesn't exist yet.
+//
+// go-git doesn't report the size of the packfile it sends, so the size is the sum of the uncompressed objects
+// created by the commit instead, which is larger than the packfile since git compresses and deltifies them.
+func (db *DBImpl) checkPushSize(commit, base *object.Commit) (size int64, err error) {
+	if commit == nil {
+		return
+	}
+
+	size, err = db.objectSize(plumbing.CommitObject, commit.Hash)
+	if err != nil {
+		return
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		err = fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.Hash, err)
+		return
+	}
+
+	var baseTree *object.Tree
+	if base != nil {
+		baseTree, err = base.Tree()
+		if err != nil {
+			err = fmt.Errorf("retrieve the tree from the commit %s error: %w", base.Hash, err)
+			return
+		}
+	}
+
+	treeSize, err := db.newTreeSize(tree, baseTree)
+	if err != nil {
+		return
+	}
+
+	size += treeSize
+	if db.maxPushBytes > 0 && size > db.maxPushBytes {
+		err = fmt.Errorf("%w: the push up to commit %s adds %d bytes, the limit is %d bytes", ErrPushTooLarge, commit.Hash, size, db.maxPushBytes)
+		return
+	}
+
+	return
+}
+
+// newTreeSize returns the size of tree and every object under it which is not at the same path in base,
+// only walking the subtrees which are different. The base may be nil.
+func (db *DBImpl) newTreeSize(tree, base *object.Tree) (size int64, err error) {
+	if base != nil && tree.Hash == base.Hash {
+		return
+	}
+
+	size, err = db.objectSize(plumbing.TreeObject, tree.Hash)
+	if err != nil {
+		return
+	}
+
+	baseEntries := make(map[string]object.TreeEntry)
+	if base != nil {
+		for _, entry := range base.Entries {
+			baseEntries[entry.Name] = entry
+		}
+	}
+
+	for _, entry := range tree.Entries {
+		baseEntry, exist := baseEntries[entry.Name]
+		if exist && baseEntry.Hash == entry.Hash {
+			continue
+		}
+
+		var entrySize int64
+		switch entry.Mode {
+		case filemode.Submodule:
+			// the commit of the submodule is not in this repository
+			continue
+
+		case filemode.Dir:
+			var subtree, baseSubtree *object.Tree
+			subtree, err = object.GetTree(db.gitRepo.Storer, entry.Hash)
+			if err != nil {
+				err = fmt.Errorf("cannot get tree %s: %w", entry.Hash, err)
+				return
+			}
+
+			if exist && baseEntry.Mode == filemode.Dir {
+				baseSubtree, err = object.GetTree(db.gitRepo.Storer, baseEntry.Hash)
+				if err != nil {
+					err = fmt.Errorf("cannot get tree %s: %w", baseEntry.Hash, err)
+					return
+				}
+			}
+
+			entrySize, err = db.newTreeSize(subtree, baseSubtree)
+
+		default:
+			entrySize, err = db.objectSize(plumbing.BlobObject, entry.Hash)
+		}
+
+		if err != nil {
+			return
+		}
+
+		size += entrySize
+	}
+
+	return
+}
+
+// objectSize returns the uncompressed size of the object, without reading its content.
+func (db *DBImpl) objectSize(objectType plumbing.ObjectType, hash plumbing.Hash) (int64, error) {
+	obj, err := db.gitRepo.Storer.EncodedObject(objectType, hash)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get %s %s: %w", objectType, hash, err)
+	}
+
+	return obj.Size(), nil
+}
+
+// reportPush calls the callback of WithOnPush, if any.
+func (db *DBImpl) reportPush(op Op, pushed plumbing.Hash, stats PushStats, startedAt time.Time, err error) {
+	if db.onPush == nil {
+		return
+	}
+
+	stats.Op = op
+	stats.Elapsed = time.Since(startedAt)
+	stats.Err = err
+	if err == nil {
+		stats.Commit = pushed.String()
+	}
+
+	db.onPush(stats)
+}
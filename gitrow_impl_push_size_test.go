@@ -0,0 +1,60 @@
+package gitrows_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestPushSize(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	pushes := make([]gitrows.PushStats, 0)
+	db := newTestDB(t, remote, gitrows.WithMaxPushBytes(4096), gitrows.WithOnPush(func(stats gitrows.PushStats) {
+		pushes = append(pushes, stats)
+	}))
+
+	created, err := db.CreateR(ctx, "users/1.json", bytes.Repeat([]byte("a"), 1000))
+	require.NoError(t, err)
+	assert.Greater(t, created.BytesPushed, int64(1000))
+
+	require.Len(t, pushes, 1)
+	assert.Equal(t, gitrows.OpCreate, pushes[0].Op)
+	assert.Equal(t, created.CommitHash, pushes[0].Commit)
+	assert.Equal(t, created.BytesPushed, pushes[0].Bytes)
+	assert.Equal(t, 1, pushes[0].Attempts)
+	assert.NoError(t, pushes[0].Err)
+
+	// the unchanged value of the other key is not pushed again
+	upserted, err := db.UpsertR(ctx, "users/2.json", []byte("b"))
+	require.NoError(t, err)
+	assert.Less(t, upserted.BytesPushed, int64(1000))
+
+	deleted, err := db.DeleteR(ctx, "users/2.json")
+	require.NoError(t, err)
+	assert.Greater(t, deleted.BytesPushed, int64(0))
+
+	// too large, so nothing is pushed nor kept in the local branch
+	tip := remoteHead(t, remote)
+	_, err = db.Create(ctx, "node_modules/big.js", bytes.Repeat([]byte("x"), 5000))
+	assert.ErrorIs(t, err, gitrows.ErrPushTooLarge)
+	assert.Equal(t, gitrows.CodeValueTooLarge, gitrows.ErrorCode(err))
+	assert.Equal(t, tip, remoteHead(t, remote))
+
+	last := pushes[len(pushes)-1]
+	assert.ErrorIs(t, last.Err, gitrows.ErrPushTooLarge)
+	assert.Empty(t, last.Commit)
+	assert.Greater(t, last.Bytes, int64(5000))
+
+	_, err = db.Get(ctx, "node_modules/big.js")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = gitrows.New(gitrows.WithGitSshUrl(remote), gitrows.WithMaxPushBytes(-1))
+	assert.Error(t, err)
+}
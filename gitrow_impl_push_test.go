@@ -0,0 +1,194 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAtomicPushUnsupported(t *testing.T) {
+	assert.False(t, isAtomicPushUnsupported(nil))
+	assert.False(t, isAtomicPushUnsupported(errors.New("non-fast-forward update")))
+	assert.True(t, isAtomicPushUnsupported(errors.New("the receiving end does not support --atomic push")))
+	assert.True(t, isAtomicPushUnsupported(fmt.Errorf("push: %w", errors.New("server does not support atomic push"))))
+	assert.True(t, isAtomicPushUnsupported(errors.New("atomic push unsupported")))
+}
+
+func TestNonFastForwardError(t *testing.T) {
+	assert.NoError(t, nonFastForwardError(nil))
+	assert.True(t, errors.Is(nonFastForwardError(errors.New("non-fast-forward update: refs/heads/master")), git.ErrNonFastForwardUpdate))
+	assert.True(t, errors.Is(nonFastForwardError(git.ErrNonFastForwardUpdate), git.ErrNonFastForwardUpdate))
+	assert.False(t, errors.Is(nonFastForwardError(errors.New("other")), git.ErrNonFastForwardUpdate))
+}
+
+func TestWithForcePush(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func(opts ...Opt) *DBImpl {
+		opts = append([]Opt{WithGitSshUrl("file://" + remoteDir), WithLocalGitVolume(t.TempDir())}, opts...)
+		db, err := New(opts...)
+		require.NoError(t, err)
+		return db
+	}
+
+	_, err = newDB().Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	// commit on top of the stale local branch, then push it after the remote is changed
+	stalePush := func(db *DBImpl) error {
+		require.NoError(t, db.forcePull(ctx))
+
+		_, err := newDB().Create(ctx, fmt.Sprintf("other-%p.md", db), []byte("other"))
+		require.NoError(t, err)
+
+		worktree, err := db.writeFile(ctx, "stale.md", []byte("stale"), "CREATE", 0)
+		require.NoError(t, err)
+
+		_, err = db.gitCommit(ctx, worktree, "stale", false)
+		require.NoError(t, err)
+
+		_, _, err = db.gitPush(ctx, OpUpsert)
+		return err
+	}
+
+	err = stalePush(newDB(WithForcePush(false), WithAtomicPush(false)))
+	assert.True(t, errors.Is(err, git.ErrNonFastForwardUpdate), err)
+	assert.Equal(t, CodeConflict, codeOf(err))
+
+	// force push by default overwrites the remote change
+	assert.NoError(t, stalePush(newDB()))
+}
+
+func TestWithLinearHistory(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func(opts ...Opt) *DBImpl {
+		opts = append([]Opt{WithGitSshUrl("file://" + remoteDir), WithLocalGitVolume(t.TempDir())}, opts...)
+		db, err := New(opts...)
+		require.NoError(t, err)
+		return db
+	}
+
+	_, err = newDB().Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	// WithForcePush is ignored
+	db := newDB(WithLinearHistory(), WithForcePush(true))
+	assert.False(t, db.forcePush)
+
+	// the local commit is made after the pull, but another writer pushes before the push
+	racingWrite := func(key string, data []byte, otherKey string) (pushed, otherHash string, attempts int, err error) {
+		require.NoError(t, db.forcePull(ctx))
+
+		otherHash, _, err = newDB().Upsert(ctx, otherKey, []byte("other"))
+		require.NoError(t, err)
+
+		worktree, err := db.writeFile(ctx, key, data, "UPSERT", 0)
+		require.NoError(t, err)
+
+		_, err = db.gitCommit(ctx, worktree, "linear", false)
+		require.NoError(t, err)
+
+		var hash plumbing.Hash
+		var stats PushStats
+		hash, stats, err = db.gitPush(ctx, OpUpsert)
+		return hash.String(), otherHash, stats.Attempts, err
+	}
+
+	// the local commit is replayed with the remote tip as the only parent
+	pushed, otherHash, attempts, err := racingWrite("linear.md", []byte("linear"), "other.md")
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	reader := newDB()
+	head, err := reader.remoteHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, head, pushed)
+
+	require.NoError(t, reader.forcePull(ctx))
+	commit, err := reader.headCommit()
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{plumbing.NewHash(otherHash)}, commit.ParentHashes)
+
+	values, err := reader.GetMany(ctx, []string{"linear.md", "other.md"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"linear.md": []byte("linear"), "other.md": []byte("other")}, values)
+
+	// the same key changed by both cannot be replayed without WithMergeResolver
+	_, otherHash, _, err = racingWrite("note.md", []byte("local"), "note.md")
+	assert.True(t, errors.Is(err, ErrPushRejected), err)
+	assert.Equal(t, CodeConflict, codeOf(err))
+
+	head, err = reader.remoteHead(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, otherHash, head)
+}
+
+func TestServerRejectionError(t *testing.T) {
+	assert.NoError(t, serverRejectionError(nil, ""))
+
+	nonFastForward := errors.New("command error on refs/heads/master: non-fast-forward update")
+	assert.Equal(t, nonFastForward, serverRejectionError(nonFastForward, ""))
+
+	other := errors.New("unexpected EOF")
+	assert.Equal(t, other, serverRejectionError(other, "remote closed"))
+
+	err := serverRejectionError(errors.New("command error on refs/heads/master: protected branch hook declined"), "")
+	assert.True(t, errors.Is(err, ErrPushRejectedByServer), err)
+	assert.Equal(t, "push rejected by server: refs/heads/master: protected branch hook declined", err.Error())
+
+	var msg serverMessage
+	_, _ = msg.Write([]byte("Counting objects:  50% (1/2)\rCounting objects: 100% (2/2), done.\n"))
+	_, _ = msg.Write([]byte("remote: first line\nsecond"))
+	assert.Equal(t, "first line\nsecond", msg.String())
+}
+
+func TestPush_rejectedByHook(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func() *DBImpl {
+		db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+		require.NoError(t, err)
+		return db
+	}
+
+	_, err = newDB().Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	// receive-pack runs the hook of the bare remote
+	hook := "#!/bin/sh\necho 'GL-HOOK-ERR: branch master is protected' >&2\nexit 1\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(remoteDir, "hooks"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(remoteDir, "hooks", "pre-receive"), []byte(hook), 0755))
+
+	_, err = newDB().Create(ctx, "blocked.md", []byte("blocked"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrPushRejectedByServer), err)
+	assert.Equal(t, CodeRejectedByServer, ErrorCode(err))
+
+	var rejection *ServerRejectionError
+	require.True(t, errors.As(err, &rejection), err)
+	assert.Equal(t, "refs/heads/master", rejection.Ref)
+	assert.Equal(t, "pre-receive hook declined", rejection.Status)
+	assert.Equal(t, "GL-HOOK-ERR: branch master is protected", rejection.Message)
+	assert.Contains(t, err.Error(), "GL-HOOK-ERR: branch master is protected")
+}
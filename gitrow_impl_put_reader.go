@@ -0,0 +1,251 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// PutReader is like Upsert, but streams the value from r into the worktree instead of taking all bytes at once,
+// so importing large value doesn't need to hold it in memory.
+// The value is written into temporary file first, then renamed into the key, therefore a failing read from r
+// never leaves the key half-written.
+func (db *DBImpl) PutReader(ctx context.Context, key string, r io.Reader, opts ...UpsertOpt) (commitHashString string, err error) {
+	defer func() {
+		err = wrapError(OpPutReader, key, err)
+		db.recordOp(OpPutReader, key, commitHashString, err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	cfg := &UpsertConfig{
+		allowEmptyCommit: false,
+	}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("put reader command: %w", err)
+			return
+		}
+	}
+
+	key, err = validateInternalKey(key, cfg.allowInternal)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	filePath, err := db.keyToPath(key)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	err = db.flushBuffered(ctx, key)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	err = db.forcePull(ctx)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	err = db.checkExpectedHead(cfg.expectedHead)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	// the value is not read from r at all when it is the same
+	sameFile, err := db.committedBlob(filePath, cfg.ifDifferentFrom)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	if sameFile != nil {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("put reader command: cannot get HEAD reference: %w", err)
+			return
+		}
+
+		commitHashString = head.Hash().String()
+		return
+	}
+
+	worktree, err := db.streamFile(ctx, filePath, r, cfg.fileMode)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	worktreeStatus, err := worktree.Status()
+	if err != nil {
+		err = fmt.Errorf("put reader command: cannot `git status`: %w", err)
+		return
+	}
+
+	fileStatus, exist := worktreeStatus[filePath]
+	changed := exist && fileStatus.Staging != git.Unmodified
+
+	if !cfg.allowEmptyCommit && !changed {
+		var head *plumbing.Reference
+		head, err = db.gitRepo.Head()
+		if err != nil {
+			err = fmt.Errorf("put reader command: cannot get HEAD reference: %w", err)
+			return
+		}
+
+		commitHashString = head.Hash().String()
+		return
+	}
+
+	var size int64
+	if fileInfo, statErr := worktree.Filesystem.Stat(filePath); statErr == nil {
+		size = fileInfo.Size()
+	}
+
+	commitMsg := db.commitMessage(OpPutReader, cfg.commitMsg, "gitrows: PUT", CommitMeta{
+		Sizes:    map[string]int64{key: size},
+		Changed:  changed,
+		Metadata: cfg.meta,
+	})
+
+	var commitHash plumbing.Hash
+	commitHash, err = db.gitCommit(ctx, worktree, commitMsg, cfg.allowEmptyCommit)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	commitHash, _, err = db.gitPush(ctx, OpPutReader)
+	if err != nil {
+		err = fmt.Errorf("put reader command: %w", err)
+		return
+	}
+
+	commitHashString = commitHash.String()
+
+	return
+}
+
+// streamFile is like writeFile in mode UPSERT, but copy the content from r into temporary file
+// in the same directory, and then rename it into the key. After that, it does `git add` command.
+func (db *DBImpl) streamFile(ctx context.Context, key string, r io.Reader, fileMode os.FileMode) (worktree *git.Worktree, err error) {
+	key = path.Clean(key)
+
+	err = db.checkCaseCollision(key)
+	if err != nil {
+		return
+	}
+
+	err = db.checkNotPreserved(key)
+	if err != nil {
+		return
+	}
+
+	worktree, err = db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("cannot get worktree: %w", err)
+		return
+	}
+
+	fs := worktree.Filesystem
+
+	fileInfo, err := fs.Stat(key)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot put '%s' because os.Stat is error: %w", key, err)
+		return
+	}
+
+	if fileInfo != nil && fileInfo.IsDir() {
+		err = fmt.Errorf("cannot put '%s' because os.Stat said that key is a directory", key)
+		return
+	}
+
+	dir := path.Dir(key)
+	err = fs.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		err = fmt.Errorf("cannot create directory '%s': %w", dir, err)
+		return
+	}
+
+	tmpFile, err := createTempFile(fs, dir, "."+path.Base(key)+".gitrows-tmp-", filePerm(fileInfo, fileMode))
+	if err != nil {
+		err = fmt.Errorf("cannot create temporary file for '%s': %w", key, err)
+		return
+	}
+
+	tmpName := tmpFile.Name()
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = fs.Remove(tmpName)
+		}
+	}()
+
+	_, err = io.Copy(tmpFile, db.normalizeLineEndingReader(&ctxReader{ctx: ctx, r: r}))
+	if err != nil {
+		_ = tmpFile.Close()
+		err = fmt.Errorf("cannot write '%s': %w", key, err)
+		return
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		err = fmt.Errorf("failed to close temporary file of '%s': %w", key, err)
+		return
+	}
+
+	err = fs.Rename(tmpName, key)
+	if err != nil {
+		err = fmt.Errorf("cannot rename temporary file into '%s': %w", key, err)
+		return
+	}
+
+	renamed = true
+
+	err = gitAdd(worktree, key)
+	if err != nil {
+		err = fmt.Errorf("cannot `git add %s`: %w", key, err)
+		return
+	}
+
+	return
+}
+
+// ctxReader stops reading from r when the ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (n int, err error) {
+	err = c.ctx.Err()
+	if err != nil {
+		return
+	}
+
+	return c.r.Read(p)
+}
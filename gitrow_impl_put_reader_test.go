@@ -0,0 +1,79 @@
+package gitrows_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+// failingReader returns data, then fails with err.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestDBImpl_PutReader(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	volume := t.TempDir()
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume))
+
+	value := bytes.Repeat([]byte("0123456789"), 100*1024)
+	commit, err := db.PutReader(ctx, "blobs/big.bin", bytes.NewReader(value), gitrows.UpsertCommitMsg("import big"))
+	require.NoError(t, err)
+	assert.Equal(t, "import big", strings.TrimSpace(remoteCommit(t, remote, commit).Message))
+
+	data, err := newTestDB(t, remote).Get(ctx, "blobs/big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, value, data)
+
+	// same content doesn't create new commit
+	sameCommit, err := db.PutReader(ctx, "blobs/big.bin", bytes.NewReader(value))
+	require.NoError(t, err)
+	assert.Equal(t, commit, sameCommit)
+
+	// failing reader must keep the previous value and no temporary file left
+	_, err = db.PutReader(ctx, "blobs/big.bin", &failingReader{data: []byte("partial"), err: io.ErrUnexpectedEOF})
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	data, err = db.Get(ctx, "blobs/big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, value, data)
+
+	files, err := os.ReadDir(filepath.Join(volume, strings.TrimPrefix(remote, "file://"), "blobs"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "big.bin", files[0].Name())
+
+	commit, err = db.PutReader(ctx, "blobs/big.bin", strings.NewReader("small"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sameCommit, commit)
+
+	data, err = db.Get(ctx, "blobs/big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("small"), data)
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = db.PutReader(canceledCtx, "blobs/other.bin", strings.NewReader("other"))
+	assert.Equal(t, gitrows.CodeCanceled, gitrows.ErrorCode(err))
+}
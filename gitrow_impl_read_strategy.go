@@ -0,0 +1,41 @@
+package gitrows
+
+import "fmt"
+
+// ReadStrategy is where the read commands read the value from, see WithReadStrategy.
+type ReadStrategy int
+
+const (
+	// ReadWorktree checks out the local branch into the worktree, and reads the value from the file,
+	// which is the default.
+	ReadWorktree ReadStrategy = iota
+
+	// ReadObjectStore reads the value from the blob of the local branch directly, so the read only fetches
+	// and never checks out the worktree.
+	ReadObjectStore
+)
+
+// WithReadStrategy set the ReadStrategy of Get, GetReader and List.
+// The other read commands (i.e: GetMany, ListTree) still read from the worktree, and so do the write commands,
+// which check out the worktree on their next sync (unless WithTreeWrites).
+//
+// With ReadWorktree, the file is locked (flock) while it is read, which is until the reader of GetReader is closed,
+// and the next write on the same worktree may change it under the reader.
+// With ReadObjectStore, nothing is locked: the blob is immutable, so the reader keeps returning the value
+// which was committed when it was opened, even after the key is changed.
+func WithReadStrategy(strategy ReadStrategy) Opt {
+	return func(db *DBImpl) error {
+		switch strategy {
+		case ReadWorktree, ReadObjectStore:
+			db.readStrategy = strategy
+			return nil
+		default:
+			return fmt.Errorf("unknown read strategy %d", strategy)
+		}
+	}
+}
+
+// readsObjects returns true when the read commands of WithReadStrategy read the blob instead of the worktree.
+func (db *DBImpl) readsObjects() bool {
+	return db.readStrategy == ReadObjectStore
+}
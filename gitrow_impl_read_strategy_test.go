@@ -0,0 +1,60 @@
+package gitrows_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithReadStrategy(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "users/1.json", []byte(`{"id":1}`))
+	require.NoError(t, err)
+
+	volume := t.TempDir()
+	repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithReadStrategy(gitrows.ReadObjectStore))
+
+	data, err := db.Get(ctx, "users/1.json", gitrows.GetRange(6, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(data))
+
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users/1.json"}, listKeys(entries))
+
+	reader, err := db.GetReader(ctx, "users/1.json")
+	require.NoError(t, err)
+
+	// the reads never check out the worktree
+	_, err = os.Stat(filepath.Join(repoDir, "users", "1.json"))
+	assert.True(t, os.IsNotExist(err), err)
+
+	// the write checks out the worktree, but the open reader still reads the blob
+	_, _, err = db.Upsert(ctx, "users/1.json", []byte(`{"id":2}`))
+	require.NoError(t, err)
+
+	data, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, `{"id":1}`, string(data))
+
+	data, err = db.Get(ctx, "users/1.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":2}`, string(data))
+
+	_, err = db.GetReader(ctx, "users/2.json")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = gitrows.New(gitrows.WithGitSshUrl(remote), gitrows.WithReadStrategy(gitrows.ReadStrategy(9)))
+	assert.Error(t, err)
+}
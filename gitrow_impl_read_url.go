@@ -0,0 +1,212 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/yusufsyaifudin/gitrows/pkg/giturl"
+)
+
+// gitMirrorRemoteName is the remote of WithReadURL, which is only fetched into its remote-tracking reference.
+const gitMirrorRemoteName = "mirror"
+
+// WithReadURL fetches the read commands (i.e: Get and List) from the read-only mirror of WithGitSshUrl,
+// i.e: the replica near the application, while the write commands always sync with and push to WithGitSshUrl.
+// The first sync still clones from WithGitSshUrl, and the mirror uses the key of WithPrivateKey
+// unless WithReadPrivateKey is set.
+//
+// The mirror lags behind the primary, so the read may return the older content than the last write by other DB.
+// The local branch is never moved back to the older mirror commit, so the DB always reads its own writes,
+// but the content written by other DB is only visible once the mirror has it.
+// When the mirror branch is diverged from the local branch (i.e: after the force push to the primary),
+// the mirror wins, the same as the sync with the primary.
+func WithReadURL(url string) Opt {
+	return func(db *DBImpl) error {
+		db.readURL = url
+		return nil
+	}
+}
+
+// WithReadPrivateKey is like WithPrivateKey, but only for the mirror of WithReadURL.
+func WithReadPrivateKey(key []byte, password string) Opt {
+	return func(db *DBImpl) error {
+		db.readPrivateKey = key
+		db.readPrivateKeyPwd = password
+		return nil
+	}
+}
+
+// initReadURL parses the url of WithReadURL and loads its authentication.
+func (db *DBImpl) initReadURL() (err error) {
+	if db.readURL == "" {
+		return
+	}
+
+	db.readURL, err = giturl.Parse(db.readURL)
+	if err != nil {
+		err = fmt.Errorf("error parse git read url: %w", err)
+		return
+	}
+
+	readURLParsed, err := url.Parse(db.readURL)
+	if err != nil {
+		err = fmt.Errorf("error parse url.Parse git read url: %w", err)
+		return
+	}
+
+	// local repository (file://) doesn't need any authentication.
+	if readURLParsed.Scheme == "file" {
+		return
+	}
+
+	key, password := db.readPrivateKey, db.readPrivateKeyPwd
+	if key == nil {
+		key, password = db.privateKey, db.privateKeyPwd
+	}
+
+	var authSSH *ssh.PublicKeys
+	authSSH, err = ssh.NewPublicKeys(db.gitSshUser, key, password)
+	if err != nil {
+		err = fmt.Errorf("error ssh private key load for read url: %w", err)
+		return
+	}
+
+	db.readAuth = authSSH
+	return
+}
+
+// readFetch is the fetch of the read commands: mirrorFetch when WithReadURL is set, otherwise gitFetch.
+func (db *DBImpl) readFetch(ctx context.Context) error {
+	if db.readURL == "" {
+		return db.gitFetch(ctx)
+	}
+
+	return db.mirrorFetch(ctx)
+}
+
+// mirrorFetch is like gitFetch, but fetches the branch of the mirror into refs/remotes/mirror/<branch>,
+// then moves the local branch to it unless the mirror commit is already in the local history.
+func (db *DBImpl) mirrorFetch(ctx context.Context) (err error) {
+	unpushed, err := db.hasUnpushed()
+	if err != nil || unpushed {
+		return
+	}
+
+	remote, err := db.ensureRemote(gitMirrorRemoteName, db.readURL)
+	if err != nil {
+		return
+	}
+
+	// git fetch mirror +<branch>:refs/remotes/mirror/<branch> --depth 1
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	mirrorName := plumbing.NewRemoteReferenceName(gitMirrorRemoteName, db.gitBranch)
+	refSpec := fmt.Sprintf("+%s:%s", branchName, mirrorName)
+	fetchOpt := &git.FetchOptions{
+		RemoteName: gitMirrorRemoteName,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(refSpec),
+		},
+		Depth:    1,
+		Auth:     db.readAuth,
+		Progress: db.progressWriter(),
+		Force:    true,
+	}
+
+	err = db.remoteCall(ctx, func(ctx context.Context) error {
+		return remote.FetchContext(ctx, fetchOpt)
+	})
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		err = db.fetchWithoutHaves(ctx, remote, fetchOpt)
+	}
+
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = nil // discard error when contain "already up-to-date" warning
+	}
+
+	if db.isEmptyRemote(err) && db.requireExistingBranch {
+		err = fmt.Errorf("%w: branch '%s' doesn't exist in %s", ErrBranchNotFound, db.gitBranch, db.readURL)
+		return
+	}
+
+	if db.isEmptyRemote(err) {
+		// the mirror doesn't have the branch yet, keep the local branch if any, otherwise like gitFetch does
+		err = nil
+		_, refErr := db.gitRepo.Storer.Reference(branchName)
+		if refErr == nil {
+			return
+		}
+
+		ref := plumbing.NewSymbolicReference(plumbing.HEAD, branchName)
+		err = db.gitRepo.Storer.SetReference(ref)
+		if err != nil {
+			err = fmt.Errorf("cannot set reference %s: %w", ref, err)
+			return
+		}
+
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot `git fetch %s %s --depth 1`: %w", gitMirrorRemoteName, refSpec, remoteError(db.readURL, err))
+		return
+	}
+
+	mirrorRef, err := db.gitRepo.Storer.Reference(mirrorName)
+	if err != nil {
+		err = fmt.Errorf("cannot get reference %s: %w", mirrorName, err)
+		return
+	}
+
+	behind, err := db.inLocalHistory(branchName, mirrorRef.Hash())
+	if err != nil || behind {
+		return
+	}
+
+	err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(branchName, mirrorRef.Hash()))
+	if err != nil {
+		err = fmt.Errorf("cannot set reference %s: %w", branchName, err)
+		return
+	}
+
+	return db.trackRemote()
+}
+
+// inLocalHistory returns true when the commit is the local branch or one of its first parents,
+// until the missing parent of the shallow commit.
+func (db *DBImpl) inLocalHistory(branchName plumbing.ReferenceName, hash plumbing.Hash) (bool, error) {
+	ref, err := db.gitRepo.Storer.Reference(branchName)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("cannot get reference %s: %w", branchName, err)
+	}
+
+	for current := ref.Hash(); ; {
+		if current == hash {
+			return true, nil
+		}
+
+		commit, err := db.gitRepo.CommitObject(current)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("cannot get commit %s: %w", current, err)
+		}
+
+		if commit.NumParents() == 0 {
+			return false, nil
+		}
+
+		current = commit.ParentHashes[0]
+	}
+}
@@ -0,0 +1,78 @@
+package gitrows_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithReadURL(t *testing.T) {
+	ctx := context.TODO()
+	primary := newTestRemote(t)
+	mirror := newTestRemote(t)
+
+	// replicate copies the master branch of the primary into the mirror
+	replicate := func() {
+		t.Helper()
+
+		repo, err := git.PlainOpen(strings.TrimPrefix(primary, "file://"))
+		require.NoError(t, err)
+
+		remote := git.NewRemote(repo.Storer, &config.RemoteConfig{Name: "mirror", URLs: []string{mirror}})
+		err = remote.Push(&git.PushOptions{
+			RemoteName: "mirror",
+			RefSpecs:   []config.RefSpec{"+refs/heads/master:refs/heads/master"},
+		})
+		if err != git.NoErrAlreadyUpToDate {
+			require.NoError(t, err)
+		}
+	}
+
+	get := func(db *gitrows.DBImpl, key string) string {
+		t.Helper()
+
+		data, err := db.Get(ctx, key)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	writer := newTestDB(t, primary)
+	_, err := writer.Create(ctx, "a.txt", []byte("1"))
+	require.NoError(t, err)
+	replicate()
+
+	reader := newTestDB(t, primary, gitrows.WithReadURL(mirror))
+	assert.Equal(t, "1", get(reader, "a.txt"))
+
+	// the mirror lags behind the primary
+	_, _, err = writer.Upsert(ctx, "a.txt", []byte("2"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", get(reader, "a.txt"))
+
+	// the write syncs with the primary, and the read doesn't move back to the older mirror commit
+	commitHash, err := reader.Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, remoteHead(t, primary), commitHash)
+	assert.Equal(t, "b", get(reader, "b.txt"))
+	assert.Equal(t, "2", get(reader, "a.txt"))
+
+	replicate()
+	_, _, err = writer.Upsert(ctx, "a.txt", []byte("3"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", get(reader, "a.txt"))
+
+	replicate()
+	assert.Equal(t, "3", get(reader, "a.txt"))
+	assert.Equal(t, remoteHead(t, mirror), remoteHead(t, primary))
+
+	// the mirror is never written
+	_, err = reader.Create(ctx, "c.txt", []byte("c"))
+	require.NoError(t, err)
+	assert.NotEqual(t, remoteHead(t, mirror), remoteHead(t, primary))
+}
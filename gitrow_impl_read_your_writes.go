@@ -0,0 +1,93 @@
+package gitrows
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// markPushed records the commit pushed by this DB, so the following fetch never rewinds the local branch before it.
+func (db *DBImpl) markPushed(commit plumbing.Hash) {
+	db.syncMu.Lock()
+	defer db.syncMu.Unlock()
+
+	db.lastPushed = commit
+	db.laggingHead = plumbing.ZeroHash
+}
+
+// keepPushed moves the local branch back to local (the local branch before the fetch), when the fetched commit is
+// the ancestor of the last commit pushed by this DB, i.e: the mirrored or replicated backend which still serves
+// the tip before the push for a while. So the read right after the write by the same DB always sees the write.
+// The lag is reported by Status until the fetch returns the pushed commit or its descendant.
+//
+// The fetched commit which is not the ancestor (i.e: the other writer has pushed after it) is kept as usual.
+// So is the branch rewound by the other writer into the ancestor, which is indistinguishable from the lag,
+// until this DB pushes again.
+func (db *DBImpl) keepPushed(local *object.Commit) (err error) {
+	db.syncMu.Lock()
+	lastPushed := db.lastPushed
+	db.syncMu.Unlock()
+
+	if lastPushed.IsZero() || local == nil {
+		return
+	}
+
+	fetched, err := db.headCommit()
+	if err != nil || fetched == nil {
+		return
+	}
+
+	lagging := false
+	if fetched.Hash != lastPushed {
+		lagging, err = db.isFirstParentOf(fetched.Hash, lastPushed)
+		if err != nil {
+			return
+		}
+	}
+
+	db.syncMu.Lock()
+	db.laggingHead = plumbing.ZeroHash
+	if lagging {
+		db.laggingHead = fetched.Hash
+	}
+	db.syncMu.Unlock()
+
+	if !lagging {
+		return
+	}
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(branchName, local.Hash))
+	if err != nil {
+		err = fmt.Errorf("cannot set reference %s: %w", branchName, err)
+		return
+	}
+
+	return
+}
+
+// isFirstParentOf returns true when the ancestor is one of the first parents of the commit,
+// until the missing parent of the shallow commit.
+func (db *DBImpl) isFirstParentOf(ancestor, commit plumbing.Hash) (bool, error) {
+	for current := commit; ; {
+		c, err := db.gitRepo.CommitObject(current)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("cannot get commit %s: %w", current, err)
+		}
+
+		if c.NumParents() == 0 {
+			return false, nil
+		}
+
+		current = c.ParentHashes[0]
+		if current == ancestor {
+			return true, nil
+		}
+	}
+}
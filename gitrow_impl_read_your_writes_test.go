@@ -0,0 +1,77 @@
+package gitrows_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	before, err := db.Create(ctx, "a.txt", []byte("v1"))
+	require.NoError(t, err)
+
+	pushed, _, err := db.Upsert(ctx, "a.txt", []byte("v2"))
+	require.NoError(t, err)
+
+	// the replica still serves the tip before the push
+	remoteRepo, err := git.PlainOpen(strings.TrimPrefix(remote, "file://"))
+	require.NoError(t, err)
+
+	serve := func(t *testing.T, commit string) {
+		t.Helper()
+
+		ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), plumbing.NewHash(commit))
+		require.NoError(t, remoteRepo.Storer.SetReference(ref))
+	}
+
+	serve(t, before)
+
+	data, err := db.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+
+	status, err := db.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, pushed, status.LocalHead)
+	assert.True(t, status.Lagging)
+	assert.Equal(t, before, status.LaggingHead)
+
+	// the other handle without the write just reads the replica
+	data, err = newTestDB(t, remote).Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	// the push is replicated
+	serve(t, pushed)
+
+	data, err = db.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+
+	status, err = db.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Lagging)
+	assert.Empty(t, status.LaggingHead)
+
+	// the write of the other writer after the push moves the local branch as usual
+	other, _, err := newTestDB(t, remote).Upsert(ctx, "a.txt", []byte("v3"))
+	require.NoError(t, err)
+
+	data, err = db.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", string(data))
+
+	status, err = db.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, other, status.LocalHead)
+	assert.False(t, status.Lagging)
+}
@@ -0,0 +1,66 @@
+package gitrows
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithUpdateRemoteURL(t *testing.T) {
+	ctx := context.TODO()
+
+	newRemote := func(value string) string {
+		remoteDir := filepath.Join(t.TempDir(), "remote.git")
+		_, err := git.PlainInit(remoteDir, true)
+		require.NoError(t, err)
+
+		db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+		require.NoError(t, err)
+
+		_, err = db.Create(ctx, "a.txt", []byte(value))
+		require.NoError(t, err)
+		return "file://" + remoteDir
+	}
+
+	oldURL, newURL := newRemote("old"), newRemote("new")
+	volume := ""
+
+	// the volume is derived from the URL, so the same local repository is forced for both URLs
+	newDB := func(gitURL string, opts ...Opt) *DBImpl {
+		opts = append([]Opt{WithGitSshUrl(gitURL), WithLocalGitVolume(t.TempDir())}, opts...)
+		db, err := New(opts...)
+		require.NoError(t, err)
+
+		if volume == "" {
+			volume = db.gitVolume
+		}
+
+		db.gitVolume = volume
+		db.progress = &bytes.Buffer{}
+		return db
+	}
+
+	data, err := newDB(oldURL).Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+
+	// the stale URL is kept when it is disabled
+	data, err = newDB(newURL, WithUpdateRemoteURL(false)).Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+
+	db := newDB(newURL)
+	data, err = db.Get(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	assert.Contains(t, db.progress.(*bytes.Buffer).String(), "gitrows: remote 'origin' URL changed from "+oldURL+" to "+newURL)
+
+	remote, err := db.gitRepo.Remote(gitRemoteName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{newURL}, remote.Config().URLs)
+}
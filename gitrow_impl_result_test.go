@@ -0,0 +1,87 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_WriteResult(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "other.yaml", []byte("other"))
+	require.NoError(t, err)
+
+	blobHash := plumbing.ComputeHash(plumbing.BlobObject, []byte("v1")).String()
+
+	created, err := db.CreateR(ctx, "config.yaml", []byte("v1"))
+	require.NoError(t, err)
+	assert.Equal(t, remoteHead(t, remote), created.CommitHash)
+	assert.Equal(t, blobHash, created.BlobHash)
+	assert.Equal(t, int64(2), created.BytesWritten)
+	assert.Equal(t, 1, created.Attempts)
+	assert.False(t, created.PushedAt.IsZero())
+
+	// nothing is pushed when the value is the same
+	upserted, err := db.UpsertR(ctx, "config.yaml", []byte("v1"))
+	require.NoError(t, err)
+	assert.False(t, upserted.Changed)
+	assert.Equal(t, created.CommitHash, upserted.CommitHash)
+	assert.Equal(t, blobHash, upserted.BlobHash)
+	assert.Zero(t, upserted.Attempts)
+	assert.True(t, upserted.PushedAt.IsZero())
+
+	upserted, err = db.UpsertR(ctx, "config.yaml", []byte("v22"))
+	require.NoError(t, err)
+	assert.True(t, upserted.Changed)
+	assert.Equal(t, remoteHead(t, remote), upserted.CommitHash)
+	assert.Equal(t, int64(3), upserted.BytesWritten)
+	assert.Equal(t, 1, upserted.Attempts)
+
+	deleted, err := db.DeleteR(ctx, "config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, remoteHead(t, remote), deleted.CommitHash)
+	assert.Equal(t, 1, deleted.Attempts)
+	assert.False(t, deleted.PushedAt.IsZero())
+}
+
+func TestDBImpl_Upsert_identicalContent(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "deploy.sh", []byte("#!/bin/sh\n"), gitrows.CreateFileMode(0755))
+	require.NoError(t, err)
+
+	head, err := db.Create(ctx, "notes.txt", []byte("a\nb\n"))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		db   *gitrows.DBImpl
+		key  string
+		data string
+	}{
+		{name: "same DB", db: db, key: "notes.txt", data: "a\nb\n"},
+		{name: "fresh DB", db: newTestDB(t, remote), key: "notes.txt", data: "a\nb\n"},
+		{name: "executable file", db: newTestDB(t, remote), key: "deploy.sh", data: "#!/bin/sh\n"},
+		{name: "normalized line ending", db: newTestDB(t, remote, gitrows.WithLineEndingPolicy(gitrows.LineEndingLF)), key: "notes.txt", data: "a\r\nb\r\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := c.db.UpsertR(ctx, c.key, []byte(c.data))
+			require.NoError(t, err)
+			assert.False(t, result.Changed)
+			assert.False(t, result.MetadataChanged)
+			assert.Equal(t, head, result.CommitHash)
+			assert.Equal(t, head, remoteHead(t, remote))
+		})
+	}
+}
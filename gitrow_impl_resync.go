@@ -0,0 +1,207 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Resync rebuilds the worktree of the local repository from scratch, i.e: as the operational recovery when
+// the worktree is left with the stale files of other branch or the untracked junk which the checkout keeps
+// (like the files ignored by .gitignore). Every file except the .git directory and WithPreservePaths is removed,
+// the index is emptied, then the local branch is checked out again and the worktree is verified against it.
+// Nothing is fetched, so the remote repository is not needed.
+//
+// The cleaned is the files which were not the same as the local branch (untracked or modified), sorted.
+// The worktree which still differs after the checkout returns ErrWorktreeDirty.
+// It must not run concurrently with the other commands of the same DB, like the writes.
+func (db *DBImpl) Resync(ctx context.Context) (cleaned []string, err error) {
+	defer func() {
+		err = wrapError(OpResync, "", err)
+	}()
+
+	err = db.waitAbandoned(ctx)
+	if err != nil {
+		err = fmt.Errorf("resync command: %w", err)
+		return
+	}
+
+	err = db.gitClone(ctx)
+	if err != nil {
+		err = fmt.Errorf("resync command: git clone error: %w", err)
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("resync command: cannot get worktree of local git repo: %w", err)
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		err = fmt.Errorf("resync command: %w", err)
+		return
+	}
+
+	cleaned, err = db.cleanWorktree(worktree.Filesystem, head)
+	if err != nil {
+		err = fmt.Errorf("resync command: %w", err)
+		return
+	}
+
+	err = db.gitRepo.Storer.SetIndex(&index.Index{Version: 2})
+	if err != nil {
+		err = fmt.Errorf("resync command: cannot empty the index: %w", err)
+		return
+	}
+
+	err = db.gitCheckout(ctx)
+	if err != nil {
+		err = fmt.Errorf("resync command: git checkout error: %w", err)
+		return
+	}
+
+	db.syncMu.Lock()
+	db.checkoutPending = false
+	db.syncMu.Unlock()
+
+	if head == nil {
+		return
+	}
+
+	dirtyPaths, err := db.verifyWorktree(db.gitRepo)
+	if len(dirtyPaths) == 0 && err != nil {
+		err = fmt.Errorf("resync command: %w", err)
+		return
+	}
+
+	// the preserved files are untracked on purpose
+	different := make([]string, 0, len(dirtyPaths))
+	for _, filePath := range dirtyPaths {
+		if len(db.preservePaths) == 0 || !db.isPreserved(filePath) {
+			different = append(different, filePath)
+		}
+	}
+
+	if len(different) > 0 {
+		err = fmt.Errorf("resync command: %w: %d files different from branch %s after checkout: %v",
+			ErrWorktreeDirty, len(different), db.gitBranch, different)
+		return
+	}
+
+	return cleaned, nil
+}
+
+// cleanWorktree removes every file of the worktree except the preserved ones, and the directories left empty.
+// It returns the removed files which are not the same as in the commit, which may be nil.
+func (db *DBImpl) cleanWorktree(fs billy.Filesystem, commit *object.Commit) (cleaned []string, err error) {
+	var tree *object.Tree
+	if commit != nil {
+		tree, err = commit.Tree()
+		if err != nil {
+			err = fmt.Errorf("retrieve the tree from the commit %s error: %w", commit.Hash, err)
+			return
+		}
+	}
+
+	files := make([]string, 0)
+	err = walkFiles(fs, "", func(filePath string) error {
+		if len(db.preservePaths) == 0 || !db.isPreserved(filePath) {
+			files = append(files, filePath)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	cleaned = make([]string, 0)
+	for _, filePath := range files {
+		var same bool
+		same, err = sameAsTree(fs, tree, filePath)
+		if err != nil {
+			return
+		}
+
+		if !same {
+			cleaned = append(cleaned, filePath)
+		}
+
+		err = fs.Remove(filePath)
+		if err != nil {
+			err = fmt.Errorf("cannot remove '%s': %w", filePath, err)
+			return
+		}
+
+		err = removeEmptyDirs(fs, path.Dir(filePath))
+		if err != nil {
+			return
+		}
+	}
+
+	sort.Strings(cleaned)
+	return
+}
+
+// sameAsTree returns true when the file in the worktree has the same content as the file path in the tree.
+func sameAsTree(fs billy.Filesystem, tree *object.Tree, filePath string) (same bool, err error) {
+	if tree == nil {
+		return
+	}
+
+	entry, err := tree.FindEntry(filePath)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot find '%s' in the tree: %w", filePath, err)
+		return
+	}
+
+	var data []byte
+	if entry.Mode == filemode.Symlink {
+		var target string
+		target, err = fs.Readlink(filePath)
+		data = []byte(target)
+	} else if entry.Mode.IsFile() {
+		data, err = readWorktreeFile(fs, filePath)
+	} else {
+		return false, nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot read '%s': %w", filePath, err)
+		return
+	}
+
+	return plumbing.ComputeHash(plumbing.BlobObject, data) == entry.Hash, nil
+}
+
+// readWorktreeFile reads all content of the file in the worktree.
+func readWorktreeFile(fs billy.Filesystem, filePath string) (data []byte, err error) {
+	file, err := fs.Open(filePath)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if _err := file.Close(); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	return io.ReadAll(file)
+}
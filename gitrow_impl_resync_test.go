@@ -0,0 +1,74 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_Resync(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	writer := newTestDB(t, remote)
+	_, err := writer.Create(ctx, ".gitignore", []byte("*.log\n"))
+	require.NoError(t, err)
+
+	_, err = writer.Create(ctx, "configs/a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	_, err = writer.Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	volume := t.TempDir()
+	repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithPreservePaths([]string{"*.cache"}))
+
+	_, err = db.Get(ctx, "b.txt")
+	require.NoError(t, err)
+
+	write := func(name, data string) {
+		t.Helper()
+
+		filePath := filepath.Join(repoDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(data), 0644))
+	}
+
+	write("b.txt", "modified")
+	write("stale/old-branch/c.txt", "c")
+	write("debug.log", "ignored junk")
+	write("configs/app.cache", "preserved")
+
+	cleaned, err := db.Resync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.txt", "debug.log", "stale/old-branch/c.txt"}, cleaned)
+
+	data, err := os.ReadFile(filepath.Join(repoDir, "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+
+	data, err = os.ReadFile(filepath.Join(repoDir, "configs", "app.cache"))
+	require.NoError(t, err)
+	assert.Equal(t, "preserved", string(data))
+
+	for _, name := range []string{"stale", "debug.log"} {
+		_, err = os.Stat(filepath.Join(repoDir, name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+
+	// nothing to clean anymore
+	cleaned, err = db.Resync(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cleaned)
+
+	data, err = db.Get(ctx, "configs/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
@@ -0,0 +1,122 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// The steps of the write command, in order. The read command only does the first three.
+const (
+	phaseClone    = "clone"
+	phaseFetch    = "fetch"
+	phaseCheckout = "checkout"
+	phaseWrite    = "write"
+	phaseCommit   = "commit"
+	phasePush     = "push"
+)
+
+// phase is called at the beginning of each step, and returns the error of the cancelled ctx,
+// so the command stops on the step boundary where the local repository is known to converge:
+//   - clone, fetch and checkout: the next sync redo the step, the same as the remote error.
+//   - write: nothing is written yet.
+//   - commit: the written files are discarded, see discardChanges.
+//   - push: the local commit is rolled back, see gitPush.
+func (db *DBImpl) phase(ctx context.Context, name string) error {
+	if db.phaseHook != nil {
+		db.phaseHook(name)
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return fmt.Errorf("%s is cancelled: %w", name, err)
+	}
+
+	return nil
+}
+
+// rollback moves the local branch back to base, or removes it when base is zero (the failed write is the first commit),
+// then discards the worktree changes. The next read is forced to pull, since the failed push may be applied
+// in the remote anyway, i.e: the ctx is cancelled after the remote has received the commit.
+func (db *DBImpl) rollback(base plumbing.Hash) (err error) {
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	if base.IsZero() {
+		err = db.gitRepo.Storer.RemoveReference(branchName)
+	} else {
+		err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(branchName, base))
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot reset local branch %s: %w", branchName, err)
+		return
+	}
+
+	if db.treeWrites {
+		db.rollbackTree()
+		return
+	}
+
+	db.syncMu.Lock()
+	db.lastSyncAt = time.Time{}
+	db.syncMu.Unlock()
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("cannot get worktree: %w", err)
+		return
+	}
+
+	return db.discardChanges(worktree)
+}
+
+// discardChanges resets the worktree and the index into the local branch, like `git reset --hard`.
+// When the branch doesn't have any commit yet, there is nothing to reset to, so every staged file is removed instead.
+func (db *DBImpl) discardChanges(worktree *git.Worktree) (err error) {
+	head, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	if head != nil {
+		// gitCheckout doesn't use the ctx, and the ctx of the command may be cancelled already
+		return db.gitCheckout(context.Background())
+	}
+
+	idx, err := db.gitRepo.Storer.Index()
+	if err != nil {
+		err = fmt.Errorf("cannot read the index: %w", err)
+		return
+	}
+
+	fs := worktree.Filesystem
+	for _, entry := range idx.Entries {
+		_, statErr := fs.Lstat(entry.Name)
+		if statErr != nil {
+			continue
+		}
+
+		err = fs.Remove(entry.Name)
+		if err != nil {
+			err = fmt.Errorf("cannot remove '%s': %w", entry.Name, err)
+			return
+		}
+
+		err = removeEmptyDirs(fs, path.Dir(entry.Name))
+		if err != nil {
+			return
+		}
+	}
+
+	err = db.gitRepo.Storer.SetIndex(&index.Index{Version: idx.Version})
+	if err != nil {
+		err = fmt.Errorf("cannot reset the index: %w", err)
+		return
+	}
+
+	return
+}
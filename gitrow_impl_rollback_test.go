@@ -0,0 +1,140 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelWrite(t *testing.T) {
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	remote, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	newDB := func() *DBImpl {
+		db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()), WithReadStaleness(time.Hour))
+		require.NoError(t, err)
+		return db
+	}
+
+	remoteHead := func() plumbing.Hash {
+		ref, err := remote.Reference(plumbing.NewBranchReferenceName("master"), false)
+		require.NoError(t, err)
+		return ref.Hash()
+	}
+
+	_, err = newDB().Create(context.TODO(), "config.yaml", []byte("v1"))
+	require.NoError(t, err)
+
+	for _, phase := range []string{phaseClone, phaseFetch, phaseCheckout, phaseWrite, phaseCommit, phasePush} {
+		t.Run(phase, func(t *testing.T) {
+			db := newDB()
+			if phase != phaseClone {
+				_, err := db.Get(context.TODO(), "config.yaml")
+				require.NoError(t, err)
+			}
+
+			before := remoteHead()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			db.phaseHook = func(name string) {
+				if name == phase {
+					cancel()
+				}
+			}
+
+			_, _, err := db.Upsert(ctx, "config.yaml", []byte("v2"))
+			assert.True(t, errors.Is(err, context.Canceled), err)
+			assert.Equal(t, CodeCanceled, ErrorCode(err))
+
+			db.phaseHook = nil
+			assert.Equal(t, before, remoteHead())
+
+			// the local repository is the same as the remote branch, without the cancelled write
+			if db.gitRepo != nil {
+				local, err := db.headCommit()
+				require.NoError(t, err)
+				assert.Equal(t, before, local.Hash)
+
+				worktree, err := db.gitRepo.Worktree()
+				require.NoError(t, err)
+
+				status, err := worktree.Status()
+				require.NoError(t, err)
+				assert.True(t, status.IsClean(), status.String())
+			}
+
+			data, err := db.Get(context.TODO(), "config.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("v1"), data)
+
+			// the next write doesn't publish the cancelled write
+			_, _, err = db.Upsert(context.TODO(), "other.yaml", []byte("v1"))
+			require.NoError(t, err)
+
+			data, err = newDB().Get(context.TODO(), "config.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("v1"), data)
+		})
+	}
+}
+
+func TestCancelWrite_emptyRemote(t *testing.T) {
+	for _, phase := range []string{phaseCommit, phasePush} {
+		t.Run(phase, func(t *testing.T) {
+			remoteDir := filepath.Join(t.TempDir(), "remote.git")
+			_, err := git.PlainInit(remoteDir, true)
+			require.NoError(t, err)
+
+			db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()))
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			db.phaseHook = func(name string) {
+				if name == phase {
+					cancel()
+				}
+			}
+
+			_, err = db.Create(ctx, "a.txt", []byte("a"))
+			assert.True(t, errors.Is(err, context.Canceled), err)
+
+			// the first commit is removed, so the branch has no commit and no file left
+			db.phaseHook = nil
+			local, err := db.headCommit()
+			require.NoError(t, err)
+			assert.Nil(t, local)
+
+			worktree, err := db.gitRepo.Worktree()
+			require.NoError(t, err)
+
+			_, err = worktree.Filesystem.Lstat("a.txt")
+			assert.True(t, errors.Is(err, os.ErrNotExist), err)
+
+			_, err = db.Create(context.TODO(), "b.txt", []byte("b"))
+			require.NoError(t, err)
+
+			head, err := db.headCommit()
+			require.NoError(t, err)
+
+			_, err = head.File("a.txt")
+			assert.ErrorIs(t, err, object.ErrFileNotFound)
+
+			_, err = head.File("b.txt")
+			assert.NoError(t, err)
+		})
+	}
+}
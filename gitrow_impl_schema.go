@@ -0,0 +1,130 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// schemaVersionPath is the file inside the reserved .gitrows directory which contains the schema version.
+var schemaVersionPath = path.Join(metadataDir, "version")
+
+// SchemaVersion returns the schema version of the repository layout stored in .gitrows/version,
+// so the data migration can be gated on the version check.
+// Repository without version marker returns 0.
+func (db *DBImpl) SchemaVersion(ctx context.Context) (version int, err error) {
+	defer func() {
+		err = wrapError(OpSchemaVersion, "", err)
+	}()
+
+	err = db.syncForRead(ctx)
+	if err != nil {
+		err = fmt.Errorf("schema version command: %w", err)
+		return
+	}
+
+	// read from the commit object, so it works too when the marker is not materialized (i.e: WithSparsePrefix)
+	file, err := db.headFile(schemaVersionPath)
+	if err != nil {
+		err = fmt.Errorf("schema version command: %w", err)
+		return
+	}
+
+	if file == nil {
+		return
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		err = fmt.Errorf("schema version command: cannot read '%s': %w", schemaVersionPath, err)
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = fmt.Errorf("schema version command: failed to close '%s': %w", schemaVersionPath, _err)
+		}
+	}()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("schema version command: cannot read '%s': %w", schemaVersionPath, err)
+		return
+	}
+
+	version, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		err = fmt.Errorf("schema version command: invalid schema version in '%s': %w", schemaVersionPath, err)
+		return
+	}
+
+	return
+}
+
+// SetSchemaVersion writes the schema version into .gitrows/version, then commit and push it like Upsert.
+// Nothing is committed when the version is already the same.
+func (db *DBImpl) SetSchemaVersion(ctx context.Context, version int) (err error) {
+	defer func() {
+		err = wrapError(OpSetSchemaVersion, "", err)
+		db.recordOp(OpSetSchemaVersion, "", "", err)
+	}()
+
+	err = db.checkWritable()
+	if err != nil {
+		err = fmt.Errorf("set schema version command: %w", err)
+		return
+	}
+
+	if version < 0 {
+		err = fmt.Errorf("set schema version command: schema version must not be negative, got %d", version)
+		return
+	}
+
+	err = db.forcePull(ctx)
+	if err != nil {
+		err = fmt.Errorf("set schema version command: %w", err)
+		return
+	}
+
+	data := []byte(strconv.Itoa(version) + "\n")
+	worktree, err := db.writeFile(ctx, schemaVersionPath, data, "UPSERT", 0)
+	if err != nil {
+		err = fmt.Errorf("set schema version command: %w", err)
+		return
+	}
+
+	worktreeStatus, err := worktree.Status()
+	if err != nil {
+		err = fmt.Errorf("set schema version command: cannot `git status`: %w", err)
+		return
+	}
+
+	fileStatus, exist := worktreeStatus[schemaVersionPath]
+	if !exist || fileStatus.Staging == git.Unmodified {
+		return
+	}
+
+	commitMsg := db.commitMessage(OpSetSchemaVersion, "", fmt.Sprintf("gitrows: SET SCHEMA VERSION %d", version), CommitMeta{
+		Sizes:   map[string]int64{schemaVersionPath: int64(len(data))},
+		Changed: true,
+	})
+
+	_, err = db.gitCommit(ctx, worktree, commitMsg, false)
+	if err != nil {
+		err = fmt.Errorf("set schema version command: %w", err)
+		return
+	}
+
+	_, _, err = db.gitPush(ctx, OpSetSchemaVersion)
+	if err != nil {
+		err = fmt.Errorf("set schema version command: %w", err)
+		return
+	}
+
+	return
+}
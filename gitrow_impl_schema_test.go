@@ -0,0 +1,61 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_SchemaVersion(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	version, err := db.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	_, err = db.Create(ctx, "configs/app.yaml", []byte("app"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.SetSchemaVersion(ctx, 2))
+
+	version, err = newTestDB(t, remote).SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	// the marker is not listed as a key
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configs/app.yaml"}, listKeys(entries))
+
+	// same version doesn't create new commit
+	head := remoteHead(t, remote)
+	require.NoError(t, db.SetSchemaVersion(ctx, 2))
+	assert.Equal(t, head, remoteHead(t, remote))
+
+	// the marker is readable with sparse prefix too
+	sparse := newTestDB(t, remote, gitrows.WithSparsePrefix("configs"))
+	version, err = sparse.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	require.NoError(t, sparse.SetSchemaVersion(ctx, 3))
+	version, err = db.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+
+	data, err := db.Get(ctx, "configs/app.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("app"), data)
+
+	// the metadata directory is reserved
+	_, err = db.Get(ctx, ".gitrows/version")
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey))
+
+	assert.Error(t, db.SetSchemaVersion(ctx, -1))
+}
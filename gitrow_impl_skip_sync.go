@@ -0,0 +1,36 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+)
+
+// syncForWriteUnless is syncForWrite, but only makes the worktree match the local branch when skipSync is true
+// (see CreateSkipSync), so the write is on the local branch as it is without fetching anything.
+// The DB without the local branch yet still does syncForWrite, since there is nothing to write on.
+func (db *DBImpl) syncForWriteUnless(ctx context.Context, skipSync bool) (err error) {
+	if !skipSync || db.gitRepo == nil {
+		return db.syncForWrite(ctx)
+	}
+
+	// the fetch abandoned by WithOperationTimeout may still write into the local repository
+	err = db.waitAbandoned(ctx)
+	if err != nil {
+		return
+	}
+
+	if db.treeWrites || !db.isCheckoutPending() {
+		return
+	}
+
+	err = db.gitCheckout(ctx)
+	if err != nil {
+		err = fmt.Errorf("git checkout error: %w", err)
+		return
+	}
+
+	db.syncMu.Lock()
+	db.checkoutPending = false
+	db.syncMu.Unlock()
+	return
+}
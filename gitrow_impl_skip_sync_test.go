@@ -0,0 +1,92 @@
+package gitrows_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestUpsertSkipSync(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("only pushes", func(t *testing.T) {
+		remote := newTestRemote(t)
+
+		var syncs int
+		db := newTestDB(t, remote, gitrows.WithOnSyncStart(func() {
+			syncs++
+		}))
+
+		// the first write still syncs, since there is no local branch yet
+		_, err := db.Create(ctx, "a.txt", []byte("a"), gitrows.CreateSkipSync())
+		require.NoError(t, err)
+		assert.Equal(t, 1, syncs)
+
+		var commitHash string
+		for i := 0; i < 3; i++ {
+			commitHash, _, err = db.Upsert(ctx, "a.txt", []byte(fmt.Sprintf("a%d", i)), gitrows.UpsertSkipSync())
+			require.NoError(t, err)
+		}
+
+		_, err = db.Create(ctx, "b.txt", []byte("b"), gitrows.CreateSkipSync())
+		require.NoError(t, err)
+
+		commitHash, err = db.Delete(ctx, "b.txt", gitrows.DeleteSkipSync())
+		require.NoError(t, err)
+		assert.Equal(t, 1, syncs)
+		assert.Equal(t, remoteHead(t, remote), commitHash)
+
+		data, err := newTestDB(t, remote).Get(ctx, "a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "a2", string(data))
+	})
+
+	t.Run("worktree after keys", func(t *testing.T) {
+		remote := newTestRemote(t)
+		_, err := newTestDB(t, remote).Create(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+
+		// Keys only fetches the branch, so the worktree must be checked out before writing on it
+		db := newTestDB(t, remote)
+		_, err = db.Keys(ctx)
+		require.NoError(t, err)
+
+		_, err = db.Create(ctx, "b.txt", []byte("b"), gitrows.CreateSkipSync())
+		require.NoError(t, err)
+
+		keys, err := newTestDB(t, remote).Keys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "b.txt"}, keys)
+	})
+
+	t.Run("stale local branch", func(t *testing.T) {
+		remote := newTestRemote(t)
+		safe := newTestDB(t, remote, gitrows.WithForcePush(false))
+		linear := newTestDB(t, remote, gitrows.WithLinearHistory())
+
+		_, err := safe.Create(ctx, "a.txt", []byte("a"))
+		require.NoError(t, err)
+
+		_, err = linear.Get(ctx, "a.txt")
+		require.NoError(t, err)
+
+		_, err = newTestDB(t, remote).Create(ctx, "other.txt", []byte("o"))
+		require.NoError(t, err)
+
+		// the remote change is not overwritten without force push
+		_, _, err = safe.Upsert(ctx, "a.txt", []byte("a2"), gitrows.UpsertSkipSync())
+		assert.Equal(t, gitrows.CodeConflict, gitrows.ErrorCode(err), err)
+
+		// and it is pulled then retried with the linear history
+		_, _, err = linear.Upsert(ctx, "a.txt", []byte("a3"), gitrows.UpsertSkipSync())
+		require.NoError(t, err)
+
+		keys, err := newTestDB(t, remote).Keys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "other.txt"}, keys)
+	})
+}
@@ -0,0 +1,55 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithOnSkippedPath(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "good.txt", []byte("good"))
+	require.NoError(t, err)
+
+	// file names which cannot be written by gitrows, committed by other git client
+	tricky := []string{"latin1-caf\xe9.txt", "tab\tname.txt"}
+	for _, name := range tricky {
+		pushCommit(t, remote, nil, name, []byte("tricky"))
+	}
+
+	skipped := make(map[string]error)
+	db := newTestDB(t, remote, gitrows.WithOnSkippedPath(func(filePath string, err error) {
+		skipped[filePath] = err
+	}))
+
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"good.txt"}, listKeys(entries))
+
+	skippedPaths := make([]string, 0, len(skipped))
+	for filePath, err := range skipped {
+		skippedPaths = append(skippedPaths, filePath)
+		assert.True(t, errors.Is(err, gitrows.ErrInvalidKey), err)
+	}
+
+	sort.Strings(skippedPaths)
+	assert.Equal(t, tricky, skippedPaths)
+
+	keys, err := db.Keys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"good.txt"}, keys)
+
+	// the skipped file cannot be read nor written as the key
+	_, err = db.Get(ctx, tricky[0])
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey), err)
+
+	_, _, err = db.Upsert(ctx, tricky[1], []byte("other"))
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey), err)
+}
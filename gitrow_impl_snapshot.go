@@ -0,0 +1,32 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+)
+
+// SnapshotID returns the string which identifies the current state of the whole dataset, i.e: as the ETag
+// of the whole dataset or the cache key of the aggregated view. It is the commit hash of the branch head
+// after `git fetch`, so two calls return the same ID if and only if no write is pushed in between.
+//
+// Only the branch reference is read, without walking the tree nor checking out the worktree.
+// The ID is empty when the branch doesn't exist yet (i.e: empty remote).
+func (db *DBImpl) SnapshotID(ctx context.Context) (id string, err error) {
+	defer func() {
+		err = wrapError(OpSnapshotID, "", err)
+	}()
+
+	err = db.syncBranch(ctx)
+	if err != nil {
+		err = fmt.Errorf("snapshot id command: %w", err)
+		return
+	}
+
+	id, err = db.localHead()
+	if err != nil {
+		err = fmt.Errorf("snapshot id command: %w", err)
+		return
+	}
+
+	return
+}
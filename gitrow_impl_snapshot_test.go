@@ -0,0 +1,40 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBImpl_SnapshotID(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	id, err := db.SnapshotID(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, id)
+
+	writer := newTestDB(t, remote)
+	commitHash, err := writer.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	id, err = db.SnapshotID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, commitHash, id)
+
+	// nothing is pushed in between
+	again, err := db.SnapshotID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	_, _, err = writer.Upsert(ctx, "a.txt", []byte("b"))
+	require.NoError(t, err)
+
+	id, err = db.SnapshotID(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, again, id)
+	assert.Equal(t, remoteHead(t, remote), id)
+}
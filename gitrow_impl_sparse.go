@@ -0,0 +1,171 @@
+package gitrows
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// WithSparsePrefix only materializes the files under prefix (i.e: "configs/payments") in the local worktree,
+// similar like `git sparse-checkout set <prefix>`. This cuts the disk usage and checkout time of big repository
+// when the service only touches one subtree.
+//
+// Get, GetReader, GetMany, Create, Upsert, PutReader, Delete and PutContentAddressed on key outside the prefix
+// return ErrOutsideSparsePrefix.
+// List and ListModifiedBetween still enumerate the full tree, because they read from the commit object.
+//
+// The sparse checkout is done by gitrows instead of CheckoutOptions.SparseCheckoutDirectories,
+// because go-git drops the skipped files from the index, which makes the next commit delete them in the remote.
+func WithSparsePrefix(prefix string) Opt {
+	return func(db *DBImpl) error {
+		cleaned, err := validateKey(prefix)
+		if err != nil {
+			return fmt.Errorf("sparse prefix: %w", err)
+		}
+
+		db.sparsePrefix = cleaned
+		return nil
+	}
+}
+
+// checkSparsePrefix returns ErrOutsideSparsePrefix when the key is not materialized because of WithSparsePrefix.
+func (db *DBImpl) checkSparsePrefix(key string) error {
+	if db.sparsePrefix == "" || strings.HasPrefix(key, db.sparsePrefix+"/") {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w: key '%s' is outside of sparse prefix '%s', widen the prefix in WithSparsePrefix to access it",
+		ErrOutsideSparsePrefix, key, db.sparsePrefix,
+	)
+}
+
+// gitCheckoutSparse is like `git checkout -f <branch>`, but only write the files under the sparse prefix.
+// The index still contains all files of the branch, so commit only changes the staged keys.
+func (db *DBImpl) gitCheckoutSparse(worktree *git.Worktree) (err error) {
+	commit, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	// the branch doesn't have any commit yet, HEAD is already pointed to it in gitFetch
+	if commit == nil {
+		return
+	}
+
+	// git symbolic-ref HEAD refs/heads/<branch> && git reset --mixed <commit>
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	err = db.gitRepo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchName))
+	if err != nil {
+		err = fmt.Errorf("cannot set HEAD to %s: %w", branchName, err)
+		return
+	}
+
+	err = worktree.Reset(&git.ResetOptions{
+		Commit: commit.Hash,
+		Mode:   git.MixedReset,
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot reset index on local git repo: %w", err)
+		return
+	}
+
+	fs := worktree.Filesystem
+
+	// discard uncommitted changes and files left by previous checkout without (or with wider) sparse prefix
+	infos, err := fs.ReadDir("")
+	if err != nil {
+		err = fmt.Errorf("cannot read worktree: %w", err)
+		return
+	}
+
+	for _, info := range infos {
+		if info.Name() == git.GitDirName {
+			continue
+		}
+
+		err = util.RemoveAll(fs, info.Name())
+		if err != nil {
+			err = fmt.Errorf("cannot clean worktree '%s': %w", info.Name(), err)
+			return
+		}
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		err = fmt.Errorf("retrieve the tree from the commit %s (%s) error: %w", commit.ID(), branchName, err)
+		return
+	}
+
+	prefixTree, err := tree.Tree(db.sparsePrefix)
+	if errors.Is(err, object.ErrDirectoryNotFound) {
+		// nothing to materialize yet, the prefix will be created by the first write
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot get tree of sparse prefix '%s': %w", db.sparsePrefix, err)
+		return
+	}
+
+	err = prefixTree.Files().ForEach(func(file *object.File) error {
+		return checkoutFile(fs, path.Join(db.sparsePrefix, file.Name), file)
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot checkout sparse prefix '%s': %w", db.sparsePrefix, err)
+		return
+	}
+
+	return
+}
+
+// checkoutFile writes the content of file from the commit object into the worktree as name.
+func checkoutFile(fs billy.Filesystem, name string, file *object.File) (err error) {
+	mode, err := file.Mode.ToOSFileMode()
+	if err != nil {
+		err = fmt.Errorf("invalid file mode of '%s': %w", name, err)
+		return
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		err = fmt.Errorf("cannot read blob of '%s': %w", name, err)
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = fmt.Errorf("failed to close blob of '%s': %w", name, _err)
+		}
+	}()
+
+	dst, err := fs.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		err = fmt.Errorf("cannot open file '%s': %w", name, err)
+		return
+	}
+
+	defer func() {
+		if _err := dst.Close(); _err != nil && err == nil {
+			err = fmt.Errorf("failed to close file '%s': %w", name, _err)
+		}
+	}()
+
+	_, err = io.Copy(dst, reader)
+	if err != nil {
+		err = fmt.Errorf("cannot write file '%s': %w", name, err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithSparsePrefix(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	writer := newTestDB(t, remote)
+	for _, key := range []string{"configs/payments/a.yaml", "configs/orders/b.yaml", "assets/big.bin", "root.txt"} {
+		_, err := writer.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	volume := t.TempDir()
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithSparsePrefix("/configs/payments/"))
+
+	data, err := db.Get(ctx, "configs/payments/a.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("configs/payments/a.yaml"), data)
+
+	// only the prefix is materialized in the worktree
+	worktreeDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+	assert.FileExists(t, filepath.Join(worktreeDir, "configs", "payments", "a.yaml"))
+	assert.NoFileExists(t, filepath.Join(worktreeDir, "root.txt"))
+	_, err = os.Stat(filepath.Join(worktreeDir, "assets"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = db.Get(ctx, "assets/big.bin")
+	assert.True(t, errors.Is(err, gitrows.ErrOutsideSparsePrefix))
+	assert.Equal(t, gitrows.CodeInvalidKey, gitrows.ErrorCode(err))
+
+	_, err = db.Create(ctx, "configs/orders/c.yaml", []byte("c"))
+	assert.True(t, errors.Is(err, gitrows.ErrOutsideSparsePrefix))
+
+	_, err = db.Delete(ctx, "root.txt")
+	assert.True(t, errors.Is(err, gitrows.ErrOutsideSparsePrefix))
+
+	// writes inside the prefix must keep the files outside the prefix in the remote
+	_, err = db.Create(ctx, "configs/payments/new.yaml", []byte("new"))
+	require.NoError(t, err)
+
+	_, changed, err := db.Upsert(ctx, "configs/payments/a.yaml", []byte("configs/payments/a.yaml"))
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	_, changed, err = db.Upsert(ctx, "configs/payments/a.yaml", []byte("updated"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	_, err = db.Delete(ctx, "configs/payments/new.yaml")
+	require.NoError(t, err)
+
+	// List still enumerates the full tree
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"configs/payments/a.yaml", "configs/orders/b.yaml", "assets/big.bin", "root.txt"}, listKeys(entries))
+
+	data, err = writer.Get(ctx, "root.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("root.txt"), data)
+
+	data, err = writer.Get(ctx, "configs/payments/a.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("updated"), data)
+}
+
+func TestWithSparsePrefix_invalid(t *testing.T) {
+	_, err := gitrows.New(gitrows.WithGitSshUrl(newTestRemote(t)), gitrows.WithSparsePrefix("../outside"))
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey))
+}
@@ -0,0 +1,99 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Status returns how fresh the local repository of this DB is, without syncing it:
+// the local branch head, the result of the last sync and whether the worktree matches the local branch.
+// Only with StatusCheckRemote, the remote branch is listed like IsUpToDate, which needs the remote repository.
+//
+// The local repository is opened from the volume when it is not synced yet by this DB (i.e: after restart),
+// so LocalHead may be set while LastSyncAt is still zero.
+func (db *DBImpl) Status(ctx context.Context, opts ...StatusOpt) (status SyncStatus, err error) {
+	defer func() {
+		err = wrapError(OpStatus, "", err)
+	}()
+
+	cfg := &StatusConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("status command: %w", err)
+			return
+		}
+	}
+
+	db.syncMu.Lock()
+	status.LastSyncAt = db.lastSyncAt
+	status.LastSyncErr = db.lastSyncErr
+	status.CheckoutPending = db.checkoutPending
+	if !db.laggingHead.IsZero() {
+		status.Lagging, status.LaggingHead = true, db.laggingHead.String()
+	}
+	db.syncMu.Unlock()
+
+	repo := db.gitRepo
+	if repo == nil {
+		repo, err = git.PlainOpen(db.gitVolume)
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			err, repo = nil, nil
+		}
+
+		if err != nil {
+			err = fmt.Errorf("status command: open local repository %s error: %w", db.gitSshUrl, err)
+			return
+		}
+	}
+
+	status.Clean = true
+	if repo != nil {
+		branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+		var ref *plumbing.Reference
+		ref, err = repo.Reference(branchName, false)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			err = nil
+		}
+
+		if err != nil {
+			err = fmt.Errorf("status command: retrieving ref for branch %s error: %w", branchName, err)
+			return
+		}
+
+		if ref != nil {
+			status.LocalHead = ref.Hash().String()
+		}
+	}
+
+	if repo != nil && status.LocalHead != "" && !status.CheckoutPending {
+		// the difference itself is reported by DirtyPaths, only the failure of `git status` is returned
+		status.DirtyPaths, err = db.verifyWorktree(repo)
+		if len(status.DirtyPaths) > 0 {
+			status.Clean, err = false, nil
+		}
+
+		if err != nil {
+			err = fmt.Errorf("status command: %w", err)
+			return
+		}
+	}
+
+	if !cfg.checkRemote {
+		return
+	}
+
+	status.RemoteHead, err = db.remoteHead(ctx)
+	if err != nil {
+		err = fmt.Errorf("status command: %w", err)
+		return
+	}
+
+	status.RemoteChecked = true
+	status.RemoteChanged = status.RemoteHead != status.LocalHead
+	return
+}
@@ -0,0 +1,78 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_Status(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	volume := t.TempDir()
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume))
+
+	// never synced
+	status, err := db.Status(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, status.LocalHead)
+	assert.True(t, status.LastSyncAt.IsZero())
+	assert.True(t, status.Clean)
+	assert.False(t, status.RemoteChecked)
+
+	hash, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	status, err = db.Status(ctx, gitrows.StatusCheckRemote(true))
+	require.NoError(t, err)
+	assert.Equal(t, hash, status.LocalHead)
+	assert.False(t, status.LastSyncAt.IsZero())
+	assert.NoError(t, status.LastSyncErr)
+	assert.True(t, status.Clean)
+	assert.True(t, status.RemoteChecked)
+	assert.Equal(t, hash, status.RemoteHead)
+	assert.False(t, status.RemoteChanged)
+
+	// another writer moves the remote branch
+	other, err := newTestDB(t, remote).Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	status, err = db.Status(ctx, gitrows.StatusCheckRemote(true))
+	require.NoError(t, err)
+	assert.Equal(t, hash, status.LocalHead)
+	assert.Equal(t, other, status.RemoteHead)
+	assert.True(t, status.RemoteChanged)
+
+	// the file changed outside gitrows
+	repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("changed"), 0644))
+
+	status, err = db.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Clean)
+	assert.Equal(t, []string{"a.txt"}, status.DirtyPaths)
+
+	// the other DB reads the same local repository without syncing it
+	status, err = newTestDB(t, remote, gitrows.WithLocalGitVolume(volume)).Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, hash, status.LocalHead)
+	assert.True(t, status.LastSyncAt.IsZero())
+
+	// the sync error is kept
+	broken := newTestDB(t, "file://"+filepath.Join(t.TempDir(), "missing.git"))
+	_, err = broken.Get(ctx, "a.txt")
+	require.Error(t, err)
+
+	status, err = broken.Status(ctx)
+	require.NoError(t, err)
+	assert.Error(t, status.LastSyncErr)
+
+	_, err = broken.Status(ctx, gitrows.StatusCheckRemote(true))
+	assert.Equal(t, gitrows.CodeRemoteUnavailable, gitrows.ErrorCode(err))
+}
@@ -0,0 +1,93 @@
+package gitrows
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// syncMarkerName is the file written into the volume of WithLocalGitVolume after every successful sync.
+const syncMarkerName = ".gitrows-last-sync"
+
+// LastSyncInfo returns the start time of the last successful sync (pull) and the commit of the local branch
+// after it, or false when this DB has not synced yet. The commit is empty when the branch doesn't exist.
+//
+// The same is written into `<volume>/.gitrows-last-sync` as the RFC3339 time and the commit separated by space,
+// i.e: for the freshness alert reading the file from the disk without asking the application.
+// The file is replaced atomically, and the DBs sharing the same volume (i.e: of other repositories)
+// overwrite the same file, so give each repository its own volume when they must be watched separately.
+func (db *DBImpl) LastSyncInfo() (syncedAt time.Time, commit string, ok bool) {
+	db.syncMu.Lock()
+	defer db.syncMu.Unlock()
+
+	return db.lastSyncAt, db.lastSyncHead, !db.lastSyncAt.IsZero()
+}
+
+// WithOnSyncMarkerError set the callback which is called when the file of LastSyncInfo cannot be written.
+// The sync itself doesn't fail. By default, the warning is written into the same writer as the git progress
+// (os.Stdout).
+func WithOnSyncMarkerError(fn func(filePath string, err error)) Opt {
+	return func(db *DBImpl) error {
+		db.onSyncMarker = fn
+		return nil
+	}
+}
+
+// writeSyncMarker writes the sync marker of LastSyncInfo, and reports the error, see WithOnSyncMarkerError.
+func (db *DBImpl) writeSyncMarker(syncedAt time.Time) {
+	if db.volumeRoot == "" {
+		return
+	}
+
+	db.syncMu.Lock()
+	commit := db.lastSyncHead
+	db.syncMu.Unlock()
+
+	filePath := filepath.Join(db.volumeRoot, syncMarkerName)
+	content := fmt.Sprintf("%s %s\n", syncedAt.UTC().Format(time.RFC3339), commit)
+	err := writeFileAtomic(filePath, []byte(content))
+	if err == nil {
+		return
+	}
+
+	if db.onSyncMarker != nil {
+		db.onSyncMarker(filePath, err)
+		return
+	}
+
+	if db.progress != nil {
+		_, _ = fmt.Fprintf(db.progress, "gitrows: warning: cannot write sync marker %q: %s\n", filePath, err)
+	}
+}
+
+// writeFileAtomic writes data into the temporary file next to filePath, then renames it,
+// so the reader never sees the partial file.
+func writeFileAtomic(filePath string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	if _err := tmp.Close(); _err != nil && err == nil {
+		err = _err
+	}
+
+	if err != nil {
+		return
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tmp.Name(), filePath)
+}
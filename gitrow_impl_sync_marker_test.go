@@ -0,0 +1,86 @@
+package gitrows_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestLastSyncInfo(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	volume := t.TempDir()
+	markerPath := filepath.Join(volume, ".gitrows-last-sync")
+
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume))
+
+	_, _, ok := db.LastSyncInfo()
+	assert.False(t, ok)
+
+	_, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	_, err = newTestDB(t, remote).Create(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	_, err = db.Get(ctx, "b.txt")
+	require.NoError(t, err)
+
+	syncedAt, commit, ok := db.LastSyncInfo()
+	require.True(t, ok)
+	assert.Equal(t, remoteHead(t, remote), commit)
+	assert.WithinDuration(t, time.Now(), syncedAt, time.Minute)
+
+	data, err := os.ReadFile(markerPath)
+	require.NoError(t, err)
+
+	fields := strings.Fields(string(data))
+	require.Len(t, fields, 2)
+	assert.Equal(t, syncedAt.UTC().Format(time.RFC3339), fields[0])
+	assert.Equal(t, commit, fields[1])
+
+	// the marker is not in the repository
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, listKeys(entries))
+
+	status, err := db.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Clean)
+
+	leftover, err := filepath.Glob(markerPath + ".tmp-*")
+	require.NoError(t, err)
+	assert.Empty(t, leftover)
+}
+
+func TestLastSyncInfo_markerError(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	volume := t.TempDir()
+
+	// the directory in place of the marker makes the rename fail
+	markerPath := filepath.Join(volume, ".gitrows-last-sync")
+	require.NoError(t, os.MkdirAll(filepath.Join(markerPath, "nested"), 0755))
+
+	var failedPath string
+	var failedErr error
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithOnSyncMarkerError(func(filePath string, err error) {
+		failedPath, failedErr = filePath, err
+	}))
+
+	_, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	assert.Equal(t, markerPath, failedPath)
+	assert.Error(t, failedErr)
+
+	_, _, ok := db.LastSyncInfo()
+	assert.True(t, ok)
+}
@@ -0,0 +1,62 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// WithTempDir clones the repository into new temporary directory (created by os.MkdirTemp, so it follows TMPDIR)
+// instead of the directory of WithLocalGitVolume, and removes it on Close.
+// This is useful for ephemeral consumer which doesn't want to keep the clone after it is done.
+func WithTempDir() Opt {
+	return func(db *DBImpl) error {
+		db.useTempDir = true
+		return nil
+	}
+}
+
+// Close flushes the writes buffered by WithWriteCoalescing and pushes the commits of WithPushEvery,
+// then ends the lifetime of the DB (see WithBaseContext), and removes the temporary directory of WithTempDir,
+// including the clones of WriteToBranches.
+// The DB must not be used after Close, even when it fails: the lifetime is still ended and the background flush
+// is stopped, so the writes which fail to flush are lost, while the temporary directory is kept to inspect
+// the commits left unpushed.
+// The journal is written into the writer of WithOpJournalDump at the end, even when Close fails.
+func (db *DBImpl) Close() error {
+	defer db.dumpOpJournal()
+
+	var flushErr error
+	if db.coalescer != nil || db.pushEvery > 1 {
+		flushErr = db.Flush(context.Background())
+	}
+
+	if db.coalescer != nil {
+		db.coalescer.stop()
+	}
+
+	if db.endLifetime != nil {
+		db.endLifetime()
+	}
+
+	if flushErr != nil {
+		return flushErr
+	}
+
+	if db.tempRoot == "" {
+		return nil
+	}
+
+	db.branchDBsMu.Lock()
+	db.branchDBs = nil
+	db.branchDBsMu.Unlock()
+
+	db.gitRepo = nil
+
+	err := os.RemoveAll(db.tempRoot)
+	if err != nil {
+		return fmt.Errorf("cannot remove temporary git volume '%s': %w", db.tempRoot, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package gitrows
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTempDir(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	db, err := New(WithGitSshUrl("file://"+remoteDir), WithTempDir())
+	require.NoError(t, err)
+
+	// the host/path structure is kept inside the temporary directory
+	assert.NotEmpty(t, db.tempRoot)
+	assert.True(t, strings.HasPrefix(db.gitVolume, db.tempRoot+"/"), db.gitVolume)
+	assert.True(t, strings.HasSuffix(db.gitVolume, remoteDir), db.gitVolume)
+
+	_, err = db.Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	_, err = db.WriteToBranches(ctx, []string{"staging"}, "note.md", []byte("staging"))
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(db.gitVolume, "note.md"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.Close())
+
+	_, err = os.Stat(db.tempRoot)
+	assert.True(t, os.IsNotExist(err), err)
+
+	// closing again and closing DB without WithTempDir do nothing
+	assert.NoError(t, db.Close())
+
+	volume := t.TempDir()
+	persistent, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(volume))
+	require.NoError(t, err)
+
+	data, err := persistent.Get(ctx, "note.md")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+
+	require.NoError(t, persistent.Close())
+
+	_, err = os.Stat(filepath.Join(persistent.gitVolume, "note.md"))
+	assert.NoError(t, err)
+}
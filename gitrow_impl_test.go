@@ -0,0 +1,511 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+// TestMain set the HOME into temporary directory containing .gitconfig,
+// so the commit author is always available regardless the machine global git config.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "gitrows-home")
+	if err != nil {
+		panic(err)
+	}
+
+	gitConfig := "[user]\n\tname = gitrows\n\temail = gitrows@example.com\n"
+	err = os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(gitConfig), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	_ = os.Setenv("HOME", home)
+
+	code := m.Run()
+	_ = os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// newTestRemote creates an empty bare repository to be used as the remote repository.
+func newTestRemote(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(dir, true)
+	require.NoError(t, err)
+
+	return "file://" + dir
+}
+
+// newTestDB creates DB against remote URL, using its own local git volume.
+func newTestDB(t *testing.T, remoteURL string, opts ...gitrows.Opt) *gitrows.DBImpl {
+	t.Helper()
+
+	opts = append([]gitrows.Opt{
+		gitrows.WithGitSshUrl(remoteURL),
+		gitrows.WithLocalGitVolume(t.TempDir()),
+	}, opts...)
+
+	db, err := gitrows.New(opts...)
+	require.NoError(t, err)
+	require.NotNil(t, db)
+
+	return db
+}
+
+// remoteCommit returns the commit object of hash from the remote repository.
+func remoteCommit(t *testing.T, remoteURL, hash string) *object.Commit {
+	t.Helper()
+
+	repo, err := git.PlainOpen(strings.TrimPrefix(remoteURL, "file://"))
+	require.NoError(t, err)
+
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	require.NoError(t, err)
+
+	return commit
+}
+
+// remoteHead returns the commit hash of master branch in the remote repository.
+func remoteHead(t *testing.T, remoteURL string) string {
+	t.Helper()
+
+	repo, err := git.PlainOpen(strings.TrimPrefix(remoteURL, "file://"))
+	require.NoError(t, err)
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+
+	return ref.Hash().String()
+}
+
+func TestRequireExistingBranch(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	t.Run("empty remote", func(t *testing.T) {
+		db := newTestDB(t, remote, gitrows.WithRequireExistingBranch(true))
+
+		_, err := db.Get(ctx, "note.md")
+		assert.True(t, errors.Is(err, gitrows.ErrBranchNotFound), err)
+	})
+
+	writer := newTestDB(t, remote)
+	_, err := writer.Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	t.Run("branch exists", func(t *testing.T) {
+		db := newTestDB(t, remote, gitrows.WithRequireExistingBranch(true))
+
+		data, err := db.Get(ctx, "note.md")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), data)
+	})
+
+	t.Run("typo in branch name", func(t *testing.T) {
+		db := newTestDB(t, remote, gitrows.WithBranch("mastr"), gitrows.WithRequireExistingBranch(true))
+
+		_, err := db.List(ctx)
+		assert.True(t, errors.Is(err, gitrows.ErrBranchNotFound), err)
+	})
+
+	t.Run("orphan branch without option", func(t *testing.T) {
+		db := newTestDB(t, remote, gitrows.WithBranch("other"))
+
+		_, err := db.Create(ctx, "other.md", []byte("other"))
+		assert.NoError(t, err)
+
+		data, err := db.Get(ctx, "other.md")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("other"), data)
+	})
+}
+
+func TestWithBranch_invalid(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	for _, branch := range []string{"", "my branch", "release..1", "/feature", "feature/", "feature\x00", "feature.lock"} {
+		_, err := gitrows.New(gitrows.WithGitSshUrl(remote), gitrows.WithBranch(branch))
+		assert.True(t, errors.Is(err, gitrows.ErrInvalidBranch), "branch %q: %v", branch, err)
+	}
+
+	// full reference name is normalized into the branch name
+	db := newTestDB(t, remote, gitrows.WithBranch("refs/heads/staging"))
+	_, err := db.Create(ctx, "note.md", []byte("staging"))
+	require.NoError(t, err)
+
+	data, err := newTestDB(t, remote, gitrows.WithBranch("staging")).Get(ctx, "note.md")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("staging"), data)
+}
+
+func TestDBImpl_NotifyRemoteChanged(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	writer := newTestDB(t, remote)
+	_, err := writer.Create(ctx, "note.md", []byte("v1"))
+	require.NoError(t, err)
+
+	reader := newTestDB(t, remote, gitrows.WithReadStaleness(time.Hour))
+	data, err := reader.Get(ctx, "note.md")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v1"), data)
+
+	hash, _, err := writer.Upsert(ctx, "note.md", []byte("v2"))
+	require.NoError(t, err)
+
+	// inside the staleness window, read is served from local repository
+	data, err = reader.Get(ctx, "note.md")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v1"), data)
+
+	// simulate the push webhook
+	reader.NotifyRemoteChanged(hash)
+
+	data, err = reader.Get(ctx, "note.md")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v2"), data)
+}
+
+func TestDBImpl_List_emptyRemote(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, entries)
+	assert.Empty(t, entries.KVs())
+}
+
+func TestWithInitCommitMessage(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote, gitrows.WithInitCommitMessage("gitrows: initialize branch"))
+
+	first, err := db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "gitrows: initialize branch", remoteCommit(t, remote, first).Message)
+
+	second, err := db.Create(ctx, "b.txt", []byte("b"), gitrows.CreateCommitMsg("add b"))
+	require.NoError(t, err)
+	assert.Equal(t, "add b", remoteCommit(t, remote, second).Message)
+}
+
+func TestDBImpl_errorCode(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	_, err := db.Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	t.Run("get not found", func(t *testing.T) {
+		_, err := db.Get(ctx, "missing.md")
+		assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+		assert.True(t, errors.Is(err, &gitrows.Error{Code: gitrows.CodeNotFound, Op: gitrows.OpGet, Key: "missing.md"}))
+	})
+
+	t.Run("create already exists", func(t *testing.T) {
+		_, err := db.Create(ctx, "note.md", []byte("hello"))
+		assert.Equal(t, gitrows.CodeAlreadyExists, gitrows.ErrorCode(err))
+		assert.True(t, errors.Is(err, os.ErrExist))
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		_, err := db.Delete(ctx, "missing.md")
+		assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		_, _, err := db.Upsert(ctx, "../outside.md", []byte("hello"))
+		assert.Equal(t, gitrows.CodeInvalidKey, gitrows.ErrorCode(err))
+		assert.True(t, errors.Is(err, gitrows.ErrInvalidKey))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := newTestDB(t, newTestRemote(t)).List(canceledCtx)
+		assert.Equal(t, gitrows.CodeCanceled, gitrows.ErrorCode(err))
+	})
+
+	t.Run("remote unavailable", func(t *testing.T) {
+		_, err := newTestDB(t, "file://"+filepath.Join(t.TempDir(), "missing.git")).List(ctx)
+		assert.Equal(t, gitrows.CodeRemoteUnavailable, gitrows.ErrorCode(err))
+	})
+}
+
+func TestWithStaleReadsOnRemoteError(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	stale := newTestDB(t, remote, gitrows.WithStaleReadsOnRemoteError())
+	strict := newTestDB(t, remote)
+
+	_, err := stale.Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	_, err = strict.Get(ctx, "note.md")
+	require.NoError(t, err)
+
+	// simulate the git host is down
+	remoteDir := strings.TrimPrefix(remote, "file://")
+	require.NoError(t, os.Rename(remoteDir, remoteDir+".down"))
+
+	data, err := stale.Get(ctx, "note.md")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+	assert.True(t, errors.Is(stale.LastSyncError(), gitrows.ErrRemoteUnavailable), stale.LastSyncError())
+
+	_, _, err = stale.Upsert(ctx, "note.md", []byte("world"))
+	assert.True(t, errors.Is(err, gitrows.ErrRemoteUnavailable), err)
+	assert.Equal(t, gitrows.CodeRemoteUnavailable, gitrows.ErrorCode(err))
+
+	_, err = strict.Get(ctx, "note.md")
+	assert.True(t, errors.Is(err, gitrows.ErrRemoteUnavailable), err)
+
+	// once the remote is back, the sync error is cleared
+	require.NoError(t, os.Rename(remoteDir+".down", remoteDir))
+
+	_, err = stale.Get(ctx, "note.md")
+	assert.NoError(t, err)
+	assert.NoError(t, stale.LastSyncError())
+}
+
+func TestSyncCallbacks(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	var started, ended int
+	var lastErr error
+	phases := make(map[string]bool)
+	db := newTestDB(t, remote,
+		gitrows.WithOnSyncStart(func() {
+			started++
+		}),
+		gitrows.WithOnSyncEnd(func(elapsed time.Duration, err error) {
+			ended++
+			lastErr = err
+			assert.True(t, elapsed > 0)
+		}),
+		gitrows.WithCloneProgress(func(p gitrows.SyncProgress) {
+			phases[p.Phase] = true
+		}),
+	)
+
+	_, err = db.Get(ctx, "note.md")
+	require.NoError(t, err)
+	assert.Equal(t, 1, started)
+	assert.Equal(t, 1, ended)
+	assert.NoError(t, lastErr)
+	assert.True(t, phases["counting"], phases)
+}
+
+func TestWithReadOnly(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	_, err := newTestDB(t, remote).Create(ctx, "note.md", []byte("hello"))
+	require.NoError(t, err)
+
+	db := newTestDB(t, remote, gitrows.WithReadOnly())
+
+	_, err = db.Create(ctx, "other.md", []byte("other"))
+	assert.True(t, errors.Is(err, gitrows.ErrReadOnly), err)
+	assert.Equal(t, gitrows.CodeReadOnly, gitrows.ErrorCode(err))
+
+	_, _, err = db.Upsert(ctx, "note.md", []byte("world"))
+	assert.True(t, errors.Is(err, gitrows.ErrReadOnly), err)
+
+	_, err = db.Delete(ctx, "note.md")
+	assert.True(t, errors.Is(err, gitrows.ErrReadOnly), err)
+
+	_, _, err = db.PutContentAddressed(ctx, []byte("world"))
+	assert.True(t, errors.Is(err, gitrows.ErrReadOnly), err)
+
+	data, err := db.Get(ctx, "note.md")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
+
+// listKeys returns the keys of entries.
+func listKeys(entries gitrows.Entries) []string {
+	keys := make([]string, 0)
+	for _, kv := range entries.KVs() {
+		keys = append(keys, kv.Key())
+	}
+
+	return keys
+}
+
+func TestDBImpl_List_limitAndPrefix(t *testing.T) {
+	ctx := context.TODO()
+	db := newTestDB(t, newTestRemote(t))
+
+	for _, key := range []string{"a.txt", "configs/a.yaml", "configs/b.yaml", "configs/c.yaml"} {
+		_, err := db.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries.KVs(), 4)
+	assert.False(t, entries.Truncated())
+
+	entries, err = db.List(ctx, gitrows.ListPrefix("configs"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"configs/a.yaml", "configs/b.yaml", "configs/c.yaml"}, listKeys(entries))
+
+	entries, err = db.List(ctx, gitrows.ListPrefix("configs"), gitrows.ListLimit(2))
+	require.NoError(t, err)
+	assert.Len(t, entries.KVs(), 2)
+	assert.True(t, entries.Truncated())
+
+	entries, err = db.List(ctx, gitrows.ListPrefix("configs"), gitrows.ListLimit(3))
+	require.NoError(t, err)
+	assert.Len(t, entries.KVs(), 3)
+	assert.False(t, entries.Truncated())
+
+	_, err = db.List(ctx, gitrows.ListLimit(-1))
+	assert.Error(t, err)
+}
+
+func TestDBImpl_List_valueOfListedCommit(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "a.txt", []byte("a1"))
+	require.NoError(t, err)
+
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries.KVs(), 1)
+
+	// the later writes and syncs don't change the listed value
+	_, _, err = newTestDB(t, remote).Upsert(ctx, "a.txt", []byte("a2"))
+	require.NoError(t, err)
+
+	_, _, err = db.Upsert(ctx, "b.txt", []byte("b"))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _err := db.List(ctx)
+		done <- _err
+	}()
+
+	values, err := entries.ToMap(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"a.txt": []byte("a1")}, values)
+	require.NoError(t, <-done)
+}
+
+func TestDBImpl_globMetacharacters(t *testing.T) {
+	ctx := context.TODO()
+
+	// each key is also the pattern matching its sibling, which must not be staged nor deleted with it
+	for _, key := range []string{"rules[prod].json", "rules[pd].json", "rules*.json", "rules?.json"} {
+		t.Run(key, func(t *testing.T) {
+			remote := newTestRemote(t)
+			db := newTestDB(t, remote)
+
+			_, err := db.Create(ctx, "rulesp.json", []byte("sibling"))
+			require.NoError(t, err)
+
+			_, err = db.Create(ctx, key, []byte("created"))
+			require.NoError(t, err)
+
+			_, changed, err := db.Upsert(ctx, key, []byte("updated"))
+			require.NoError(t, err)
+			assert.True(t, changed)
+
+			_, err = db.PutReader(ctx, key, strings.NewReader("streamed"))
+			require.NoError(t, err)
+
+			reader := newTestDB(t, remote)
+			data, err := reader.Get(ctx, key)
+			require.NoError(t, err)
+			assert.Equal(t, "streamed", string(data))
+
+			data, err = reader.Get(ctx, "rulesp.json")
+			require.NoError(t, err)
+			assert.Equal(t, "sibling", string(data))
+
+			_, err = db.Delete(ctx, key)
+			require.NoError(t, err)
+
+			entries, err := newTestDB(t, remote).List(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"rulesp.json"}, listKeys(entries))
+		})
+	}
+}
+
+func TestDBImpl_Delete(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	volume := t.TempDir()
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume))
+	worktreeDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+
+	for _, key := range []string{"root.txt", "nested/keep.txt", "nested/dir/last.txt"} {
+		_, err := db.Create(ctx, key, []byte(key))
+		require.NoError(t, err)
+	}
+
+	// last file in nested directory
+	commit, err := db.Delete(ctx, "nested/dir/last.txt")
+	require.NoError(t, err)
+
+	tree, err := remoteCommit(t, remote, commit).Tree()
+	require.NoError(t, err)
+
+	_, err = tree.Tree("nested/dir")
+	assert.True(t, errors.Is(err, object.ErrDirectoryNotFound))
+	_, err = tree.File("nested/keep.txt")
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(worktreeDir, "nested", "dir"))
+	assert.True(t, os.IsNotExist(err))
+	assert.DirExists(t, filepath.Join(worktreeDir, "nested"))
+
+	// key at repository root
+	commit, err = db.Delete(ctx, "root.txt")
+	require.NoError(t, err)
+
+	tree, err = remoteCommit(t, remote, commit).Tree()
+	require.NoError(t, err)
+
+	_, err = tree.File("root.txt")
+	assert.True(t, errors.Is(err, object.ErrFileNotFound))
+	assert.NoFileExists(t, filepath.Join(worktreeDir, "root.txt"))
+
+	_, err = db.Delete(ctx, "root.txt")
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+	// directory is not a key
+	_, err = db.Delete(ctx, "nested")
+	assert.Error(t, err)
+
+	data, err := db.Get(ctx, "nested/keep.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("nested/keep.txt"), data)
+}
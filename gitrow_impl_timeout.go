@@ -0,0 +1,106 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithOperationTimeout bounds every call to the remote repository (fetch, push and `git ls-remote`) by d,
+// on top of the deadline of the ctx given to the command. Zero means only the ctx deadline, which is the default.
+//
+// The cancellation is best-effort: the context given to go-git is cancelled, which closes the connection
+// of the transport which honors it, but go-git may still block (i.e: the network stall without progress).
+// The command then returns context.DeadlineExceeded as soon as the deadline passes, while the abandoned call
+// winds down in the background, and may still report its progress (WithCloneProgress) meanwhile.
+// The next call to the remote repository waits for it to finish first, so they never run concurrently
+// on the same local repository.
+func WithOperationTimeout(d time.Duration) Opt {
+	return func(db *DBImpl) error {
+		if d < 0 {
+			return fmt.Errorf("operation timeout must not be negative, got %s", d)
+		}
+
+		db.operationTimeout = d
+		return nil
+	}
+}
+
+// remoteCall runs fn which calls the remote repository, returning when fn is done or the deadline passes,
+// whichever comes first, see WithOperationTimeout.
+func (db *DBImpl) remoteCall(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := db.waitAbandoned(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := db.withLifetime(ctx)
+	defer cancel()
+
+	// the DB is closed
+	if db.lifetime != nil && db.lifetime.Err() != nil {
+		return fmt.Errorf("remote operation is cancelled: %w", db.lifetime.Err())
+	}
+
+	if db.operationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, db.operationTimeout)
+		defer cancel()
+	}
+
+	// no deadline nor cancellation to watch
+	if ctx.Done() == nil {
+		return fn(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err = fn(ctx)
+	}()
+
+	select {
+	case <-done:
+		return err
+
+	case <-ctx.Done():
+	}
+
+	// fn may have returned just as the deadline passed
+	select {
+	case <-done:
+		return err
+
+	default:
+	}
+
+	db.syncMu.Lock()
+	db.abandoned = done
+	db.syncMu.Unlock()
+
+	return fmt.Errorf("remote operation is abandoned: %w", ctx.Err())
+}
+
+// waitAbandoned waits for the remote call abandoned by remoteCall to finish.
+func (db *DBImpl) waitAbandoned(ctx context.Context) error {
+	db.syncMu.Lock()
+	abandoned := db.abandoned
+	db.syncMu.Unlock()
+
+	if abandoned == nil {
+		return nil
+	}
+
+	select {
+	case <-abandoned:
+		db.syncMu.Lock()
+		if db.abandoned == abandoned {
+			db.abandoned = nil
+		}
+		db.syncMu.Unlock()
+		return nil
+
+	case <-ctx.Done():
+		return fmt.Errorf("the abandoned remote operation is still running: %w", ctx.Err())
+	}
+}
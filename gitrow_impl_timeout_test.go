@@ -0,0 +1,67 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOperationTimeout(t *testing.T) {
+	ctx := context.TODO()
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	db, err := New(WithGitSshUrl("file://"+remoteDir), WithLocalGitVolume(t.TempDir()), WithOperationTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	// the call which ignores the cancellation, like go-git blocked on the stalled connection
+	release := make(chan struct{})
+	stalled := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+
+	start := time.Now()
+	err = db.remoteCall(ctx, stalled)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal(t, CodeCanceled, codeOf(err))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the next call waits for the abandoned one, within its own deadline
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	called := false
+	err = db.remoteCall(shortCtx, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.False(t, called)
+
+	_, err = db.Create(shortCtx, "a.txt", []byte("a"))
+	assert.Equal(t, CodeCanceled, ErrorCode(err))
+
+	close(release)
+	_, err = db.Create(ctx, "a.txt", []byte("a"))
+	require.NoError(t, err)
+
+	// the call which finishes in time is not affected
+	err = db.remoteCall(ctx, func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	_, err = New(WithGitSshUrl("file://"+remoteDir), WithOperationTimeout(-time.Second))
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package gitrows
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+func (e *entriesImpl) ToMap(ctx context.Context, maxTotalBytes int64) (values map[string][]byte, err error) {
+	key := ""
+	defer func() {
+		err = wrapError(OpToMap, key, err)
+	}()
+
+	if maxTotalBytes < 0 {
+		err = fmt.Errorf("to map: budget must not be negative, got %d", maxTotalBytes)
+		return
+	}
+
+	values = make(map[string][]byte, len(e.kvs))
+	remaining := maxTotalBytes
+
+	for _, kv := range e.kvs {
+		key = kv.Key()
+
+		err = ctx.Err()
+		if err != nil {
+			err = fmt.Errorf("to map: %w", err)
+			values = nil
+			return
+		}
+
+		var data []byte
+		data, err = readValue(kv, maxTotalBytes > 0, remaining)
+		if err != nil {
+			err = fmt.Errorf("to map: %w", err)
+			values = nil
+			return
+		}
+
+		if maxTotalBytes > 0 && int64(len(data)) > remaining {
+			err = fmt.Errorf("to map: %w: value of key '%s' crosses the budget of %d bytes", ErrBudgetExceeded, key, maxTotalBytes)
+			values = nil
+			return
+		}
+
+		remaining -= int64(len(data))
+		values[key] = data
+	}
+
+	key = ""
+	return
+}
+
+// readValue reads the value of kv. When limited, it reads at most one byte more than remaining,
+// which is enough to know that the value crosses the remaining bytes without reading it all.
+func readValue(kv KV, limited bool, remaining int64) (data []byte, err error) {
+	reader, err := kv.Value()
+	if err != nil {
+		err = fmt.Errorf("cannot open value of key '%s': %w", kv.Key(), err)
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = fmt.Errorf("cannot close value of key '%s': %w", kv.Key(), _err)
+		}
+	}()
+
+	var r io.Reader = reader
+	if limited {
+		r = io.LimitReader(reader, remaining+1)
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = buf.ReadFrom(r)
+	if err != nil {
+		err = fmt.Errorf("cannot read value of key '%s': %w", kv.Key(), err)
+		return
+	}
+
+	data = buf.Bytes()
+	return
+}
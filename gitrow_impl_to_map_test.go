@@ -0,0 +1,57 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestEntries_ToMap(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	_, err := db.Create(ctx, "configs/app.yaml", []byte("app"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "configs/db.yaml", []byte("db"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "configs/huge.bin", []byte(strings.Repeat("x", 1024)))
+	require.NoError(t, err)
+
+	entries, err := db.List(ctx, gitrows.ListPrefix("configs"))
+	require.NoError(t, err)
+
+	values, err := entries.ToMap(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"configs/app.yaml": []byte("app"),
+		"configs/db.yaml":  []byte("db"),
+		"configs/huge.bin": []byte(strings.Repeat("x", 1024)),
+	}, values)
+
+	// exactly the total size is within the budget
+	values, err = entries.ToMap(ctx, 3+2+1024)
+	require.NoError(t, err)
+	assert.Len(t, values, 3)
+
+	values, err = entries.ToMap(ctx, 100)
+	assert.Nil(t, values)
+	assert.True(t, errors.Is(err, gitrows.ErrBudgetExceeded), err)
+	assert.True(t, errors.Is(err, &gitrows.Error{Code: gitrows.CodeValueTooLarge, Key: "configs/huge.bin"}), err)
+
+	_, err = entries.ToMap(ctx, -1)
+	assert.Error(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = entries.ToMap(canceled, 0)
+	assert.Equal(t, gitrows.CodeCanceled, gitrows.ErrorCode(err))
+}
@@ -0,0 +1,105 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ListTree is like List, but returns the keys as nested directories and files under the root directory,
+// i.e: for rendering a file explorer without reconstructing the hierarchy from the slashes in the key.
+// It accepts the same ListOpt as List, and the same caveat of LastCommit applies.
+//
+// When ListLimit truncates the keys, the tree only contains the returned keys, use List to know whether it is truncated.
+func (db *DBImpl) ListTree(ctx context.Context, opts ...ListOpt) (root TreeNode, err error) {
+	defer func() {
+		err = wrapError(OpListTree, "", err)
+	}()
+
+	cfg := &ListConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("list tree command: %w", err)
+			return
+		}
+	}
+
+	err = db.syncForRead(ctx)
+	if err != nil {
+		err = fmt.Errorf("list tree command: %w", err)
+		return
+	}
+
+	kvIters, _, err := db.list(cfg)
+	if err != nil {
+		err = fmt.Errorf("list tree command: %w", err)
+		return
+	}
+
+	err = db.resolveLastCommits(kvIters)
+	if err != nil {
+		err = fmt.Errorf("list tree command: %w", err)
+		return
+	}
+
+	root = buildTree("", kvIters)
+	return
+}
+
+// buildTree returns the directory of dir, containing all kvIters which key is under dir.
+func buildTree(dir string, kvIters []*kvIter) TreeNode {
+	node := TreeNode{
+		Name:     path.Base(dir),
+		Path:     dir,
+		IsDir:    true,
+		Children: make([]TreeNode, 0),
+	}
+
+	if dir == "" {
+		node.Name = ""
+	}
+
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	// group the keys by the first element after dir, keeping the order of first appearance
+	subDirs := make([]string, 0)
+	subDirKVs := make(map[string][]*kvIter)
+	for _, kv := range kvIters {
+		name := strings.TrimPrefix(kv.k, prefix)
+
+		i := strings.Index(name, "/")
+		if i < 0 {
+			node.Children = append(node.Children, TreeNode{
+				Name:       name,
+				Path:       kv.k,
+				LastCommit: kv.LastCommit(),
+				Size:       kv.size,
+				Mode:       kv.mode,
+			})
+			continue
+		}
+
+		subDir := prefix + name[:i]
+		if _, exist := subDirKVs[subDir]; !exist {
+			subDirs = append(subDirs, subDir)
+		}
+
+		subDirKVs[subDir] = append(subDirKVs[subDir], kv)
+	}
+
+	for _, subDir := range subDirs {
+		node.Children = append(node.Children, buildTree(subDir, subDirKVs[subDir]))
+	}
+
+	sort.Slice(node.Children, func(i, j int) bool {
+		return node.Children[i].Name < node.Children[j].Name
+	})
+
+	return node
+}
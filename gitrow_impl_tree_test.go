@@ -0,0 +1,90 @@
+package gitrows_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_ListTree(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	root, err := db.ListTree(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, gitrows.TreeNode{IsDir: true, Children: []gitrows.TreeNode{}}, root)
+
+	for key, value := range map[string]string{
+		"readme.md":              "hello",
+		"configs/app.yaml":       "app: 1",
+		"configs/db/mysql.yaml":  "mysql",
+		"configs/db/redis.yaml":  "redis",
+		"users/alice/profile.md": "alice",
+	} {
+		_, err = db.Create(ctx, key, []byte(value))
+		require.NoError(t, err)
+	}
+
+	// fresh clone, so every file is last modified by the head commit, see List
+	head := remoteHead(t, remote)
+
+	root, err = newTestDB(t, remote).ListTree(ctx)
+	require.NoError(t, err)
+
+	file := func(name, path string, size int64) gitrows.TreeNode {
+		return gitrows.TreeNode{Name: name, Path: path, LastCommit: head, Size: size, Mode: 0644}
+	}
+
+	expected := gitrows.TreeNode{
+		IsDir: true,
+		Children: []gitrows.TreeNode{
+			{
+				Name:  "configs",
+				Path:  "configs",
+				IsDir: true,
+				Children: []gitrows.TreeNode{
+					file("app.yaml", "configs/app.yaml", 6),
+					{
+						Name:  "db",
+						Path:  "configs/db",
+						IsDir: true,
+						Children: []gitrows.TreeNode{
+							file("mysql.yaml", "configs/db/mysql.yaml", 5),
+							file("redis.yaml", "configs/db/redis.yaml", 5),
+						},
+					},
+				},
+			},
+			file("readme.md", "readme.md", 5),
+			{
+				Name:  "users",
+				Path:  "users",
+				IsDir: true,
+				Children: []gitrows.TreeNode{
+					{
+						Name:  "alice",
+						Path:  "users/alice",
+						IsDir: true,
+						Children: []gitrows.TreeNode{
+							file("profile.md", "users/alice/profile.md", 5),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, root)
+
+	// ListPrefix only keeps the keys under the prefix
+	root, err = newTestDB(t, remote).ListTree(ctx, gitrows.ListPrefix("configs/db"))
+	require.NoError(t, err)
+
+	configs := expected.Children[0]
+	configs.Children = configs.Children[1:]
+	assert.Equal(t, gitrows.TreeNode{IsDir: true, Children: []gitrows.TreeNode{configs}}, root)
+}
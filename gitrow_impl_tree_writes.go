@@ -0,0 +1,448 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// WithTreeWrites makes Create, Upsert and Delete build the new commit from the tree of the local branch,
+// replacing only the entries along the key, and store it directly into the object storage without touching
+// the worktree: there is no checkout, `git add` or hard reset, which are the most of the write time
+// on the large repository. Get reads the value from the committed tree too, so it doesn't need the checkout either.
+//
+// The other commands still use the worktree, and check it out on their next sync.
+// When the push is reconciled (see WithMergeResolver), the reconciled commit is also created in the worktree.
+func WithTreeWrites() Opt {
+	return func(db *DBImpl) error {
+		db.treeWrites = true
+		return nil
+	}
+}
+
+// syncForWrite is forcePull, but without the checkout when WithTreeWrites is enabled.
+func (db *DBImpl) syncForWrite(ctx context.Context) error {
+	return db.pull(ctx, db.gitFetch, !db.treeWrites)
+}
+
+// writeTree is like writeFile, but returns the tree of the local branch with the file path replaced by data,
+// or removed when mode is "DELETE". Every changed tree is stored, but the local branch is not moved yet, see commitTree.
+func (db *DBImpl) writeTree(ctx context.Context, key string, data []byte, mode string, fileMode os.FileMode) (treeHash plumbing.Hash, err error) {
+	key = path.Clean(key)
+
+	err = db.phase(ctx, phaseWrite)
+	if err != nil {
+		return
+	}
+
+	err = db.checkCaseCollision(key)
+	if err != nil {
+		return
+	}
+
+	err = db.checkNotPreserved(key)
+	if err != nil {
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	var tree *object.Tree
+	if head != nil {
+		tree, err = head.Tree()
+		if err != nil {
+			err = fmt.Errorf("retrieve the tree from the commit %s error: %w", head.Hash, err)
+			return
+		}
+	}
+
+	var existing *object.TreeEntry
+	if tree != nil {
+		existing, err = tree.FindEntry(key)
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			err = nil
+		}
+
+		if err != nil {
+			err = fmt.Errorf("cannot find '%s' in the tree: %w", key, err)
+			return
+		}
+	}
+
+	isDir := existing != nil && existing.Mode == filemode.Dir
+
+	var entry *object.TreeEntry
+	switch mode {
+	case "CREATE":
+		if existing != nil {
+			err = fmt.Errorf("%w: cannot create '%s' because it exists in the tree", os.ErrExist, key)
+			return
+		}
+
+	case "UPSERT":
+		if isDir {
+			err = fmt.Errorf("cannot upsert '%s' because it is a directory in the tree", key)
+			return
+		}
+
+	case "DELETE":
+		if existing == nil {
+			err = fmt.Errorf("cannot delete '%s': %w", key, os.ErrNotExist)
+			return
+		}
+
+		if isDir {
+			err = fmt.Errorf("cannot delete '%s' because it is a directory", key)
+			return
+		}
+
+	default:
+		err = fmt.Errorf("unknown write tree mode=%s", mode)
+		return
+	}
+
+	if mode != "DELETE" {
+		entry, err = db.storeBlob(key, data, treeFileMode(existing, fileMode))
+		if err != nil {
+			return
+		}
+	}
+
+	treeHash, _, err = db.replaceTreeEntry(tree, strings.Split(key, "/"), entry)
+	if err != nil {
+		err = fmt.Errorf("cannot write '%s' into the tree: %w", key, err)
+		return
+	}
+
+	return
+}
+
+// treeFileMode is like filePerm, but for the tree entry: zero mode keeps the mode of the existing entry.
+func treeFileMode(existing *object.TreeEntry, mode os.FileMode) filemode.FileMode {
+	if mode == 0 && existing != nil {
+		return existing.Mode
+	}
+
+	fm, err := filemode.NewFromOSFileMode(filePerm(nil, mode))
+	if err != nil {
+		return filemode.Regular
+	}
+
+	return fm
+}
+
+// storeBlob stores data as blob, and returns the tree entry of the file path pointing to it.
+func (db *DBImpl) storeBlob(filePath string, data []byte, mode filemode.FileMode) (entry *object.TreeEntry, err error) {
+	obj := db.gitRepo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(data)))
+
+	writer, err := obj.Writer()
+	if err != nil {
+		err = fmt.Errorf("cannot write blob of '%s': %w", filePath, err)
+		return
+	}
+
+	_, err = writer.Write(data)
+	if _err := writer.Close(); _err != nil && err == nil {
+		err = _err
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot write blob of '%s': %w", filePath, err)
+		return
+	}
+
+	hash, err := db.gitRepo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		err = fmt.Errorf("cannot store blob of '%s': %w", filePath, err)
+		return
+	}
+
+	entry = &object.TreeEntry{
+		Name: path.Base(filePath),
+		Mode: mode,
+		Hash: hash,
+	}
+	return
+}
+
+// replaceTreeEntry stores the copy of tree (nil is the empty tree) with the entry at the path parts replaced by entry,
+// or removed when entry is nil, then returns its hash. Every subtree along the path is rewritten the same way,
+// and the subtree which becomes empty is removed, since git doesn't track empty directory.
+func (db *DBImpl) replaceTreeEntry(tree *object.Tree, parts []string, entry *object.TreeEntry) (treeHash plumbing.Hash, empty bool, err error) {
+	entries := make([]object.TreeEntry, 0)
+	var current *object.TreeEntry
+	if tree != nil {
+		for i := range tree.Entries {
+			if tree.Entries[i].Name == parts[0] {
+				current = &tree.Entries[i]
+				continue
+			}
+
+			entries = append(entries, tree.Entries[i])
+		}
+	}
+
+	replacement := entry
+	if len(parts) > 1 {
+		var subtree *object.Tree
+		if current != nil {
+			if current.Mode != filemode.Dir {
+				err = fmt.Errorf("'%s' is not a directory", parts[0])
+				return
+			}
+
+			subtree, err = db.gitRepo.TreeObject(current.Hash)
+			if err != nil {
+				err = fmt.Errorf("cannot get tree '%s': %w", parts[0], err)
+				return
+			}
+		}
+
+		var subtreeHash plumbing.Hash
+		var subtreeEmpty bool
+		subtreeHash, subtreeEmpty, err = db.replaceTreeEntry(subtree, parts[1:], entry)
+		if err != nil {
+			return
+		}
+
+		replacement = nil
+		if !subtreeEmpty {
+			replacement = &object.TreeEntry{Name: parts[0], Mode: filemode.Dir, Hash: subtreeHash}
+		}
+	}
+
+	if replacement != nil {
+		entries = append(entries, *replacement)
+	}
+
+	// git sorts the directory as if its name ends with slash
+	sortKey := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+
+		return e.Name
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return sortKey(entries[i]) < sortKey(entries[j])
+	})
+
+	obj := db.gitRepo.Storer.NewEncodedObject()
+	err = (&object.Tree{Entries: entries}).Encode(obj)
+	if err != nil {
+		err = fmt.Errorf("cannot encode tree: %w", err)
+		return
+	}
+
+	treeHash, err = db.gitRepo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		err = fmt.Errorf("cannot store tree: %w", err)
+		return
+	}
+
+	return treeHash, len(entries) == 0, nil
+}
+
+// commitTree is like gitCommit, but commits the tree built by writeTree on top of the local branch,
+// and moves the local branch to it without touching the worktree, which is checked out on the next sync that needs it.
+func (db *DBImpl) commitTree(ctx context.Context, treeHash plumbing.Hash, commitMsg string, allowEmptyCommit bool) (commitHash plumbing.Hash, err error) {
+	err = db.phase(ctx, phaseCommit)
+	if err != nil {
+		return
+	}
+
+	// the author and the parent are resolved the same as `git commit`
+	opts := &git.CommitOptions{}
+	err = opts.Validate(db.gitRepo)
+	if err != nil {
+		err = fmt.Errorf("cannot `git commit -m %q`: %w", commitMsg, err)
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		return
+	}
+
+	if head == nil && !db.createBranch {
+		err = fmt.Errorf("%w: branch '%s' doesn't exist in %s, and WithCreateBranchIfMissing is false", ErrBranchNotFound, db.gitBranch, db.gitSshUrl)
+		return
+	}
+
+	if head == nil && db.initCommitMsg != "" {
+		commitMsg = db.initCommitMessage(commitMsg)
+	}
+
+	if !allowEmptyCommit && head != nil && head.TreeHash == treeHash {
+		err = fmt.Errorf("cannot `git commit -m %q`: %w", commitMsg, git.ErrEmptyCommit)
+		return
+	}
+
+	commit := &object.Commit{
+		Author:       *opts.Author,
+		Committer:    *opts.Committer,
+		Message:      commitMsg,
+		TreeHash:     treeHash,
+		ParentHashes: opts.Parents,
+	}
+
+	obj := db.gitRepo.Storer.NewEncodedObject()
+	err = commit.Encode(obj)
+	if err != nil {
+		err = fmt.Errorf("cannot encode commit: %w", err)
+		return
+	}
+
+	commitHash, err = db.gitRepo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		err = fmt.Errorf("cannot store commit: %w", err)
+		return
+	}
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	err = db.gitRepo.Storer.SetReference(plumbing.NewHashReference(branchName, commitHash))
+	if err != nil {
+		err = fmt.Errorf("cannot set reference %s: %w", branchName, err)
+		return
+	}
+
+	db.syncMu.Lock()
+	db.checkoutPending = true
+	db.syncMu.Unlock()
+
+	if db.commitEncoding != "" {
+		commitHash, err = db.setCommitEncoding(commitHash)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// readTreeFile is like readFile, but reads the committed file from the tree of the local branch.
+func (db *DBImpl) readTreeFile(filePath string, cfg *GetConfig) (data []byte, err error) {
+	reader, err := db.openTreeFile(filePath, cfg)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if _err := reader.Close(); _err != nil && err == nil {
+			err = _err
+		}
+	}()
+
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("cannot read file buffer: %w", err)
+		return
+	}
+
+	return
+}
+
+// openTreeFile is like openFile, but opens the blob of the committed file from the tree of the local branch.
+// The blob never changes, so there is no lock to hold until the reader is closed.
+func (db *DBImpl) openTreeFile(filePath string, cfg *GetConfig) (reader io.ReadCloser, err error) {
+	file, err := db.headFile(filePath)
+	if err != nil {
+		return
+	}
+
+	if file == nil {
+		err = fmt.Errorf("cannot open file: %w", os.ErrNotExist)
+		return
+	}
+
+	blob, err := file.Reader()
+	if err != nil {
+		err = fmt.Errorf("cannot open file: %w", err)
+		return
+	}
+
+	var content io.Reader = blob
+	if cfg.offset > 0 {
+		_, err = io.CopyN(io.Discard, blob, cfg.offset)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+
+		if err != nil {
+			_ = blob.Close()
+			err = fmt.Errorf("cannot read file buffer: %w", err)
+			return
+		}
+	}
+
+	if cfg.length > 0 {
+		content = io.LimitReader(content, cfg.length)
+	}
+
+	if cfg.maxBytes > 0 {
+		content = &limitReader{
+			r:         content,
+			key:       filePath,
+			maxBytes:  cfg.maxBytes,
+			remaining: cfg.maxBytes,
+		}
+	}
+
+	reader = &blobReader{
+		Reader: content,
+		Closer: blob,
+	}
+	return
+}
+
+// blobReader reads the value of the blob within the range and limit of GetConfig, and closes the blob.
+type blobReader struct {
+	io.Reader
+	io.Closer
+}
+
+// rollbackTree is the rollback of WithTreeWrites: only the ref is reset, since the worktree is never written,
+// and it is checked out on the next sync that needs it.
+func (db *DBImpl) rollbackTree() {
+	db.syncMu.Lock()
+	defer db.syncMu.Unlock()
+
+	db.lastSyncAt = time.Time{}
+	db.checkoutPending = true
+}
+
+// commitChange is gitCommit of the worktree, or commitTree of the tree built by writeTree with WithTreeWrites.
+func (db *DBImpl) commitChange(ctx context.Context, worktree *git.Worktree, treeHash plumbing.Hash, commitMsg string, allowEmptyCommit bool) (plumbing.Hash, error) {
+	if db.treeWrites {
+		return db.commitTree(ctx, treeHash, commitMsg, allowEmptyCommit)
+	}
+
+	return db.gitCommit(ctx, worktree, commitMsg, allowEmptyCommit)
+}
+
+// isTreeChanged returns true when the tree differs from the tree of the local branch, like `git status`.
+func (db *DBImpl) isTreeChanged(treeHash plumbing.Hash) (bool, error) {
+	head, err := db.headCommit()
+	if err != nil {
+		return false, err
+	}
+
+	return head == nil || head.TreeHash != treeHash, nil
+}
@@ -0,0 +1,110 @@
+package gitrows_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestWithTreeWrites(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	volume := t.TempDir()
+	db := newTestDB(t, remote, gitrows.WithLocalGitVolume(volume), gitrows.WithTreeWrites())
+	repoDir := filepath.Join(volume, strings.TrimPrefix(remote, "file://"))
+
+	_, err := db.Create(ctx, "a/b/c.txt", []byte("c"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "a/d.txt", []byte("d"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "top.txt", []byte("top"))
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, "a/d.txt", []byte("again"))
+	assert.ErrorIs(t, err, os.ErrExist)
+
+	// the worktree is never checked out
+	_, err = os.Stat(filepath.Join(repoDir, "a"))
+	assert.True(t, os.IsNotExist(err))
+
+	commitHash, changed, err := db.Upsert(ctx, "a/d.txt", []byte("d2"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, remoteHead(t, remote), commitHash)
+
+	// the same content is not committed
+	sameHash, changed, err := db.Upsert(ctx, "a/d.txt", []byte("d2"))
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, commitHash, sameHash)
+
+	// the mode only change is committed
+	result, err := db.UpsertR(ctx, "a/d.txt", []byte("d2"), gitrows.UpsertFileMode(0755))
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.True(t, result.MetadataChanged)
+	assert.NotEqual(t, commitHash, result.CommitHash)
+
+	data, err := db.Get(ctx, "a/d.txt", gitrows.GetRange(1, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(data))
+
+	_, err = db.Get(ctx, "a/d.txt", gitrows.GetLimit(1))
+	assert.ErrorIs(t, err, gitrows.ErrValueTooLarge)
+
+	// deleting the only file of the directory removes the directory
+	_, err = db.Delete(ctx, "a/b/c.txt")
+	require.NoError(t, err)
+
+	_, err = db.Delete(ctx, "a/b/c.txt")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = db.Delete(ctx, "a")
+	assert.Error(t, err)
+
+	// the commits are the same as the usual writes for other DB
+	other := newTestDB(t, remote)
+	entries, err := other.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/d.txt", "top.txt"}, listKeys(entries))
+
+	for key, value := range map[string]string{"a/d.txt": "d2", "top.txt": "top"} {
+		data, err = other.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, value, string(data))
+	}
+
+	entries, err = other.List(ctx, gitrows.ListPrefix("a/"))
+	require.NoError(t, err)
+	require.Len(t, entries.KVs(), 1)
+	assert.Equal(t, os.FileMode(0755), entries.KVs()[0].Mode())
+
+	// the writes of other DB are pulled without checkout
+	_, _, err = other.Upsert(ctx, "top.txt", []byte("top2"))
+	require.NoError(t, err)
+
+	data, err = db.Get(ctx, "top.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "top2", string(data))
+
+	// the commands using the worktree still check it out
+	reader, err := db.GetReader(ctx, "top.txt")
+	require.NoError(t, err)
+
+	data, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "top2", string(data))
+
+	_, err = os.Stat(filepath.Join(repoDir, "top.txt"))
+	assert.NoError(t, err)
+}
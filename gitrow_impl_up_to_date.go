@@ -0,0 +1,127 @@
+package gitrows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// IsUpToDate compares the local branch with the branch in the remote repository, without fetching any object.
+// This is like `git ls-remote <url> <branch>`, so it is much cheaper than syncing, and can be used by scheduler
+// to decide whether the sync is needed.
+//
+// Both localHead and remoteHead are empty when the branch doesn't exist (i.e: never synced or empty remote),
+// so the new DB is up-to-date with empty remote. When WithRequireExistingBranch is enabled,
+// the missing remote branch returns ErrBranchNotFound instead.
+func (db *DBImpl) IsUpToDate(ctx context.Context) (upToDate bool, localHead, remoteHead string, err error) {
+	defer func() {
+		err = wrapError(OpIsUpToDate, "", err)
+	}()
+
+	remoteHead, err = db.remoteHead(ctx)
+	if err != nil {
+		err = fmt.Errorf("is up to date command: %w", err)
+		return
+	}
+
+	localHead, err = db.localHead()
+	if err != nil {
+		err = fmt.Errorf("is up to date command: %w", err)
+		return
+	}
+
+	upToDate = localHead == remoteHead
+	return
+}
+
+// remoteRefs returns the references in the remote repository, or empty if the remote repository is empty.
+func (db *DBImpl) remoteRefs(ctx context.Context) (refs []*plumbing.Reference, err error) {
+	// in-memory storage, so listing doesn't need nor touch the local repository
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: gitRemoteName,
+		URLs: []string{db.gitSshUrl},
+	})
+
+	// the result is only read when the listing finishes in time, see remoteCall
+	var listed []*plumbing.Reference
+	err = db.remoteCall(ctx, func(ctx context.Context) (err error) {
+		listed, err = remote.ListContext(ctx, &git.ListOptions{
+			Auth: db.auth,
+		})
+		return
+	})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) ||
+		(db.allowMissingRemote && errors.Is(err, transport.ErrRepositoryNotFound)) {
+		return nil, nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("cannot `git ls-remote %s`: %w", gitRemoteName, remoteError(db.gitSshUrl, err))
+		return
+	}
+
+	refs = listed
+	return
+}
+
+// remoteHead returns the commit hash of the branch in the remote repository, or empty if it doesn't exist.
+func (db *DBImpl) remoteHead(ctx context.Context) (hash string, err error) {
+	refs, err := db.remoteRefs(ctx)
+	if err != nil {
+		return
+	}
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	for _, ref := range refs {
+		if ref.Name() == branchName {
+			hash = ref.Hash().String()
+			return
+		}
+	}
+
+	if db.requireExistingBranch {
+		err = fmt.Errorf("%w: branch '%s' doesn't exist in %s", ErrBranchNotFound, db.gitBranch, db.gitSshUrl)
+		return
+	}
+
+	return
+}
+
+// localHead returns the commit hash of the local branch, or empty if it doesn't exist.
+// The local repository is opened from the volume when it is not synced yet by this DB (i.e: after restart).
+func (db *DBImpl) localHead() (hash string, err error) {
+	repo := db.gitRepo
+	if repo == nil {
+		repo, err = git.PlainOpen(db.gitVolume)
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			err = nil
+			return
+		}
+
+		if err != nil {
+			err = fmt.Errorf("open local repository %s error: %w", db.gitSshUrl, err)
+			return
+		}
+	}
+
+	branchName := plumbing.NewBranchReferenceName(db.gitBranch)
+	ref, err := repo.Reference(branchName, false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("retrieving ref for branch %s error: %w", branchName, err)
+		return
+	}
+
+	hash = ref.Hash().String()
+	return
+}
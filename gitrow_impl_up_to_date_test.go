@@ -0,0 +1,72 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+func TestDBImpl_IsUpToDate(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+	db := newTestDB(t, remote)
+
+	upToDate, localHead, remoteHeadHash, err := db.IsUpToDate(ctx)
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+	assert.Empty(t, localHead)
+	assert.Empty(t, remoteHeadHash)
+
+	writer := newTestDB(t, remote)
+	_, err = writer.Create(ctx, "note.md", []byte("v1"))
+	require.NoError(t, err)
+
+	upToDate, localHead, remoteHeadHash, err = db.IsUpToDate(ctx)
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+	assert.Empty(t, localHead)
+	assert.Equal(t, remoteHead(t, remote), remoteHeadHash)
+
+	upToDate, _, _, err = writer.IsUpToDate(ctx)
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	_, err = db.Get(ctx, "note.md")
+	require.NoError(t, err)
+
+	upToDate, localHead, remoteHeadHash, err = db.IsUpToDate(ctx)
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+	assert.Equal(t, remoteHeadHash, localHead)
+
+	synced := localHead
+
+	_, _, err = writer.Upsert(ctx, "note.md", []byte("v2"))
+	require.NoError(t, err)
+
+	upToDate, localHead, remoteHeadHash, err = db.IsUpToDate(ctx)
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+	assert.Equal(t, synced, localHead)
+	assert.Equal(t, remoteHead(t, remote), remoteHeadHash)
+
+	t.Run("missing branch", func(t *testing.T) {
+		strict := newTestDB(t, remote, gitrows.WithBranch("missing"), gitrows.WithRequireExistingBranch(true))
+
+		_, _, _, err := strict.IsUpToDate(ctx)
+		assert.True(t, errors.Is(err, gitrows.ErrBranchNotFound), err)
+	})
+
+	t.Run("unreachable remote", func(t *testing.T) {
+		unreachable := newTestDB(t, "file://"+filepath.Join(t.TempDir(), "missing.git"))
+
+		_, _, _, err := unreachable.IsUpToDate(ctx)
+		assert.Error(t, err)
+		assert.Equal(t, gitrows.CodeRemoteUnavailable, gitrows.ErrorCode(err), err)
+	})
+}
@@ -0,0 +1,142 @@
+package gitrows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// WithVerifyCommits verifies the PGP signature of the last commit resolved by List, ListModifiedBetween
+// and GetWithCommit against the keyring, and report the result as CommitInfo.Verified.
+// Use WithRejectUnsignedCommits to fail the read instead.
+func WithVerifyCommits(keyring openpgp.KeyRing) Opt {
+	return func(db *DBImpl) error {
+		db.verifyKeyring = keyring
+		return nil
+	}
+}
+
+// WithRejectUnsignedCommits makes List, ListModifiedBetween and GetWithCommit return ErrUnverifiedCommit
+// when the last commit of any returned key is not signed by the keyring of WithVerifyCommits.
+// Without WithVerifyCommits, every commit is unverified.
+func WithRejectUnsignedCommits() Opt {
+	return func(db *DBImpl) error {
+		db.rejectUnverified = true
+		return nil
+	}
+}
+
+// verifyCommit returns true when the commit signature is valid against the keyring of WithVerifyCommits.
+func (db *DBImpl) verifyCommit(commit *object.Commit) bool {
+	if db.verifyKeyring == nil || commit == nil || commit.PGPSignature == "" {
+		return false
+	}
+
+	// the signature is made over the commit object without the signature itself, see object.Commit.Verify
+	encoded := &plumbing.MemoryObject{}
+	err := commit.EncodeWithoutSignature(encoded)
+	if err != nil {
+		return false
+	}
+
+	reader, err := encoded.Reader()
+	if err != nil {
+		return false
+	}
+
+	_, err = openpgp.CheckArmoredDetachedSignature(db.verifyKeyring, reader, strings.NewReader(commit.PGPSignature), nil)
+	return err == nil
+}
+
+// unverifiedCommitError returns ErrUnverifiedCommit for the key.
+func unverifiedCommitError(key string, commit *object.Commit) error {
+	return fmt.Errorf("%w: last commit %s of key '%s' is not signed by trusted key", ErrUnverifiedCommit, commit.Hash, key)
+}
+
+// newCommitInfo returns CommitInfo of the commit, or empty CommitInfo when commit is nil.
+func newCommitInfo(commit *object.Commit, verified bool) CommitInfo {
+	if commit == nil {
+		return CommitInfo{}
+	}
+
+	return CommitInfo{
+		Hash:        commit.Hash.String(),
+		AuthorName:  commit.Author.Name,
+		AuthorEmail: commit.Author.Email,
+		When:        commit.Author.When,
+		Message:     commit.Message,
+		Verified:    verified,
+	}
+}
+
+// GetWithCommit is like Get, but also returns the last commit which modifies the key.
+// See List for the caveat of last commit in the shallow local repository.
+func (db *DBImpl) GetWithCommit(ctx context.Context, key string, opts ...GetOpt) (data []byte, info CommitInfo, err error) {
+	defer func() {
+		err = wrapError(OpGetWithCommit, key, err)
+	}()
+
+	cfg := &GetConfig{}
+
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("get with commit command: %w", err)
+			return
+		}
+	}
+
+	err = db.syncForRead(ctx)
+	if err != nil {
+		err = fmt.Errorf("get with commit command: %w", err)
+		return
+	}
+
+	filePath, err := db.readPath(key)
+	if err != nil {
+		err = fmt.Errorf("get with commit command: %w", err)
+		return
+	}
+
+	head, err := db.headCommit()
+	if err != nil {
+		err = fmt.Errorf("get with commit command: %w", err)
+		return
+	}
+
+	revs, err := db.lastCommits(head, []string{filePath})
+	if err != nil {
+		err = fmt.Errorf("get with commit command: %w", err)
+		return
+	}
+
+	lastCommit := head // use current commit as default, the same as List
+	if rev, exist := revs[filePath]; exist && rev != nil {
+		lastCommit = rev
+	}
+
+	verified := db.verifyCommit(lastCommit)
+	if !verified && db.rejectUnverified {
+		err = fmt.Errorf("get with commit command: %w", unverifiedCommitError(key, lastCommit))
+		return
+	}
+
+	worktree, err := db.gitRepo.Worktree()
+	if err != nil {
+		err = fmt.Errorf("get with commit command: cannot get worktree: %w", err)
+		return
+	}
+
+	data, err = readFile(worktree.Filesystem, filePath, cfg)
+	if err != nil {
+		err = fmt.Errorf("get with commit command: %w", err)
+		return
+	}
+
+	info = newCommitInfo(lastCommit, verified)
+	return
+}
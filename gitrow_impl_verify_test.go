@@ -0,0 +1,112 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+// pushCommit commits the file into the remote without gitrows, signed by the entity unless it is nil.
+func pushCommit(t *testing.T, remoteURL string, entity *openpgp.Entity, name string, data []byte) {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainClone(dir, false, &git.CloneOptions{URL: remoteURL})
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+
+	_, err = worktree.Add(name)
+	require.NoError(t, err)
+
+	_, err = worktree.Commit("commit: "+name, &git.CommitOptions{
+		Author:  &object.Signature{Name: "signer", Email: "signer@example.com", When: time.Now()},
+		SignKey: entity,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Push(&git.PushOptions{}))
+}
+
+func TestDBImpl_VerifyCommits(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	entity, err := openpgp.NewEntity("signer", "", "signer@example.com", nil)
+	require.NoError(t, err)
+
+	keyring := gitrows.WithVerifyCommits(openpgp.EntityList{entity})
+
+	// unsigned commit written through DB is not verified
+	_, err = newTestDB(t, remote).Create(ctx, "unsigned.txt", []byte("unsigned"))
+	require.NoError(t, err)
+
+	data, info, err := newTestDB(t, remote, keyring).GetWithCommit(ctx, "unsigned.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("unsigned"), data)
+	assert.False(t, info.Verified)
+	assert.Equal(t, remoteHead(t, remote), info.Hash)
+
+	strict := newTestDB(t, remote, keyring, gitrows.WithRejectUnsignedCommits())
+
+	_, _, err = strict.GetWithCommit(ctx, "unsigned.txt")
+	assert.True(t, errors.Is(err, gitrows.ErrUnverifiedCommit), err)
+	assert.Equal(t, gitrows.CodeUnverifiedCommit, gitrows.ErrorCode(err))
+
+	_, err = strict.List(ctx)
+	assert.True(t, errors.Is(err, gitrows.ErrUnverifiedCommit), err)
+
+	// signed commit is verified
+	pushCommit(t, remote, entity, "signed.txt", []byte("signed"))
+
+	data, info, err = newTestDB(t, remote, keyring).GetWithCommit(ctx, "signed.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("signed"), data)
+	assert.True(t, info.Verified)
+	assert.Equal(t, remoteHead(t, remote), info.Hash)
+	assert.Equal(t, "signer", info.AuthorName)
+	assert.Equal(t, "signer@example.com", info.AuthorEmail)
+	assert.Equal(t, "commit: signed.txt", info.Message)
+
+	entries, err := newTestDB(t, remote, keyring).List(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, listKeys(entries), "signed.txt")
+
+	for _, kv := range entries.KVs() {
+		if kv.Key() == "signed.txt" {
+			assert.True(t, kv.LastCommitInfo().Verified)
+		}
+	}
+
+	strict = newTestDB(t, remote, keyring, gitrows.WithRejectUnsignedCommits())
+
+	data, _, err = strict.GetWithCommit(ctx, "signed.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("signed"), data)
+
+	// without keyring or with other keyring, the commit is not verified
+	_, info, err = newTestDB(t, remote).GetWithCommit(ctx, "signed.txt")
+	require.NoError(t, err)
+	assert.False(t, info.Verified)
+
+	other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+	require.NoError(t, err)
+
+	_, info, err = newTestDB(t, remote, gitrows.WithVerifyCommits(openpgp.EntityList{other})).GetWithCommit(ctx, "signed.txt")
+	require.NoError(t, err)
+	assert.False(t, info.Verified)
+}
@@ -0,0 +1,143 @@
+package gitrows
+
+import "os"
+
+// The helpers below are for the implementations of DB other than DBImpl (i.e: the fake of gitrowstest),
+// so they interpret the same options and return the same errors as DBImpl.
+
+// NewError wraps err into *Error of the command op on the key, with the Code classified from err like DBImpl does.
+// The err which is already *Error is returned as is.
+func NewError(op Op, key string, err error) error {
+	return wrapError(op, key, err)
+}
+
+// NewEntries returns the Entries of the kvs, as returned by List.
+func NewEntries(kvs []KV, truncated bool) Entries {
+	return &entriesImpl{kvs: kvs, truncated: truncated}
+}
+
+// ValidateKey returns the cleaned key (i.e: "/configs//a.yaml" is "configs/a.yaml"), or ErrInvalidKey
+// when the key cannot be written, the same as the write commands without the AllowInternalPaths option.
+func ValidateKey(key string) (string, error) {
+	return validateKey(key)
+}
+
+// ValidateInternalKey is like ValidateKey, but also accepts the key inside the reserved .gitrows directory
+// when allowInternal is true, see CreateAllowInternalPaths.
+func ValidateInternalKey(key string, allowInternal bool) (string, error) {
+	return validateInternalKey(key, allowInternal)
+}
+
+// IsInternalKey returns true when the key is inside the reserved .gitrows directory, which List excludes
+// without ListIncludeInternal.
+func IsInternalKey(key string) bool {
+	return isMetadataPath(key)
+}
+
+// Limit returns the limit of GetLimit, zero means unlimited.
+func (c *GetConfig) Limit() int64 {
+	return c.maxBytes
+}
+
+// Range returns the offset and length of GetRange, zero length means until the end of the value.
+func (c *GetConfig) Range() (offset, length int64) {
+	return c.offset, c.length
+}
+
+// CommitMsg returns the message of CreateCommitMsg, empty means the default message.
+func (c *CreateConfig) CommitMsg() string {
+	return c.commitMsg
+}
+
+// FileMode returns the mode of CreateFileMode, zero means the default mode.
+func (c *CreateConfig) FileMode() os.FileMode {
+	return c.fileMode
+}
+
+// AllowInternalPaths returns true with CreateAllowInternalPaths.
+func (c *CreateConfig) AllowInternalPaths() bool {
+	return c.allowInternal
+}
+
+// ExpectedHead returns the commit of CreateExpectedHead, empty means no expectation.
+func (c *CreateConfig) ExpectedHead() string {
+	return c.expectedHead
+}
+
+// CommitMsg returns the message of UpsertCommitMsg, empty means the default message.
+func (c *UpsertConfig) CommitMsg() string {
+	return c.commitMsg
+}
+
+// FileMode returns the mode of UpsertFileMode, zero means the mode of the existing value is kept.
+func (c *UpsertConfig) FileMode() os.FileMode {
+	return c.fileMode
+}
+
+// AllowInternalPaths returns true with UpsertAllowInternalPaths.
+func (c *UpsertConfig) AllowInternalPaths() bool {
+	return c.allowInternal
+}
+
+// AllowEmptyCommit returns the value of UpsertAllowEmptyCommit.
+func (c *UpsertConfig) AllowEmptyCommit() bool {
+	return c.allowEmptyCommit
+}
+
+// ExpectedHead returns the commit of UpsertExpectedHead, empty means no expectation.
+func (c *UpsertConfig) ExpectedHead() string {
+	return c.expectedHead
+}
+
+// CommitMsg returns the message of DeleteCommitMsg, empty means the default message.
+func (c *DeleteConfig) CommitMsg() string {
+	return c.commitMsg
+}
+
+// AllowInternalPaths returns true with DeleteAllowInternalPaths.
+func (c *DeleteConfig) AllowInternalPaths() bool {
+	return c.allowInternal
+}
+
+// ExpectedHead returns the commit of DeleteExpectedHead, empty means no expectation.
+func (c *DeleteConfig) ExpectedHead() string {
+	return c.expectedHead
+}
+
+// Limit returns the limit of ListLimit, zero means unlimited.
+func (c *ListConfig) Limit() int {
+	return c.limit
+}
+
+// Match returns true when List returns the key with the options: ListPrefix, ListExclude, ListIncludeInternal
+// and ListIncludeGitkeep.
+func (c *ListConfig) Match(key string) bool {
+	if isMetadataPath(key) && !c.includeInternal {
+		return false
+	}
+
+	return c.match(key)
+}
+
+// Meta returns the metadata of CreateMeta.
+func (c *CreateConfig) Meta() map[string]string {
+	return copyCommitMeta(c.meta)
+}
+
+// Meta returns the metadata of UpsertMeta.
+func (c *UpsertConfig) Meta() map[string]string {
+	return copyCommitMeta(c.meta)
+}
+
+func copyCommitMeta(meta map[string]string) map[string]string {
+	if meta == nil {
+		return nil
+	}
+
+	dst := make(map[string]string, len(meta))
+	for name, value := range meta {
+		dst[name] = value
+	}
+
+	return dst
+}
@@ -0,0 +1,299 @@
+package gitrows
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidKey returned when the key cannot be used as a file path inside the repository.
+var ErrInvalidKey = errors.New("invalid key")
+
+// metadataDir is the directory of files managed by gitrows itself (i.e: schema version),
+// it is reserved and not a valid key unless explicitly allowed, see validateInternalKey.
+const metadataDir = ".gitrows"
+
+// validateKey cleans the key into the canonical slash-separated path relative to the repository root.
+// It rejects empty key and key that escapes the repository root (i.e: "../secret") or touches the .git directory
+// or the reserved .gitrows directory.
+// Leading slash is removed, so "/note.md" and "note.md" are the same key.
+func validateKey(key string) (string, error) {
+	return validateInternalKey(key, false)
+}
+
+// validateInternalKey is like validateKey, but accepts the key inside the reserved .gitrows directory
+// when allowInternal is true (i.e: CreateAllowInternalPaths).
+func validateInternalKey(key string, allowInternal bool) (string, error) {
+	cleaned := strings.TrimLeft(path.Clean(key), "/")
+
+	switch {
+	case strings.TrimSpace(key) == "" || cleaned == "" || cleaned == ".":
+		return "", fmt.Errorf("%w: key must not be empty", ErrInvalidKey)
+
+	case cleaned == ".." || strings.HasPrefix(cleaned, "../"):
+		return "", fmt.Errorf("%w: key '%s' must not escape the repository root", ErrInvalidKey, key)
+
+	case cleaned == ".git" || strings.HasPrefix(cleaned, ".git/"):
+		return "", fmt.Errorf("%w: key '%s' must not point inside the .git directory", ErrInvalidKey, key)
+
+	case isMetadataPath(cleaned) && !allowInternal:
+		return "", fmt.Errorf(
+			"%w: key '%s' must not point inside the reserved %s directory without the AllowInternalPaths option",
+			ErrInvalidKey, key, metadataDir,
+		)
+	}
+
+	return cleaned, nil
+}
+
+// cleanPrefix normalizes the key prefix of ListPrefix, CopyPrefix and Migrate, so "configs", "/configs/"
+// and "configs//" are the same prefix. The root ("", "/" or ".") is the empty prefix.
+func cleanPrefix(prefix string) string {
+	return strings.Trim(path.Clean("/"+strings.TrimSpace(prefix)), "/")
+}
+
+// hasPathPrefix returns true when the key is the cleaned prefix itself or under it at the path component boundary,
+// so "config" doesn't match "configs/app.yaml". The empty prefix matches every key.
+func hasPathPrefix(key, prefix string) bool {
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+"/")
+}
+
+// windowsReservedNames are the device names which cannot be used as file name on Windows,
+// with or without extension (i.e: "con" and "con.txt").
+var windowsReservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {}, "COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {}, "LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// validatePath is like validateKey, but also rejects file path which is not portable across OS,
+// that is containing invalid UTF-8, control characters or Windows reserved device names.
+func validatePath(filePath string) (string, error) {
+	cleaned, err := validateKey(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	err = checkPathEncoding(cleaned)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range strings.Split(cleaned, "/") {
+		base := strings.ToUpper(strings.SplitN(name, ".", 2)[0])
+		if _, reserved := windowsReservedNames[base]; reserved {
+			return "", fmt.Errorf("%w: path '%s' must not use reserved device name '%s'", ErrInvalidKey, filePath, name)
+		}
+	}
+
+	return cleaned, nil
+}
+
+// checkPathEncoding rejects file path containing invalid UTF-8 or control characters,
+// which cannot be round-tripped as the key.
+func checkPathEncoding(filePath string) error {
+	if !utf8.ValidString(filePath) {
+		return fmt.Errorf("%w: path %q must be valid UTF-8", ErrInvalidKey, filePath)
+	}
+
+	for _, r := range filePath {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w: path %q must not contain control character", ErrInvalidKey, filePath)
+		}
+	}
+
+	return nil
+}
+
+// isMetadataPath returns true when the cleaned file path is inside the reserved .gitrows directory.
+func isMetadataPath(cleaned string) bool {
+	return cleaned == metadataDir || strings.HasPrefix(cleaned, metadataDir+"/")
+}
+
+// KeyMapper maps the key into the file path inside the repository and vice versa,
+// so the repository layout can be customized, i.e: sharded "ab/abcdef.json" or date-partitioned "2023/01/02/key".
+type KeyMapper interface {
+	// ToPath returns the file path of the key. The key is already validated and cleaned.
+	ToPath(key string) string
+
+	// FromPath returns the key of file path, or ok false when the path is not managed by this mapper.
+	// Paths which don't map back to a key are skipped in List.
+	FromPath(path string) (key string, ok bool)
+}
+
+// WithKeyMapper set the KeyMapper used for all commands, see KeyMapper.
+// By default, the key is used as the file path as is.
+func WithKeyMapper(mapper KeyMapper) Opt {
+	return func(db *DBImpl) error {
+		db.keyMapper = mapper
+		return nil
+	}
+}
+
+// WithOnSkippedPath set the callback which is called when the file path in the repository is skipped
+// because it cannot be used as the key, i.e: file name with invalid UTF-8 committed by other git client.
+// By default, the warning is written into the same writer as the git progress (os.Stdout).
+func WithOnSkippedPath(fn func(filePath string, err error)) Opt {
+	return func(db *DBImpl) error {
+		db.onSkippedPath = fn
+		return nil
+	}
+}
+
+// skipPath reports the file path skipped by pathToKey, see WithOnSkippedPath.
+func (db *DBImpl) skipPath(filePath string, err error) {
+	if db.onSkippedPath != nil {
+		db.onSkippedPath(filePath, err)
+		return
+	}
+
+	if db.progress != nil {
+		_, _ = fmt.Fprintf(db.progress, "gitrows: warning: skip file %q: %s\n", filePath, err)
+	}
+}
+
+// KeyEncoding is how the key is encoded into the file path, see WithKeyEncoding.
+type KeyEncoding int
+
+const (
+	// NoEncoding uses the key as the file path as is, which is the default.
+	NoEncoding KeyEncoding = iota
+
+	// PercentEncode encodes every byte other than ASCII letters, digits, "-", ".", "_", "~" and "/" as %XX,
+	// i.e: "hello world:1.txt" is stored as "hello%20world%3A1.txt".
+	PercentEncode
+)
+
+// WithKeyEncoding encodes the key into portable file path, so the key can contain
+// characters which behave differently across OS (i.e: space, unicode or ":").
+// List still returns the decoded key. When used with WithKeyMapper, the path returned by KeyMapper is encoded.
+func WithKeyEncoding(encoding KeyEncoding) Opt {
+	return func(db *DBImpl) error {
+		switch encoding {
+		case NoEncoding, PercentEncode:
+			db.keyEncoding = encoding
+			return nil
+		default:
+			return fmt.Errorf("unknown key encoding %d", encoding)
+		}
+	}
+}
+
+// percentEncode encodes s using PercentEncode.
+func percentEncode(s string) string {
+	const upperHex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreserved(c) || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+
+		b.WriteByte('%')
+		b.WriteByte(upperHex[c>>4])
+		b.WriteByte(upperHex[c&15])
+	}
+
+	return b.String()
+}
+
+// percentDecode decodes s which is encoded using PercentEncode.
+// It returns ok false when s is not the canonical encoding, i.e: file which is not written by gitrows.
+func percentDecode(s string) (decoded string, ok bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s) && isUpperHex(s[i+1]) && isUpperHex(s[i+2]):
+			b.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+
+		case isUnreserved(c) || c == '/':
+			b.WriteByte(c)
+
+		default:
+			return "", false
+		}
+	}
+
+	decoded = b.String()
+	return decoded, percentEncode(decoded) == s
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isUpperHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	if c <= '9' {
+		return c - '0'
+	}
+
+	return c - 'A' + 10
+}
+
+// keyToPath returns the file path of the validated key using the KeyMapper and KeyEncoding.
+// The path is validated to be portable too, so a KeyMapper cannot escape the repository root or WithSparsePrefix.
+func (db *DBImpl) keyToPath(key string) (filePath string, err error) {
+	// key inside the .gitrows directory is only accepted by validateInternalKey, and it is always the file path as is
+	if isMetadataPath(key) {
+		return key, nil
+	}
+
+	filePath = key
+	if db.keyMapper != nil {
+		filePath = db.keyMapper.ToPath(filePath)
+	}
+
+	if db.keyEncoding == PercentEncode {
+		filePath = percentEncode(filePath)
+	}
+
+	mapped := filePath
+	filePath, err = validatePath(mapped)
+	if err != nil {
+		if mapped != key {
+			err = fmt.Errorf("invalid path '%s' of key '%s': %w", mapped, key, err)
+		}
+
+		return
+	}
+
+	err = db.checkSparsePrefix(filePath)
+	return
+}
+
+// pathToKey returns the key of file path using the KeyEncoding and KeyMapper,
+// or ok false when the path cannot be mapped back.
+func (db *DBImpl) pathToKey(filePath string) (key string, ok bool) {
+	// path committed by other git client may not be writable by keyToPath, so it is skipped instead of
+	// returning the key which cannot be used in Get, see WithOnSkippedPath
+	if err := checkPathEncoding(filePath); err != nil {
+		db.skipPath(filePath, err)
+		return "", false
+	}
+
+	key = filePath
+	if db.keyEncoding == PercentEncode {
+		key, ok = percentDecode(key)
+		if !ok {
+			return
+		}
+	}
+
+	if db.keyMapper == nil {
+		return key, true
+	}
+
+	return db.keyMapper.FromPath(key)
+}
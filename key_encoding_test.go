@@ -0,0 +1,118 @@
+package gitrows
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithKeyEncoding(t *testing.T) {
+	keys := map[string]string{
+		"hello world.txt":     "hello%20world.txt",
+		"日本語/ファイル.md":         "%E6%97%A5%E6%9C%AC%E8%AA%9E/%E3%83%95%E3%82%A1%E3%82%A4%E3%83%AB.md",
+		"time:12:00.json":     "time%3A12%3A00.json",
+		"percent/100% ok.txt": "percent/100%25%20ok.txt",
+	}
+
+	filesystems := map[string]func() func(dir string) billy.Filesystem{
+		"osfs": func() func(dir string) billy.Filesystem {
+			return nil
+		},
+		"memfs": func() func(dir string) billy.Filesystem {
+			fs := memfs.New()
+			return func(dir string) billy.Filesystem {
+				return fs
+			}
+		},
+	}
+
+	for name, newFS := range filesystems {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.TODO()
+
+			remoteDir := filepath.Join(t.TempDir(), "remote.git")
+			_, err := git.PlainInit(remoteDir, true)
+			require.NoError(t, err)
+
+			db, err := New(
+				WithGitSshUrl("file://"+remoteDir),
+				WithLocalGitVolume(t.TempDir()),
+				WithKeyEncoding(PercentEncode),
+			)
+			require.NoError(t, err)
+
+			db.worktreeFS = newFS()
+
+			for key := range keys {
+				_, err = db.Create(ctx, key, []byte(key))
+				require.NoError(t, err, key)
+			}
+
+			for key := range keys {
+				data, err := db.Get(ctx, key)
+				require.NoError(t, err, key)
+				assert.Equal(t, []byte(key), data)
+			}
+
+			// the committed paths are encoded
+			commit, err := db.headCommit()
+			require.NoError(t, err)
+
+			for key, encoded := range keys {
+				file, err := commit.File(encoded)
+				require.NoError(t, err, key)
+
+				content, err := file.Contents()
+				require.NoError(t, err)
+				assert.Equal(t, key, content)
+			}
+
+			// List returns the logical (decoded) keys
+			entries, err := db.List(ctx)
+			require.NoError(t, err)
+
+			listed := make([]string, 0)
+			for _, kv := range entries.KVs() {
+				listed = append(listed, kv.Key())
+			}
+
+			expected := make([]string, 0)
+			for key := range keys {
+				expected = append(expected, key)
+			}
+
+			assert.ElementsMatch(t, expected, listed)
+
+			_, err = db.Delete(ctx, "hello world.txt")
+			require.NoError(t, err)
+
+			_, err = db.Get(ctx, "hello world.txt")
+			assert.Equal(t, CodeNotFound, ErrorCode(err))
+
+			// the reserved device name is still rejected, because letters are not encoded
+			_, err = db.Create(ctx, "aux.json", []byte("aux"))
+			assert.Equal(t, CodeInvalidKey, ErrorCode(err))
+		})
+	}
+}
+
+func TestKeyToPath_rejectsUnportablePath(t *testing.T) {
+	db, err := New(WithGitSshUrl("file:///tmp/remote.git"))
+	require.NoError(t, err)
+
+	_, err = db.keyToPath("new\nline")
+	assert.Equal(t, CodeInvalidKey, codeOf(err))
+
+	_, err = db.keyToPath("prn.txt")
+	assert.Equal(t, CodeInvalidKey, codeOf(err))
+
+	filePath, err := db.keyToPath("a:b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a:b.txt", filePath)
+}
@@ -0,0 +1,93 @@
+package gitrows_test
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+// shardKeyMapper stores key "abc" as "data/ab/abc".
+type shardKeyMapper struct{}
+
+func (shardKeyMapper) ToPath(key string) string {
+	shard := key
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+
+	return path.Join("data", shard, key)
+}
+
+func (shardKeyMapper) FromPath(filePath string) (string, bool) {
+	parts := strings.SplitN(filePath, "/", 3)
+	if len(parts) != 3 || parts[0] != "data" {
+		return "", false
+	}
+
+	return parts[2], true
+}
+
+// escapeKeyMapper maps every key outside the repository root.
+type escapeKeyMapper struct{}
+
+func (escapeKeyMapper) ToPath(key string) string { return "../" + key }
+
+func (escapeKeyMapper) FromPath(filePath string) (string, bool) { return filePath, true }
+
+func TestWithKeyMapper(t *testing.T) {
+	ctx := context.TODO()
+	remote := newTestRemote(t)
+
+	plain := newTestDB(t, remote)
+	_, err := plain.Create(ctx, "README.md", []byte("readme"))
+	require.NoError(t, err)
+
+	db := newTestDB(t, remote, gitrows.WithKeyMapper(shardKeyMapper{}))
+
+	commit, err := db.Create(ctx, "user-1.json", []byte("1"))
+	require.NoError(t, err)
+
+	_, changed, err := db.Upsert(ctx, "order-9.json", []byte("9"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// the file is stored in the mapped path
+	data, err := plain.Get(ctx, "data/us/user-1.json")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), data)
+
+	data, err = db.Get(ctx, "user-1.json")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), data)
+
+	values, err := db.GetMany(ctx, []string{"user-1.json", "order-9.json"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"user-1.json": []byte("1"), "order-9.json": []byte("9")}, values)
+
+	// README.md doesn't map back to a key, so it is skipped
+	entries, err := db.List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user-1.json", "order-9.json"}, listKeys(entries))
+
+	for _, kv := range entries.KVs() {
+		if kv.Key() == "user-1.json" {
+			assert.Equal(t, commit, kv.LastCommit())
+		}
+	}
+
+	_, err = db.Delete(ctx, "user-1.json")
+	require.NoError(t, err)
+
+	_, err = db.Get(ctx, "user-1.json")
+	assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+	escape := newTestDB(t, remote, gitrows.WithKeyMapper(escapeKeyMapper{}))
+	_, err = escape.Create(ctx, "secret", []byte("secret"))
+	assert.True(t, errors.Is(err, gitrows.ErrInvalidKey))
+}
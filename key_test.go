@@ -0,0 +1,211 @@
+package gitrows
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		cleaned string
+		invalid bool
+	}{
+		{key: "note.md", cleaned: "note.md"},
+		{key: "/note.md", cleaned: "note.md"},
+		{key: "configs//app.yaml", cleaned: "configs/app.yaml"},
+		{key: "configs/../app.yaml", cleaned: "app.yaml"},
+		{key: "", invalid: true},
+		{key: " ", invalid: true},
+		{key: ".", invalid: true},
+		{key: "/", invalid: true},
+		{key: "..", invalid: true},
+		{key: "../secret", invalid: true},
+		{key: "configs/../../secret", invalid: true},
+		{key: ".git/config", invalid: true},
+		{key: ".gitrows/version", invalid: true},
+		{key: ".gitrows", invalid: true},
+		{key: ".gitrowsx", cleaned: ".gitrowsx"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			cleaned, err := validateKey(test.key)
+			if test.invalid {
+				assert.True(t, errors.Is(err, ErrInvalidKey), err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.cleaned, cleaned)
+		})
+	}
+}
+
+func TestValidateInternalKey(t *testing.T) {
+	for _, key := range []string{".gitrows/version", "/.gitrows/trash/a.txt"} {
+		_, err := validateInternalKey(key, false)
+		assert.True(t, errors.Is(err, ErrInvalidKey), err)
+
+		cleaned, err := validateInternalKey(key, true)
+		assert.NoError(t, err)
+		assert.True(t, isMetadataPath(cleaned), cleaned)
+	}
+
+	// other rules still apply
+	for _, key := range []string{".gitrows/../../secret", ".git/config", ""} {
+		_, err := validateInternalKey(key, true)
+		assert.True(t, errors.Is(err, ErrInvalidKey), err)
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		invalid bool
+	}{
+		{path: "hello world.txt"},
+		{path: "日本語/ファイル.md"},
+		{path: "configs/console.yaml"},
+		{path: "a\x00b", invalid: true},
+		{path: "line\nbreak", invalid: true},
+		{path: "tab\tname", invalid: true},
+		{path: "\xff\xfe", invalid: true},
+		{path: "CON", invalid: true},
+		{path: "dir/nul.txt", invalid: true},
+		{path: "Com1.json", invalid: true},
+		{path: "lpt9/file", invalid: true},
+		{path: "../escape", invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			_, err := validatePath(test.path)
+			if test.invalid {
+				assert.True(t, errors.Is(err, ErrInvalidKey), err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	tests := []struct {
+		key     string
+		encoded string
+	}{
+		{key: "plain/file-1_a.b~c", encoded: "plain/file-1_a.b~c"},
+		{key: "hello world:1.txt", encoded: "hello%20world%3A1.txt"},
+		{key: "100%", encoded: "100%25"},
+		{key: "日本", encoded: "%E6%97%A5%E6%9C%AC"},
+		{key: "ctrl\x01", encoded: "ctrl%01"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			assert.Equal(t, test.encoded, percentEncode(test.key))
+
+			decoded, ok := percentDecode(test.encoded)
+			assert.True(t, ok)
+			assert.Equal(t, test.key, decoded)
+		})
+	}
+
+	// not written by gitrows
+	for _, filePath := range []string{"hello world.txt", "100%", "%e6%97", "%2", "a:b"} {
+		_, ok := percentDecode(filePath)
+		assert.False(t, ok, filePath)
+	}
+}
+
+func TestValidateBranch(t *testing.T) {
+	tests := []struct {
+		branch     string
+		normalized string
+		invalid    bool
+	}{
+		{branch: "master", normalized: "master"},
+		{branch: "feature/new-config", normalized: "feature/new-config"},
+		{branch: "release-1.2", normalized: "release-1.2"},
+		{branch: "refs/heads/staging", normalized: "staging"},
+		{branch: "", invalid: true},
+		{branch: "refs/heads/", invalid: true},
+		{branch: "@", invalid: true},
+		{branch: "my branch", invalid: true},
+		{branch: "feature\tx", invalid: true},
+		{branch: "feature\x00x", invalid: true},
+		{branch: "feature\x7fx", invalid: true},
+		{branch: "release..1", invalid: true},
+		{branch: "/feature", invalid: true},
+		{branch: "feature/", invalid: true},
+		{branch: "feature//x", invalid: true},
+		{branch: "-feature", invalid: true},
+		{branch: "feature.", invalid: true},
+		{branch: "feature.lock", invalid: true},
+		{branch: "feature/.hidden", invalid: true},
+		{branch: "feature@{1}", invalid: true},
+		{branch: "feature:x", invalid: true},
+		{branch: "feature~1", invalid: true},
+		{branch: "feature^", invalid: true},
+		{branch: "feature?", invalid: true},
+		{branch: "feature*", invalid: true},
+		{branch: "feature[x", invalid: true},
+		{branch: "feature\\x", invalid: true},
+	}
+
+	for _, test := range tests {
+		normalized, err := validateBranch(test.branch)
+		if test.invalid {
+			assert.True(t, errors.Is(err, ErrInvalidBranch), "branch %q: %v", test.branch, err)
+			continue
+		}
+
+		assert.NoError(t, err, "branch %q", test.branch)
+		assert.Equal(t, test.normalized, normalized, "branch %q", test.branch)
+	}
+}
+
+func TestPrefixMatch(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		key     string
+		matched bool
+	}{
+		{prefix: "", key: "app.yaml", matched: true},
+		{prefix: "/", key: "configs/app.yaml", matched: true},
+		{prefix: ".", key: "configs/app.yaml", matched: true},
+		{prefix: "configs", key: "configs/app.yaml", matched: true},
+		{prefix: "configs/", key: "configs/app.yaml", matched: true},
+		{prefix: "/configs//", key: "configs/app.yaml", matched: true},
+		{prefix: " configs ", key: "configs/app.yaml", matched: true},
+		{prefix: "configs", key: "configs/db/mysql.yaml", matched: true},
+		{prefix: "configs/db", key: "configs/db/mysql.yaml", matched: true},
+		{prefix: "configs/app.yaml", key: "configs/app.yaml", matched: true},
+		{prefix: "configs/app.yaml/", key: "configs/app.yaml", matched: true},
+		{prefix: "configs", key: "configs", matched: true},
+		{prefix: "config", key: "configs/app.yaml", matched: false},
+		{prefix: "configs/app", key: "configs/app.yaml", matched: false},
+		{prefix: "configs/app.yaml", key: "configs/app.yaml.bak", matched: false},
+		{prefix: "configs/db", key: "configs/app.yaml", matched: false},
+		{prefix: "configs", key: "other/configs/app.yaml", matched: false},
+		{prefix: "configs", key: "app.yaml", matched: false},
+	}
+
+	for _, test := range tests {
+		// every API taking the key prefix agrees on the same match
+		listCfg := &ListConfig{}
+		assert.NoError(t, ListPrefix(test.prefix)(listCfg))
+		assert.Equal(t, test.matched, listCfg.match(test.key), "ListPrefix(%q) %q", test.prefix, test.key)
+
+		copyCfg := &CopyConfig{}
+		assert.NoError(t, CopyPrefix(test.prefix)(copyCfg))
+		assert.Equal(t, test.matched, copyCfg.match(test.key), "CopyPrefix(%q) %q", test.prefix, test.key)
+
+		// Migrate
+		assert.Equal(t, test.matched, hasPathPrefix(test.key, cleanPrefix(test.prefix)), "Migrate(%q) %q", test.prefix, test.key)
+	}
+}
@@ -0,0 +1,556 @@
+// Package gitrowstest provides the in-memory gitrows.DB for the unit test of the code using gitrows,
+// without the git repository.
+package gitrowstest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yusufsyaifudin/gitrows"
+)
+
+// OpPreload is the Op of the commit created by Preload.
+const OpPreload gitrows.Op = "preload"
+
+const defaultFileMode os.FileMode = 0644
+
+// Commit is the fake commit created by each write of FakeDB.
+type Commit struct {
+	// Hash is the fabricated commit hash, which increases monotonically: the first commit is 000...001.
+	Hash string
+
+	Op gitrows.Op
+
+	// Keys is the written or deleted keys (sorted).
+	Keys []string
+
+	Message string
+	When    time.Time
+
+	// Meta is the metadata of CreateMeta or UpsertMeta, nil without it.
+	Meta map[string]string
+}
+
+// FakeDB is the in-memory gitrows.DB. It behaves like DBImpl with the default options: the same validation
+// of the key and the options, os.ErrExist on Create of the existing key, os.ErrNotExist on Get and Delete
+// of the missing key, ErrKeyIsDirectory on the key which contains other keys, ErrStaleHead with the expected head,
+// and Upsert reporting changed (and skipping the commit without UpsertAllowEmptyCommit) by comparing the value.
+// The writes are committed one fake commit each, with the same default message as DBImpl (i.e: "gitrows: UPSERT"),
+// see Commits to inspect them.
+//
+// List returns the keys sorted, and GetDirAsList is not supported. It is safe for concurrent use.
+type FakeDB struct {
+	mu      sync.Mutex
+	values  map[string]*value
+	commits []Commit
+}
+
+type value struct {
+	data   []byte
+	mode   os.FileMode
+	commit int // index of the last commit writing the value in commits
+}
+
+var _ gitrows.DB = (*FakeDB)(nil)
+
+// NewFakeDB returns the empty FakeDB, like the DB of the empty remote repository.
+func NewFakeDB() *FakeDB {
+	return &FakeDB{
+		values:  make(map[string]*value),
+		commits: make([]Commit, 0),
+	}
+}
+
+// Preload writes every key and value as one commit of OpPreload, i.e: to set up the data before the test.
+// It returns the commit hash, or the error of the first invalid key without writing anything.
+func (f *FakeDB) Preload(values map[string][]byte) (commitHash string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cleaned := make(map[string][]byte, len(values))
+	for key, data := range values {
+		var validKey string
+		validKey, err = gitrows.ValidateInternalKey(key, true)
+		if err != nil {
+			return "", err
+		}
+
+		cleaned[validKey] = data
+	}
+
+	for key := range cleaned {
+		err = f.checkPath(key, cleaned)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	keys := make([]string, 0, len(cleaned))
+	for key := range cleaned {
+		keys = append(keys, key)
+	}
+
+	commit := f.commit(OpPreload, fmt.Sprintf("gitrowstest: PRELOAD %d keys", len(keys)), keys...)
+	for key, data := range cleaned {
+		mode := defaultFileMode
+		if old, exist := f.values[key]; exist {
+			mode = old.mode
+		}
+
+		f.values[key] = &value{data: clone(data), mode: mode, commit: commit}
+	}
+
+	return f.commits[commit].Hash, nil
+}
+
+// Commits returns every commit created so far, the oldest first.
+func (f *FakeDB) Commits() []Commit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	commits := make([]Commit, len(f.commits))
+	for i, commit := range f.commits {
+		commit.Keys = append([]string(nil), commit.Keys...)
+		commits[i] = commit
+	}
+
+	return commits
+}
+
+// CommitMessages returns the message of every commit created so far, the oldest first.
+func (f *FakeDB) CommitMessages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := make([]string, len(f.commits))
+	for i, commit := range f.commits {
+		messages[i] = commit.Message
+	}
+
+	return messages
+}
+
+// Head returns the hash of the last commit, or empty when nothing is written yet.
+func (f *FakeDB) Head() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.head()
+}
+
+func (f *FakeDB) Get(ctx context.Context, key string, opts ...gitrows.GetOpt) (data []byte, err error) {
+	defer func() {
+		err = gitrows.NewError(gitrows.OpGet, key, err)
+	}()
+
+	cfg := &gitrows.GetConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("get command: %w", err)
+			return
+		}
+	}
+
+	key, err = gitrows.ValidateKey(key)
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		err = fmt.Errorf("get command: %w", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.isDir(key) {
+		err = fmt.Errorf("get command: %w: '%s' contains other keys", gitrows.ErrKeyIsDirectory, key)
+		return
+	}
+
+	v, exist := f.values[key]
+	if !exist {
+		err = fmt.Errorf("get command: cannot open file: %w", os.ErrNotExist)
+		return
+	}
+
+	data = v.data
+	offset, length := cfg.Range()
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	data = data[offset:]
+	if length > 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+
+	if limit := cfg.Limit(); limit > 0 && int64(len(data)) > limit {
+		err = fmt.Errorf("get command: %w: value of key '%s' is larger than %d bytes", gitrows.ErrValueTooLarge, key, limit)
+		return nil, err
+	}
+
+	return clone(data), nil
+}
+
+func (f *FakeDB) Create(ctx context.Context, key string, data []byte, opts ...gitrows.CreateOpt) (commitHashString string, err error) {
+	defer func() {
+		err = gitrows.NewError(gitrows.OpCreate, key, err)
+	}()
+
+	cfg := &gitrows.CreateConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("create command: %w", err)
+			return
+		}
+	}
+
+	key, err = gitrows.ValidateInternalKey(key, cfg.AllowInternalPaths())
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err = f.checkWrite(key, cfg.ExpectedHead())
+	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
+		return
+	}
+
+	if _, exist := f.values[key]; exist {
+		err = fmt.Errorf("create command: %w: cannot create '%s' because os.Stat said that key is exist", os.ErrExist, key)
+		return
+	}
+
+	mode := cfg.FileMode()
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+
+	commit := f.commit(gitrows.OpCreate, message(cfg.CommitMsg(), "gitrows: CREATE"), key)
+	f.commits[commit].Meta = cfg.Meta()
+	f.values[key] = &value{data: clone(data), mode: mode, commit: commit}
+	return f.commits[commit].Hash, nil
+}
+
+func (f *FakeDB) Upsert(ctx context.Context, key string, data []byte, opts ...gitrows.UpsertOpt) (commitHashString string, changed bool, err error) {
+	defer func() {
+		err = gitrows.NewError(gitrows.OpUpsert, key, err)
+	}()
+
+	cfg := &gitrows.UpsertConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("upsert command: %w", err)
+			return
+		}
+	}
+
+	key, err = gitrows.ValidateInternalKey(key, cfg.AllowInternalPaths())
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err = f.checkWrite(key, cfg.ExpectedHead())
+	if err != nil {
+		err = fmt.Errorf("upsert command: %w", err)
+		return
+	}
+
+	// zero mode keeps the mode of the existing value
+	old, exist := f.values[key]
+	mode := cfg.FileMode()
+	if mode == 0 {
+		mode = defaultFileMode
+		if exist {
+			mode = old.mode
+		}
+	}
+
+	changed = !exist || !bytes.Equal(old.data, data)
+	metadataChanged := !changed && old.mode != mode
+	if !cfg.AllowEmptyCommit() && !changed && !metadataChanged {
+		return f.head(), false, nil
+	}
+
+	commit := f.commit(gitrows.OpUpsert, message(cfg.CommitMsg(), "gitrows: UPSERT"), key)
+	f.commits[commit].Meta = cfg.Meta()
+	f.values[key] = &value{data: clone(data), mode: mode, commit: commit}
+	return f.commits[commit].Hash, changed, nil
+}
+
+func (f *FakeDB) Delete(ctx context.Context, key string, opts ...gitrows.DeleteOpt) (commitHashString string, err error) {
+	defer func() {
+		err = gitrows.NewError(gitrows.OpDelete, key, err)
+	}()
+
+	cfg := &gitrows.DeleteConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("delete command: %w", err)
+			return
+		}
+	}
+
+	key, err = gitrows.ValidateInternalKey(key, cfg.AllowInternalPaths())
+	if err != nil {
+		err = fmt.Errorf("delete command: %w", err)
+		return
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		err = fmt.Errorf("delete command: %w", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err = checkExpectedHead(f.head(), cfg.ExpectedHead())
+	if err != nil {
+		err = fmt.Errorf("delete command: %w", err)
+		return
+	}
+
+	if f.isDir(key) {
+		err = fmt.Errorf("delete command: cannot delete '%s' because it is a directory", key)
+		return
+	}
+
+	if _, exist := f.values[key]; !exist {
+		err = fmt.Errorf("delete command: cannot delete '%s': %w", key, os.ErrNotExist)
+		return
+	}
+
+	commit := f.commit(gitrows.OpDelete, message(cfg.CommitMsg(), "gitrows: DELETE"), key)
+	delete(f.values, key)
+	return f.commits[commit].Hash, nil
+}
+
+func (f *FakeDB) List(ctx context.Context, opts ...gitrows.ListOpt) (entries gitrows.Entries, err error) {
+	defer func() {
+		err = gitrows.NewError(gitrows.OpList, "", err)
+	}()
+
+	cfg := &gitrows.ListConfig{}
+	for _, opt := range opts {
+		err = opt(cfg)
+		if err != nil {
+			err = fmt.Errorf("list command: %w", err)
+			return
+		}
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		err = fmt.Errorf("list command: %w", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.values))
+	for key := range f.values {
+		if cfg.Match(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	truncated := cfg.Limit() > 0 && len(keys) > cfg.Limit()
+	if truncated {
+		keys = keys[:cfg.Limit()]
+	}
+
+	kvs := make([]gitrows.KV, 0, len(keys))
+	for _, key := range keys {
+		v := f.values[key]
+		kvs = append(kvs, &kv{key: key, data: v.data, mode: v.mode, commit: f.commits[v.commit]})
+	}
+
+	return gitrows.NewEntries(kvs, truncated), nil
+}
+
+// checkWrite returns the error of DBImpl writing the key: the expected head doesn't match,
+// the key is the directory of other keys, or the parent directory of the key is the other key.
+// The caller must hold mu.
+func (f *FakeDB) checkWrite(key, expectedHead string) error {
+	err := checkExpectedHead(f.head(), expectedHead)
+	if err != nil {
+		return err
+	}
+
+	return f.checkPath(key, nil)
+}
+
+// checkPath returns the error when the key cannot be a file with the existing keys and the pending keys.
+// The caller must hold mu.
+func (f *FakeDB) checkPath(key string, pending map[string][]byte) error {
+	if f.isDir(key) {
+		return fmt.Errorf("%w: '%s' contains other keys", gitrows.ErrKeyIsDirectory, key)
+	}
+
+	for dir := parentDir(key); dir != ""; dir = parentDir(dir) {
+		_, exist := f.values[dir]
+		if _, pendingExist := pending[dir]; exist || pendingExist {
+			return fmt.Errorf("cannot write '%s' because '%s' is not a directory", key, dir)
+		}
+	}
+
+	return nil
+}
+
+// isDir returns true when the key contains other keys. The caller must hold mu.
+func (f *FakeDB) isDir(key string) bool {
+	for other := range f.values {
+		if strings.HasPrefix(other, key+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commit appends the new commit and returns its index. The caller must hold mu.
+func (f *FakeDB) commit(op gitrows.Op, msg string, keys ...string) int {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	f.commits = append(f.commits, Commit{
+		Hash:    fmt.Sprintf("%040x", len(f.commits)+1),
+		Op:      op,
+		Keys:    sorted,
+		Message: msg,
+		When:    time.Now(),
+	})
+
+	return len(f.commits) - 1
+}
+
+// head returns the hash of the last commit. The caller must hold mu.
+func (f *FakeDB) head() string {
+	if len(f.commits) == 0 {
+		return ""
+	}
+
+	return f.commits[len(f.commits)-1].Hash
+}
+
+// checkExpectedHead returns gitrows.ErrStaleHead like the ExpectedHead options of DBImpl.
+func checkExpectedHead(head, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	actual := head
+	if actual == "" {
+		actual = strings.Repeat("0", 40)
+	}
+
+	if actual != strings.ToLower(expected) {
+		return fmt.Errorf("%w: branch is at %s, expected %s", gitrows.ErrStaleHead, actual, expected)
+	}
+
+	return nil
+}
+
+// message returns the per-call message, or the default message of the command.
+func message(explicit, builtin string) string {
+	if explicit != "" {
+		return explicit
+	}
+
+	return builtin
+}
+
+func parentDir(key string) string {
+	i := strings.LastIndex(key, "/")
+	if i < 0 {
+		return ""
+	}
+
+	return key[:i]
+}
+
+func clone(data []byte) []byte {
+	return append(make([]byte, 0, len(data)), data...)
+}
+
+// kv is the gitrows.KV of FakeDB, the snapshot of the value when it is listed.
+type kv struct {
+	key    string
+	data   []byte
+	mode   os.FileMode
+	commit Commit
+}
+
+var _ gitrows.KV = (*kv)(nil)
+
+func (k *kv) Key() string {
+	return k.key
+}
+
+func (k *kv) Value() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(k.data)), nil
+}
+
+func (k *kv) LastCommit() string {
+	return k.commit.Hash
+}
+
+func (k *kv) LastCommitErr() error {
+	return nil
+}
+
+func (k *kv) LastCommitInfo() gitrows.CommitInfo {
+	return gitrows.CommitInfo{
+		Hash:    k.commit.Hash,
+		When:    k.commit.When,
+		Message: k.commit.Message,
+	}
+}
+
+func (k *kv) Mode() os.FileMode {
+	return k.mode
+}
+
+func (k *kv) BlobHash() string {
+	return gitrows.BlobHash(k.data)
+}
@@ -0,0 +1,153 @@
+package gitrowstest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufsyaifudin/gitrows"
+	"github.com/yusufsyaifudin/gitrows/pkg/gitrowstest"
+)
+
+// TestFakeDB runs the same assertions against the FakeDB and DBImpl, so the fake doesn't drift from the real one.
+func TestFakeDB(t *testing.T) {
+	ctx := context.TODO()
+
+	dbs := map[string]func(t *testing.T) gitrows.DB{
+		"fake": func(t *testing.T) gitrows.DB {
+			return gitrowstest.NewFakeDB()
+		},
+		"real": func(t *testing.T) gitrows.DB {
+			// the commit author regardless the machine global git config
+			home := t.TempDir()
+			gitConfig := "[user]\n\tname = gitrows\n\temail = gitrows@example.com\n"
+			require.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(gitConfig), 0644))
+			t.Setenv("HOME", home)
+
+			remoteDir := filepath.Join(t.TempDir(), "remote.git")
+			_, err := git.PlainInit(remoteDir, true)
+			require.NoError(t, err)
+
+			db, err := gitrows.New(gitrows.WithGitSshUrl("file://"+remoteDir), gitrows.WithLocalGitVolume(t.TempDir()))
+			require.NoError(t, err)
+			return db
+		},
+	}
+
+	for name, newDB := range dbs {
+		t.Run(name, func(t *testing.T) {
+			db := newDB(t)
+
+			_, err := db.Get(ctx, "configs/a.yaml")
+			assert.ErrorIs(t, err, os.ErrNotExist)
+			assert.Equal(t, gitrows.CodeNotFound, gitrows.ErrorCode(err))
+
+			first, err := db.Create(ctx, "/configs/a.yaml", []byte("a"))
+			require.NoError(t, err)
+
+			_, err = db.Create(ctx, "configs/a.yaml", []byte("a"))
+			assert.ErrorIs(t, err, os.ErrExist)
+
+			_, err = db.Create(ctx, "../a.yaml", []byte("a"))
+			assert.ErrorIs(t, err, gitrows.ErrInvalidKey)
+
+			_, err = db.Get(ctx, "configs")
+			assert.ErrorIs(t, err, gitrows.ErrKeyIsDirectory)
+
+			// the same value is not committed
+			commitHash, changed, err := db.Upsert(ctx, "configs/a.yaml", []byte("a"))
+			require.NoError(t, err)
+			assert.False(t, changed)
+			assert.Equal(t, first, commitHash)
+
+			commitHash, changed, err = db.Upsert(ctx, "configs/a.yaml", []byte("a"), gitrows.UpsertAllowEmptyCommit(true))
+			require.NoError(t, err)
+			assert.False(t, changed)
+			assert.NotEqual(t, first, commitHash)
+
+			_, changed, err = db.Upsert(ctx, "configs/b.yaml", []byte("b"), gitrows.UpsertFileMode(0755))
+			require.NoError(t, err)
+			assert.True(t, changed)
+
+			_, _, err = db.Upsert(ctx, "configs/b.yaml", []byte("b2"), gitrows.UpsertExpectedHead(first))
+			assert.ErrorIs(t, err, gitrows.ErrStaleHead)
+
+			data, err := db.Get(ctx, "configs/a.yaml", gitrows.GetRange(0, 1))
+			require.NoError(t, err)
+			assert.Equal(t, "a", string(data))
+
+			_, err = db.Get(ctx, "configs/b.yaml", gitrows.GetLimit(0))
+			require.NoError(t, err)
+
+			_, err = db.Create(ctx, "configs/b.yaml/c", []byte("c"))
+			assert.Error(t, err)
+
+			entries, err := db.List(ctx, gitrows.ListPrefix("configs/"))
+			require.NoError(t, err)
+
+			modes := make(map[string]os.FileMode)
+			for _, kv := range entries.KVs() {
+				modes[kv.Key()] = kv.Mode()
+				assert.NotEmpty(t, kv.LastCommit())
+			}
+
+			assert.Equal(t, map[string]os.FileMode{"configs/a.yaml": 0644, "configs/b.yaml": 0755}, modes)
+
+			values, err := entries.ToMap(ctx, 0)
+			require.NoError(t, err)
+			assert.Equal(t, map[string][]byte{"configs/a.yaml": []byte("a"), "configs/b.yaml": []byte("b")}, values)
+
+			entries, err = db.List(ctx, gitrows.ListLimit(1))
+			require.NoError(t, err)
+			assert.Len(t, entries.KVs(), 1)
+			assert.True(t, entries.Truncated())
+
+			_, err = db.Delete(ctx, "configs/a.yaml", gitrows.DeleteCommitMsg("remove a"))
+			require.NoError(t, err)
+
+			_, err = db.Delete(ctx, "configs/a.yaml")
+			assert.True(t, errors.Is(err, os.ErrNotExist), "%v", err)
+		})
+	}
+}
+
+func TestFakeDB_commits(t *testing.T) {
+	ctx := context.TODO()
+	db := gitrowstest.NewFakeDB()
+
+	preloaded, err := db.Preload(map[string][]byte{"a.txt": []byte("a"), "b/c.txt": []byte("c")})
+	require.NoError(t, err)
+	assert.Equal(t, "0000000000000000000000000000000000000001", preloaded)
+
+	data, err := db.Get(ctx, "b/c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(data))
+
+	_, _, err = db.Upsert(ctx, "a.txt", []byte("a2"), gitrows.UpsertCommitMsg("update a"),
+		gitrows.UpsertMeta(map[string]string{"Request-Id": "42"}))
+	require.NoError(t, err)
+
+	commitHash, err := db.Delete(ctx, "b/c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "0000000000000000000000000000000000000003", commitHash)
+	assert.Equal(t, commitHash, db.Head())
+
+	assert.Equal(t, []string{"gitrowstest: PRELOAD 2 keys", "update a", "gitrows: DELETE"}, db.CommitMessages())
+
+	commits := db.Commits()
+	require.Len(t, commits, 3)
+	assert.Equal(t, gitrowstest.OpPreload, commits[0].Op)
+	assert.Equal(t, []string{"a.txt", "b/c.txt"}, commits[0].Keys)
+	assert.Equal(t, gitrows.OpUpsert, commits[1].Op)
+	assert.Equal(t, map[string]string{"Request-Id": "42"}, commits[1].Meta)
+	assert.Equal(t, []string{"b/c.txt"}, commits[2].Keys)
+
+	_, err = db.Preload(map[string][]byte{"a.txt/d": []byte("d")})
+	assert.Error(t, err)
+	assert.Len(t, db.Commits(), 3)
+}
@@ -33,7 +33,7 @@ func Parse(str string) (remoteRepo string, err error) {
 	}
 
 	// https://github.com/golang/go/blob/88a06f40dfcdc4d37346be169f2b1b9070f38bb3/src/cmd/go/internal/vcs/vcs.go#L243
-	vcsGitScheme := []string{"git", "https", "http", "git+ssh", "ssh"}
+	vcsGitScheme := []string{"git", "https", "http", "git+ssh", "ssh", "file"}
 
 	// Iterate over insecure schemes too, because this function simply
 	// reports the state of the repo. If we can't see insecure schemes then
@@ -21,6 +21,10 @@ func TestParse(t *testing.T) {
 			path:       "ssh://git@github.com/yusufsyaifudin/common-dev-config.git",
 			remoteRepo: "ssh://git@github.com/yusufsyaifudin/common-dev-config.git",
 		},
+		{
+			path:       "file:///tmp/gitrows/common-dev-config.git",
+			remoteRepo: "file:///tmp/gitrows/common-dev-config.git",
+		},
 	}
 
 	for _, test := range tests {
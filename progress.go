@@ -0,0 +1,122 @@
+package gitrows
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyncProgress is the structured form of the progress messages sent by the remote repository
+// (git sideband channel) during clone, fetch and push.
+//
+// Please note that go-git doesn't expose the number of bytes received,
+// so only the phases reported by the remote are available.
+type SyncProgress struct {
+	// Phase is the lower-cased first word of the progress message,
+	// i.e: "enumerating", "counting", "compressing", "total", "resolving".
+	Phase string
+
+	// Percent, Current and Total are only filled when the message contains them,
+	// i.e: "Counting objects:  50% (1/2)" has Percent=50, Current=1 and Total=2.
+	Percent int
+	Current int64
+	Total   int64
+
+	// Message is the raw progress message without trailing newline.
+	Message string
+}
+
+// WithCloneProgress set the callback which receives the progress of the remote operation (clone, fetch, push).
+// This can be used to expose readiness probe such as "cloning, 62%", instead of printing it into os.Stdout.
+func WithCloneProgress(fn func(p SyncProgress)) Opt {
+	return func(db *DBImpl) error {
+		db.onProgress = fn
+		return nil
+	}
+}
+
+// WithOnSyncStart set the callback which is called every time gitrows start to sync with the remote repository.
+func WithOnSyncStart(fn func()) Opt {
+	return func(db *DBImpl) error {
+		db.onSyncStart = fn
+		return nil
+	}
+}
+
+// WithOnSyncEnd set the callback which is called every time gitrows done syncing with the remote repository,
+// along with the duration of sync process and its error (nil when succeed).
+func WithOnSyncEnd(fn func(elapsed time.Duration, err error)) Opt {
+	return func(db *DBImpl) error {
+		db.onSyncEnd = fn
+		return nil
+	}
+}
+
+// progressWriter returns the writer used as git Progress option.
+func (db *DBImpl) progressWriter() io.Writer {
+	if db.onProgress == nil {
+		return db.progress
+	}
+
+	return &progressParser{fn: db.onProgress}
+}
+
+// progressLineRe matches message like "Counting objects:  50% (1/2)" or "Compressing objects: 100% (2/2), done."
+var progressLineRe = regexp.MustCompile(`^([^:]+):\s+(\d+)% \((\d+)/(\d+)\)`)
+
+// progressParser parses the git sideband progress and calls fn for each message.
+// Messages are separated by either '\r' (updated in-place) or '\n'.
+type progressParser struct {
+	fn  func(p SyncProgress)
+	buf bytes.Buffer
+}
+
+var _ io.Writer = (*progressParser)(nil)
+
+func (p *progressParser) Write(b []byte) (n int, err error) {
+	n, err = p.buf.Write(b)
+	if err != nil {
+		return
+	}
+
+	for {
+		data := p.buf.Bytes()
+		idx := bytes.IndexAny(data, "\r\n")
+		if idx < 0 {
+			return
+		}
+
+		line := string(data[:idx])
+		p.buf.Next(idx + 1)
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		p.fn(parseProgress(line))
+	}
+}
+
+func parseProgress(line string) SyncProgress {
+	progress := SyncProgress{
+		Message: line,
+	}
+
+	if fields := strings.Fields(line); len(fields) > 0 {
+		progress.Phase = strings.ToLower(strings.TrimSuffix(fields[0], ":"))
+	}
+
+	m := progressLineRe.FindStringSubmatch(line)
+	if len(m) < 5 {
+		return progress
+	}
+
+	progress.Percent, _ = strconv.Atoi(m[2])
+	progress.Current, _ = strconv.ParseInt(m[3], 10, 64)
+	progress.Total, _ = strconv.ParseInt(m[4], 10, 64)
+	return progress
+}
@@ -0,0 +1,36 @@
+package gitrows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressParser(t *testing.T) {
+	progress := make([]SyncProgress, 0)
+	parser := &progressParser{fn: func(p SyncProgress) {
+		progress = append(progress, p)
+	}}
+
+	// the message may be split in arbitrary chunks
+	chunks := []string{
+		"Enumerating objects: 3, done.\n",
+		"Counting objects:  50% (1/2)\rCounting obj",
+		"ects: 100% (2/2), done.\n",
+		"Total 3 (delta 0), reused 0 (delta 0)\n",
+		"Compressing objects:  33% (1/3)",
+	}
+
+	for _, chunk := range chunks {
+		n, err := parser.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+
+	assert.Equal(t, []SyncProgress{
+		{Phase: "enumerating", Message: "Enumerating objects: 3, done."},
+		{Phase: "counting", Percent: 50, Current: 1, Total: 2, Message: "Counting objects:  50% (1/2)"},
+		{Phase: "counting", Percent: 100, Current: 2, Total: 2, Message: "Counting objects: 100% (2/2), done."},
+		{Phase: "total", Message: "Total 3 (delta 0), reused 0 (delta 0)"},
+	}, progress)
+}